@@ -0,0 +1,132 @@
+package apierror
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Code is a machine-readable error code, stable across languages
+type Code string
+
+// FieldError describes a problem with a single field of the request
+type FieldError struct {
+	Field   string            `json:"field"`
+	Code    Code              `json:"code"`
+	Message string            `json:"message"`
+	Params  map[string]string `json:"-"`
+}
+
+func (fe FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", fe.Field, Message(fe.Code, DefaultLanguage, fe.Params))
+}
+
+// FieldErrors groups every field error found while validating a request
+type FieldErrors []FieldError
+
+func (fe FieldErrors) Error() string {
+	messages := make([]string, 0, len(fe))
+	for _, e := range fe {
+		messages = append(messages, e.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Problem is an RFC 7807 problem details response
+type Problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Code     Code              `json:"code"`
+	Errors   []FieldError      `json:"errors,omitempty"`
+	Params   map[string]string `json:"-"`
+}
+
+func (p *Problem) Error() string {
+	return fmt.Sprintf("%d %s", p.Status, Message(p.Code, DefaultLanguage, p.Params))
+}
+
+// Field builds a field error. params are key/value pairs used in the message.
+func Field(field string, code Code, params ...string) FieldError {
+	return FieldError{Field: field, Code: code, Params: pairs(params)}
+}
+
+// New builds a problem with the given status and code. params are key/value
+// pairs used in the localized detail message.
+func New(status int, code Code, params ...string) *Problem {
+	return &Problem{Status: status, Code: code, Params: pairs(params)}
+}
+
+// Validation builds a 400 problem listing every invalid field
+func Validation(errs ...FieldError) *Problem {
+	return &Problem{Status: http.StatusBadRequest, Code: ValidationFailed, Errors: errs}
+}
+
+// NotFound builds a 404 problem for a resource identified by a route parameter
+func NotFound(field string, code Code, params ...string) *Problem {
+	return &Problem{Status: http.StatusNotFound, Code: code, Errors: []FieldError{Field(field, code, params...)}}
+}
+
+// Conflict builds a 409 problem for a field that conflicts with existing records
+func Conflict(field string, code Code, params ...string) *Problem {
+	return &Problem{Status: http.StatusConflict, Code: code, Errors: []FieldError{Field(field, code, params...)}}
+}
+
+// Internal builds a 500 problem. The code tells which operation failed.
+func Internal(code Code) *Problem {
+	return &Problem{Status: http.StatusInternalServerError, Code: code}
+}
+
+// From converts an error returned by a validation helper into a problem
+func From(err error) *Problem {
+	switch e := err.(type) {
+	case *Problem:
+		return e
+	case FieldErrors:
+		return Validation(e...)
+	case FieldError:
+		return Validation(e)
+	}
+	return Internal(InternalError)
+}
+
+// Write localizes the problem for the request and writes it as application/problem+json
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	lang := Language(r)
+
+	response := *p
+	response.Type = "urn:lavanderia:problem:" + string(p.Code)
+	response.Title = Title(p.Code, p.Status, lang)
+	response.Detail = Message(p.Code, lang, p.Params)
+	if r != nil && r.URL != nil {
+		response.Instance = r.URL.Path
+	}
+
+	response.Errors = make([]FieldError, len(p.Errors))
+	for i, fe := range p.Errors {
+		fe.Message = Message(fe.Code, lang, fe.Params)
+		response.Errors[i] = fe
+	}
+	if len(response.Errors) == 0 {
+		response.Errors = nil
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("Content-Language", string(lang))
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(response)
+}
+
+func pairs(params []string) map[string]string {
+	if len(params) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(params)/2)
+	for i := 0; i+1 < len(params); i += 2 {
+		m[params[i]] = params[i+1]
+	}
+	return m
+}
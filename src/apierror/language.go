@@ -0,0 +1,68 @@
+package apierror
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lang is a supported response language
+type Lang string
+
+const (
+	// PtBR is Brazilian Portuguese
+	PtBR Lang = "pt-BR"
+	// En is English
+	En Lang = "en"
+)
+
+// DefaultLanguage is used when the client does not ask for a supported language
+const DefaultLanguage = PtBR
+
+// Language picks the response language from the Accept-Language header
+func Language(r *http.Request) Lang {
+	if r == nil {
+		return DefaultLanguage
+	}
+
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		candidates = append(candidates, candidate{tag: tag, q: q})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if c.q <= 0 {
+			continue
+		}
+		switch {
+		case c.tag == "pt" || strings.HasPrefix(c.tag, "pt-"):
+			return PtBR
+		case c.tag == "en" || strings.HasPrefix(c.tag, "en-"):
+			return En
+		}
+	}
+
+	return DefaultLanguage
+}
@@ -0,0 +1,299 @@
+package apierror
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Problem codes, describing the whole response
+const (
+	ValidationFailed   Code = "validation_failed"
+	InvalidPayload     Code = "invalid_payload"
+	InternalError      Code = "internal_error"
+	DatabaseError      Code = "database_error"
+	Unauthorized       Code = "unauthorized"
+	InvalidToken       Code = "invalid_token"
+	Forbidden          Code = "forbidden"
+	InvalidCredentials Code = "invalid_credentials"
+	MethodNotAllowed   Code = "method_not_allowed"
+	TooManyRequests    Code = "too_many_requests"
+)
+
+// Field codes, describing a single invalid field
+const (
+	Required              Code = "required"
+	InvalidUUID           Code = "invalid_uuid"
+	MustBePositive        Code = "must_be_positive"
+	MaxLength             Code = "max_length"
+	OutOfRange            Code = "out_of_range"
+	OneOf                 Code = "one_of"
+	AlreadyExists         Code = "already_exists"
+	InUse                 Code = "in_use"
+	LineHasIncidents      Code = "line_has_incidents"
+	InvalidBoolean        Code = "invalid_boolean"
+	InvalidDate           Code = "invalid_date"
+	InvalidTime           Code = "invalid_time"
+	InvalidCEP            Code = "invalid_cep"
+	InvalidUF             Code = "invalid_uf"
+	InvalidPhone          Code = "invalid_phone"
+	InvalidCPF            Code = "invalid_cpf"
+	InvalidEmail          Code = "invalid_email"
+	InvalidURL            Code = "invalid_url"
+	DateInPast            Code = "date_in_past"
+	DateTooFar            Code = "date_too_far"
+	BeforeCreatedAt       Code = "before_created_at"
+	ServiceNotFound       Code = "service_not_found"
+	ClientNotFound        Code = "client_not_found"
+	ItemNotFound          Code = "item_not_found"
+	ItemServiceNotFound   Code = "item_service_not_found"
+	ClientNotMonthly      Code = "client_not_monthly"
+	InvalidSortField      Code = "invalid_sort_field"
+	InvalidCursor         Code = "invalid_cursor"
+	CursorWithPage        Code = "cursor_with_page"
+	CursorMixedDirections Code = "cursor_mixed_directions"
+	EndBeforeStart        Code = "end_before_start"
+	TimeSlotNotFound      Code = "time_slot_not_found"
+	TimeSlotInactive      Code = "time_slot_inactive"
+	TimeSlotFull          Code = "time_slot_full"
+	BookingNotFound       Code = "booking_not_found"
+	AlreadyBooked         Code = "already_booked"
+	DriverNotFound        Code = "driver_not_found"
+	InvalidTransition     Code = "invalid_transition"
+	ShopLocationNotSet    Code = "shop_location_not_set"
+	ClientAddressNotFound Code = "client_address_not_found"
+	DefaultAddress        Code = "default_address"
+	CEPNotFound           Code = "cep_not_found"
+	CEPMismatch           Code = "cep_mismatch"
+	WebhookNotFound       Code = "webhook_not_found"
+	DeliveryNotFound      Code = "delivery_not_found"
+	TrackingCodeNotFound  Code = "tracking_code_not_found"
+	ShopClosed            Code = "shop_closed"
+	BusinessDaysTooFar    Code = "business_days_too_far"
+	HolidayNotFound       Code = "holiday_not_found"
+	ClosureNotFound       Code = "closure_not_found"
+	CapacityExceeded      Code = "capacity_exceeded"
+	SameDayCutoffPassed   Code = "same_day_cutoff_passed"
+	SameDayNotToday       Code = "same_day_not_today"
+	MachineNotFound       Code = "machine_not_found"
+	MachineInactive       Code = "machine_inactive"
+	MachineBusy           Code = "machine_busy"
+	LoadNotFound          Code = "load_not_found"
+	LoadFinished          Code = "load_finished"
+	LoadTooHeavy          Code = "load_too_heavy"
+	OperatorNotFound      Code = "operator_not_found"
+	ServiceFinished       Code = "service_finished"
+	SupplyNotFound        Code = "supply_not_found"
+	InsufficientStock     Code = "insufficient_stock"
+	IncidentNotFound      Code = "incident_not_found"
+	IncidentClosed        Code = "incident_closed"
+	AttachmentNotFound    Code = "attachment_not_found"
+	FileTooLarge          Code = "file_too_large"
+	UnsupportedFileType   Code = "unsupported_file_type"
+	InvalidImage          Code = "invalid_image"
+	InsufficientBalance   Code = "insufficient_balance"
+	ServiceAlreadyPaid    Code = "service_already_paid"
+	PaidServiceChanged    Code = "paid_service_changed"
+	LoyaltyInactive       Code = "loyalty_inactive"
+	BelowMinRedemption    Code = "below_min_redemption"
+	DiscountTooHigh       Code = "discount_too_high"
+	InsufficientPoints    Code = "insufficient_points"
+	CashSessionNotFound   Code = "cash_session_not_found"
+	CashSessionOpen       Code = "cash_session_open"
+	CashSessionClosed     Code = "cash_session_closed"
+	InsufficientCash      Code = "insufficient_cash"
+	CashDrawerClosed      Code = "cash_drawer_closed"
+	PaymentShort          Code = "payment_short"
+	ServiceNotPaid        Code = "service_not_paid"
+	NothingToInvoice      Code = "nothing_to_invoice"
+	InvoiceAlreadyIssued  Code = "invoice_already_issued"
+	InvoiceProcessing     Code = "invoice_processing"
+	InvoiceRejected       Code = "invoice_rejected"
+	InvoiceNotFound       Code = "invoice_not_found"
+	InvoicingDisabled     Code = "invoicing_disabled"
+	InvoiceProviderError  Code = "invoice_provider_error"
+)
+
+// titles are the short, stable summaries of each problem code
+var titles = map[Code]map[Lang]string{
+	ValidationFailed:   {PtBR: "Falha na validação", En: "Validation failed"},
+	InvalidPayload:     {PtBR: "Corpo da requisição inválido", En: "Invalid request payload"},
+	InternalError:      {PtBR: "Erro interno", En: "Internal error"},
+	DatabaseError:      {PtBR: "Erro no banco de dados", En: "Database error"},
+	Unauthorized:       {PtBR: "Autenticação necessária", En: "Authentication required"},
+	InvalidToken:       {PtBR: "Token de autenticação inválido", En: "Invalid authentication token"},
+	Forbidden:          {PtBR: "Acesso negado", En: "Forbidden"},
+	InvalidCredentials: {PtBR: "Credenciais inválidas", En: "Invalid credentials"},
+	MethodNotAllowed:   {PtBR: "Método não permitido", En: "Method not allowed"},
+	TooManyRequests:    {PtBR: "Muitas requisições", En: "Too many requests"},
+}
+
+// messages are the human readable explanations of each code. Parameters
+// are written as {name} and replaced by the values given when the error
+// was built.
+var messages = map[Code]map[Lang]string{
+	ValidationFailed:   {PtBR: "Um ou mais campos são inválidos.", En: "One or more fields are invalid."},
+	InvalidPayload:     {PtBR: "O corpo da requisição não é um JSON válido.", En: "The request body is not valid JSON."},
+	InternalError:      {PtBR: "Ocorreu um erro inesperado.", En: "An unexpected error occurred."},
+	DatabaseError:      {PtBR: "Não foi possível concluir a operação no banco de dados.", En: "The database operation could not be completed."},
+	Unauthorized:       {PtBR: "Nenhum cookie de autenticação foi enviado.", En: "No authentication cookie was sent."},
+	InvalidToken:       {PtBR: "O token de autenticação é inválido ou expirou.", En: "The authentication token is invalid or expired."},
+	Forbidden:          {PtBR: "Seu perfil não tem acesso a este recurso.", En: "Your role cannot access this resource."},
+	InvalidCredentials: {PtBR: "Usuário ou senha inválidos.", En: "Invalid username or password."},
+	MethodNotAllowed:   {PtBR: "Método não permitido para este recurso.", En: "Method not allowed for this resource."},
+	TooManyRequests:    {PtBR: "Limite de requisições atingido. Tente novamente em {seconds} segundos.", En: "Rate limit reached. Try again in {seconds} seconds."},
+
+	Required:              {PtBR: "Campo obrigatório.", En: "This field is required."},
+	InvalidUUID:           {PtBR: "Deve ser um UUID válido.", En: "Must be a valid UUID."},
+	MustBePositive:        {PtBR: "Deve ser um número positivo.", En: "Must be a positive number."},
+	MaxLength:             {PtBR: "Deve ter no máximo {max} caracteres.", En: "Must have at most {max} characters."},
+	OutOfRange:            {PtBR: "Deve estar entre {min} e {max}.", En: "Must be between {min} and {max}."},
+	OneOf:                 {PtBR: "Deve ser um destes valores: {values}.", En: "Must be one of: {values}."},
+	AlreadyExists:         {PtBR: "Já existe um registro com este valor.", En: "A record with this value already exists."},
+	InUse:                 {PtBR: "Não pode ser removido pois é referenciado por outros registros.", En: "Cannot be deleted because it is referenced by other records."},
+	LineHasIncidents:      {PtBR: "O item não pode ser removido pois tem os incidentes {ids}.", En: "The item can't be removed because it has incidents {ids}."},
+	InvalidBoolean:        {PtBR: "Deve ser true ou false.", En: "Must be true or false."},
+	InvalidDate:           {PtBR: "Deve ser uma data (AAAA-MM-DD) ou data e hora RFC 3339.", En: "Must be a date (YYYY-MM-DD) or an RFC 3339 timestamp."},
+	InvalidTime:           {PtBR: "Deve ser um horário no formato HH:MM.", En: "Must be a time of day as HH:MM."},
+	InvalidCEP:            {PtBR: "Deve ser um CEP com 8 dígitos (00000-000).", En: "Must be an 8-digit CEP (00000-000)."},
+	InvalidUF:             {PtBR: "Deve ser a sigla de um estado brasileiro (UF).", En: "Must be a Brazilian state abbreviation (UF)."},
+	InvalidPhone:          {PtBR: "Deve ser um telefone com DDD, fixo ou celular.", En: "Must be a landline or mobile number with area code."},
+	InvalidCPF:            {PtBR: "CPF inválido.", En: "Invalid CPF."},
+	InvalidEmail:          {PtBR: "Deve ser um e-mail válido.", En: "Must be a valid e-mail address."},
+	InvalidURL:            {PtBR: "Deve ser uma URL http ou https completa.", En: "Must be an absolute http or https URL."},
+	DateInPast:            {PtBR: "A data {date} está no passado.", En: "The date {date} is in the past."},
+	DateTooFar:            {PtBR: "A data {date} ultrapassa o limite de {days} dias.", En: "The date {date} is beyond the limit of {days} days."},
+	BeforeCreatedAt:       {PtBR: "Não pode ser anterior à data de criação do serviço.", En: "Cannot be before the service creation date."},
+	ServiceNotFound:       {PtBR: "Serviço {id} não encontrado.", En: "Service {id} not found."},
+	ClientNotFound:        {PtBR: "Cliente {id} não encontrado.", En: "Client {id} not found."},
+	ItemNotFound:          {PtBR: "Item {id} não encontrado.", En: "Item {id} not found."},
+	ItemServiceNotFound:   {PtBR: "O item {id} não faz parte deste serviço.", En: "Item {id} is not part of this service."},
+	ClientNotMonthly:      {PtBR: "O cliente não está cadastrado como mensal.", En: "The client does not have a monthly plan."},
+	InvalidSortField:      {PtBR: "Não é possível ordenar por '{value}'.", En: "Cannot sort by '{value}'."},
+	InvalidCursor:         {PtBR: "Cursor inválido.", En: "Invalid cursor."},
+	CursorWithPage:        {PtBR: "Cursor não pode ser combinado com page.", En: "Cursor cannot be combined with page."},
+	CursorMixedDirections: {PtBR: "A paginação por cursor exige todos os campos de ordenação na mesma direção.", En: "Cursor pagination requires every sort field in the same direction."},
+	EndBeforeStart:        {PtBR: "Deve ser posterior ao início.", En: "Must be after the start."},
+	TimeSlotNotFound:      {PtBR: "Faixa de horário {id} não encontrada.", En: "Time slot {id} not found."},
+	TimeSlotInactive:      {PtBR: "A faixa de horário {id} está desativada.", En: "Time slot {id} is disabled."},
+	TimeSlotFull:          {PtBR: "A faixa de horário não tem vagas em {date}.", En: "The time slot is fully booked on {date}."},
+	BookingNotFound:       {PtBR: "Agendamento {id} não encontrado.", En: "Booking {id} not found."},
+	AlreadyBooked:         {PtBR: "O serviço já tem um agendamento de {type} em andamento ou concluído.", En: "The service already has a {type} in progress or done."},
+	DriverNotFound:        {PtBR: "Motorista {id} não encontrado.", En: "Driver {id} not found."},
+	InvalidTransition:     {PtBR: "Não é possível mudar de {from} para {to}.", En: "Cannot change from {from} to {to}."},
+	ShopLocationNotSet:    {PtBR: "A localização da lavanderia não está configurada.", En: "The shop location is not configured."},
+	ClientAddressNotFound: {PtBR: "Endereço {id} não encontrado para este cliente.", En: "Address {id} not found for this client."},
+	DefaultAddress:        {PtBR: "O endereço padrão não pode ser removido. Defina outro endereço como padrão antes.", En: "The default address cannot be deleted. Make another address the default first."},
+	CEPNotFound:           {PtBR: "CEP {cep} não encontrado.", En: "CEP {cep} not found."},
+	CEPMismatch:           {PtBR: "Não corresponde ao CEP {cep}, que indica {expected}.", En: "Does not match CEP {cep}, which points to {expected}."},
+	WebhookNotFound:       {PtBR: "Webhook {id} não encontrado.", En: "Webhook {id} not found."},
+	DeliveryNotFound:      {PtBR: "Entrega {id} não encontrada para este webhook.", En: "Delivery {id} not found for this webhook."},
+	TrackingCodeNotFound:  {PtBR: "Nenhum pedido com o código {code}.", En: "No order with code {code}."},
+	ShopClosed:            {PtBR: "A lavanderia não abre em {date} ({reason}).", En: "The shop is closed on {date} ({reason})."},
+	BusinessDaysTooFar:    {PtBR: "A data {date} ultrapassa o limite de {days} dias úteis.", En: "The date {date} is beyond the limit of {days} business days."},
+	HolidayNotFound:       {PtBR: "Feriado {id} não encontrado.", En: "Holiday {id} not found."},
+	ClosureNotFound:       {PtBR: "Fechamento {id} não encontrado.", En: "Closure {id} not found."},
+	CapacityExceeded:      {PtBR: "A capacidade de produção de {date} está esgotada.", En: "The production capacity of {date} is exhausted."},
+	SameDayCutoffPassed:   {PtBR: "Pedidos para o mesmo dia são aceitos até {cutoff}.", En: "Same-day orders are accepted until {cutoff}."},
+	SameDayNotToday:       {PtBR: "Pedidos para o mesmo dia ficam prontos hoje.", En: "Same-day orders are due today."},
+	MachineNotFound:       {PtBR: "Máquina {id} não encontrada.", En: "Machine {id} not found."},
+	MachineInactive:       {PtBR: "A máquina está desativada.", En: "The machine is disabled."},
+	MachineBusy:           {PtBR: "A máquina já está com a carga {id} em andamento.", En: "The machine is already running load {id}."},
+	LoadNotFound:          {PtBR: "Carga {id} não encontrada.", En: "Load {id} not found."},
+	LoadFinished:          {PtBR: "A carga já foi finalizada.", En: "The load has already finished."},
+	LoadTooHeavy:          {PtBR: "A carga ultrapassa a capacidade de {capacity} kg da máquina.", En: "The load exceeds the machine capacity of {capacity} kg."},
+	OperatorNotFound:      {PtBR: "Operador {id} não encontrado.", En: "Operator {id} not found."},
+	ServiceFinished:       {PtBR: "O serviço {id} já foi finalizado.", En: "Service {id} has already finished."},
+	SupplyNotFound:        {PtBR: "Insumo {id} não encontrado.", En: "Supply {id} not found."},
+	InsufficientStock:     {PtBR: "O estoque tem apenas {stock} {unit}.", En: "Only {stock} {unit} in stock."},
+	IncidentNotFound:      {PtBR: "Ocorrência {id} não encontrada.", En: "Incident {id} not found."},
+	IncidentClosed:        {PtBR: "A ocorrência já foi encerrada como {status}.", En: "The incident was already closed as {status}."},
+	AttachmentNotFound:    {PtBR: "Anexo {id} não encontrado.", En: "Attachment {id} not found."},
+	FileTooLarge:          {PtBR: "O arquivo deve ter no máximo {max}.", En: "The file must be at most {max}."},
+	UnsupportedFileType:   {PtBR: "Tipo de arquivo não aceito. Use: {types}.", En: "Unsupported file type. Use: {types}."},
+	InvalidImage:          {PtBR: "O arquivo não é uma imagem válida.", En: "The file is not a valid image."},
+	InsufficientBalance:   {PtBR: "O saldo da carteira é de apenas R$ {balance}.", En: "The wallet balance is only R$ {balance}."},
+	ServiceAlreadyPaid:    {PtBR: "O serviço {id} já está pago.", En: "Service {id} is already paid."},
+	PaidServiceChanged:    {PtBR: "O serviço {id} já foi pago; o pagamento e o preço não podem ser alterados.", En: "Service {id} is already paid; its payment and price can't be changed."},
+	LoyaltyInactive:       {PtBR: "O programa de fidelidade está desativado.", En: "The loyalty program is turned off."},
+	BelowMinRedemption:    {PtBR: "Resgate pelo menos {min} pontos.", En: "Redeem at least {min} points."},
+	DiscountTooHigh:       {PtBR: "Os pontos pagam no máximo {max}% do serviço, R$ {amount}.", En: "Points pay at most {max}% of the service, R$ {amount}."},
+	InsufficientPoints:    {PtBR: "O cliente tem apenas {points} pontos.", En: "The client only has {points} points."},
+	CashSessionNotFound:   {PtBR: "Sessão de caixa {id} não encontrada.", En: "Cash session {id} not found."},
+	CashSessionOpen:       {PtBR: "O caixa já está aberto na sessão {id}.", En: "The cash drawer is already open in session {id}."},
+	CashSessionClosed:     {PtBR: "A sessão de caixa {id} já foi fechada.", En: "Cash session {id} is already closed."},
+	InsufficientCash:      {PtBR: "O caixa tem apenas R$ {cash} em dinheiro.", En: "The drawer only holds R$ {cash} in cash."},
+	CashDrawerClosed:      {PtBR: "O caixa está fechado.", En: "The cash drawer is closed."},
+	PaymentShort:          {PtBR: "O pagamento deve cobrir o total do serviço, R$ {total}.", En: "The payment must cover the service total, R$ {total}."},
+	ServiceNotPaid:        {PtBR: "O serviço {id} ainda não foi pago.", En: "Service {id} hasn't been paid yet."},
+	NothingToInvoice:      {PtBR: "O serviço {id} não tem valor a faturar.", En: "Service {id} has no amount to invoice."},
+	InvoiceAlreadyIssued:  {PtBR: "A NFS-e {number} já foi emitida para o serviço.", En: "NFS-e {number} was already issued for the service."},
+	InvoiceProcessing:     {PtBR: "O RPS {rps} do serviço já está sendo enviado à prefeitura.", En: "RPS {rps} of the service is already being sent to the city."},
+	InvoiceRejected:       {PtBR: "A prefeitura rejeitou o RPS {rps}: {reason}", En: "The city rejected RPS {rps}: {reason}"},
+	InvoiceNotFound:       {PtBR: "Nenhuma NFS-e emitida para o serviço {id}.", En: "No NFS-e issued for service {id}."},
+	InvoicingDisabled:     {PtBR: "A emissão de NFS-e não está configurada.", En: "NFS-e issuing isn't configured."},
+	InvoiceProviderError:  {PtBR: "O provedor de NFS-e não respondeu. Tente novamente.", En: "The NFS-e provider didn't answer. Try again."},
+}
+
+// statusTitles are the titles of the codes without their own, by the
+// status of the problem. Messages can't stand in for them as they hold
+// {placeholders}.
+var statusTitles = map[int]map[Lang]string{
+	http.StatusBadRequest:          {PtBR: "Requisição inválida", En: "Bad request"},
+	http.StatusUnauthorized:        {PtBR: "Autenticação necessária", En: "Authentication required"},
+	http.StatusForbidden:           {PtBR: "Acesso negado", En: "Forbidden"},
+	http.StatusNotFound:            {PtBR: "Não encontrado", En: "Not found"},
+	http.StatusMethodNotAllowed:    {PtBR: "Método não permitido", En: "Method not allowed"},
+	http.StatusConflict:            {PtBR: "Conflito", En: "Conflict"},
+	http.StatusUnprocessableEntity: {PtBR: "Requisição não processável", En: "Unprocessable request"},
+	http.StatusTooManyRequests:     {PtBR: "Muitas requisições", En: "Too many requests"},
+	http.StatusInternalServerError: {PtBR: "Erro interno", En: "Internal error"},
+	http.StatusBadGateway:          {PtBR: "Falha no serviço externo", En: "Bad gateway"},
+	http.StatusServiceUnavailable:  {PtBR: "Serviço indisponível", En: "Service unavailable"},
+}
+
+// Title returns the localized title of a problem code, or the title of
+// its status when the code has none
+func Title(code Code, status int, lang Lang) string {
+	if t, ok := titles[code][lang]; ok {
+		return t
+	}
+	if t, ok := statusTitles[status][lang]; ok {
+		return t
+	}
+	if t, ok := statusTitles[status][DefaultLanguage]; ok {
+		return t
+	}
+	return http.StatusText(status)
+}
+
+// Codes lists every code with a message, sorted
+func Codes() []Code {
+	codes := make([]Code, 0, len(messages))
+	for code := range messages {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// Message returns the localized message of a code with its parameters applied
+func Message(code Code, lang Lang, params map[string]string) string {
+	translations, ok := messages[code]
+	if !ok {
+		return string(code)
+	}
+	message, ok := translations[lang]
+	if !ok {
+		message = translations[DefaultLanguage]
+	}
+
+	if len(params) == 0 {
+		return message
+	}
+	replacements := make([]string, 0, len(params)*2)
+	for key, value := range params {
+		replacements = append(replacements, "{"+key+"}", value)
+	}
+	return strings.NewReplacer(replacements...).Replace(message)
+}
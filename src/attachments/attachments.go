@@ -0,0 +1,113 @@
+// Package attachments keeps the photos of the garments of a service, taken
+// when they are dropped off and when they are delivered, so damage
+// disputes can be settled on what the garment looked like.
+package attachments
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png" // decodes the PNG photos
+	"io"
+	"net/http"
+)
+
+// Stages a photo is taken at
+const (
+	Intake   = "intake"
+	Delivery = "delivery"
+)
+
+// Stages lists the stages a photo is taken at
+var Stages = []string{Intake, Delivery}
+
+// MaxSize is the largest photo accepted, in bytes
+const MaxSize = 10 << 20
+
+// ThumbnailSize is the longest side of the thumbnails, in pixels
+const ThumbnailSize = 320
+
+// Columns selects an attachment
+const Columns = `id, laundry_service_id, stage, filename, content_type, size, width, height, storage_key, thumbnail_key, notes, uploaded_by, created_at`
+
+// extensions are the accepted content types, with the extension of their
+// files
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// ContentTypes lists the accepted content types
+var ContentTypes = []string{"image/jpeg", "image/png"}
+
+// Detect sniffs the content type of a file from its first bytes, ignoring
+// what the client claims, and returns the extension of its files. ok is
+// false when the type isn't accepted.
+func Detect(head []byte) (contentType, extension string, ok bool) {
+	contentType = http.DetectContentType(head)
+	extension, ok = extensions[contentType]
+	return contentType, extension, ok
+}
+
+// Thumbnail decodes the photo and returns its size with a JPEG thumbnail
+// whose longest side is ThumbnailSize. Smaller photos keep their size.
+func Thumbnail(r io.Reader) (thumbnail []byte, width, height int, err error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	bounds := img.Bounds()
+	width, height = bounds.Dx(), bounds.Dy()
+
+	w, h := fit(width, height, ThumbnailSize)
+	var buf bytes.Buffer
+	err = jpeg.Encode(&buf, scale(img, w, h), &jpeg.Options{Quality: 80})
+	return buf.Bytes(), width, height, err
+}
+
+// fit returns the size of a width × height image scaled down to fit a
+// square of side max, keeping its proportions
+func fit(width, height, max int) (int, int) {
+	if width <= max && height <= max {
+		return width, height
+	}
+	if width >= height {
+		return max, maxInt(1, height*max/width)
+	}
+	return maxInt(1, width*max/height), max
+}
+
+// samples is how many source pixels are averaged, per side, for each
+// pixel of the thumbnail
+const samples = 4
+
+// scale resizes img to w × h averaging a grid of samples over the area of
+// each pixel, which is enough for thumbnails and keeps to the standard
+// library
+func scale(img image.Image, w, h int) image.Image {
+	src := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var r, g, b, a, n uint32
+			for sy := 0; sy < samples; sy++ {
+				for sx := 0; sx < samples; sx++ {
+					px := src.Min.X + ((2*x*samples+2*sx+1)*src.Dx())/(2*w*samples)
+					py := src.Min.Y + ((2*y*samples+2*sy+1)*src.Dy())/(2*h*samples)
+					cr, cg, cb, ca := img.At(px, py).RGBA()
+					r, g, b, a, n = r+cr, g+cg, b+cb, a+ca, n+1
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,285 @@
+// Package calendar is the business calendar of the laundry: the weekly
+// opening hours, the holidays and the ad-hoc closures. Due dates and
+// turnaround times are counted on it instead of on wall-clock days.
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"lavanderia/entities"
+)
+
+const dateLayout = "2006-01-02"
+
+// maxDays bounds the walks over the calendar, so a calendar without any
+// working day doesn't loop forever
+const maxDays = 3660
+
+// Shift is the opening and closing time of a weekday, as offsets from
+// midnight
+type Shift struct {
+	Open  time.Duration
+	Close time.Duration
+}
+
+// DefaultWeek opens from 8:00 to 18:00 on weekdays and from 8:00 to 13:00
+// on Saturdays. It is used until the opening hours are configured.
+var DefaultWeek = map[time.Weekday]Shift{
+	time.Monday:    {Open: 8 * time.Hour, Close: 18 * time.Hour},
+	time.Tuesday:   {Open: 8 * time.Hour, Close: 18 * time.Hour},
+	time.Wednesday: {Open: 8 * time.Hour, Close: 18 * time.Hour},
+	time.Thursday:  {Open: 8 * time.Hour, Close: 18 * time.Hour},
+	time.Friday:    {Open: 8 * time.Hour, Close: 18 * time.Hour},
+	time.Saturday:  {Open: 8 * time.Hour, Close: 13 * time.Hour},
+}
+
+// weekdayNames name the weekdays when telling why a day is closed
+var weekdayNames = [...]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"}
+
+// Calendar tells when the shop is open. Besides the registered holidays,
+// the national holidays are always closed.
+type Calendar struct {
+	week     map[time.Weekday]Shift
+	custom   []entities.HolidayEntity
+	closures []entities.ClosureEntity
+
+	mu       sync.Mutex
+	holidays map[string]entities.HolidayEntity // by date, for the years in years
+	years    map[int]bool
+}
+
+// New returns the calendar with the opening hours of week, the registered
+// holidays and the closures
+func New(week map[time.Weekday]Shift, holidays []entities.HolidayEntity, closures []entities.ClosureEntity) *Calendar {
+	return &Calendar{week: week, custom: holidays, closures: closures}
+}
+
+// Default returns the calendar of DefaultWeek with the national holidays only
+func Default() *Calendar {
+	return New(DefaultWeek, nil, nil)
+}
+
+// Period is a time the shop is open within a day, as HH:MM
+type Period struct {
+	OpensAt  string `json:"opens_at"`
+	ClosesAt string `json:"closes_at"`
+}
+
+// Day tells whether the shop opens on a date and when
+type Day struct {
+	Date   string   `json:"date"`
+	IsOpen bool     `json:"is_open"`
+	Hours  []Period `json:"hours"`
+	Reason string   `json:"reason,omitempty"`
+}
+
+type span struct{ start, end time.Time }
+
+// Holidays returns the holidays of year, national and registered, by date
+func (c *Calendar) Holidays(year int) []entities.HolidayEntity {
+	prefix := fmt.Sprintf("%04d-", year)
+	holidays := NationalHolidays(year)
+	for _, h := range c.custom {
+		if h.Recurring && len(h.Date) == len(dateLayout) {
+			h.Date = prefix + h.Date[len(prefix):]
+		}
+		if strings.HasPrefix(h.Date, prefix) {
+			holidays = append(holidays, h)
+		}
+	}
+
+	sort.SliceStable(holidays, func(i, j int) bool { return holidays[i].Date < holidays[j].Date })
+	return holidays
+}
+
+// Holiday returns the holiday on the date of t, if any
+func (c *Calendar) Holiday(t time.Time) (entities.HolidayEntity, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.years[t.Year()] {
+		if c.holidays == nil {
+			c.holidays = map[string]entities.HolidayEntity{}
+			c.years = map[int]bool{}
+		}
+		for _, h := range c.Holidays(t.Year()) {
+			if _, ok := c.holidays[h.Date]; !ok {
+				c.holidays[h.Date] = h
+			}
+		}
+		c.years[t.Year()] = true
+	}
+
+	h, ok := c.holidays[t.Format(dateLayout)]
+	return h, ok
+}
+
+// Day returns the opening hours of the date of t
+func (c *Calendar) Day(t time.Time) Day {
+	day := Day{Date: t.Format(dateLayout), Hours: []Period{}}
+	spans, reason := c.windows(t)
+	for _, s := range spans {
+		day.Hours = append(day.Hours, Period{OpensAt: s.start.Format("15:04"), ClosesAt: s.end.Format("15:04")})
+	}
+	day.IsOpen = len(spans) > 0
+	if !day.IsOpen {
+		day.Reason = reason
+	}
+	return day
+}
+
+// Closed tells whether the shop stays closed the whole day of t, and why
+func (c *Calendar) Closed(t time.Time) (string, bool) {
+	spans, reason := c.windows(t)
+	return reason, len(spans) == 0
+}
+
+// IsOpen tells whether the shop is open at t
+func (c *Calendar) IsOpen(t time.Time) bool {
+	spans, _ := c.windows(t)
+	for _, s := range spans {
+		if !t.Before(s.start) && t.Before(s.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// Closes returns when the shop closes on the date of t, or false when it
+// stays closed the whole day
+func (c *Calendar) Closes(t time.Time) (time.Time, bool) {
+	spans, _ := c.windows(t)
+	if len(spans) == 0 {
+		return time.Time{}, false
+	}
+	return spans[len(spans)-1].end, true
+}
+
+// AddDays moves t by n days the shop opens, keeping the time of day.
+// Negative n moves back.
+func (c *Calendar) AddDays(t time.Time, n int) time.Time {
+	step := 1
+	if n < 0 {
+		step = -1
+	}
+	for i := 0; n != 0 && i < maxDays; i++ {
+		t = t.AddDate(0, 0, step)
+		if _, closed := c.Closed(t); !closed {
+			n -= step
+		}
+	}
+	return t
+}
+
+// Add returns the instant reached after the shop works d starting at t.
+// Adding nothing returns the next time the shop is open.
+func (c *Calendar) Add(t time.Time, d time.Duration) time.Time {
+	day := midnight(t)
+	for i := 0; i < maxDays; i++ {
+		spans, _ := c.windows(day)
+		for _, s := range spans {
+			if !t.Before(s.end) {
+				continue
+			}
+			start := t
+			if start.Before(s.start) {
+				start = s.start
+			}
+			left := s.end.Sub(start)
+			if d <= left {
+				return start.Add(d)
+			}
+			d -= left
+			t = s.end
+		}
+		day = day.AddDate(0, 0, 1)
+		t = day
+	}
+	return t.Add(d)
+}
+
+// Between returns the time the shop is open between from and to
+func (c *Calendar) Between(from, to time.Time) time.Duration {
+	var total time.Duration
+	for day, i := midnight(from), 0; day.Before(to) && i < maxDays; day, i = day.AddDate(0, 0, 1), i+1 {
+		spans, _ := c.windows(day)
+		for _, s := range spans {
+			start, end := s.start, s.end
+			if start.Before(from) {
+				start = from
+			}
+			if end.After(to) {
+				end = to
+			}
+			if end.After(start) {
+				total += end.Sub(start)
+			}
+		}
+	}
+	return total
+}
+
+// windows returns when the shop is open on the date of t, or why it is
+// closed all day
+func (c *Calendar) windows(t time.Time) ([]span, string) {
+	day := midnight(t)
+	if h, ok := c.Holiday(day); ok {
+		return nil, h.Name
+	}
+
+	shift, ok := c.week[day.Weekday()]
+	if !ok || shift.Close <= shift.Open {
+		return nil, weekdayNames[day.Weekday()]
+	}
+
+	spans := []span{{at(day, shift.Open), at(day, shift.Close)}}
+	reason := ""
+	for _, closure := range c.closures {
+		start, end := wall(closure.StartsAt, day.Location()), wall(closure.EndsAt, day.Location())
+		if len(spans) == 0 || !start.Before(spans[len(spans)-1].end) || !end.After(spans[0].start) {
+			continue
+		}
+		spans = subtract(spans, start, end)
+		reason = closure.Reason
+	}
+	return spans, reason
+}
+
+// subtract removes the period from start to end from spans
+func subtract(spans []span, start, end time.Time) []span {
+	var result []span
+	for _, s := range spans {
+		if !start.Before(s.end) || !end.After(s.start) {
+			result = append(result, s)
+			continue
+		}
+		if start.After(s.start) {
+			result = append(result, span{s.start, start})
+		}
+		if end.Before(s.end) {
+			result = append(result, span{end, s.end})
+		}
+	}
+	return result
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// at returns the time of day offset after midnight, keeping the wall clock
+// right across daylight saving changes
+func at(midnight time.Time, offset time.Duration) time.Time {
+	minutes := int(offset / time.Minute)
+	return time.Date(midnight.Year(), midnight.Month(), midnight.Day(), minutes/60, minutes%60, 0, 0, midnight.Location())
+}
+
+// wall reads the wall clock of t in loc. Closures are stored without a
+// time zone, so their wall clock is the shop's.
+func wall(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
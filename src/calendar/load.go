@@ -0,0 +1,66 @@
+package calendar
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/entities"
+)
+
+// HolidayColumns selects a holiday with its date formatted as YYYY-MM-DD
+const HolidayColumns = `id, to_char(date, 'YYYY-MM-DD') AS date, name, recurring`
+
+// OpeningHoursColumns selects the opening hours of a weekday as HH:MM
+const OpeningHoursColumns = `weekday, to_char(opens_at, 'HH24:MI') AS opens_at, to_char(closes_at, 'HH24:MI') AS closes_at`
+
+// Load reads the calendar from the database. Until opening hours are
+// configured, the shop opens on DefaultWeek.
+func Load(db sqlx.Queryer) (*Calendar, error) {
+	var hours []entities.OpeningHoursEntity
+	err := sqlx.Select(db, &hours, "SELECT "+OpeningHoursColumns+" FROM opening_hours")
+	if err != nil {
+		return nil, err
+	}
+
+	holidays := []entities.HolidayEntity{}
+	err = sqlx.Select(db, &holidays, "SELECT "+HolidayColumns+" FROM holidays")
+	if err != nil {
+		return nil, err
+	}
+
+	closures := []entities.ClosureEntity{}
+	err = sqlx.Select(db, &closures, "SELECT id, starts_at, ends_at, reason, created_at FROM closures")
+	if err != nil {
+		return nil, err
+	}
+
+	week, err := Week(hours)
+	if err != nil {
+		return nil, err
+	}
+	if len(hours) == 0 {
+		week = DefaultWeek
+	}
+	return New(week, holidays, closures), nil
+}
+
+// Week turns the opening hours of each weekday into shifts
+func Week(hours []entities.OpeningHoursEntity) (map[time.Weekday]Shift, error) {
+	week := make(map[time.Weekday]Shift, len(hours))
+	for _, h := range hours {
+		opens, err := time.Parse("15:04", h.OpensAt)
+		if err != nil {
+			return nil, err
+		}
+		closes, err := time.Parse("15:04", h.ClosesAt)
+		if err != nil {
+			return nil, err
+		}
+		week[time.Weekday(h.Weekday)] = Shift{
+			Open:  time.Duration(opens.Hour())*time.Hour + time.Duration(opens.Minute())*time.Minute,
+			Close: time.Duration(closes.Hour())*time.Hour + time.Duration(closes.Minute())*time.Minute,
+		}
+	}
+	return week, nil
+}
@@ -0,0 +1,67 @@
+package calendar
+
+import (
+	"sort"
+	"time"
+
+	"lavanderia/entities"
+)
+
+// fixedHolidays are the national holidays on the same day every year
+var fixedHolidays = []struct {
+	month time.Month
+	day   int
+	name  string
+	since int
+}{
+	{time.January, 1, "Confraternização Universal", 0},
+	{time.April, 21, "Tiradentes", 0},
+	{time.May, 1, "Dia do Trabalho", 0},
+	{time.September, 7, "Independência do Brasil", 0},
+	{time.October, 12, "Nossa Senhora Aparecida", 0},
+	{time.November, 2, "Finados", 0},
+	{time.November, 15, "Proclamação da República", 0},
+	{time.November, 20, "Dia Nacional de Zumbi e da Consciência Negra", 2024},
+	{time.December, 25, "Natal", 0},
+}
+
+// NationalHolidays returns the Brazilian national holidays of year, by
+// date. Carnival and Corpus Christi are optional days off, not holidays,
+// so shops that close on them register them as holidays.
+func NationalHolidays(year int) []entities.HolidayEntity {
+	holidays := []entities.HolidayEntity{
+		national(easter(year).AddDate(0, 0, -2), "Sexta-feira Santa"),
+	}
+	for _, h := range fixedHolidays {
+		if year >= h.since {
+			holidays = append(holidays, national(time.Date(year, h.month, h.day, 0, 0, 0, 0, time.UTC), h.name))
+		}
+	}
+
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i].Date < holidays[j].Date })
+	return holidays
+}
+
+func national(date time.Time, name string) entities.HolidayEntity {
+	return entities.HolidayEntity{Date: date.Format(dateLayout), Name: name, National: true}
+}
+
+// easter returns Easter Sunday of year in the Gregorian calendar, by the
+// anonymous Gregorian algorithm (Meeus/Jones/Butcher)
+func easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,216 @@
+// Package cash keeps the cash drawer of the counter: sessions opened with a
+// change float, the payments taken and the withdrawals made while they are
+// open, and the amount counted when they close. A session is reconciled
+// against the services paid while it was open, so payments marked without
+// going through the drawer stand out.
+package cash
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"lavanderia/entities"
+)
+
+// Types of movement
+const (
+	Payment    = "payment"
+	Withdrawal = "withdrawal"
+)
+
+// Payment methods. Only cash goes into the drawer.
+const (
+	Cash = "cash"
+	Card = "card"
+	Pix  = "pix"
+)
+
+// Methods lists the payment methods
+var Methods = []string{Cash, Card, Pix}
+
+// SessionColumns selects a session with how much it was over or short
+const SessionColumns = `id, opening_float, opened_by, opened_at, expected, counted, counted - expected AS difference, closed_by, closed_at, notes`
+
+// MovementColumns selects a movement
+const MovementColumns = `id, session_id, type, method, amount, laundry_service_id, reason, created_by, created_at`
+
+var (
+	// ErrSessionOpen is returned when a session is opened while another
+	// one is
+	ErrSessionOpen = errors.New("cash: a session is already open")
+	// ErrSessionClosed is returned when a closed session is moved or
+	// closed again
+	ErrSessionClosed = errors.New("cash: session closed")
+	// ErrInsufficientCash is returned when a withdrawal would take more
+	// than the cash in the drawer
+	ErrInsufficientCash = errors.New("cash: insufficient cash")
+)
+
+// Totals sums the movements of a session. Expected is the cash the drawer
+// should hold: the opening float and the cash payments, less the
+// withdrawals.
+type Totals struct {
+	Payments    int     `json:"payments"`
+	Cash        float64 `json:"cash"`
+	Card        float64 `json:"card"`
+	Pix         float64 `json:"pix"`
+	Received    float64 `json:"received"`
+	Withdrawals float64 `json:"withdrawals"`
+	Expected    float64 `json:"expected"`
+}
+
+// Summarize sums the movements of a session opened with openingFloat
+func Summarize(openingFloat float64, movements []entities.CashMovementEntity) Totals {
+	var totals Totals
+	for _, m := range movements {
+		if m.Type == Withdrawal {
+			totals.Withdrawals += m.Amount
+			continue
+		}
+		totals.Payments++
+		switch m.Method {
+		case Cash:
+			totals.Cash += m.Amount
+		case Card:
+			totals.Card += m.Amount
+		case Pix:
+			totals.Pix += m.Amount
+		}
+	}
+
+	totals.Cash = round(totals.Cash)
+	totals.Card = round(totals.Card)
+	totals.Pix = round(totals.Pix)
+	totals.Received = round(totals.Cash + totals.Card + totals.Pix)
+	totals.Withdrawals = round(totals.Withdrawals)
+	totals.Expected = round(openingFloat + totals.Cash - totals.Withdrawals)
+	return totals
+}
+
+// Open opens a session with the change float in the drawer
+func Open(tx *sqlx.Tx, openingFloat float64, openedBy *uuid.UUID, notes string) (entities.CashSessionEntity, error) {
+	open := []uuid.UUID{}
+	err := tx.Select(&open, "SELECT id FROM cash_sessions WHERE closed_at IS NULL FOR UPDATE")
+	if err != nil {
+		return entities.CashSessionEntity{}, err
+	}
+	if len(open) > 0 {
+		return entities.CashSessionEntity{}, ErrSessionOpen
+	}
+
+	// With no session open there is nothing to lock, so a session opened
+	// at the same time shows up as a violation of the index of the open one
+	var session entities.CashSessionEntity
+	err = tx.Get(&session, "INSERT INTO cash_sessions (opening_float, opened_by, notes) VALUES ($1, $2, $3) RETURNING "+SessionColumns,
+		round(openingFloat), openedBy, notes)
+	if e, ok := err.(*pq.Error); ok && e.Code == "23505" && e.Constraint == "idx_cash_sessions_open" {
+		return session, ErrSessionOpen
+	}
+	return session, err
+}
+
+// Record writes a movement of an open session. The session is locked, so
+// a withdrawal sees the cash the drawer holds. A missing session returns
+// sql.ErrNoRows.
+func Record(tx *sqlx.Tx, m entities.CashMovementEntity) (entities.CashMovementEntity, error) {
+	session, err := lock(tx, m.SessionID)
+	if err != nil {
+		return entities.CashMovementEntity{}, err
+	}
+
+	if m.Type == Withdrawal {
+		m.Method = Cash
+		totals, err := sessionTotals(tx, session)
+		if err != nil {
+			return entities.CashMovementEntity{}, err
+		}
+		if m.Amount > totals.Expected {
+			return entities.CashMovementEntity{}, ErrInsufficientCash
+		}
+	}
+
+	var movement entities.CashMovementEntity
+	err = tx.Get(&movement, `
+		INSERT INTO cash_movements (session_id, type, method, amount, laundry_service_id, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+MovementColumns,
+		m.SessionID, m.Type, m.Method, round(m.Amount), m.LaundryServiceID, m.Reason, m.CreatedBy)
+	return movement, err
+}
+
+// Close closes a session with the cash counted in the drawer, recording
+// the cash it was expected to hold
+func Close(tx *sqlx.Tx, id uuid.UUID, counted float64, closedBy *uuid.UUID, notes string) (entities.CashSessionEntity, error) {
+	session, err := lock(tx, id)
+	if err != nil {
+		return entities.CashSessionEntity{}, err
+	}
+
+	totals, err := sessionTotals(tx, session)
+	if err != nil {
+		return entities.CashSessionEntity{}, err
+	}
+
+	err = tx.Get(&session, `
+		UPDATE cash_sessions
+		SET expected = $1, counted = $2, closed_by = $3, closed_at = CURRENT_TIMESTAMP,
+			notes = CASE WHEN $4 = '' THEN notes ELSE $4 END
+		WHERE id = $5
+		RETURNING `+SessionColumns,
+		totals.Expected, round(counted), closedBy, notes, id)
+	return session, err
+}
+
+// Current returns the open session, or sql.ErrNoRows when the drawer is
+// closed
+func Current(q sqlx.Queryer) (entities.CashSessionEntity, error) {
+	var session entities.CashSessionEntity
+	err := sqlx.Get(q, &session, "SELECT "+SessionColumns+" FROM cash_sessions WHERE closed_at IS NULL")
+	return session, err
+}
+
+// Movements lists the movements of a session, oldest first
+func Movements(q sqlx.Queryer, sessionID uuid.UUID) ([]entities.CashMovementEntity, error) {
+	movements := []entities.CashMovementEntity{}
+	err := sqlx.Select(q, &movements, "SELECT "+MovementColumns+" FROM cash_movements WHERE session_id = $1 ORDER BY created_at, id", sessionID)
+	return movements, err
+}
+
+// lock locks an open session
+func lock(tx *sqlx.Tx, id uuid.UUID) (entities.CashSessionEntity, error) {
+	var session entities.CashSessionEntity
+	err := tx.Get(&session, "SELECT "+SessionColumns+" FROM cash_sessions WHERE id = $1 FOR UPDATE", id)
+	if err != nil {
+		return session, err
+	}
+	if session.ClosedAt != nil {
+		return session, ErrSessionClosed
+	}
+	return session, nil
+}
+
+func sessionTotals(q sqlx.Queryer, session entities.CashSessionEntity) (Totals, error) {
+	movements, err := Movements(q, session.ID)
+	if err != nil {
+		return Totals{}, err
+	}
+	return Summarize(session.OpeningFloat, movements), nil
+}
+
+// end is when a session stopped taking payments: when it was closed, or
+// now while it is open
+func end(session entities.CashSessionEntity) time.Time {
+	if session.ClosedAt != nil {
+		return *session.ClosedAt
+	}
+	return time.Now()
+}
+
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,195 @@
+package cash
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/entities"
+)
+
+// zWidth is the width, in characters, of a printed Z-report, the width of
+// an 80mm receipt printer
+const zWidth = 40
+
+// Unrecorded is a service paid while a session was open without a payment
+// taken at the drawer
+type Unrecorded struct {
+	ServiceID  uuid.UUID `json:"service_id" db:"service_id"`
+	TotalPrice float64   `json:"total_price" db:"total_price"`
+	PaidAt     time.Time `json:"paid_at" db:"paid_at"`
+}
+
+// Mismatch is a payment of another amount than the price of its service
+type Mismatch struct {
+	MovementID uuid.UUID `json:"movement_id" db:"movement_id"`
+	ServiceID  uuid.UUID `json:"service_id" db:"service_id"`
+	Amount     float64   `json:"amount" db:"amount"`
+	TotalPrice float64   `json:"total_price" db:"total_price"`
+}
+
+// Reconciliation compares a session with the payments recorded against the
+// services: those marked paid, or created paid, while it was open without
+// going through the drawer, and those taken for another amount than their
+// price. Payments from the wallet don't go through the drawer.
+type Reconciliation struct {
+	Unrecorded []Unrecorded `json:"unrecorded"`
+	Mismatched []Mismatch   `json:"mismatched"`
+}
+
+// Report is a session with its totals, its reconciliation and its
+// movements
+type Report struct {
+	Session        entities.CashSessionEntity    `json:"session"`
+	Totals         Totals                        `json:"totals"`
+	Reconciliation Reconciliation                `json:"reconciliation"`
+	Movements      []entities.CashMovementEntity `json:"movements"`
+}
+
+// OverShort is the difference between the cash counted and expected of the
+// sessions closed over a period. Over and Short sum the sessions over and
+// short; Net is their sum.
+type OverShort struct {
+	Sessions []entities.CashSessionEntity `json:"sessions"`
+	Over     float64                      `json:"over"`
+	Short    float64                      `json:"short"`
+	Net      float64                      `json:"net"`
+}
+
+// Load reads the report of a session. A missing session returns
+// sql.ErrNoRows.
+func Load(q sqlx.Queryer, id uuid.UUID) (Report, error) {
+	var report Report
+	err := sqlx.Get(q, &report.Session, "SELECT "+SessionColumns+" FROM cash_sessions WHERE id = $1", id)
+	if err != nil {
+		return report, err
+	}
+
+	report.Movements, err = Movements(q, id)
+	if err != nil {
+		return report, err
+	}
+	report.Totals = Summarize(report.Session.OpeningFloat, report.Movements)
+
+	report.Reconciliation, err = Reconcile(q, report.Session)
+	return report, err
+}
+
+// Reconcile compares a session with the payments recorded against the
+// services while it was open
+func Reconcile(q sqlx.Queryer, session entities.CashSessionEntity) (Reconciliation, error) {
+	reconciliation := Reconciliation{Unrecorded: []Unrecorded{}, Mismatched: []Mismatch{}}
+
+	err := sqlx.Select(q, &reconciliation.Unrecorded, `
+		SELECT ls.id AS service_id, ls.total_price, ls.paid_at
+		FROM laundry_services ls
+		WHERE ls.paid_at >= $1 AND ls.paid_at < $2
+			AND COALESCE(ls.payment_method, '') <> 'wallet'
+			AND COALESCE(ls.total_price, 0) > 0
+			AND NOT EXISTS (SELECT 1 FROM cash_movements m WHERE m.session_id = $3 AND m.laundry_service_id = ls.id AND m.type = $4)
+		ORDER BY ls.paid_at`,
+		session.OpenedAt, end(session), session.ID, Payment)
+	if err != nil {
+		return reconciliation, err
+	}
+
+	err = sqlx.Select(q, &reconciliation.Mismatched, `
+		SELECT m.id AS movement_id, m.laundry_service_id AS service_id, m.amount, COALESCE(ls.total_price, 0) AS total_price
+		FROM cash_movements m
+		JOIN laundry_services ls ON ls.id = m.laundry_service_id
+		WHERE m.session_id = $1 AND m.type = $2 AND m.amount <> COALESCE(ls.total_price, 0)
+		ORDER BY m.created_at`, session.ID, Payment)
+	return reconciliation, err
+}
+
+// OverShortReport lists the sessions closed over a period with how much
+// each was over or short
+func OverShortReport(q sqlx.Queryer, from, to time.Time) (OverShort, error) {
+	report := OverShort{Sessions: []entities.CashSessionEntity{}}
+	err := sqlx.Select(q, &report.Sessions, "SELECT "+SessionColumns+" FROM cash_sessions WHERE closed_at >= $1 AND closed_at < $2 ORDER BY closed_at", from, to)
+	if err != nil {
+		return report, err
+	}
+
+	for _, session := range report.Sessions {
+		if session.Difference == nil {
+			continue
+		}
+		if *session.Difference > 0 {
+			report.Over += *session.Difference
+		} else {
+			report.Short += *session.Difference
+		}
+	}
+	report.Over = round(report.Over)
+	report.Short = round(report.Short)
+	report.Net = round(report.Over + report.Short)
+	return report, nil
+}
+
+// WriteZReport prints the report of a session for a receipt printer. A
+// session still open prints as a partial X-report.
+func WriteZReport(w io.Writer, report Report) error {
+	var b strings.Builder
+	rule := strings.Repeat("-", zWidth) + "\n"
+	line := func(label, value string) {
+		gap := zWidth - utf8.RuneCountInString(label) - utf8.RuneCountInString(value)
+		b.WriteString(label + strings.Repeat(" ", max(gap, 1)) + value + "\n")
+	}
+
+	session := report.Session
+	title := "RELATÓRIO Z"
+	if session.ClosedAt == nil {
+		title = "RELATÓRIO X (PARCIAL)"
+	}
+	b.WriteString(strings.Repeat(" ", (zWidth-utf8.RuneCountInString(title))/2) + title + "\n")
+	line("Sessão", session.ID.String()[:8])
+	line("Abertura", session.OpenedAt.Format("02/01/2006 15:04"))
+	if session.ClosedAt != nil {
+		line("Fechamento", session.ClosedAt.Format("02/01/2006 15:04"))
+	}
+
+	totals := report.Totals
+	b.WriteString(rule)
+	line("Fundo de troco", money(session.OpeningFloat))
+	line("Dinheiro", money(totals.Cash))
+	line("Cartão", money(totals.Card))
+	line("Pix", money(totals.Pix))
+	line("Total recebido", money(totals.Received))
+	line("Sangrias", money(totals.Withdrawals))
+
+	b.WriteString(rule)
+	line("Esperado em caixa", money(totals.Expected))
+	if session.Counted != nil && session.Difference != nil {
+		line("Contado", money(*session.Counted))
+		line("Diferença", money(*session.Difference))
+	}
+
+	b.WriteString(rule)
+	line("Pagamentos", strconv.Itoa(totals.Payments))
+	line("Pagos fora do caixa", strconv.Itoa(len(report.Reconciliation.Unrecorded)))
+	for _, u := range report.Reconciliation.Unrecorded {
+		line("  "+u.ServiceID.String()[:8], money(u.TotalPrice))
+	}
+	line("Valores divergentes", strconv.Itoa(len(report.Reconciliation.Mismatched)))
+	for _, m := range report.Reconciliation.Mismatched {
+		line("  "+m.ServiceID.String()[:8], fmt.Sprintf("%s / %s", money(m.Amount), money(m.TotalPrice)))
+	}
+	if session.Notes != "" {
+		b.WriteString(rule + session.Notes + "\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// money formats an amount in reais, with a decimal comma
+func money(amount float64) string {
+	return strings.Replace(strconv.FormatFloat(amount, 'f', 2, 64), ".", ",", 1)
+}
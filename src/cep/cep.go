@@ -0,0 +1,93 @@
+// Package cep looks up Brazilian postal codes (CEP) to fill and check
+// addresses. The lookup goes through a Provider, so the local dataset can be
+// replaced by another source without touching the handlers.
+package cep
+
+import (
+	"errors"
+	"sync"
+
+	"lavanderia/apierror"
+)
+
+// ErrNotFound is returned by providers for a CEP they don't know
+var ErrNotFound = errors.New("cep: not found")
+
+// Address is what a CEP tells about an address. Street and Neighborhood are
+// empty for CEPs that cover a whole city.
+type Address struct {
+	CEP          string `json:"cep"`
+	Street       string `json:"street"`
+	Neighborhood string `json:"neighborhood"`
+	City         string `json:"city"`
+	State        string `json:"state"`
+}
+
+// Provider finds the address of a CEP given as 8 digits
+type Provider interface {
+	Lookup(cep string) (Address, error)
+}
+
+var (
+	mu       sync.RWMutex
+	provider Provider
+)
+
+// Use sets the provider used by Lookup and Verify. Without a provider every
+// CEP is unknown.
+func Use(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	provider = p
+}
+
+// Lookup finds the address of a CEP, formatted or not
+func Lookup(cep string) (Address, error) {
+	mu.RLock()
+	p := provider
+	mu.RUnlock()
+
+	cep = digits(cep)
+	if p == nil || len(cep) != 8 {
+		return Address{}, ErrNotFound
+	}
+	return p.Lookup(cep)
+}
+
+// Verify checks that the city, state and street of an address match its
+// CEP and returns the address the CEP points to. CEPs the provider doesn't
+// know can't be checked and are accepted with an empty Address.
+func Verify(cep, street, city, state string) (Address, error) {
+	address, err := Lookup(cep)
+	if err == ErrNotFound {
+		return Address{}, nil
+	}
+	if err != nil {
+		return Address{}, err
+	}
+
+	var errs apierror.FieldErrors
+	if address.Street != "" && normalizeStreet(street) != normalizeStreet(address.Street) {
+		errs = append(errs, apierror.Field("street", apierror.CEPMismatch, "cep", address.CEP, "expected", address.Street))
+	}
+	if fold(city) != fold(address.City) {
+		errs = append(errs, apierror.Field("city", apierror.CEPMismatch, "cep", address.CEP, "expected", address.City))
+	}
+	if NormalizeState(state) != address.State {
+		errs = append(errs, apierror.Field("state", apierror.CEPMismatch, "cep", address.CEP, "expected", address.State))
+	}
+	if len(errs) > 0 {
+		return address, errs
+	}
+	return address, nil
+}
+
+func digits(s string) string {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			b = append(b, s[i])
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,113 @@
+package cep
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// columns maps the accepted header names of a dataset to Address fields
+var columns = map[string]string{
+	"cep": "cep", "street": "street", "logradouro": "street",
+	"neighborhood": "neighborhood", "bairro": "neighborhood",
+	"city": "city", "cidade": "city", "localidade": "city",
+	"state": "state", "uf": "state",
+}
+
+// FileProvider is a Provider backed by a dataset loaded in memory
+type FileProvider struct {
+	addresses map[string]Address
+}
+
+// LoadFile reads a CEP dataset from a CSV file. See Load for the format.
+func LoadFile(path string) (*FileProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Load reads a CEP dataset in CSV, separated by commas or semicolons. The
+// first line names the columns: cep, street (or logradouro), neighborhood
+// (or bairro), city (or cidade) and state (or uf).
+func Load(r io.Reader) (*FileProvider, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	if header, _, _ := strings.Cut(string(data), "\n"); strings.Count(header, ";") > strings.Count(header, ",") {
+		reader.Comma = ';'
+	}
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cep: reading header: %w", err)
+	}
+	index := make(map[string]int)
+	for i, name := range header {
+		if field, ok := columns[fold(name)]; ok {
+			index[field] = i
+		}
+	}
+	for _, field := range []string{"cep", "city", "state"} {
+		if _, ok := index[field]; !ok {
+			return nil, fmt.Errorf("cep: dataset has no %s column", field)
+		}
+	}
+
+	provider := &FileProvider{addresses: make(map[string]Address)}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cep: %w", err)
+		}
+
+		get := func(field string) string {
+			i, ok := index[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		address := Address{
+			CEP:          digits(get("cep")),
+			Street:       get("street"),
+			Neighborhood: get("neighborhood"),
+			City:         get("city"),
+			State:        NormalizeState(get("state")),
+		}
+		if len(address.CEP) != 8 {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("cep: invalid CEP %q on line %d", get("cep"), line)
+		}
+		provider.addresses[address.CEP] = address
+	}
+
+	return provider, nil
+}
+
+// Lookup implements Provider
+func (p *FileProvider) Lookup(cep string) (Address, error) {
+	address, ok := p.addresses[cep]
+	if !ok {
+		return Address{}, ErrNotFound
+	}
+	return address, nil
+}
+
+// Len returns the number of CEPs in the dataset
+func (p *FileProvider) Len() int {
+	return len(p.addresses)
+}
@@ -0,0 +1,72 @@
+package cep
+
+import (
+	"strings"
+	"unicode"
+)
+
+// states maps the folded name of each Brazilian state to its UF
+var states = map[string]string{
+	"acre": "AC", "alagoas": "AL", "amapa": "AP", "amazonas": "AM", "bahia": "BA",
+	"ceara": "CE", "distrito federal": "DF", "espirito santo": "ES", "goias": "GO",
+	"maranhao": "MA", "mato grosso": "MT", "mato grosso do sul": "MS", "minas gerais": "MG",
+	"para": "PA", "paraiba": "PB", "parana": "PR", "pernambuco": "PE", "piaui": "PI",
+	"rio de janeiro": "RJ", "rio grande do norte": "RN", "rio grande do sul": "RS",
+	"rondonia": "RO", "roraima": "RR", "santa catarina": "SC", "sao paulo": "SP",
+	"sergipe": "SE", "tocantins": "TO",
+}
+
+// abbreviations expands the words commonly abbreviated in street names
+var abbreviations = map[string]string{
+	"r": "rua", "av": "avenida", "tv": "travessa", "trav": "travessa", "al": "alameda",
+	"pc": "praca", "pca": "praca", "rod": "rodovia", "estr": "estrada", "lgo": "largo",
+	"dr": "doutor", "cel": "coronel", "pres": "presidente", "sen": "senador",
+	"prof": "professor", "gov": "governador", "eng": "engenheiro", "sta": "santa", "sto": "santo",
+}
+
+var accents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+)
+
+// NormalizeState returns the UF of a state given by its UF or its name,
+// with or without accents. Unknown values are returned trimmed so the uf
+// validation rule can report them.
+func NormalizeState(state string) string {
+	state = strings.TrimSpace(state)
+	if len(state) == 2 {
+		return strings.ToUpper(state)
+	}
+	if uf, ok := states[fold(state)]; ok {
+		return uf
+	}
+	return state
+}
+
+// fold lowercases s, removes accents and punctuation and collapses spaces
+func fold(s string) string {
+	s = accents.Replace(strings.ToLower(s))
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// normalizeStreet folds a street name and expands its abbreviations, so
+// "R. Dr. João" and "Rua Doutor Joao" compare equal
+func normalizeStreet(s string) string {
+	words := strings.Fields(fold(s))
+	for i, word := range words {
+		if full, ok := abbreviations[word]; ok {
+			words[i] = full
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1 @@
+ALTER TABLE clients ADD COLUMN cpf CHAR(11);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS service_bookings;
+DROP TABLE IF EXISTS time_slots;
+ALTER TABLE address DROP COLUMN neighborhood;
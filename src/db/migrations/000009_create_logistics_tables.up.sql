@@ -0,0 +1,30 @@
+ALTER TABLE address ADD COLUMN neighborhood VARCHAR(100);
+
+CREATE TABLE IF NOT EXISTS time_slots (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    capacity INT NOT NULL,
+    is_active boolean NOT NULL DEFAULT TRUE,
+    CHECK (end_time > start_time),
+    CHECK (capacity > 0)
+);
+
+CREATE TABLE IF NOT EXISTS service_bookings (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    laundry_service_id UUID NOT NULL,
+    type VARCHAR(10) NOT NULL,
+    date DATE NOT NULL,
+    time_slot_id UUID NOT NULL,
+    driver_id UUID,
+    status VARCHAR(15) NOT NULL DEFAULT 'scheduled',
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (laundry_service_id, type),
+    FOREIGN KEY (laundry_service_id) REFERENCES laundry_services(id) ON DELETE CASCADE,
+    FOREIGN KEY (time_slot_id) REFERENCES time_slots(id),
+    FOREIGN KEY (driver_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_bookings_date ON service_bookings (date, time_slot_id);
@@ -0,0 +1,2 @@
+ALTER TABLE address DROP COLUMN longitude;
+ALTER TABLE address DROP COLUMN latitude;
@@ -0,0 +1,2 @@
+ALTER TABLE address ADD COLUMN latitude NUMERIC(9, 6);
+ALTER TABLE address ADD COLUMN longitude NUMERIC(9, 6);
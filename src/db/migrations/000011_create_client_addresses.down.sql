@@ -0,0 +1,3 @@
+ALTER TABLE laundry_services DROP CONSTRAINT fk_laundry_services_address;
+ALTER TABLE laundry_services DROP COLUMN address_id;
+DROP TABLE IF EXISTS client_addresses;
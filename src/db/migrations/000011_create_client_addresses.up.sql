@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS client_addresses (
+    address_id UUID PRIMARY KEY,
+    client_id UUID NOT NULL,
+    label VARCHAR(10) NOT NULL DEFAULT 'home',
+    is_default boolean NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (address_id) REFERENCES address(address_id) ON DELETE CASCADE,
+    FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_client_addresses_default ON client_addresses (client_id) WHERE is_default;
+
+INSERT INTO client_addresses (address_id, client_id, label, is_default)
+SELECT address_id, id, 'home', TRUE FROM clients WHERE address_id IS NOT NULL;
+
+ALTER TABLE laundry_services ADD COLUMN address_id UUID;
+
+ALTER TABLE laundry_services
+ADD CONSTRAINT fk_laundry_services_address
+FOREIGN KEY (address_id) REFERENCES address(address_id);
+
+UPDATE laundry_services ls SET address_id = cli.address_id FROM clients cli WHERE cli.id = ls.client_id;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS domain_events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(50) NOT NULL,
+    aggregate_id UUID NOT NULL,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    dispatched_at TIMESTAMP,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_domain_events_pending ON domain_events (next_attempt_at) WHERE dispatched_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_domain_events_aggregate ON domain_events (aggregate_id, id);
+
+CREATE TABLE IF NOT EXISTS domain_event_deliveries (
+    event_id BIGINT NOT NULL,
+    subscriber VARCHAR(50) NOT NULL,
+    delivered_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (event_id, subscriber),
+    FOREIGN KEY (event_id) REFERENCES domain_events(id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_laundry_services_tracking_code;
+
+ALTER TABLE laundry_services
+DROP COLUMN IF EXISTS tracking_code;
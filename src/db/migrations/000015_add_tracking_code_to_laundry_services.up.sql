@@ -0,0 +1,6 @@
+-- The default gives existing rows, and rows inserted outside the API, a
+-- random hexadecimal code; the API generates its own codes
+ALTER TABLE laundry_services
+ADD COLUMN IF NOT EXISTS tracking_code VARCHAR(10) NOT NULL DEFAULT upper(substr(replace(uuid_generate_v4()::text, '-', ''), 1, 10));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_laundry_services_tracking_code ON laundry_services (tracking_code);
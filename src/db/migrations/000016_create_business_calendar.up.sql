@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS opening_hours (
+    weekday SMALLINT PRIMARY KEY,
+    opens_at TIME NOT NULL,
+    closes_at TIME NOT NULL,
+    CHECK (weekday BETWEEN 0 AND 6),
+    CHECK (closes_at > opens_at)
+);
+
+-- Segunda a sexta das 8h às 18h e sábado das 8h às 13h
+INSERT INTO opening_hours (weekday, opens_at, closes_at) VALUES
+    (1, '08:00', '18:00'),
+    (2, '08:00', '18:00'),
+    (3, '08:00', '18:00'),
+    (4, '08:00', '18:00'),
+    (5, '08:00', '18:00'),
+    (6, '08:00', '13:00')
+ON CONFLICT (weekday) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS holidays (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    date DATE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    recurring boolean NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_holidays_date ON holidays (date);
+
+CREATE TABLE IF NOT EXISTS closures (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_closures_period ON closures (starts_at, ends_at);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS production_settings;
+
+DROP INDEX IF EXISTS idx_laundry_services_queue;
+
+ALTER TABLE laundry_services
+DROP COLUMN IF EXISTS surcharge,
+DROP COLUMN IF EXISTS priority;
@@ -0,0 +1,21 @@
+-- The surcharge is the percentage added to the price of the service for
+-- its priority, as configured when the service was created
+ALTER TABLE laundry_services
+ADD COLUMN IF NOT EXISTS priority VARCHAR(10) NOT NULL DEFAULT 'normal' CHECK (priority IN ('normal', 'express', 'same_day')),
+ADD COLUMN IF NOT EXISTS surcharge NUMERIC(5, 2) NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_laundry_services_queue ON laundry_services (status, priority, estimated_completion_date) WHERE completed_at IS NULL;
+
+-- A single row: the daily capacity, where 0 means unlimited, and the
+-- surcharges of each priority
+CREATE TABLE IF NOT EXISTS production_settings (
+    id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    max_kg_per_day NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (max_kg_per_day >= 0),
+    max_pieces_per_day INT NOT NULL DEFAULT 0 CHECK (max_pieces_per_day >= 0),
+    express_surcharge NUMERIC(5, 2) NOT NULL DEFAULT 50 CHECK (express_surcharge >= 0),
+    same_day_surcharge NUMERIC(5, 2) NOT NULL DEFAULT 100 CHECK (same_day_surcharge >= 0),
+    same_day_cutoff TIME NOT NULL DEFAULT '12:00',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO production_settings (id) VALUES (1) ON CONFLICT (id) DO NOTHING;
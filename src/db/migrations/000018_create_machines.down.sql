@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS machine_load_services;
+DROP TABLE IF EXISTS machine_loads;
+DROP TABLE IF EXISTS machines;
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS machines (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('washer', 'dryer', 'iron')),
+    capacity_kg NUMERIC(6, 2) NOT NULL CHECK (capacity_kg > 0),
+    is_active boolean NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A load is a cycle of a machine with one or more services. stage is the
+-- status the services were worked in: Lavando, Secando or Passando.
+CREATE TABLE IF NOT EXISTS machine_loads (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    machine_id UUID NOT NULL,
+    operator_id UUID,
+    stage VARCHAR(50) NOT NULL,
+    weight NUMERIC(6, 2) NOT NULL DEFAULT 0,
+    notes VARCHAR(255) NOT NULL DEFAULT '',
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ended_at TIMESTAMP,
+    CHECK (ended_at IS NULL OR ended_at >= started_at),
+    FOREIGN KEY (machine_id) REFERENCES machines(id),
+    FOREIGN KEY (operator_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- A machine runs one load at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_machine_loads_running ON machine_loads (machine_id) WHERE ended_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_machine_loads_started_at ON machine_loads (machine_id, started_at);
+
+CREATE TABLE IF NOT EXISTS machine_load_services (
+    load_id UUID NOT NULL,
+    laundry_service_id UUID NOT NULL,
+    PRIMARY KEY (load_id, laundry_service_id),
+    FOREIGN KEY (load_id) REFERENCES machine_loads(id) ON DELETE CASCADE,
+    FOREIGN KEY (laundry_service_id) REFERENCES laundry_services(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_machine_load_services_service ON machine_load_services (laundry_service_id);
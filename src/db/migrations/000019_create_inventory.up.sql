@@ -0,0 +1,31 @@
+-- usage_per_load and usage_per_kg estimate what each wash load consumes,
+-- per load and per kilo washed
+CREATE TABLE IF NOT EXISTS supplies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    unit VARCHAR(5) NOT NULL CHECK (unit IN ('un', 'kg', 'g', 'l', 'ml')),
+    stock NUMERIC(12, 3) NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    reorder_level NUMERIC(12, 3) NOT NULL DEFAULT 0 CHECK (reorder_level >= 0),
+    usage_per_load NUMERIC(10, 4) NOT NULL DEFAULT 0 CHECK (usage_per_load >= 0),
+    usage_per_kg NUMERIC(10, 4) NOT NULL DEFAULT 0 CHECK (usage_per_kg >= 0),
+    is_active boolean NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- quantity is signed: purchases add to the stock, consumption takes from
+-- it and adjustments correct it to what was counted
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    supply_id UUID NOT NULL,
+    type VARCHAR(15) NOT NULL CHECK (type IN ('purchase', 'consumption', 'adjustment')),
+    quantity NUMERIC(12, 3) NOT NULL,
+    unit_cost NUMERIC(10, 2),
+    load_id UUID,
+    estimated boolean NOT NULL DEFAULT FALSE,
+    notes VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (supply_id) REFERENCES supplies(id),
+    FOREIGN KEY (load_id) REFERENCES machine_loads(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movements_supply ON stock_movements (supply_id, created_at);
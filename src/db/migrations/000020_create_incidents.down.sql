@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS incidents;
+ALTER TABLE clients DROP COLUMN IF EXISTS balance;
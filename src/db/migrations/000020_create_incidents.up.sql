@@ -0,0 +1,26 @@
+-- balance is the credit the client has with the laundry, such as the
+-- compensation of incidents, to be discounted from the next services
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS balance NUMERIC(10, 2) NOT NULL DEFAULT 0;
+
+-- An incident is a garment damaged or lost, or a complaint, on a service.
+-- laundry_item_id points it to a line of the service when the incident is
+-- about an item. The compensation is credited to the client's balance when
+-- the incident is resolved.
+CREATE TABLE IF NOT EXISTS incidents (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    laundry_service_id UUID NOT NULL,
+    laundry_item_id UUID,
+    type VARCHAR(15) NOT NULL CHECK (type IN ('damage', 'loss', 'complaint')),
+    description TEXT NOT NULL,
+    photos TEXT[] NOT NULL DEFAULT '{}',
+    compensation NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (compensation >= 0),
+    status VARCHAR(15) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'investigating', 'resolved', 'rejected')),
+    resolution TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP,
+    FOREIGN KEY (laundry_service_id) REFERENCES laundry_services(id) ON DELETE CASCADE,
+    FOREIGN KEY (laundry_service_id, laundry_item_id) REFERENCES laundry_items_services(laundry_service_id, laundry_item_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_service ON incidents (laundry_service_id);
+CREATE INDEX IF NOT EXISTS idx_incidents_created_at ON incidents (created_at);
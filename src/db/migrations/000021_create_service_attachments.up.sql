@@ -0,0 +1,21 @@
+-- Photos of the garments of a service, taken when they are dropped off
+-- (intake) and when they are delivered. The files are kept in the storage
+-- under storage_key, with a JPEG thumbnail under thumbnail_key.
+CREATE TABLE IF NOT EXISTS service_attachments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    laundry_service_id UUID NOT NULL,
+    stage VARCHAR(10) NOT NULL CHECK (stage IN ('intake', 'delivery')),
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    content_type VARCHAR(50) NOT NULL,
+    size BIGINT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    thumbnail_key VARCHAR(255) NOT NULL,
+    notes VARCHAR(255) NOT NULL DEFAULT '',
+    uploaded_by UUID,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (laundry_service_id) REFERENCES laundry_services(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_attachments_service ON service_attachments (laundry_service_id, created_at);
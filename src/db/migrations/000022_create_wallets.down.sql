@@ -0,0 +1,9 @@
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS balance NUMERIC(10, 2) NOT NULL DEFAULT 0;
+
+UPDATE clients c SET balance = a.balance
+FROM wallet_accounts a
+WHERE a.client_id = c.id;
+
+DROP TABLE IF EXISTS wallet_entries;
+DROP TABLE IF EXISTS wallet_transactions;
+DROP TABLE IF EXISTS wallet_accounts;
@@ -0,0 +1,64 @@
+-- The wallet is a double-entry ledger. Every client with a wallet has an
+-- account, and the counterparts of its movements are kept on the system
+-- accounts: cash for top-ups, sales for the services paid from the wallet,
+-- compensation for the incidents credited and adjustments for the
+-- corrections. The entries of a transaction always sum to zero, and the
+-- balance of an account is the sum of its entries.
+CREATE TABLE IF NOT EXISTS wallet_accounts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    client_id UUID UNIQUE REFERENCES clients(id) ON DELETE CASCADE,
+    code VARCHAR(20) UNIQUE,
+    balance NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((client_id IS NULL) <> (code IS NULL)),
+    CHECK (client_id IS NULL OR balance >= 0)
+);
+
+INSERT INTO wallet_accounts (code) VALUES ('cash'), ('sales'), ('compensation'), ('adjustments')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS wallet_transactions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    type VARCHAR(15) NOT NULL CHECK (type IN ('top_up', 'payment', 'credit', 'adjustment')),
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    laundry_service_id UUID REFERENCES laundry_services(id) ON DELETE SET NULL,
+    incident_id UUID REFERENCES incidents(id) ON DELETE SET NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_by UUID,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS wallet_entries (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id UUID NOT NULL REFERENCES wallet_transactions(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES wallet_accounts(id),
+    amount NUMERIC(12, 2) NOT NULL CHECK (amount <> 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_entries_account ON wallet_entries (account_id, id);
+CREATE INDEX IF NOT EXISTS idx_wallet_transactions_client ON wallet_transactions (client_id, created_at);
+
+-- The balances credited by incidents move to the ledger, as credits from
+-- the compensation account
+INSERT INTO wallet_accounts (client_id, balance)
+SELECT id, balance FROM clients WHERE balance > 0;
+
+WITH opening AS (
+    INSERT INTO wallet_transactions (type, client_id, reason)
+    SELECT 'credit', id, 'Saldo de indenizações anterior à carteira' FROM clients WHERE balance > 0
+    RETURNING id, client_id
+)
+INSERT INTO wallet_entries (transaction_id, account_id, amount)
+SELECT o.id, a.id, c.balance
+FROM opening o
+JOIN clients c ON c.id = o.client_id
+JOIN wallet_accounts a ON a.client_id = o.client_id
+UNION ALL
+SELECT o.id, (SELECT id FROM wallet_accounts WHERE code = 'compensation'), -c.balance
+FROM opening o
+JOIN clients c ON c.id = o.client_id;
+
+UPDATE wallet_accounts SET balance = balance - (SELECT COALESCE(SUM(balance), 0) FROM clients WHERE balance > 0)
+WHERE code = 'compensation';
+
+ALTER TABLE clients DROP COLUMN IF EXISTS balance;
@@ -0,0 +1,5 @@
+ALTER TABLE laundry_services
+DROP COLUMN IF EXISTS loyalty_discount;
+
+DROP TABLE IF EXISTS loyalty_entries;
+DROP TABLE IF EXISTS loyalty_settings;
@@ -0,0 +1,40 @@
+-- A single row: points earned per real paid, what a point is worth when
+-- redeemed, the fewest points redeemed at once, the largest share of a
+-- service paid with points and how many days points last, where 0 means
+-- they never expire
+CREATE TABLE IF NOT EXISTS loyalty_settings (
+    id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    is_active boolean NOT NULL DEFAULT TRUE,
+    points_per_real NUMERIC(6, 2) NOT NULL DEFAULT 1 CHECK (points_per_real >= 0),
+    point_value NUMERIC(6, 4) NOT NULL DEFAULT 0.05 CHECK (point_value >= 0),
+    min_redemption INT NOT NULL DEFAULT 100 CHECK (min_redemption >= 0),
+    max_discount NUMERIC(5, 2) NOT NULL DEFAULT 50 CHECK (max_discount BETWEEN 0 AND 100),
+    expiry_days INT NOT NULL DEFAULT 365 CHECK (expiry_days >= 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO loyalty_settings (id) VALUES (1) ON CONFLICT (id) DO NOTHING;
+
+-- points is signed: earned on a paid service, taken when redeemed on a new
+-- one or when they expire. remaining is what is left of the points of an
+-- earn entry; redemptions and expiry take from the oldest first.
+CREATE TABLE IF NOT EXISTS loyalty_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('earn', 'redeem', 'expire')),
+    points INT NOT NULL CHECK (points <> 0),
+    remaining INT NOT NULL DEFAULT 0 CHECK (remaining >= 0),
+    laundry_service_id UUID REFERENCES laundry_services(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (remaining <= GREATEST(points, 0))
+);
+
+-- A service earns points once, however many times it is paid
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loyalty_entries_earned ON loyalty_entries (laundry_service_id) WHERE type = 'earn';
+CREATE INDEX IF NOT EXISTS idx_loyalty_entries_client ON loyalty_entries (client_id, created_at);
+
+-- The discount of the points redeemed on the service, already taken from
+-- its total price
+ALTER TABLE laundry_services
+ADD COLUMN IF NOT EXISTS loyalty_discount NUMERIC(10, 2) NOT NULL DEFAULT 0;
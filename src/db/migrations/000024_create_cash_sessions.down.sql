@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_laundry_services_paid_at;
+
+ALTER TABLE laundry_services
+DROP COLUMN IF EXISTS payment_method,
+DROP COLUMN IF EXISTS paid_at;
+
+DROP TABLE IF EXISTS cash_movements;
+DROP TABLE IF EXISTS cash_sessions;
@@ -0,0 +1,46 @@
+-- A session of the cash drawer, from when it is opened with the change
+-- float to when it is closed with the amount counted. expected is the cash
+-- the drawer should hold when it was closed. Only one drawer is open at a
+-- time.
+CREATE TABLE IF NOT EXISTS cash_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    opening_float NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (opening_float >= 0),
+    opened_by UUID,
+    opened_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expected NUMERIC(10, 2),
+    counted NUMERIC(10, 2) CHECK (counted >= 0),
+    closed_by UUID,
+    closed_at TIMESTAMP,
+    notes VARCHAR(255) NOT NULL DEFAULT '',
+    CHECK ((closed_at IS NULL) = (counted IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cash_sessions_open ON cash_sessions ((closed_at IS NULL)) WHERE closed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_cash_sessions_opened_at ON cash_sessions (opened_at);
+
+-- Payments are taken at the counter for a service, in cash, by card or by
+-- pix; only cash goes into the drawer. Withdrawals take cash out of it.
+CREATE TABLE IF NOT EXISTS cash_movements (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id UUID NOT NULL REFERENCES cash_sessions(id) ON DELETE CASCADE,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('payment', 'withdrawal')),
+    method VARCHAR(5) NOT NULL DEFAULT 'cash' CHECK (method IN ('cash', 'card', 'pix')),
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    laundry_service_id UUID REFERENCES laundry_services(id) ON DELETE SET NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_by UUID,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (type = 'payment' OR method = 'cash')
+);
+
+CREATE INDEX IF NOT EXISTS idx_cash_movements_session ON cash_movements (session_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_cash_movements_service ON cash_movements (laundry_service_id);
+
+-- When and how a service was paid. Sessions are reconciled against the
+-- services paid while they were open; a service marked paid without a
+-- payment taken keeps a NULL method.
+ALTER TABLE laundry_services
+ADD COLUMN IF NOT EXISTS payment_method VARCHAR(6) CHECK (payment_method IN ('cash', 'card', 'pix', 'wallet')),
+ADD COLUMN IF NOT EXISTS paid_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_laundry_services_paid_at ON laundry_services (paid_at);
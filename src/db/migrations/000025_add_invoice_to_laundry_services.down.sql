@@ -0,0 +1,10 @@
+ALTER TABLE laundry_services
+DROP COLUMN IF EXISTS rps_number,
+DROP COLUMN IF EXISTS invoice_status,
+DROP COLUMN IF EXISTS invoice_number,
+DROP COLUMN IF EXISTS invoice_verification_code,
+DROP COLUMN IF EXISTS invoice_pdf_url,
+DROP COLUMN IF EXISTS invoice_message,
+DROP COLUMN IF EXISTS invoiced_at;
+
+DROP SEQUENCE IF EXISTS nfse_rps_number;
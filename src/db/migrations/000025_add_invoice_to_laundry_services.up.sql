@@ -0,0 +1,16 @@
+-- Each service invoiced is sent as an RPS numbered from this sequence. The
+-- number is kept when the city rejects the RPS, so it is sent again with
+-- the same number once corrected.
+CREATE SEQUENCE IF NOT EXISTS nfse_rps_number;
+
+-- The NFS-e issued by the city for the service, or the reason it rejected
+-- its RPS. While the RPS is sent to the provider it is processing, since
+-- invoiced_at; once issued, invoiced_at is when the NFS-e was.
+ALTER TABLE laundry_services
+ADD COLUMN IF NOT EXISTS rps_number BIGINT UNIQUE,
+ADD COLUMN IF NOT EXISTS invoice_status VARCHAR(10) CHECK (invoice_status IN ('processing', 'issued', 'rejected')),
+ADD COLUMN IF NOT EXISTS invoice_number VARCHAR(20),
+ADD COLUMN IF NOT EXISTS invoice_verification_code VARCHAR(50),
+ADD COLUMN IF NOT EXISTS invoice_pdf_url TEXT,
+ADD COLUMN IF NOT EXISTS invoice_message VARCHAR(255),
+ADD COLUMN IF NOT EXISTS invoiced_at TIMESTAMP;
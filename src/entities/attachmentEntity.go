@@ -0,0 +1,26 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AttachmentEntity represents the service_attachments table in the
+// database: a photo of the garments of a service. The keys locate the
+// photo and its thumbnail in the storage and aren't sent to clients.
+type AttachmentEntity struct {
+	ID               uuid.UUID  `json:"id" db:"id"`
+	LaundryServiceID uuid.UUID  `json:"laundry_service_id" db:"laundry_service_id"`
+	Stage            string     `json:"stage" db:"stage"`
+	Filename         string     `json:"filename" db:"filename"`
+	ContentType      string     `json:"content_type" db:"content_type"`
+	Size             int64      `json:"size" db:"size"`
+	Width            int        `json:"width" db:"width"`
+	Height           int        `json:"height" db:"height"`
+	StorageKey       string     `json:"-" db:"storage_key"`
+	ThumbnailKey     string     `json:"-" db:"thumbnail_key"`
+	Notes            string     `json:"notes" db:"notes"`
+	UploadedBy       *uuid.UUID `json:"uploaded_by" db:"uploaded_by"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+}
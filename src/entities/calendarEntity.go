@@ -0,0 +1,38 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OpeningHoursEntity represents the opening_hours table in the database:
+// when the shop opens on a weekday, from 0 (Sunday) to 6 (Saturday).
+// Weekdays without a row are closed.
+type OpeningHoursEntity struct {
+	Weekday  int    `json:"weekday" db:"weekday"`
+	OpensAt  string `json:"opens_at" db:"opens_at"`
+	ClosesAt string `json:"closes_at" db:"closes_at"`
+}
+
+// HolidayEntity represents the holidays table in the database. Recurring
+// holidays repeat every year on the same day. National holidays aren't
+// stored: they are listed with National set and no ID.
+type HolidayEntity struct {
+	ID        *uuid.UUID `json:"id" db:"id"`
+	Date      string     `json:"date" db:"date"`
+	Name      string     `json:"name" db:"name"`
+	Recurring bool       `json:"recurring" db:"recurring"`
+	National  bool       `json:"national" db:"-"`
+}
+
+// ClosureEntity represents the closures table in the database: a period
+// the shop is closed outside of holidays, such as a power cut or a
+// staff event
+type ClosureEntity struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	StartsAt  time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt    time.Time `json:"ends_at" db:"ends_at"`
+	Reason    string    `json:"reason" db:"reason"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,40 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CashSessionEntity represents the cash_sessions table in the database: the
+// cash drawer from when it was opened with OpeningFloat to when it was
+// closed with Counted. Expected is the cash it should have held when
+// closed; Difference isn't stored: it is Counted less Expected, positive
+// when the drawer is over and negative when it is short.
+type CashSessionEntity struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	OpeningFloat float64    `json:"opening_float" db:"opening_float"`
+	OpenedBy     *uuid.UUID `json:"opened_by" db:"opened_by"`
+	OpenedAt     time.Time  `json:"opened_at" db:"opened_at"`
+	Expected     *float64   `json:"expected" db:"expected"`
+	Counted      *float64   `json:"counted" db:"counted"`
+	Difference   *float64   `json:"difference" db:"difference"`
+	ClosedBy     *uuid.UUID `json:"closed_by" db:"closed_by"`
+	ClosedAt     *time.Time `json:"closed_at" db:"closed_at"`
+	Notes        string     `json:"notes" db:"notes"`
+}
+
+// CashMovementEntity represents the cash_movements table in the database:
+// a payment taken at the counter for a service, or a withdrawal of cash
+// from the drawer
+type CashMovementEntity struct {
+	ID               uuid.UUID  `json:"id" db:"id"`
+	SessionID        uuid.UUID  `json:"session_id" db:"session_id"`
+	Type             string     `json:"type" db:"type"`
+	Method           string     `json:"method" db:"method"`
+	Amount           float64    `json:"amount" db:"amount"`
+	LaundryServiceID *uuid.UUID `json:"laundry_service_id" db:"laundry_service_id"`
+	Reason           string     `json:"reason" db:"reason"`
+	CreatedBy        *uuid.UUID `json:"created_by" db:"created_by"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,23 @@
+package entities
+
+import "github.com/google/uuid"
+
+// ClientAddressEntity represents an address of a client, the client_addresses
+// table joined with address. The default address is the one kept in
+// clients.address_id and used when a service doesn't choose another.
+type ClientAddressEntity struct {
+	AddressID    uuid.UUID `json:"address_id" db:"address_id"`
+	ClientID     uuid.UUID `json:"client_id" db:"client_id"`
+	Label        string    `json:"label" db:"label"`
+	IsDefault    bool      `json:"is_default" db:"is_default"`
+	Street       string    `json:"street" db:"street"`
+	Neighborhood string    `json:"neighborhood" db:"neighborhood"`
+	City         string    `json:"city" db:"city"`
+	State        string    `json:"state" db:"state"`
+	PostalCode   string    `json:"postal_code" db:"postal_code"`
+	Number       string    `json:"number" db:"number"`
+	Complement   string    `json:"complement" db:"complement"`
+	Landmark     string    `json:"landmark" db:"landmark"`
+	Latitude     *float64  `json:"latitude" db:"latitude"`
+	Longitude    *float64  `json:"longitude" db:"longitude"`
+}
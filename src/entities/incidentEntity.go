@@ -0,0 +1,25 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// IncidentEntity represents the incidents table in the database: a garment
+// damaged or lost, or a complaint, on a service. LaundryItemID is set when
+// the incident is about a line of the service.
+type IncidentEntity struct {
+	ID               uuid.UUID      `json:"id" db:"id"`
+	LaundryServiceID uuid.UUID      `json:"laundry_service_id" db:"laundry_service_id"`
+	LaundryItemID    *uuid.UUID     `json:"laundry_item_id" db:"laundry_item_id"`
+	Type             string         `json:"type" db:"type"`
+	Description      string         `json:"description" db:"description"`
+	Photos           pq.StringArray `json:"photos" db:"photos"`
+	Compensation     float64        `json:"compensation" db:"compensation"`
+	Status           string         `json:"status" db:"status"`
+	Resolution       string         `json:"resolution" db:"resolution"`
+	CreatedAt        time.Time      `json:"created_at" db:"created_at"`
+	ResolvedAt       *time.Time     `json:"resolved_at" db:"resolved_at"`
+}
@@ -0,0 +1,22 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InvoiceEntity represents the NFS-e columns of the laundry_services table
+// in the database. RPSNumber is the number of the RPS sent to the city and
+// Status whether the city issued the invoice or rejected the RPS, with the
+// reason in Message.
+type InvoiceEntity struct {
+	ServiceID        uuid.UUID  `json:"service_id" db:"id"`
+	RPSNumber        *int64     `json:"rps_number" db:"rps_number"`
+	Status           *string    `json:"status" db:"invoice_status"`
+	Number           *string    `json:"number" db:"invoice_number"`
+	VerificationCode *string    `json:"verification_code" db:"invoice_verification_code"`
+	PDFURL           *string    `json:"pdf_url" db:"invoice_pdf_url"`
+	Message          *string    `json:"message" db:"invoice_message"`
+	InvoicedAt       *time.Time `json:"invoiced_at" db:"invoiced_at"`
+}
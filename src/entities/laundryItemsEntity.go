@@ -7,6 +7,6 @@ import (
 // LaundryItemsEntity represents the laundry_items table in the database
 type LaundryItemsEntity struct {
 	ID    uuid.UUID `json:"id" db:"id"`
-	Name  string    `json:"name" db:"name"`
-	Price float64   `json:"price" db:"price"`
+	Name  string    `json:"name" db:"name" validate:"required,max=100"`
+	Price float64   `json:"price" db:"price" validate:"required,positive"`
 }
@@ -9,16 +9,19 @@ import (
 // LaundryServicesEntity represents the laundry_services table in the database
 type LaundryServicesEntity struct {
 	ID                      uuid.UUID  `json:"id" db:"id"`
-	Status                  string     `json:"status" db:"status"`
+	Status                  string     `json:"status" db:"status" validate:"required,oneof=Separado|Lavando|Secando|Passando|Finalizado"`
 	Type                    string     `json:"type" db:"type"`
 	CreatedAt               time.Time  `json:"created_at" db:"created_at"`
 	CompletedAt             *time.Time `json:"completed_at" db:"completed_at"`
 	EstimatedCompletionDate time.Time  `json:"estimated_completion_date" db:"estimated_completion_date"`
-	TotalPrice              float64    `json:"price" db:"price"`
-	Weight                  float64    `json:"weight"`
-	IsWeight                bool       `json:"is_weight"`
-	IsPiece                 bool       `json:"is_piece"`
-	IsMonthly               bool       `json:"is_monthly"`
-	ClientID                uuid.UUID  `json:"client_id"`
-	IsPaid                  bool       `json:"is_paid"`
+	TotalPrice              float64    `json:"price" db:"total_price"`
+	Weight                  float64    `json:"weight" db:"weight" validate:"required_if=IsWeight,positive"`
+	IsWeight                bool       `json:"is_weight" db:"is_weight"`
+	IsPiece                 bool       `json:"is_piece" db:"is_piece"`
+	IsMonthly               bool       `json:"is_monthly" db:"is_monthly"`
+	ClientID                uuid.UUID  `json:"client_id" db:"client_id" validate:"required"`
+	IsPaid                  bool       `json:"is_paid" db:"is_paid"`
+	AddressID               *uuid.UUID `json:"address_id" db:"address_id"`
+	Priority                string     `json:"priority" db:"priority" validate:"oneof=normal|express|same_day"`
+	Surcharge               float64    `json:"surcharge" db:"surcharge"`
 }
@@ -0,0 +1,36 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoyaltySettingsEntity represents the loyalty_settings table in the
+// database, a single row: the points earned per real paid, what a point
+// is worth when redeemed, the fewest points redeemed at once, the largest
+// percentage of a service paid with points and how many days points last,
+// where 0 means they never expire.
+type LoyaltySettingsEntity struct {
+	IsActive      bool       `json:"is_active" db:"is_active"`
+	PointsPerReal float64    `json:"points_per_real" db:"points_per_real"`
+	PointValue    float64    `json:"point_value" db:"point_value"`
+	MinRedemption int        `json:"min_redemption" db:"min_redemption"`
+	MaxDiscount   float64    `json:"max_discount" db:"max_discount"`
+	ExpiryDays    int        `json:"expiry_days" db:"expiry_days"`
+	UpdatedAt     *time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// LoyaltyEntryEntity represents the loyalty_entries table in the database.
+// Points are positive when earned. Remaining is what is left of the points
+// of an earn entry, until ExpiresAt.
+type LoyaltyEntryEntity struct {
+	ID               uuid.UUID  `json:"id" db:"id"`
+	ClientID         uuid.UUID  `json:"client_id" db:"client_id"`
+	Type             string     `json:"type" db:"type"`
+	Points           int        `json:"points" db:"points"`
+	Remaining        int        `json:"remaining" db:"remaining"`
+	LaundryServiceID *uuid.UUID `json:"laundry_service_id" db:"laundry_service_id"`
+	ExpiresAt        *time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,34 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MachineEntity represents the machines table in the database: a washer,
+// dryer or iron and how many kilos it takes per load. Machines that were
+// used are disabled instead of deleted.
+type MachineEntity struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	Name       string    `json:"name" db:"name"`
+	Type       string    `json:"type" db:"type"`
+	CapacityKg float64   `json:"capacity_kg" db:"capacity_kg"`
+	IsActive   bool      `json:"is_active" db:"is_active"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// MachineLoadEntity represents the machine_loads table in the database: a
+// cycle of a machine with the services of machine_load_services. Stage is
+// the status the services were worked in, and EndedAt is nil while the
+// machine runs.
+type MachineLoadEntity struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	MachineID  uuid.UUID  `json:"machine_id" db:"machine_id"`
+	OperatorID *uuid.UUID `json:"operator_id" db:"operator_id"`
+	Stage      string     `json:"stage" db:"stage"`
+	Weight     float64    `json:"weight" db:"weight"`
+	Notes      string     `json:"notes" db:"notes"`
+	StartedAt  time.Time  `json:"started_at" db:"started_at"`
+	EndedAt    *time.Time `json:"ended_at" db:"ended_at"`
+}
@@ -0,0 +1,14 @@
+package entities
+
+import "github.com/google/uuid"
+
+// NotificationPreferencesEntity represents the notification_preferences
+// table in the database: the channels through which a client is told about
+// their orders. Clients without a row are notified by WhatsApp only.
+type NotificationPreferencesEntity struct {
+	ClientID        uuid.UUID `json:"client_id" db:"client_id"`
+	Email           string    `json:"email" db:"email" validate:"required_if=EmailEnabled,max=255,email"`
+	EmailEnabled    bool      `json:"email_enabled" db:"email_enabled"`
+	SMSEnabled      bool      `json:"sms_enabled" db:"sms_enabled"`
+	WhatsAppEnabled bool      `json:"whatsapp_enabled" db:"whatsapp_enabled"`
+}
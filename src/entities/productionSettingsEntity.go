@@ -0,0 +1,16 @@
+package entities
+
+import "time"
+
+// ProductionSettingsEntity represents the production_settings table in the
+// database, a single row: how much the laundry finishes per day, where 0
+// means unlimited, and the percentage added to the price of express and
+// same-day services. Same-day services are taken until SameDayCutoff.
+type ProductionSettingsEntity struct {
+	MaxKgPerDay      float64    `json:"max_kg_per_day" db:"max_kg_per_day"`
+	MaxPiecesPerDay  int        `json:"max_pieces_per_day" db:"max_pieces_per_day"`
+	ExpressSurcharge float64    `json:"express_surcharge" db:"express_surcharge"`
+	SameDaySurcharge float64    `json:"same_day_surcharge" db:"same_day_surcharge"`
+	SameDayCutoff    string     `json:"same_day_cutoff" db:"same_day_cutoff"`
+	UpdatedAt        *time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,22 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServiceBookingEntity represents the service_bookings table in the database,
+// a pickup or delivery of a laundry service in a time slot
+type ServiceBookingEntity struct {
+	ID               uuid.UUID  `json:"id" db:"id"`
+	LaundryServiceID uuid.UUID  `json:"laundry_service_id" db:"laundry_service_id"`
+	Type             string     `json:"type" db:"type"`
+	Date             string     `json:"date" db:"date"`
+	TimeSlotID       uuid.UUID  `json:"time_slot_id" db:"time_slot_id"`
+	DriverID         *uuid.UUID `json:"driver_id" db:"driver_id"`
+	Status           string     `json:"status" db:"status"`
+	Notes            string     `json:"notes" db:"notes"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+}
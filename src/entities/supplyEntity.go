@@ -0,0 +1,39 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SupplyEntity represents the supplies table in the database: a consumable
+// such as detergent, softener or bags, and how much of it is in stock.
+// UsagePerLoad and UsagePerKg estimate what each wash load consumes.
+// LowStock isn't stored: it tells whether the stock fell to ReorderLevel.
+type SupplyEntity struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	Name         string    `json:"name" db:"name"`
+	Unit         string    `json:"unit" db:"unit"`
+	Stock        float64   `json:"stock" db:"stock"`
+	ReorderLevel float64   `json:"reorder_level" db:"reorder_level"`
+	UsagePerLoad float64   `json:"usage_per_load" db:"usage_per_load"`
+	UsagePerKg   float64   `json:"usage_per_kg" db:"usage_per_kg"`
+	IsActive     bool      `json:"is_active" db:"is_active"`
+	LowStock     bool      `json:"low_stock" db:"low_stock"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// StockMovementEntity represents the stock_movements table in the
+// database. Quantity is positive when it adds to the stock. Consumption
+// estimated from a wash load is flagged Estimated.
+type StockMovementEntity struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	SupplyID  uuid.UUID  `json:"supply_id" db:"supply_id"`
+	Type      string     `json:"type" db:"type"`
+	Quantity  float64    `json:"quantity" db:"quantity"`
+	UnitCost  *float64   `json:"unit_cost" db:"unit_cost"`
+	LoadID    *uuid.UUID `json:"load_id" db:"load_id"`
+	Estimated bool       `json:"estimated" db:"estimated"`
+	Notes     string     `json:"notes" db:"notes"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,14 @@
+package entities
+
+import "github.com/google/uuid"
+
+// TimeSlotEntity represents the time_slots table in the database. Every
+// active slot is offered each day for pickups and deliveries, up to
+// Capacity bookings.
+type TimeSlotEntity struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	StartTime string    `json:"start_time" db:"start_time"`
+	EndTime   string    `json:"end_time" db:"end_time"`
+	Capacity  int       `json:"capacity" db:"capacity"`
+	IsActive  bool      `json:"is_active" db:"is_active"`
+}
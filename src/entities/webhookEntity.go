@@ -0,0 +1,39 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// WebhookEntity represents the webhooks table in the database: an endpoint
+// told about the domain events it subscribed to. The secret is only
+// returned when the webhook is created or its secret is changed.
+type WebhookEntity struct {
+	ID          uuid.UUID      `json:"id" db:"id"`
+	URL         string         `json:"url" db:"url"`
+	Secret      string         `json:"secret,omitempty" db:"secret"`
+	EventTypes  pq.StringArray `json:"event_types" db:"event_types"`
+	Description string         `json:"description" db:"description"`
+	IsActive    bool           `json:"is_active" db:"is_active"`
+	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookDeliveryEntity represents the webhook_deliveries table in the
+// database: the attempts to send one event to one webhook
+type WebhookDeliveryEntity struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	WebhookID     uuid.UUID  `json:"webhook_id" db:"webhook_id"`
+	EventID       int64      `json:"event_id" db:"event_id"`
+	EventType     string     `json:"event_type" db:"event_type"`
+	Status        string     `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	ResponseCode  *int       `json:"response_code" db:"response_code"`
+	ResponseBody  *string    `json:"response_body" db:"response_body"`
+	LastError     *string    `json:"last_error" db:"last_error"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at" db:"delivered_at"`
+}
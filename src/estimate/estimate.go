@@ -0,0 +1,247 @@
+// Package estimate proposes when a new service will be ready, from how long
+// similar services took, how much work is queued and the working hours of
+// the laundry.
+package estimate
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Basis tells which history an estimate was based on, from the most to the
+// least specific
+type Basis string
+
+const (
+	BasisMix     Basis = "mix"     // same kind and size, sharing most items
+	BasisSize    Basis = "size"    // same kind and similar size
+	BasisKind    Basis = "kind"    // same kind, by weight or by piece
+	BasisAll     Basis = "all"     // every finished service
+	BasisDefault Basis = "default" // not enough history
+)
+
+// MinSamples is how many similar services are needed to trust their times
+const MinSamples = 5
+
+// DefaultTurnaround is the working time assumed without enough history
+const DefaultTurnaround = 20 * time.Hour
+
+// Remaining is the share of its work a queued service still needs in each
+// status. Finished services aren't queued.
+var Remaining = map[string]float64{
+	"Separado": 1,
+	"Lavando":  0.75,
+	"Secando":  0.5,
+	"Passando": 0.25,
+}
+
+// Order describes the service to estimate
+type Order struct {
+	IsWeight bool
+	Weight   float64
+	Pieces   int
+	Items    []string
+}
+
+// Sample is a finished service
+type Sample struct {
+	Order
+	CreatedAt   time.Time
+	CompletedAt time.Time
+}
+
+// Input is what an estimate is based on
+type Input struct {
+	Order   Order
+	History []Sample
+	Queue   map[string]int // services per status
+	Now     time.Time
+}
+
+// Result is the proposed completion date and how it was reached
+type Result struct {
+	EstimatedCompletionDate time.Time      `json:"estimated_completion_date"`
+	WorkingHours            float64        `json:"working_hours"`
+	HistoryHours            float64        `json:"history_hours"`
+	QueueHours              float64        `json:"queue_hours"`
+	QueueDepth              map[string]int `json:"queue_depth"`
+	Basis                   Basis          `json:"basis"`
+	Samples                 int            `json:"samples"`
+}
+
+// Estimate proposes the completion date of the order. The turnaround is
+// the 75th percentile of the working time similar services took, so most
+// promises are kept. When more work is queued than usual, the time the
+// laundry needs to clear the excess at its recent pace is added on top.
+func Estimate(in Input, hours Hours) Result {
+	durations := make([]time.Duration, 0, len(in.History))
+	samples := make([]Sample, 0, len(in.History))
+	for _, s := range in.History {
+		if d := hours.Between(s.CreatedAt, s.CompletedAt); d > 0 {
+			durations = append(durations, d)
+			samples = append(samples, s)
+		}
+	}
+
+	basis, similar := similarDurations(in.Order, samples, durations)
+	turnaround := DefaultTurnaround
+	if basis != BasisDefault {
+		turnaround = percentile(similar, 0.75)
+	}
+
+	queue := queueDelay(in, hours, samples, durations)
+
+	total := turnaround + queue
+	eta := ceilHour(hours.Add(in.Now, total))
+
+	depth := make(map[string]int, len(in.Queue))
+	for status, n := range in.Queue {
+		if _, ok := Remaining[status]; ok {
+			depth[status] = n
+		}
+	}
+
+	return Result{
+		EstimatedCompletionDate: eta,
+		WorkingHours:            roundHours(total),
+		HistoryHours:            roundHours(turnaround),
+		QueueHours:              roundHours(queue),
+		QueueDepth:              depth,
+		Basis:                   basis,
+		Samples:                 len(similar),
+	}
+}
+
+// similarDurations returns the durations of the most specific group of
+// samples that has at least MinSamples services
+func similarDurations(order Order, samples []Sample, durations []time.Duration) (Basis, []time.Duration) {
+	groups := []struct {
+		basis Basis
+		match func(Sample) bool
+	}{
+		{BasisMix, func(s Sample) bool { return sameSize(order, s.Order) && overlap(order.Items, s.Items) >= 0.5 }},
+		{BasisSize, func(s Sample) bool { return sameSize(order, s.Order) }},
+		{BasisKind, func(s Sample) bool { return s.IsWeight == order.IsWeight }},
+		{BasisAll, func(Sample) bool { return true }},
+	}
+
+	for _, g := range groups {
+		var matched []time.Duration
+		for i, s := range samples {
+			if g.match(s) {
+				matched = append(matched, durations[i])
+			}
+		}
+		if len(matched) >= MinSamples {
+			return g.basis, matched
+		}
+	}
+	return BasisDefault, nil
+}
+
+// sameSize tells whether two services are of the same kind and their
+// weight, or number of pieces, are within 50% of each other
+func sameSize(a, b Order) bool {
+	if a.IsWeight != b.IsWeight {
+		return false
+	}
+	if a.IsWeight {
+		return within(a.Weight, b.Weight, 1.5)
+	}
+	return within(float64(a.Pieces), float64(b.Pieces), 1.5)
+}
+
+func within(a, b, ratio float64) bool {
+	if a <= 0 || b <= 0 {
+		return a == b
+	}
+	return a <= b*ratio && b <= a*ratio
+}
+
+// overlap is the Jaccard index of two item lists: the items in both over
+// the items in either
+func overlap(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	set := make(map[string]bool, len(a))
+	for _, id := range a {
+		set[id] = true
+	}
+	union := len(set)
+	common := 0
+	seen := map[string]bool{}
+	for _, id := range b {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if set[id] {
+			common++
+		} else {
+			union++
+		}
+	}
+	return float64(common) / float64(union)
+}
+
+// queueDelay is the working time needed to clear the queued work above
+// the usual. By Little's law the laundry usually holds throughput times
+// turnaround services; anything above that is cleared at the throughput
+// of the last 30 days.
+func queueDelay(in Input, hours Hours, samples []Sample, durations []time.Duration) time.Duration {
+	var load float64
+	for status, n := range in.Queue {
+		load += Remaining[status] * float64(n)
+	}
+	if load == 0 || len(samples) == 0 {
+		return 0
+	}
+
+	since := in.Now.AddDate(0, 0, -30)
+	finished := 0
+	for _, s := range samples {
+		if !s.CompletedAt.Before(since) {
+			finished++
+		}
+	}
+	worked := hours.Between(since, in.Now).Hours()
+	if finished == 0 || worked == 0 {
+		return 0
+	}
+
+	throughput := float64(finished) / worked
+	usual := throughput * percentile(durations, 0.5).Hours()
+	if load <= usual {
+		return 0
+	}
+	return time.Duration((load - usual) / throughput * float64(time.Hour))
+}
+
+// percentile returns the p-th percentile of durations, interpolating
+// between the closest ranks
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + time.Duration(float64(sorted[upper]-sorted[lower])*(rank-float64(lower)))
+}
+
+// ceilHour rounds t up to the next full hour, so proposals read naturally
+func ceilHour(t time.Time) time.Time {
+	if rounded := t.Truncate(time.Hour); rounded.Before(t) {
+		return rounded.Add(time.Hour)
+	}
+	return t
+}
+
+func roundHours(d time.Duration) float64 {
+	return math.Round(d.Hours()*10) / 10
+}
@@ -0,0 +1,68 @@
+package estimate
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// HistoryWindow is how far back finished services are used as history
+const HistoryWindow = 180 * 24 * time.Hour
+
+// historyLimit caps the services loaded, keeping the most recent ones
+const historyLimit = 2000
+
+// Load reads the finished services of the history window and the current
+// queue, ready to estimate order at now
+func Load(db sqlx.Queryer, order Order, now time.Time) (Input, error) {
+	var rows []struct {
+		CreatedAt   time.Time      `db:"created_at"`
+		CompletedAt time.Time      `db:"completed_at"`
+		IsWeight    bool           `db:"is_weight"`
+		Weight      float64        `db:"weight"`
+		Pieces      int            `db:"pieces"`
+		Items       pq.StringArray `db:"items"`
+	}
+	err := sqlx.Select(db, &rows, `
+		SELECT ls.created_at,
+		       ls.completed_at,
+		       COALESCE(ls.is_weight, FALSE) AS is_weight,
+		       COALESCE(ls.weight, 0) AS weight,
+		       COALESCE(SUM(lis.item_quantity), 0) AS pieces,
+		       COALESCE(array_agg(lis.laundry_item_id::text) FILTER (WHERE lis.laundry_item_id IS NOT NULL), '{}') AS items
+		FROM laundry_services ls
+		LEFT JOIN laundry_items_services lis ON lis.laundry_service_id = ls.id
+		WHERE ls.completed_at IS NOT NULL AND ls.completed_at > ls.created_at AND ls.created_at >= $1
+		GROUP BY ls.id
+		ORDER BY ls.completed_at DESC
+		LIMIT $2`, now.Add(-HistoryWindow), historyLimit)
+	if err != nil {
+		return Input{}, err
+	}
+
+	var queue []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	err = sqlx.Select(db, &queue, `
+		SELECT status, COUNT(*) AS count FROM laundry_services
+		WHERE completed_at IS NULL
+		GROUP BY status`)
+	if err != nil {
+		return Input{}, err
+	}
+
+	in := Input{Order: order, Now: now, Queue: make(map[string]int, len(queue))}
+	for _, row := range rows {
+		in.History = append(in.History, Sample{
+			Order:       Order{IsWeight: row.IsWeight, Weight: row.Weight, Pieces: row.Pieces, Items: row.Items},
+			CreatedAt:   row.CreatedAt,
+			CompletedAt: row.CompletedAt,
+		})
+	}
+	for _, q := range queue {
+		in.Queue[q.Status] = q.Count
+	}
+	return in, nil
+}
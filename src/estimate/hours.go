@@ -0,0 +1,13 @@
+package estimate
+
+import "time"
+
+// Hours is the working schedule of the laundry, such as its business
+// calendar. Turnaround times are measured in working time, so a service
+// left overnight or over a holiday doesn't count as being worked on.
+type Hours interface {
+	// Add returns the instant reached after working d starting at t
+	Add(t time.Time, d time.Duration) time.Time
+	// Between returns the working time between from and to
+	Between(from, to time.Time) time.Duration
+}
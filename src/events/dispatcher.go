@@ -0,0 +1,205 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Handler receives an event. Delivery is at least once: a handler may see
+// the same event again if it, or the dispatcher, fails before the delivery
+// is recorded, so handlers must be idempotent on Event.ID.
+type Handler func(ctx context.Context, event Event) error
+
+type subscriber struct {
+	name   string
+	types  map[Type]bool
+	handle Handler
+}
+
+func (s subscriber) wants(typ Type) bool {
+	return len(s.types) == 0 || s.types[typ]
+}
+
+// Dispatcher delivers the outbox to the subscribers. Each subscriber's
+// delivery of an event is recorded, so when one subscriber fails only that
+// one is retried.
+type Dispatcher struct {
+	DB *sqlx.DB
+
+	Interval  time.Duration // between outbox polls, defaults to 5 seconds
+	BatchSize int           // events dispatched per poll, defaults to 50
+
+	mu          sync.RWMutex
+	subscribers []subscriber
+}
+
+// Subscribe registers handle under a unique name for the given types, or
+// for every type when none is given. The name is what records the
+// deliveries, so it must not change between releases.
+func (d *Dispatcher) Subscribe(name string, handle Handler, types ...Type) {
+	wanted := make(map[Type]bool, len(types))
+	for _, typ := range types {
+		wanted[typ] = true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.subscribers = append(d.subscribers, subscriber{name: name, types: wanted, handle: handle})
+}
+
+// RetryDelay is how long to wait before the next attempt after attempt
+// failed ones: 10 seconds doubling up to 1 hour
+func RetryDelay(attempt int) time.Duration {
+	delay := 10 * time.Second
+	for i := 1; i < attempt && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	return min(delay, time.Hour)
+}
+
+// Run dispatches the outbox until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.defaults()
+
+	poll := time.NewTicker(d.Interval)
+	defer poll.Stop()
+
+	for {
+		for {
+			dispatched, err := d.Dispatch(ctx)
+			if err != nil {
+				log.Printf("events: dispatching outbox: %v", err)
+			}
+			if dispatched < d.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		}
+	}
+}
+
+func (d *Dispatcher) defaults() {
+	if d.Interval <= 0 {
+		d.Interval = 5 * time.Second
+	}
+	if d.BatchSize <= 0 {
+		d.BatchSize = 50
+	}
+}
+
+// eventRow is an event claimed for dispatch
+type eventRow struct {
+	ID          int64     `db:"id"`
+	Type        Type      `db:"type"`
+	AggregateID uuid.UUID `db:"aggregate_id"`
+	Payload     string    `db:"payload"`
+	OccurredAt  time.Time `db:"occurred_at"`
+	Attempts    int       `db:"attempts"`
+}
+
+// Dispatch hands one batch of due events, oldest first, to the subscribers
+// that haven't received them yet and returns how many were attempted.
+// Events are locked while being dispatched, so several dispatchers can run
+// at once. An event whose subscribers all succeeded is marked dispatched;
+// otherwise it is retried later for the failed ones only.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	d.defaults()
+
+	tx, err := d.DB.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	// Rolls back on the early returns; a no-op after Commit
+	defer tx.Rollback()
+
+	rows := []eventRow{}
+	err = tx.Select(&rows, `
+		SELECT id, type, aggregate_id, payload::text AS payload, occurred_at, attempts
+		FROM domain_events
+		WHERE dispatched_at IS NULL AND next_attempt_at <= NOW()
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`, d.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	d.mu.RLock()
+	subscribers := d.subscribers
+	d.mu.RUnlock()
+
+	for _, row := range rows {
+		event := Event{ID: row.ID, Type: row.Type, AggregateID: row.AggregateID, Payload: json.RawMessage(row.Payload), OccurredAt: row.OccurredAt}
+
+		delivered := []string{}
+		err = tx.Select(&delivered, "SELECT subscriber FROM domain_event_deliveries WHERE event_id = $1", event.ID)
+		if err != nil {
+			return 0, err
+		}
+		done := make(map[string]bool, len(delivered))
+		for _, name := range delivered {
+			done[name] = true
+		}
+
+		var failure error
+		for _, s := range subscribers {
+			if done[s.name] || !s.wants(event.Type) {
+				continue
+			}
+			if handleErr := deliver(ctx, s, event); handleErr != nil {
+				failure = handleErr
+				continue
+			}
+			_, err = tx.Exec("INSERT INTO domain_event_deliveries (event_id, subscriber) VALUES ($1, $2) ON CONFLICT DO NOTHING", event.ID, s.name)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		if failure == nil {
+			_, err = tx.Exec("UPDATE domain_events SET dispatched_at = NOW(), attempts = attempts + 1, last_error = NULL WHERE id = $1", event.ID)
+		} else {
+			attempts := row.Attempts + 1
+			_, err = tx.Exec("UPDATE domain_events SET attempts = $1, next_attempt_at = $2, last_error = $3 WHERE id = $4",
+				attempts, time.Now().Add(RetryDelay(attempts)), failure.Error(), event.ID)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(rows), tx.Commit()
+}
+
+// deliver calls the subscriber, turning a panic into an error so one bad
+// handler doesn't stop the dispatcher
+func deliver(ctx context.Context, s subscriber, event Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("events: subscriber %s panicked: %v", s.name, r)
+		}
+	}()
+
+	if err := s.handle(ctx, event); err != nil {
+		return fmt.Errorf("events: subscriber %s: %w", s.name, err)
+	}
+	return nil
+}
+
+// Log is a subscriber that writes every event to the standard logger
+func Log(ctx context.Context, event Event) error {
+	log.Printf("events: %d %s %s %s", event.ID, event.Type, event.AggregateID, event.Payload)
+	return nil
+}
@@ -0,0 +1,118 @@
+// Package events records what happened to clients, services, incidents and
+// supplies as domain events. Events are written to the domain_events table in the same
+// transaction as the change they describe, and a Dispatcher later hands them
+// to the registered subscribers.
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Type names an event. The aggregate of client events is the client, the
+// aggregate of service and incident events is the service and the
+// aggregate of supply events is the supply.
+type Type string
+
+// Event types
+const (
+	ClientCreated        Type = "client.created"
+	ServiceCreated       Type = "service.created"
+	ServiceRepriced      Type = "service.repriced"
+	ServiceStatusChanged Type = "service.status_changed"
+	ServicePaid          Type = "service.paid"
+	IncidentReported     Type = "incident.reported"
+	IncidentResolved     Type = "incident.resolved"
+	SupplyLowStock       Type = "supply.low_stock"
+)
+
+// Types lists every event type, in the order they are documented
+var Types = []Type{ClientCreated, ServiceCreated, ServiceRepriced, ServiceStatusChanged, ServicePaid, IncidentReported, IncidentResolved, SupplyLowStock}
+
+// Event is a row of the domain_events table
+type Event struct {
+	ID          int64           `json:"id" db:"id"`
+	Type        Type            `json:"type" db:"type"`
+	AggregateID uuid.UUID       `json:"aggregate_id" db:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	OccurredAt  time.Time       `json:"occurred_at" db:"occurred_at"`
+}
+
+// Client is the payload of ClientCreated
+type Client struct {
+	ClientID  uuid.UUID `json:"client_id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Username  string    `json:"username"`
+	IsMonthly bool      `json:"is_monthly"`
+}
+
+// Service is the payload of ServiceCreated
+type Service struct {
+	ServiceID               uuid.UUID `json:"service_id"`
+	ClientID                uuid.UUID `json:"client_id"`
+	Status                  string    `json:"status"`
+	TotalPrice              float64   `json:"total_price"`
+	IsPaid                  bool      `json:"is_paid"`
+	EstimatedCompletionDate time.Time `json:"estimated_completion_date"`
+	Priority                string    `json:"priority"`
+}
+
+// StatusChange is the payload of ServiceStatusChanged
+type StatusChange struct {
+	ServiceID uuid.UUID `json:"service_id"`
+	ClientID  uuid.UUID `json:"client_id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+}
+
+// Reprice is the payload of ServiceRepriced
+type Reprice struct {
+	ServiceID  uuid.UUID `json:"service_id"`
+	From       float64   `json:"from"`
+	TotalPrice float64   `json:"total_price"`
+}
+
+// Payment is the payload of ServicePaid
+type Payment struct {
+	ServiceID  uuid.UUID `json:"service_id"`
+	ClientID   uuid.UUID `json:"client_id"`
+	TotalPrice float64   `json:"total_price"`
+	Method     string    `json:"method,omitempty"` // "wallet" when paid from the client's wallet
+}
+
+// Incident is the payload of IncidentReported and IncidentResolved.
+// Compensation is credited to the client's wallet when it is resolved.
+type Incident struct {
+	IncidentID   uuid.UUID `json:"incident_id"`
+	ServiceID    uuid.UUID `json:"service_id"`
+	ClientID     uuid.UUID `json:"client_id"`
+	Type         string    `json:"type"`
+	Status       string    `json:"status"`
+	Compensation float64   `json:"compensation"`
+}
+
+// Supply is the payload of SupplyLowStock
+type Supply struct {
+	SupplyID     uuid.UUID `json:"supply_id"`
+	Name         string    `json:"name"`
+	Unit         string    `json:"unit"`
+	Stock        float64   `json:"stock"`
+	ReorderLevel float64   `json:"reorder_level"`
+}
+
+// Publish writes an event to the outbox. Pass the transaction of the change
+// the event describes, so the event exists if and only if the change was
+// committed.
+func Publish(q sqlx.Execer, typ Type, aggregateID uuid.UUID, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec("INSERT INTO domain_events (type, aggregate_id, payload) VALUES ($1, $2, $3)", typ, aggregateID, body)
+	return err
+}
@@ -0,0 +1,40 @@
+package addresseshandlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"lavanderia/apierror"
+	"lavanderia/cep"
+	"lavanderia/validation"
+)
+
+// LookupQuery is the query string of the CEP lookup
+type LookupQuery struct {
+	CEP string `json:"cep" validate:"required,cep"`
+}
+
+// LookupAddressHandler handles the lookup of the address of a CEP, used to
+// fill the street, neighborhood, city and state of a form
+func LookupAddressHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := LookupQuery{CEP: r.URL.Query().Get("cep")}
+		if err := validation.Struct(query); err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		address, err := cep.Lookup(query.CEP)
+		if err == cep.ErrNotFound {
+			apierror.Write(w, r, apierror.NotFound("cep", apierror.CEPNotFound, "cep", query.CEP))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.InternalError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(address)
+	}
+}
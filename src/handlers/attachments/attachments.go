@@ -0,0 +1,301 @@
+package attachmentshandlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/attachments"
+	"lavanderia/entities"
+	middleware "lavanderia/middlewares"
+	"lavanderia/storage"
+	"lavanderia/validation"
+)
+
+// AttachmentRequest holds the form fields sent with the photo, in the
+// file part, to POST /services/{id}/attachments
+type AttachmentRequest struct {
+	Stage string `json:"stage" validate:"required,oneof=intake|delivery"`
+	Notes string `json:"notes" validate:"max=255"`
+}
+
+// memoryLimit is how much of an upload is kept in memory; the rest of the
+// photo goes to a temporary file
+const memoryLimit = 1 << 20
+
+// UploadAttachmentHandler handles the upload of a photo of the garments of
+// a service as a multipart/form-data request. The type of the photo is
+// sniffed from its content and its thumbnail is made on upload.
+func UploadAttachmentHandler(db *sqlx.DB, store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serviceID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		err = validateServiceExists(db, serviceID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		// The form fields and the multipart boundaries take a little more
+		// than the photo itself
+		r.Body = http.MaxBytesReader(w, r.Body, attachments.MaxSize+memoryLimit)
+		err = r.ParseMultipartForm(memoryLimit)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			apierror.Write(w, r, apierror.Validation(fileTooLarge()))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		req := AttachmentRequest{Stage: r.FormValue("stage"), Notes: r.FormValue("notes")}
+		err = validation.Struct(req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		file, header, err := r.FormFile("file")
+		if err == http.ErrMissingFile {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("file", apierror.Required)))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+		defer file.Close()
+
+		photo, err := readPhoto(file, header)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		attachmentID := uuid.New()
+		prefix := "services/" + serviceID.String() + "/" + attachmentID.String()
+		photo.storageKey = prefix + photo.extension
+		photo.thumbnailKey = prefix + "_thumb.jpg"
+
+		err = store.Put(r.Context(), photo.storageKey, bytes.NewReader(photo.content), int64(len(photo.content)), photo.contentType)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.InternalError))
+			return
+		}
+		err = store.Put(r.Context(), photo.thumbnailKey, bytes.NewReader(photo.thumbnail), int64(len(photo.thumbnail)), "image/jpeg")
+		if err != nil {
+			store.Delete(r.Context(), photo.storageKey)
+			apierror.Write(w, r, apierror.Internal(apierror.InternalError))
+			return
+		}
+
+		var attachment entities.AttachmentEntity
+		err = db.Get(&attachment, `
+			INSERT INTO service_attachments (id, laundry_service_id, stage, filename, content_type, size, width, height, storage_key, thumbnail_key, notes, uploaded_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			RETURNING `+attachments.Columns,
+			attachmentID, serviceID, req.Stage, filepath.Base(header.Filename), photo.contentType, len(photo.content),
+			photo.width, photo.height, photo.storageKey, photo.thumbnailKey, req.Notes, middleware.StaffID(db, r))
+		if err != nil {
+			store.Delete(r.Context(), photo.storageKey)
+			store.Delete(r.Context(), photo.thumbnailKey)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(attachment)
+	}
+}
+
+// ListAttachmentsHandler handles the listing of the photos of a service,
+// the oldest first. Clients only see the photos of their own services.
+func ListAttachmentsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serviceID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		err = middleware.AuthorizeService(db, r, serviceID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		list := []entities.AttachmentEntity{}
+		err = db.Select(&list, "SELECT "+attachments.Columns+" FROM service_attachments WHERE laundry_service_id = $1 ORDER BY created_at", serviceID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+	}
+}
+
+// DownloadAttachmentHandler handles the download of a photo
+func DownloadAttachmentHandler(db *sqlx.DB, store storage.Storage) http.HandlerFunc {
+	return serveAttachment(db, store, false)
+}
+
+// ThumbnailHandler handles the download of the thumbnail of a photo
+func ThumbnailHandler(db *sqlx.DB, store storage.Storage) http.HandlerFunc {
+	return serveAttachment(db, store, true)
+}
+
+// DeleteAttachmentHandler handles the deletion of a photo and its
+// thumbnail
+func DeleteAttachmentHandler(db *sqlx.DB, store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		attachmentID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var attachment entities.AttachmentEntity
+		err = db.Get(&attachment, "DELETE FROM service_attachments WHERE id = $1 RETURNING "+attachments.Columns, attachmentID)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.AttachmentNotFound, "id", attachmentID.String()))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		// The row is gone, so files left behind by a failed delete are
+		// only wasted space
+		store.Delete(r.Context(), attachment.StorageKey)
+		store.Delete(r.Context(), attachment.ThumbnailKey)
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func serveAttachment(db *sqlx.DB, store storage.Storage, thumbnail bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		attachmentID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var attachment entities.AttachmentEntity
+		err = db.Get(&attachment, "SELECT "+attachments.Columns+" FROM service_attachments WHERE id = $1", attachmentID)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.AttachmentNotFound, "id", attachmentID.String()))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		err = middleware.AuthorizeService(db, r, attachment.LaundryServiceID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		key, contentType := attachment.StorageKey, attachment.ContentType
+		if thumbnail {
+			key, contentType = attachment.ThumbnailKey, "image/jpeg"
+		}
+		content, err := store.Get(r.Context(), key)
+		if err == storage.ErrNotFound {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.AttachmentNotFound, "id", attachmentID.String()))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.InternalError))
+			return
+		}
+		defer content.Close()
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+		if !thumbnail {
+			w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+			w.Header().Set("Content-Disposition", `inline; filename="`+strings.ReplaceAll(attachment.Filename, `"`, "")+`"`)
+		}
+		io.Copy(w, content)
+	}
+}
+
+// photo is an uploaded photo checked and ready to be stored
+type photo struct {
+	content       []byte
+	contentType   string
+	extension     string
+	thumbnail     []byte
+	width, height int
+	storageKey    string
+	thumbnailKey  string
+}
+
+// readPhoto reads the uploaded file, checking its size and its type
+func readPhoto(file multipart.File, header *multipart.FileHeader) (photo, error) {
+	if header.Size > attachments.MaxSize {
+		return photo{}, fileTooLarge()
+	}
+	content, err := io.ReadAll(io.LimitReader(file, attachments.MaxSize+1))
+	if err != nil {
+		return photo{}, err
+	}
+	if len(content) > attachments.MaxSize {
+		return photo{}, fileTooLarge()
+	}
+
+	p := photo{content: content}
+	var ok bool
+	p.contentType, p.extension, ok = attachments.Detect(content)
+	if !ok {
+		return photo{}, apierror.Field("file", apierror.UnsupportedFileType, "types", strings.Join(attachments.ContentTypes, ", "))
+	}
+
+	p.thumbnail, p.width, p.height, err = attachments.Thumbnail(bytes.NewReader(content))
+	if err != nil {
+		return photo{}, apierror.Field("file", apierror.InvalidImage)
+	}
+	return p, nil
+}
+
+func fileTooLarge() apierror.FieldError {
+	return apierror.Field("file", apierror.FileTooLarge, "max", strconv.Itoa(attachments.MaxSize>>20)+" MB")
+}
+
+func validateServiceExists(db *sqlx.DB, serviceID uuid.UUID) error {
+	var exists bool
+	err := db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM laundry_services WHERE id = $1)", serviceID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return apierror.NotFound("id", apierror.ServiceNotFound, "id", serviceID.String())
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package calendarhandlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/entities"
+	"lavanderia/validation"
+)
+
+// closureColumns selects a closure
+const closureColumns = `id, starts_at, ends_at, reason, created_at`
+
+// ClosureRequest is the request body to close the shop for a period
+type ClosureRequest struct {
+	StartsAt time.Time `json:"starts_at" validate:"required"`
+	EndsAt   time.Time `json:"ends_at" validate:"required"`
+	Reason   string    `json:"reason" validate:"required,max=255"`
+}
+
+// ListClosuresHandler handles the listing of the closures that haven't
+// ended yet, soonest first
+func ListClosuresHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		closures := []entities.ClosureEntity{}
+		err := db.Select(&closures, "SELECT "+closureColumns+" FROM closures WHERE ends_at > $1 ORDER BY starts_at", time.Now())
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(closures)
+	}
+}
+
+// CreateClosureHandler handles the closing of the shop for a period
+func CreateClosureHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ClosureRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+		if !req.EndsAt.After(req.StartsAt) {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("ends_at", apierror.EndBeforeStart)))
+			return
+		}
+
+		var closure entities.ClosureEntity
+		err = db.Get(&closure,
+			"INSERT INTO closures (starts_at, ends_at, reason) VALUES ($1, $2, $3) RETURNING "+closureColumns,
+			req.StartsAt, req.EndsAt, req.Reason)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(closure)
+	}
+}
+
+// DeleteClosureHandler handles the removal of a closure, reopening the shop
+// for its period
+func DeleteClosureHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		closureID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		result, err := db.Exec("DELETE FROM closures WHERE id = $1", closureID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.ClosureNotFound, "id", closureID.String()))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,106 @@
+package calendarhandlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/calendar"
+	"lavanderia/entities"
+	"lavanderia/validation"
+)
+
+// HolidayRequest is the request body to register a holiday, such as a
+// state or city holiday. Recurring holidays repeat every year on the same
+// day.
+type HolidayRequest struct {
+	Date      string `json:"date" validate:"required,date"`
+	Name      string `json:"name" validate:"required,max=100"`
+	Recurring bool   `json:"recurring"`
+}
+
+// ListHolidaysHandler handles the listing of the holidays of a year, the
+// current one by default: the national holidays and the registered ones
+func ListHolidaysHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		year := time.Now().Year()
+		if param := r.URL.Query().Get("year"); param != "" {
+			n, err := strconv.Atoi(param)
+			if err != nil || n < 1900 || n > 2199 {
+				apierror.Write(w, r, apierror.Validation(apierror.Field("year", apierror.OutOfRange, "min", "1900", "max", "2199")))
+				return
+			}
+			year = n
+		}
+
+		cal, err := calendar.Load(db)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(cal.Holidays(year))
+	}
+}
+
+// CreateHolidayHandler handles the registration of a holiday
+func CreateHolidayHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req HolidayRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		var holiday entities.HolidayEntity
+		err = db.Get(&holiday,
+			"INSERT INTO holidays (date, name, recurring) VALUES ($1, $2, $3) RETURNING "+calendar.HolidayColumns,
+			req.Date[:10], req.Name, req.Recurring)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(holiday)
+	}
+}
+
+// DeleteHolidayHandler handles the removal of a registered holiday.
+// National holidays can't be removed.
+func DeleteHolidayHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		holidayID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		result, err := db.Exec("DELETE FROM holidays WHERE id = $1", holidayID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.HolidayNotFound, "id", holidayID.String()))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,195 @@
+package calendarhandlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/calendar"
+	"lavanderia/entities"
+	"lavanderia/validation"
+)
+
+// maxDays is how many days GET /calendar/days returns at most
+const maxDays = 62
+
+// OpeningHoursRequest is the request body to replace the weekly opening
+// hours. Weekdays left out are closed.
+type OpeningHoursRequest struct {
+	Hours []OpeningHoursDay `json:"hours" validate:"required,dive"`
+}
+
+// OpeningHoursDay is when the shop opens on a weekday, from 0 (Sunday) to
+// 6 (Saturday)
+type OpeningHoursDay struct {
+	Weekday  int    `json:"weekday"`
+	OpensAt  string `json:"opens_at" validate:"required,clock"`
+	ClosesAt string `json:"closes_at" validate:"required,clock"`
+}
+
+// validate checks the request and normalizes its times to HH:MM
+func (req *OpeningHoursRequest) validate() error {
+	if err := validation.Struct(req); err != nil {
+		return err
+	}
+
+	seen := map[int]bool{}
+	for i := range req.Hours {
+		day := &req.Hours[i]
+		field := "hours[" + strconv.Itoa(i) + "]."
+		if day.Weekday < 0 || day.Weekday > 6 {
+			return apierror.Field(field+"weekday", apierror.OutOfRange, "min", "0", "max", "6")
+		}
+		if seen[day.Weekday] {
+			return apierror.Field(field+"weekday", apierror.AlreadyExists)
+		}
+		seen[day.Weekday] = true
+
+		opens, _ := time.Parse("15:04", day.OpensAt)
+		closes, _ := time.Parse("15:04", day.ClosesAt)
+		if !closes.After(opens) {
+			return apierror.Field(field+"closes_at", apierror.EndBeforeStart)
+		}
+		day.OpensAt = opens.Format("15:04")
+		day.ClosesAt = closes.Format("15:04")
+	}
+	return nil
+}
+
+// DaysQuery is the period of GET /calendar/days, as YYYY-MM-DD
+type DaysQuery struct {
+	From string `json:"from" validate:"date"`
+	To   string `json:"to" validate:"date"`
+}
+
+// ListOpeningHoursHandler handles the listing of the weekly opening hours
+// by weekday. Until they are configured, the default hours are listed.
+func ListOpeningHoursHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hours := []entities.OpeningHoursEntity{}
+		err := db.Select(&hours, "SELECT "+calendar.OpeningHoursColumns+" FROM opening_hours ORDER BY weekday")
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		if len(hours) == 0 {
+			for weekday, shift := range calendar.DefaultWeek {
+				hours = append(hours, entities.OpeningHoursEntity{
+					Weekday:  int(weekday),
+					OpensAt:  clock(shift.Open),
+					ClosesAt: clock(shift.Close),
+				})
+			}
+			sort.Slice(hours, func(i, j int) bool { return hours[i].Weekday < hours[j].Weekday })
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hours)
+	}
+}
+
+// UpdateOpeningHoursHandler handles the replacement of the weekly opening hours
+func UpdateOpeningHoursHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req OpeningHoursRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = req.validate()
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			tx.Commit()
+		}()
+
+		_, err = tx.Exec("DELETE FROM opening_hours")
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		hours := make([]entities.OpeningHoursEntity, 0, len(req.Hours))
+		for _, day := range req.Hours {
+			_, err = tx.Exec("INSERT INTO opening_hours (weekday, opens_at, closes_at) VALUES ($1, $2, $3)", day.Weekday, day.OpensAt, day.ClosesAt)
+			if err != nil {
+				apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+				return
+			}
+			hours = append(hours, entities.OpeningHoursEntity(day))
+		}
+		sort.Slice(hours, func(i, j int) bool { return hours[i].Weekday < hours[j].Weekday })
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hours)
+	}
+}
+
+// ListDaysHandler handles the listing of the days of a period with the
+// hours the shop opens on each one, two weeks from today by default
+func ListDaysHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := DaysQuery{From: r.URL.Query().Get("from"), To: r.URL.Query().Get("to")}
+		if err := validation.Struct(query); err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		now := time.Now()
+		from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+		if query.From != "" {
+			from, _ = time.ParseInLocation("2006-01-02", query.From, time.Local)
+		}
+		to := from.AddDate(0, 0, 13)
+		if query.To != "" {
+			to, _ = time.ParseInLocation("2006-01-02", query.To, time.Local)
+		}
+		if to.Before(from) {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("to", apierror.EndBeforeStart)))
+			return
+		}
+		if to.Sub(from) >= maxDays*24*time.Hour {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("to", apierror.DateTooFar, "date", to.Format("2006-01-02"), "days", strconv.Itoa(maxDays))))
+			return
+		}
+
+		cal, err := calendar.Load(db)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		days := []calendar.Day{}
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			days = append(days, cal.Day(day))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(days)
+	}
+}
+
+// clock formats an offset from midnight as HH:MM
+func clock(offset time.Duration) string {
+	return time.Time{}.Add(offset).Format("15:04")
+}
@@ -0,0 +1,187 @@
+package cashhandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/cash"
+	"lavanderia/entities"
+	"lavanderia/events"
+	middleware "lavanderia/middlewares"
+	"lavanderia/validation"
+)
+
+// PaymentRequest is the request body of a payment taken at the counter.
+// Amount defaults to the total price of the service, and can't be short of
+// it, and Method to cash.
+type PaymentRequest struct {
+	ServiceID uuid.UUID `json:"service_id" validate:"required"`
+	Method    string    `json:"method" validate:"oneof=cash|card|pix"`
+	Amount    float64   `json:"amount" validate:"positive"`
+}
+
+// WithdrawalRequest is the request body of a withdrawal of cash from the
+// drawer
+type WithdrawalRequest struct {
+	Amount float64 `json:"amount" validate:"required,positive"`
+	Reason string  `json:"reason" validate:"required,max=255"`
+}
+
+// PaymentHandler handles a payment taken at the counter for a service,
+// marking the service paid
+func PaymentHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PaymentRequest
+		sessionID, err := decodeSessionRequest(r, &req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+		if req.Method == "" {
+			req.Method = cash.Cash
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			tx.Commit()
+		}()
+
+		var service struct {
+			ClientID   uuid.UUID `db:"client_id"`
+			IsPaid     bool      `db:"is_paid"`
+			TotalPrice float64   `db:"total_price"`
+		}
+		err = tx.Get(&service, "SELECT client_id, COALESCE(is_paid, false) AS is_paid, COALESCE(total_price, 0) AS total_price FROM laundry_services WHERE id = $1 FOR UPDATE", req.ServiceID)
+		if err == sql.ErrNoRows {
+			err = apierror.Field("service_id", apierror.ServiceNotFound, "id", req.ServiceID.String())
+		}
+		if err == nil && service.IsPaid {
+			err = apierror.Conflict("service_id", apierror.ServiceAlreadyPaid, "id", req.ServiceID.String())
+		}
+		if err == nil && req.Amount == 0 {
+			req.Amount = service.TotalPrice
+			if req.Amount <= 0 {
+				err = apierror.Field("amount", apierror.Required)
+			}
+		}
+		// A payment pays the whole service, so it is never marked paid
+		// for part of its price
+		if err == nil && math.Round(req.Amount*100) < math.Round(service.TotalPrice*100) {
+			err = apierror.Conflict("amount", apierror.PaymentShort, "total", strconv.FormatFloat(service.TotalPrice, 'f', 2, 64))
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		movement, err := cash.Record(tx, entities.CashMovementEntity{
+			SessionID:        sessionID,
+			Type:             cash.Payment,
+			Method:           req.Method,
+			Amount:           req.Amount,
+			LaundryServiceID: &req.ServiceID,
+			CreatedBy:        middleware.StaffID(db, r),
+		})
+		if err != nil {
+			apierror.Write(w, r, apierror.From(sessionError(err, sessionID)))
+			return
+		}
+
+		_, err = tx.Exec("UPDATE laundry_services SET is_paid = true, payment_method = $2, paid_at = CURRENT_TIMESTAMP WHERE id = $1", req.ServiceID, req.Method)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		err = events.Publish(tx, events.ServicePaid, req.ServiceID, events.Payment{
+			ServiceID:  req.ServiceID,
+			ClientID:   service.ClientID,
+			TotalPrice: service.TotalPrice,
+			Method:     req.Method,
+		})
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(movement)
+	}
+}
+
+// WithdrawalHandler handles a withdrawal of cash from the drawer
+func WithdrawalHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req WithdrawalRequest
+		sessionID, err := decodeSessionRequest(r, &req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			tx.Commit()
+		}()
+
+		movement, err := cash.Record(tx, entities.CashMovementEntity{
+			SessionID: sessionID,
+			Type:      cash.Withdrawal,
+			Amount:    req.Amount,
+			Reason:    req.Reason,
+			CreatedBy: middleware.StaffID(db, r),
+		})
+		if err == cash.ErrInsufficientCash {
+			report, _ := cash.Load(tx, sessionID)
+			err = apierror.Conflict("amount", apierror.InsufficientCash, "cash", strconv.FormatFloat(report.Totals.Expected, 'f', 2, 64))
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.From(sessionError(err, sessionID)))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(movement)
+	}
+}
+
+// decodeSessionRequest reads the session of the URL and the request body
+// into req, validating both
+func decodeSessionRequest(r *http.Request, req interface{}) (uuid.UUID, error) {
+	sessionID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		return uuid.Nil, apierror.Field("id", apierror.InvalidUUID)
+	}
+
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		return uuid.Nil, apierror.New(http.StatusBadRequest, apierror.InvalidPayload)
+	}
+	return sessionID, validation.Struct(req)
+}
@@ -0,0 +1,269 @@
+package cashhandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/cash"
+	"lavanderia/entities"
+	middleware "lavanderia/middlewares"
+	"lavanderia/queryspec"
+	"lavanderia/validation"
+)
+
+// OpenRequest is the request body to open the cash drawer with the change
+// float in it
+type OpenRequest struct {
+	OpeningFloat float64 `json:"opening_float" validate:"positive"`
+	Notes        string  `json:"notes" validate:"max=255"`
+}
+
+// CloseRequest is the request body to close a session with the cash
+// counted in the drawer
+type CloseRequest struct {
+	Counted *float64 `json:"counted"`
+	Notes   string   `json:"notes" validate:"max=255"`
+}
+
+// OverShortResponse is the over/short report of a period
+type OverShortResponse struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	cash.OverShort
+}
+
+// OpenSessionHandler handles the opening of the cash drawer. Only one
+// session is open at a time.
+func OpenSessionHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req OpenRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			tx.Commit()
+		}()
+
+		session, err := cash.Open(tx, req.OpeningFloat, middleware.StaffID(db, r), req.Notes)
+		if err == cash.ErrSessionOpen {
+			// Read outside the transaction, which a session opened at the
+			// same time aborts
+			current, _ := cash.Current(db)
+			err = apierror.Conflict("opening_float", apierror.CashSessionOpen, "id", current.ID.String())
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(session)
+	}
+}
+
+// ListSessionsHandler handles the listing of the sessions opened over a
+// period, newest first
+func ListSessionsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, to, err := queryspec.Period(r.URL.Query(), queryspec.MaxPeriodDays)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		sessions := []entities.CashSessionEntity{}
+		err = db.Select(&sessions, "SELECT "+cash.SessionColumns+" FROM cash_sessions WHERE opened_at >= $1 AND opened_at < $2 ORDER BY opened_at DESC", from, to)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sessions)
+	}
+}
+
+// CurrentSessionHandler handles the display of the open session with its
+// totals so far
+func CurrentSessionHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, err := cash.Current(db)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.New(http.StatusNotFound, apierror.CashDrawerClosed))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		writeReport(db, w, r, session.ID)
+	}
+}
+
+// ShowSessionHandler handles the display of a session with its totals, its
+// movements and its reconciliation with the services paid while it was
+// open
+func ShowSessionHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		writeReport(db, w, r, id)
+	}
+}
+
+// ZReportHandler handles the printable Z-report of a session, as plain
+// text for a receipt printer. A session still open prints as a partial
+// X-report.
+func ZReportHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		report, err := cash.Load(db, id)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.CashSessionNotFound, "id", id.String()))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		cash.WriteZReport(w, report)
+	}
+}
+
+// CloseSessionHandler handles the closing of a session with the cash
+// counted in the drawer, recording how much it was over or short
+func CloseSessionHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var req CloseRequest
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(req)
+		if err == nil && req.Counted == nil {
+			err = apierror.Field("counted", apierror.Required)
+		}
+		if err == nil && *req.Counted < 0 {
+			err = apierror.Field("counted", apierror.MustBePositive)
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			tx.Commit()
+		}()
+
+		session, err := cash.Close(tx, id, *req.Counted, middleware.StaffID(db, r), req.Notes)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(sessionError(err, id)))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(session)
+	}
+}
+
+// OverShortHandler handles the over/short report of the sessions closed
+// over a period
+func OverShortHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, to, err := queryspec.Period(r.URL.Query(), queryspec.MaxPeriodDays)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		report, err := cash.OverShortReport(db, from, to)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(OverShortResponse{From: from.Format("2006-01-02"), To: to.AddDate(0, 0, -1).Format("2006-01-02"), OverShort: report})
+	}
+}
+
+// writeReport writes the report of a session
+func writeReport(db *sqlx.DB, w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	report, err := cash.Load(db, id)
+	if err == sql.ErrNoRows {
+		apierror.Write(w, r, apierror.NotFound("id", apierror.CashSessionNotFound, "id", id.String()))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// sessionError turns the errors of a session into problems
+func sessionError(err error, id uuid.UUID) error {
+	switch err {
+	case sql.ErrNoRows:
+		return apierror.NotFound("id", apierror.CashSessionNotFound, "id", id.String())
+	case cash.ErrSessionClosed:
+		return apierror.Conflict("id", apierror.CashSessionClosed, "id", id.String())
+	}
+	return err
+}
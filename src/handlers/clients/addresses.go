@@ -0,0 +1,344 @@
+package clientshandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/cep"
+	"lavanderia/entities"
+	middleware "lavanderia/middlewares"
+	"lavanderia/validation"
+)
+
+// Address labels
+const (
+	LabelHome  = "home"
+	LabelWork  = "work"
+	LabelOther = "other"
+)
+
+// clientAddressColumns selects a client address joined with its address row
+const clientAddressColumns = `
+	ca.address_id,
+	ca.client_id,
+	ca.label,
+	ca.is_default,
+	ad.street,
+	COALESCE(ad.neighborhood, '') AS neighborhood,
+	ad.city,
+	ad.state,
+	COALESCE(ad.postal_code, '') AS postal_code,
+	ad.number,
+	COALESCE(ad.complement, '') AS complement,
+	COALESCE(ad.landmark, '') AS landmark,
+	ad.latitude,
+	ad.longitude`
+
+// ClientAddressRequest is the request body to create or update an address
+// of a client. The first address of a client is always its default.
+type ClientAddressRequest struct {
+	Label        string   `json:"label" validate:"required,oneof=home|work|other"`
+	IsDefault    bool     `json:"is_default"`
+	Street       string   `json:"street" validate:"required,max=255"`
+	Neighborhood string   `json:"neighborhood" validate:"max=100"`
+	City         string   `json:"city" validate:"required,max=100"`
+	State        string   `json:"state" validate:"required,uf"`
+	PostalCode   string   `json:"postal_code" validate:"required,cep"`
+	Number       string   `json:"number" validate:"required,max=5"`
+	Complement   string   `json:"complement" validate:"max=255"`
+	Landmark     string   `json:"landmark" validate:"max=255"`
+	Latitude     *float64 `json:"latitude" validate:"lat"`
+	Longitude    *float64 `json:"longitude" validate:"lng"`
+}
+
+// ListClientAddressesHandler handles the listing of the addresses of a
+// client, the default one first
+func ListClientAddressesHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		err = middleware.ValidateClientExists(db, clientID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		addresses := []entities.ClientAddressEntity{}
+		err = db.Select(&addresses, `
+			SELECT `+clientAddressColumns+`
+			FROM client_addresses ca
+			JOIN address ad ON ad.address_id = ca.address_id
+			WHERE ca.client_id = $1
+			ORDER BY ca.is_default DESC, ca.created_at`, clientID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(addresses)
+	}
+}
+
+// CreateClientAddressHandler handles the creation of an address of a client
+func CreateClientAddressHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		req, err := decodeClientAddress(r)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			tx.Commit()
+		}()
+
+		address, err := createClientAddress(tx, clientID, req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(address)
+	}
+}
+
+// UpdateClientAddressHandler handles the update of an address of a client.
+// Marking it as default unmarks the previous default; to stop using an
+// address as default, mark another one.
+func UpdateClientAddressHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, addressID, err := clientAddressIDs(r)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		req, err := decodeClientAddress(r)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			tx.Commit()
+		}()
+
+		address, err := updateClientAddress(tx, clientID, addressID, req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(address)
+	}
+}
+
+// DeleteClientAddressHandler handles the deletion of an address of a
+// client. The default address and addresses used by services are kept.
+func DeleteClientAddressHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, addressID, err := clientAddressIDs(r)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		isDefault, err := clientAddressIsDefault(db, clientID, addressID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+		if isDefault {
+			apierror.Write(w, r, apierror.Conflict("address_id", apierror.DefaultAddress))
+			return
+		}
+
+		var inUse bool
+		err = db.Get(&inUse, "SELECT EXISTS(SELECT 1 FROM laundry_services WHERE address_id = $1)", addressID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if inUse {
+			apierror.Write(w, r, apierror.Conflict("address_id", apierror.InUse))
+			return
+		}
+
+		_, err = db.Exec("DELETE FROM address WHERE address_id = $1", addressID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func clientAddressIDs(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	vars := mux.Vars(r)
+	clientID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		return uuid.Nil, uuid.Nil, apierror.Field("id", apierror.InvalidUUID)
+	}
+	addressID, err := uuid.Parse(vars["addressID"])
+	if err != nil {
+		return uuid.Nil, uuid.Nil, apierror.Field("address_id", apierror.InvalidUUID)
+	}
+	return clientID, addressID, nil
+}
+
+func decodeClientAddress(r *http.Request) (ClientAddressRequest, error) {
+	var req ClientAddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return req, apierror.New(http.StatusBadRequest, apierror.InvalidPayload)
+	}
+	req.State = cep.NormalizeState(req.State)
+	if err := validation.Struct(req); err != nil {
+		return req, err
+	}
+	req.PostalCode = validation.Digits(req.PostalCode)
+	return req, checkPostalCode(req.PostalCode, req.Street, req.City, req.State, &req.Neighborhood)
+}
+
+func createClientAddress(tx *sqlx.Tx, clientID uuid.UUID, req ClientAddressRequest) (entities.ClientAddressEntity, error) {
+	var address entities.ClientAddressEntity
+
+	var exists bool
+	err := tx.Get(&exists, "SELECT EXISTS(SELECT 1 FROM clients WHERE id = $1)", clientID)
+	if err != nil {
+		return address, err
+	}
+	if !exists {
+		return address, apierror.NotFound("id", apierror.ClientNotFound, "id", clientID.String())
+	}
+
+	var hasDefault bool
+	err = tx.Get(&hasDefault, "SELECT EXISTS(SELECT 1 FROM client_addresses WHERE client_id = $1 AND is_default)", clientID)
+	if err != nil {
+		return address, err
+	}
+
+	addressID := uuid.New()
+	_, err = tx.Exec(
+		"INSERT INTO address (address_id, street, neighborhood, city, state, postal_code, number, complement, landmark, latitude, longitude) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+		addressID, req.Street, req.Neighborhood, req.City, req.State, req.PostalCode, req.Number, req.Complement, req.Landmark, req.Latitude, req.Longitude,
+	)
+	if err != nil {
+		return address, err
+	}
+
+	_, err = tx.Exec("INSERT INTO client_addresses (address_id, client_id, label) VALUES ($1, $2, $3)", addressID, clientID, req.Label)
+	if err != nil {
+		return address, err
+	}
+
+	if req.IsDefault || !hasDefault {
+		err = setDefaultAddress(tx, clientID, addressID)
+		if err != nil {
+			return address, err
+		}
+	}
+
+	return getClientAddress(tx, addressID)
+}
+
+func updateClientAddress(tx *sqlx.Tx, clientID, addressID uuid.UUID, req ClientAddressRequest) (entities.ClientAddressEntity, error) {
+	isDefault, err := clientAddressIsDefault(tx, clientID, addressID)
+	if err != nil {
+		return entities.ClientAddressEntity{}, err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE address SET street=$1, neighborhood=$2, city=$3, state=$4, postal_code=$5, number=$6, complement=$7, landmark=$8, latitude=$9, longitude=$10 WHERE address_id=$11",
+		req.Street, req.Neighborhood, req.City, req.State, req.PostalCode, req.Number, req.Complement, req.Landmark, req.Latitude, req.Longitude, addressID,
+	)
+	if err != nil {
+		return entities.ClientAddressEntity{}, err
+	}
+
+	_, err = tx.Exec("UPDATE client_addresses SET label=$1 WHERE address_id=$2", req.Label, addressID)
+	if err != nil {
+		return entities.ClientAddressEntity{}, err
+	}
+
+	if req.IsDefault && !isDefault {
+		err = setDefaultAddress(tx, clientID, addressID)
+		if err != nil {
+			return entities.ClientAddressEntity{}, err
+		}
+	}
+
+	return getClientAddress(tx, addressID)
+}
+
+// setDefaultAddress makes addressID the only default address of the client
+// and keeps clients.address_id pointing to it
+func setDefaultAddress(tx *sqlx.Tx, clientID, addressID uuid.UUID) error {
+	_, err := tx.Exec("UPDATE client_addresses SET is_default = FALSE WHERE client_id = $1 AND is_default", clientID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE client_addresses SET is_default = TRUE WHERE address_id = $1", addressID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE clients SET address_id = $1 WHERE id = $2", addressID, clientID)
+	return err
+}
+
+func getClientAddress(tx *sqlx.Tx, addressID uuid.UUID) (entities.ClientAddressEntity, error) {
+	var address entities.ClientAddressEntity
+	err := tx.Get(&address, `
+		SELECT `+clientAddressColumns+`
+		FROM client_addresses ca
+		JOIN address ad ON ad.address_id = ca.address_id
+		WHERE ca.address_id = $1`, addressID)
+	return address, err
+}
+
+func clientAddressIsDefault(db sqlx.Queryer, clientID, addressID uuid.UUID) (bool, error) {
+	var isDefault bool
+	err := sqlx.Get(db, &isDefault, "SELECT is_default FROM client_addresses WHERE client_id = $1 AND address_id = $2", clientID, addressID)
+	if err == sql.ErrNoRows {
+		return false, apierror.NotFound("address_id", apierror.ClientAddressNotFound, "id", addressID.String())
+	}
+	return isDefault, err
+}
@@ -7,27 +7,36 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"golang.org/x/crypto/bcrypt"
+
+	"lavanderia/apierror"
+	"lavanderia/cep"
+	"lavanderia/events"
+	"lavanderia/validation"
 )
 
 // CreateClient represents the creation of clients
 type CreateClient struct {
-	ID          uuid.UUID `json:"id" db:"id"`
-	FirstName   string    `json:"first_name" db:"first_name"`
-	LastName    string    `json:"last_name" db:"last_name"`
-	Username    string    `json:"username" db:"username"`
-	Password    string    `json:"password" db:"password"`
-	Phone       string    `json:"phone" db:"phone"`
-	IsAdmin     bool      `json:"is_admin" db:"is_admin"`
-	IsMonthly   bool      `json:"is_monthly" db:"is_mensal"`
-	MonthlyDate *string   `json:"monthly_date" db:"monthly_date"`
-	AddressID   uuid.UUID `json:"address_id" db:"address_id"`
-	Street      string    `json:"street" db:"street"`
-	City        string    `json:"city" db:"city"`
-	State       string    `json:"state" db:"state"`
-	PostalCode  string    `json:"postal_code" db:"postal_code"`
-	Number      string    `json:"number" db:"number"`
-	Complement  string    `json:"complement" db:"complement"`
-	Landmark    string    `json:"landmark" db:"landmark"`
+	ID           uuid.UUID `json:"id" db:"id"`
+	FirstName    string    `json:"first_name" db:"first_name" validate:"required,max=255"`
+	LastName     string    `json:"last_name" db:"last_name" validate:"required,max=255"`
+	Username     string    `json:"username" db:"username" validate:"required,max=255"`
+	Password     string    `json:"password" db:"password"`
+	Phone        string    `json:"phone" db:"phone" validate:"required,phone"`
+	CPF          *string   `json:"cpf" db:"cpf" validate:"cpf"`
+	IsAdmin      bool      `json:"is_admin" db:"is_admin"`
+	IsMonthly    bool      `json:"is_monthly" db:"is_mensal"`
+	MonthlyDate  *string   `json:"monthly_date" db:"monthly_date" validate:"required_if=IsMonthly,date"`
+	AddressID    uuid.UUID `json:"address_id" db:"address_id"`
+	Street       string    `json:"street" db:"street" validate:"required,max=255"`
+	Neighborhood string    `json:"neighborhood" db:"neighborhood" validate:"max=100"`
+	City         string    `json:"city" db:"city" validate:"required,max=100"`
+	State        string    `json:"state" db:"state" validate:"required,uf"`
+	PostalCode   string    `json:"postal_code" db:"postal_code" validate:"required,cep"`
+	Number       string    `json:"number" db:"number" validate:"required,max=5"`
+	Complement   string    `json:"complement" db:"complement" validate:"max=255"`
+	Landmark     string    `json:"landmark" db:"landmark" validate:"max=255"`
+	Latitude     *float64  `json:"latitude" db:"latitude" validate:"lat"`
+	Longitude    *float64  `json:"longitude" db:"longitude" validate:"lng"`
 }
 
 // CreateClientHandler handles the creation of a new item
@@ -37,13 +46,27 @@ func CreateClientHandler(db *sqlx.DB) http.HandlerFunc {
 		var newClient CreateClient
 		err := json.NewDecoder(r.Body).Decode(&newClient)
 		if err != nil {
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		newClient.State = cep.NormalizeState(newClient.State)
+		err = validation.Struct(newClient)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+		normalizeDocuments(&newClient.Phone, &newClient.PostalCode, newClient.CPF)
+
+		err = checkPostalCode(newClient.PostalCode, newClient.Street, newClient.City, newClient.State, &newClient.Neighborhood)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
 		tx, err := db.Beginx()
 		if err != nil {
-			http.Error(w, "Error starting database transaction", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 		defer func() {
@@ -59,11 +82,11 @@ func CreateClientHandler(db *sqlx.DB) http.HandlerFunc {
 		newClient.AddressID = uuid.New()
 
 		_, err = tx.Exec(
-			"INSERT INTO address (address_id, street, city, state, postal_code, number, complement, landmark) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
-			newClient.AddressID, newClient.Street, newClient.City, newClient.State, newClient.PostalCode, newClient.Number, newClient.Complement, newClient.Landmark,
+			"INSERT INTO address (address_id, street, neighborhood, city, state, postal_code, number, complement, landmark, latitude, longitude) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+			newClient.AddressID, newClient.Street, newClient.Neighborhood, newClient.City, newClient.State, newClient.PostalCode, newClient.Number, newClient.Complement, newClient.Landmark, newClient.Latitude, newClient.Longitude,
 		)
 		if err != nil {
-			http.Error(w, "Error inserting address into database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
@@ -75,18 +98,37 @@ func CreateClientHandler(db *sqlx.DB) http.HandlerFunc {
 
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newClient.Password), bcrypt.DefaultCost)
 		if err != nil {
-			http.Error(w, "Failed to encrypt password", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.InternalError))
 			return
 		}
 
 		// Insert the new user into the database
 		err = tx.QueryRow(
-			"INSERT INTO clients (first_name, last_name, username, is_admin, phone, is_mensal, address_id, monthly_date, role, password) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING first_name, last_name",
-			newClient.FirstName, newClient.LastName, newClient.Username, isAdmin, newClient.Phone, newClient.IsMonthly, newClient.AddressID, newClient.MonthlyDate, "Client", hashedPassword,
-		).Scan(&newClient.FirstName, &newClient.LastName)
+			"INSERT INTO clients (first_name, last_name, username, is_admin, phone, is_mensal, address_id, monthly_date, role, password, cpf) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id",
+			newClient.FirstName, newClient.LastName, newClient.Username, isAdmin, newClient.Phone, newClient.IsMonthly, newClient.AddressID, newClient.MonthlyDate, "Client", hashedPassword, newClient.CPF,
+		).Scan(&newClient.ID)
+
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		_, err = tx.Exec("INSERT INTO client_addresses (address_id, client_id, label, is_default) VALUES ($1, $2, $3, TRUE)",
+			newClient.AddressID, newClient.ID, LabelHome)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
 
+		err = events.Publish(tx, events.ClientCreated, newClient.ID, events.Client{
+			ClientID:  newClient.ID,
+			FirstName: newClient.FirstName,
+			LastName:  newClient.LastName,
+			Username:  newClient.Username,
+			IsMonthly: newClient.IsMonthly,
+		})
 		if err != nil {
-			http.Error(w, "Error inserting client into database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
@@ -94,3 +136,26 @@ func CreateClientHandler(db *sqlx.DB) http.HandlerFunc {
 		w.WriteHeader(http.StatusCreated)
 	}
 }
+
+// checkPostalCode checks the street, city and state against the CEP and
+// fills the neighborhood when it was left empty
+func checkPostalCode(postalCode, street, city, state string, neighborhood *string) error {
+	address, err := cep.Verify(postalCode, street, city, state)
+	if err != nil {
+		return err
+	}
+	if *neighborhood == "" {
+		*neighborhood = address.Neighborhood
+	}
+	return nil
+}
+
+// normalizeDocuments keeps only the digits of the phone, postal code and
+// CPF, so they fit their columns and are searchable the same way
+func normalizeDocuments(phone *string, postalCode *string, cpf *string) {
+	*phone = validation.NationalPhone(*phone)
+	*postalCode = validation.Digits(*postalCode)
+	if cpf != nil {
+		*cpf = validation.Digits(*cpf)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
 )
 
 // DeleteClientHandler handles the deletion of a client by ID
@@ -15,21 +17,21 @@ func DeleteClientHandler(db *sqlx.DB) http.HandlerFunc {
 		vars := mux.Vars(r)
 		clientIDStr, ok := vars["id"]
 		if !ok {
-			http.Error(w, "client ID not provided in URL", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.Required)))
 			return
 		}
 
 		// Parse clientIDStr as UUID
 		clientID, err := uuid.Parse(clientIDStr)
 		if err != nil {
-			http.Error(w, "Invalid client ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
 			return
 		}
 
 		// Execute the delete query
 		_, err = db.Exec("DELETE FROM clients WHERE id=$1", clientID)
 		if err != nil {
-			http.Error(w, "Error deleting client from the database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
@@ -2,11 +2,14 @@ package clientshandlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/queryspec"
 )
 
 // ClientList is the interface of the return
@@ -17,59 +20,65 @@ type ClientList struct {
 	Phone     string    `json:"phone" db:"phone"`
 }
 
+// ListClientsOptions are the pagination, sort and filter parameters accepted by ListClientsHandler
+var ListClientsOptions = queryspec.Options{
+	SortFields: map[string]string{
+		"first_name": "first_name",
+		"last_name":  "last_name",
+		"phone":      "phone",
+	},
+	NullSorts:   map[string]string{"phone": "''"},
+	DefaultSort: "first_name,last_name",
+	TieBreaker:  "id",
+	Filters: []queryspec.Filter{
+		{Param: "is_monthly", Column: "is_mensal", Type: queryspec.Bool},
+		{Param: "search", Column: "to_tsvector('english', first_name || ' ' || last_name)", Type: queryspec.Search},
+	},
+}
+
 // ListClientsHandler handles the listing of all clients with pagination
 func ListClientsHandler(db *sqlx.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Get page and limit from query params, with defaults
-		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-
-		var clients []ClientList
-		var response map[string]interface{}
-
-		if page < 1 {
-			err := db.Select(&clients, "SELECT id, first_name, last_name, phone FROM clients ORDER BY first_name, last_name")
-			if err != nil {
-				http.Error(w, "Error retrieving clients from database", http.StatusInternalServerError)
-				return
-			}
-
-			response = map[string]interface{}{
-				"clients": clients,
-			}
-		} else {
-			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-			if limit < 1 {
-				limit = 10 // default limit
-			}
-
-			offset := (page - 1) * limit
+		spec, err := queryspec.Parse(r.URL.Query(), ListClientsOptions)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
 
-			// Query all clients from the database with pagination
-			err := db.Select(&clients, "SELECT id, first_name, last_name, phone FROM clients ORDER BY first_name, last_name LIMIT $1 OFFSET $2", limit, offset)
-			if err != nil {
-				http.Error(w, "Error retrieving clients from database", http.StatusInternalServerError)
-				return
-			}
+		// Count total number of clients matching the filters
+		var countArgs queryspec.Args
+		var totalClients int
+		err = db.Get(&totalClients, "SELECT COUNT(*) FROM clients "+spec.CountWhere(&countArgs), countArgs.Values()...)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
 
-			// Count total number of clients for pagination
-			var totalClients int
-			err = db.Get(&totalClients, "SELECT COUNT(*) FROM clients")
-			if err != nil {
-				http.Error(w, "Error counting clients", http.StatusInternalServerError)
-				return
-			}
+		var args queryspec.Args
+		query := fmt.Sprintf("SELECT id, first_name, last_name, phone, %s AS cursor FROM clients %s %s %s",
+			spec.CursorColumns(), spec.Where(&args), spec.OrderBy(), spec.Limit(&args))
 
-			totalPages := (totalClients + limit - 1) / limit
+		var rows []struct {
+			ClientList
+			Cursor string `db:"cursor"`
+		}
+		err = db.Select(&rows, query, args.Values()...)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
 
-			// Format response with clients map and pagination info
-			response = map[string]interface{}{
-				"clients":     clients,
-				"page":        page,
-				"total_pages": totalPages,
-			}
+		clients := make([]ClientList, 0, len(rows))
+		var last string
+		for _, row := range rows[:spec.Trim(len(rows))] {
+			clients = append(clients, row.ClientList)
+			last = row.Cursor
 		}
 
+		// Format response with clients map and pagination info
+		pagination := spec.Paginate(totalClients, len(rows), last)
+
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		json.NewEncoder(w).Encode(pagination.Response("clients", clients))
 	}
 }
@@ -7,11 +7,14 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/validation"
 )
 
 // Update represents the fields allowed to be updated for a client's monthly fee
 type Update struct {
-	RenewalDate string `json:"renewal_date"`
+	RenewalDate string `json:"renewal_date" validate:"required,date"`
 }
 
 // RenewMonthlyFeeHandler handles the renewal of monthly fees for a client
@@ -21,14 +24,14 @@ func RenewMonthlyFeeHandler(db *sqlx.DB) http.HandlerFunc {
 		vars := mux.Vars(r)
 		clientIDStr, ok := vars["id"]
 		if !ok {
-			http.Error(w, "client ID not provided in URL", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.Required)))
 			return
 		}
 
 		// Convert clientIDStr to uuid.UUID
 		clientID, err := uuid.Parse(clientIDStr)
 		if err != nil {
-			http.Error(w, "Invalid client ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
 			return
 		}
 
@@ -36,13 +39,19 @@ func RenewMonthlyFeeHandler(db *sqlx.DB) http.HandlerFunc {
 		var update Update
 		err = json.NewDecoder(r.Body).Decode(&update)
 		if err != nil {
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(update)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
 		tx, err := db.Beginx()
 		if err != nil {
-			http.Error(w, "Error starting database transaction", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 		defer func() {
@@ -57,7 +66,7 @@ func RenewMonthlyFeeHandler(db *sqlx.DB) http.HandlerFunc {
 		_, err = tx.Exec("UPDATE clients SET monthly_date=$1, is_mensal=$2 WHERE id=$3", update.RenewalDate, true, clientID)
 
 		if err != nil {
-			http.Error(w, "Error updating client's monthly fee renewal in the database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
@@ -8,6 +8,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
 )
 
 // ClientDetail is the interface of the return
@@ -17,18 +19,23 @@ type ClientDetail struct {
 	LastName  string    `json:"last_name" db:"last_name"`
 	Phone     string    `json:"phone" db:"phone"`
 	Username  string    `json:"username" db:"username"`
+	CPF       *string   `json:"cpf" db:"cpf"`
 
 	IsMonthly   bool         `json:"is_monthly" db:"is_mensal"`
 	MonthlyDate sql.NullTime `json:"monthly_date" db:"monthly_date"`
+	Balance     float64      `json:"balance" db:"balance"`
 
-	AddressID  uuid.UUID      `json:"address_id" db:"address_id"`
-	Street     string         `json:"street" db:"street"`
-	City       string         `json:"city" db:"city"`
-	State      string         `json:"state" db:"state"`
-	PostalCode string         `json:"postal_code" db:"postal_code"`
-	Number     string         `json:"number" db:"number"`
-	Complement sql.NullString `json:"complement" db:"complement"`
-	Landmark   sql.NullString `json:"landmark" db:"landmark"`
+	AddressID    uuid.UUID      `json:"address_id" db:"address_id"`
+	Street       string         `json:"street" db:"street"`
+	Neighborhood sql.NullString `json:"neighborhood" db:"neighborhood"`
+	City         string         `json:"city" db:"city"`
+	State        string         `json:"state" db:"state"`
+	PostalCode   string         `json:"postal_code" db:"postal_code"`
+	Number       string         `json:"number" db:"number"`
+	Complement   sql.NullString `json:"complement" db:"complement"`
+	Landmark     sql.NullString `json:"landmark" db:"landmark"`
+	Latitude     *float64       `json:"latitude" db:"latitude"`
+	Longitude    *float64       `json:"longitude" db:"longitude"`
 }
 
 // ShowClientHandler handles the Showing of all clients with pagination
@@ -36,37 +43,51 @@ func ShowClientHandler(db *sqlx.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Get serviceID from URL parameters
 		vars := mux.Vars(r)
-		clientID := vars["id"]
+		clientID, err := uuid.Parse(vars["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
 
 		// Query all clients from the database with pagination
 		var client ClientDetail
-		err := db.Get(&client,
+		err = db.Get(&client,
 			`SELECT
 			cli.id,
 			cli.first_name,
 			cli.last_name,
 			cli.phone,
 			cli.username,
+			cli.cpf,
 			cli.is_mensal,
 			cli.monthly_date,
+			COALESCE(wa.balance, 0) AS balance,
 			ad.address_id,
 			ad.street,
+			ad.neighborhood,
 			ad.city,
 			ad.state,
 			ad.postal_code,
 			ad.number,
 			ad.complement,
-			ad.landmark
+			ad.landmark,
+			ad.latitude,
+			ad.longitude
 		  FROM
 			clients cli
 			LEFT JOIN address ad ON cli.address_id = ad.address_id
+			LEFT JOIN wallet_accounts wa ON wa.client_id = cli.id
 		   WHERE
 			cli.id = $1
 		  ORDER BY
 			first_name,
 			last_name`, clientID)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.ClientNotFound, "id", clientID.String()))
+			return
+		}
 		if err != nil {
-			http.Error(w, "Error retrieving clients from database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
@@ -2,32 +2,39 @@ package clientshandlers
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/cep"
+	"lavanderia/validation"
 )
 
 // UpdateClient is the interface of the return
 type UpdateClient struct {
 	ID          uuid.UUID `json:"id" db:"id"`
-	FirstName   string    `json:"first_name" db:"first_name"`
-	LastName    string    `json:"last_name" db:"last_name"`
-	Phone       string    `json:"phone" db:"phone"`
-	Username    string    `json:"username" db:"username"`
+	FirstName   string    `json:"first_name" db:"first_name" validate:"required,max=255"`
+	LastName    string    `json:"last_name" db:"last_name" validate:"required,max=255"`
+	Phone       string    `json:"phone" db:"phone" validate:"required,phone"`
+	CPF         *string   `json:"cpf" db:"cpf" validate:"cpf"`
+	Username    string    `json:"username" db:"username" validate:"required,max=255"`
 	IsMonthly   bool      `json:"is_monthly" db:"is_mensal"`
-	MonthlyDate *string   `json:"monthly_date" db:"monthly_date"`
-
-	AddressID  uuid.UUID `json:"address_id" db:"address_id"`
-	Street     string    `json:"street" db:"street"`
-	City       string    `json:"city" db:"city"`
-	State      string    `json:"state" db:"state"`
-	PostalCode string    `json:"postal_code" db:"postal_code"`
-	Number     string    `json:"number" db:"number"`
-	Complement string    `json:"complement" db:"complement"`
-	Landmark   string    `json:"landmark" db:"landmark"`
+	MonthlyDate *string   `json:"monthly_date" db:"monthly_date" validate:"required_if=IsMonthly,date"`
+
+	AddressID    uuid.UUID `json:"address_id" db:"address_id" validate:"required"`
+	Street       string    `json:"street" db:"street" validate:"required,max=255"`
+	Neighborhood string    `json:"neighborhood" db:"neighborhood" validate:"max=100"`
+	City         string    `json:"city" db:"city" validate:"required,max=100"`
+	State        string    `json:"state" db:"state" validate:"required,uf"`
+	PostalCode   string    `json:"postal_code" db:"postal_code" validate:"required,cep"`
+	Number       string    `json:"number" db:"number" validate:"required,max=5"`
+	Complement   string    `json:"complement" db:"complement" validate:"max=255"`
+	Landmark     string    `json:"landmark" db:"landmark" validate:"max=255"`
+	Latitude     *float64  `json:"latitude" db:"latitude" validate:"lat"`
+	Longitude    *float64  `json:"longitude" db:"longitude" validate:"lng"`
 }
 
 // UpdateClientHandler handles the update of client information
@@ -37,14 +44,14 @@ func UpdateClientHandler(db *sqlx.DB) http.HandlerFunc {
 		vars := mux.Vars(r)
 		clientIDStr, ok := vars["id"]
 		if !ok {
-			http.Error(w, "client ID not provided in URL", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.Required)))
 			return
 		}
 
 		// Convert clientIDStr to int
 		clientID, err := uuid.Parse(clientIDStr)
 		if err != nil {
-			http.Error(w, "Invalid client ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
 			return
 		}
 
@@ -52,13 +59,27 @@ func UpdateClientHandler(db *sqlx.DB) http.HandlerFunc {
 		var updatedClient UpdateClient
 		err = json.NewDecoder(r.Body).Decode(&updatedClient)
 		if err != nil {
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		updatedClient.State = cep.NormalizeState(updatedClient.State)
+		err = validation.Struct(updatedClient)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+		normalizeDocuments(&updatedClient.Phone, &updatedClient.PostalCode, updatedClient.CPF)
+
+		err = checkPostalCode(updatedClient.PostalCode, updatedClient.Street, updatedClient.City, updatedClient.State, &updatedClient.Neighborhood)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
 		tx, err := db.Beginx()
 		if err != nil {
-			http.Error(w, "Error starting database transaction", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 		defer func() {
@@ -70,7 +91,7 @@ func UpdateClientHandler(db *sqlx.DB) http.HandlerFunc {
 		}()
 
 		// Update client information in the database
-		_, err = tx.Exec("UPDATE address SET street=$1, city=$2, state=$3, postal_code=$4, number=$5, complement=$6, landmark=$7  WHERE address_id=$8",
+		_, err = tx.Exec("UPDATE address SET street=$1, city=$2, state=$3, postal_code=$4, number=$5, complement=$6, landmark=$7, neighborhood=$8, latitude=$9, longitude=$10 WHERE address_id=$11",
 			updatedClient.Street,
 			updatedClient.City,
 			updatedClient.State,
@@ -78,9 +99,12 @@ func UpdateClientHandler(db *sqlx.DB) http.HandlerFunc {
 			updatedClient.Number,
 			updatedClient.Complement,
 			updatedClient.Landmark,
+			updatedClient.Neighborhood,
+			updatedClient.Latitude,
+			updatedClient.Longitude,
 			updatedClient.AddressID)
 		if err != nil {
-			http.Error(w, "Error updating client address in the database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
@@ -88,12 +112,10 @@ func UpdateClientHandler(db *sqlx.DB) http.HandlerFunc {
 			updatedClient.MonthlyDate = nil
 		}
 
-		_, err = tx.Exec("UPDATE clients SET first_name=$1, last_name=$2, username=$3, phone=$4, is_mensal=$5, monthly_date=$6  WHERE id=$7", updatedClient.FirstName, updatedClient.LastName, updatedClient.Username, updatedClient.Phone, updatedClient.IsMonthly, updatedClient.MonthlyDate, clientID)
-
-		fmt.Print(err)
+		_, err = tx.Exec("UPDATE clients SET first_name=$1, last_name=$2, username=$3, phone=$4, is_mensal=$5, monthly_date=$6, cpf=$7  WHERE id=$8", updatedClient.FirstName, updatedClient.LastName, updatedClient.Username, updatedClient.Phone, updatedClient.IsMonthly, updatedClient.MonthlyDate, updatedClient.CPF, clientID)
 
 		if err != nil {
-			http.Error(w, "Error updating client in the database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
@@ -0,0 +1,430 @@
+package incidentshandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"lavanderia/apierror"
+	"lavanderia/entities"
+	"lavanderia/events"
+	"lavanderia/incidents"
+	"lavanderia/queryspec"
+	"lavanderia/validation"
+	"lavanderia/wallet"
+)
+
+// IncidentRequest is the request body to report or update an incident.
+// LaundryItemID points it to a line of the service; Photos are the URLs of
+// the pictures of the garment.
+type IncidentRequest struct {
+	LaundryItemID *uuid.UUID `json:"laundry_item_id"`
+	Type          string     `json:"type" validate:"required,oneof=damage|loss|complaint"`
+	Description   string     `json:"description" validate:"required,max=2000"`
+	Photos        []string   `json:"photos"`
+	Compensation  float64    `json:"compensation" validate:"positive"`
+}
+
+// validate checks the request and that the item is a line of the service
+func (req IncidentRequest) validate(db *sqlx.DB, serviceID uuid.UUID) error {
+	if err := validation.Struct(req); err != nil {
+		return err
+	}
+
+	for i, photo := range req.Photos {
+		u, err := url.ParseRequestURI(photo)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return apierror.Field(fmt.Sprintf("photos[%d]", i), apierror.InvalidURL)
+		}
+	}
+
+	if req.LaundryItemID == nil {
+		return nil
+	}
+	var exists bool
+	err := db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM laundry_items_services WHERE laundry_service_id = $1 AND laundry_item_id = $2)", serviceID, *req.LaundryItemID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return apierror.Field("laundry_item_id", apierror.ItemServiceNotFound, "id", req.LaundryItemID.String())
+	}
+	return nil
+}
+
+// StatusRequest is the request body to move an incident along its
+// workflow. Closing it requires the resolution; Compensation replaces the
+// one reported when resolving it.
+type StatusRequest struct {
+	Status       string   `json:"status" validate:"required,oneof=open|investigating|resolved|rejected"`
+	Resolution   string   `json:"resolution" validate:"max=2000"`
+	Compensation *float64 `json:"compensation" validate:"positive"`
+}
+
+// IncidentsQuery filters GET /incidents. The period is the day the
+// incidents were reported, as YYYY-MM-DD, both days included.
+type IncidentsQuery struct {
+	Status string `json:"status" validate:"oneof=open|investigating|resolved|rejected"`
+	Type   string `json:"type" validate:"oneof=damage|loss|complaint"`
+	From   string `json:"from" validate:"date"`
+	To     string `json:"to" validate:"date"`
+}
+
+// CreateIncidentHandler handles the report of an incident on a service
+func CreateIncidentHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serviceID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var req IncidentRequest
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		var clientID uuid.UUID
+		err = db.Get(&clientID, "SELECT client_id FROM laundry_services WHERE id = $1", serviceID)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.ServiceNotFound, "id", serviceID.String()))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		err = req.validate(db, serviceID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			tx.Commit()
+		}()
+
+		var incident entities.IncidentEntity
+		err = tx.Get(&incident, `
+			INSERT INTO incidents (laundry_service_id, laundry_item_id, type, description, photos, compensation)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING `+incidents.Columns,
+			serviceID, req.LaundryItemID, req.Type, req.Description, pq.StringArray(photos(req.Photos)), req.Compensation)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		err = events.Publish(tx, events.IncidentReported, serviceID, incidentPayload(incident, clientID))
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(incident)
+	}
+}
+
+// ListServiceIncidentsHandler handles the listing of the incidents of a
+// service, the oldest first
+func ListServiceIncidentsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serviceID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var exists bool
+		err = db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM laundry_services WHERE id = $1)", serviceID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if !exists {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.ServiceNotFound, "id", serviceID.String()))
+			return
+		}
+
+		list := []entities.IncidentEntity{}
+		err = db.Select(&list, "SELECT "+incidents.Columns+" FROM incidents WHERE laundry_service_id = $1 ORDER BY created_at", serviceID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+	}
+}
+
+// ListIncidentsHandler handles the listing of the incidents reported over
+// a period, the newest first
+func ListIncidentsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		query := IncidentsQuery{Status: params.Get("status"), Type: params.Get("type"), From: params.Get("from"), To: params.Get("to")}
+		if err := validation.Struct(query); err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		from, to, err := queryspec.Period(r.URL.Query(), queryspec.MaxPeriodDays)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		list := []entities.IncidentEntity{}
+		err = db.Select(&list, `
+			SELECT `+incidents.Columns+` FROM incidents
+			WHERE created_at >= $1 AND created_at < $2
+			  AND ($3 = '' OR status = $3)
+			  AND ($4 = '' OR type = $4)
+			ORDER BY created_at DESC`, from, to, query.Status, query.Type)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+	}
+}
+
+// UpdateIncidentHandler handles the update of an incident still open or
+// under investigation
+func UpdateIncidentHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		incidentID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var req IncidentRequest
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		incident, err := findIncident(db, incidentID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+		if incidents.Closed(incident.Status) {
+			apierror.Write(w, r, apierror.Conflict("id", apierror.IncidentClosed, "status", incident.Status))
+			return
+		}
+
+		err = req.validate(db, incident.LaundryServiceID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		err = db.Get(&incident, `
+			UPDATE incidents SET laundry_item_id=$1, type=$2, description=$3, photos=$4, compensation=$5
+			WHERE id=$6
+			RETURNING `+incidents.Columns,
+			req.LaundryItemID, req.Type, req.Description, pq.StringArray(photos(req.Photos)), req.Compensation, incidentID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(incident)
+	}
+}
+
+// IncidentStatusHandler handles the moves of an incident along its
+// workflow. Resolving it credits the compensation to the client's wallet.
+func IncidentStatusHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		incidentID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var req StatusRequest
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(req)
+		if err == nil && incidents.Closed(req.Status) && req.Resolution == "" {
+			err = apierror.Field("resolution", apierror.Required)
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			tx.Commit()
+		}()
+
+		incident, err := moveIncident(tx, incidentID, req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(incident)
+	}
+}
+
+// ReportHandler handles the report of the incidents by laundry item over a
+// period
+func ReportHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := IncidentsQuery{From: r.URL.Query().Get("from"), To: r.URL.Query().Get("to")}
+		if err := validation.Struct(query); err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		from, to, err := queryspec.Period(r.URL.Query(), queryspec.MaxPeriodDays)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		lines, err := incidents.Report(db, from, to)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(lines)
+	}
+}
+
+// moveIncident moves the incident to the status of req. When it is
+// resolved, the compensation is credited to the client's wallet and
+// IncidentResolved is published.
+func moveIncident(tx *sqlx.Tx, incidentID uuid.UUID, req StatusRequest) (entities.IncidentEntity, error) {
+	var incident entities.IncidentEntity
+	err := tx.Get(&incident, "SELECT "+incidents.Columns+" FROM incidents WHERE id = $1 FOR UPDATE", incidentID)
+	if err == sql.ErrNoRows {
+		return incident, apierror.NotFound("id", apierror.IncidentNotFound, "id", incidentID.String())
+	}
+	if err != nil {
+		return incident, err
+	}
+
+	if incidents.Closed(incident.Status) {
+		return incident, apierror.Conflict("id", apierror.IncidentClosed, "status", incident.Status)
+	}
+	if !incidents.CanMove(incident.Status, req.Status) {
+		return incident, apierror.Validation(apierror.Field("status", apierror.InvalidTransition, "from", incident.Status, "to", req.Status))
+	}
+
+	compensation := incident.Compensation
+	if req.Compensation != nil && req.Status == incidents.Resolved {
+		compensation = *req.Compensation
+	}
+	resolution := incident.Resolution
+	if req.Resolution != "" {
+		resolution = req.Resolution
+	}
+
+	err = tx.Get(&incident, `
+		UPDATE incidents SET status=$1, resolution=$2, compensation=$3,
+			resolved_at = CASE WHEN $1 IN ('resolved', 'rejected') THEN NOW() END
+		WHERE id=$4
+		RETURNING `+incidents.Columns,
+		req.Status, resolution, compensation, incidentID)
+	if err != nil || req.Status != incidents.Resolved {
+		return incident, err
+	}
+
+	var clientID uuid.UUID
+	err = tx.Get(&clientID, "SELECT client_id FROM laundry_services WHERE id = $1", incident.LaundryServiceID)
+	if err != nil {
+		return incident, err
+	}
+
+	if compensation > 0 {
+		_, err = wallet.Post(tx, wallet.Transaction{
+			Type:       wallet.Credit,
+			ClientID:   clientID,
+			Amount:     compensation,
+			ServiceID:  &incident.LaundryServiceID,
+			IncidentID: &incident.ID,
+			Reason:     "Indenização de ocorrência",
+		})
+		if err != nil {
+			return incident, err
+		}
+	}
+
+	err = events.Publish(tx, events.IncidentResolved, incident.LaundryServiceID, incidentPayload(incident, clientID))
+	return incident, err
+}
+
+func findIncident(db *sqlx.DB, incidentID uuid.UUID) (entities.IncidentEntity, error) {
+	var incident entities.IncidentEntity
+	err := db.Get(&incident, "SELECT "+incidents.Columns+" FROM incidents WHERE id = $1", incidentID)
+	if err == sql.ErrNoRows {
+		return incident, apierror.NotFound("id", apierror.IncidentNotFound, "id", incidentID.String())
+	}
+	return incident, err
+}
+
+func incidentPayload(incident entities.IncidentEntity, clientID uuid.UUID) events.Incident {
+	return events.Incident{
+		IncidentID:   incident.ID,
+		ServiceID:    incident.LaundryServiceID,
+		ClientID:     clientID,
+		Type:         incident.Type,
+		Status:       incident.Status,
+		Compensation: incident.Compensation,
+	}
+}
+
+// photos keeps an incident without photos as an empty list rather than NULL
+func photos(urls []string) []string {
+	if urls == nil {
+		return []string{}
+	}
+	return urls
+}
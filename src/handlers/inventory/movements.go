@@ -0,0 +1,270 @@
+package inventoryhandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/entities"
+	"lavanderia/inventory"
+	"lavanderia/queryspec"
+	"lavanderia/validation"
+)
+
+// PurchaseRequest is the request body of a purchase entry
+type PurchaseRequest struct {
+	Quantity float64  `json:"quantity" validate:"required,positive"`
+	UnitCost *float64 `json:"unit_cost" validate:"positive"`
+	Notes    string   `json:"notes" validate:"max=255"`
+}
+
+// ConsumptionRequest is the request body of a consumption taken by hand,
+// optionally on a wash load
+type ConsumptionRequest struct {
+	Quantity float64    `json:"quantity" validate:"required,positive"`
+	LoadID   *uuid.UUID `json:"load_id"`
+	Notes    string     `json:"notes" validate:"max=255"`
+}
+
+// AdjustmentRequest is the request body of a stock count: the stock is
+// set to the counted one
+type AdjustmentRequest struct {
+	Stock *float64 `json:"stock" validate:"positive"`
+	Notes string   `json:"notes" validate:"max=255"`
+}
+
+// PurchaseHandler handles a purchase entry, adding to the stock
+func PurchaseHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		supplyID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var req PurchaseRequest
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		movement, err := record(db, supplyID, func(entities.SupplyEntity) inventory.Movement {
+			return inventory.Movement{Type: inventory.Purchase, Quantity: req.Quantity, UnitCost: req.UnitCost, Notes: req.Notes}
+		})
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(movement)
+	}
+}
+
+// ConsumptionHandler handles a consumption taken from the stock by hand
+func ConsumptionHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		supplyID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var req ConsumptionRequest
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		if req.LoadID != nil {
+			err = validateLoadExists(db, *req.LoadID)
+			if err != nil {
+				apierror.Write(w, r, apierror.From(err))
+				return
+			}
+		}
+
+		movement, err := record(db, supplyID, func(entities.SupplyEntity) inventory.Movement {
+			return inventory.Movement{Type: inventory.Consumption, Quantity: -req.Quantity, LoadID: req.LoadID, Notes: req.Notes}
+		})
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(movement)
+	}
+}
+
+// AdjustmentHandler handles a stock count, recording the difference to the
+// stock as an adjustment
+func AdjustmentHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		supplyID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var req AdjustmentRequest
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		// A count of zero is valid, so stock is only required to be sent
+		err = validation.Struct(req)
+		if err == nil && req.Stock == nil {
+			err = apierror.Field("stock", apierror.Required)
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		movement, err := record(db, supplyID, func(supply entities.SupplyEntity) inventory.Movement {
+			return inventory.Movement{Type: inventory.Adjustment, Quantity: *req.Stock - supply.Stock, Notes: req.Notes}
+		})
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(movement)
+	}
+}
+
+// ListMovementsHandler handles the listing of the stock movements of a
+// supply over a period, the newest first
+func ListMovementsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		supplyID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		from, to, err := queryspec.Period(r.URL.Query(), queryspec.MaxPeriodDays)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		var exists bool
+		err = db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM supplies WHERE id = $1)", supplyID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if !exists {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.SupplyNotFound, "id", supplyID.String()))
+			return
+		}
+
+		movements := []entities.StockMovementEntity{}
+		err = db.Select(&movements, `
+			SELECT `+inventory.MovementColumns+` FROM stock_movements
+			WHERE supply_id = $1 AND created_at >= $2 AND created_at < $3
+			ORDER BY created_at DESC`, supplyID, from, to)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(movements)
+	}
+}
+
+// ReportHandler handles the stock movement report of every supply over a
+// period
+func ReportHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, to, err := queryspec.Period(r.URL.Query(), queryspec.MaxPeriodDays)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		lines, err := inventory.Report(db, from, to)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(lines)
+	}
+}
+
+// record writes the movement built from the supply in a transaction. The
+// supply is locked while the movement is built, so the stock it sees is
+// the one the movement applies to.
+func record(db *sqlx.DB, supplyID uuid.UUID, build func(entities.SupplyEntity) inventory.Movement) (movement entities.StockMovementEntity, err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return movement, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var supply entities.SupplyEntity
+	err = tx.Get(&supply, "SELECT "+inventory.SupplyColumns+" FROM supplies WHERE id = $1 FOR UPDATE", supplyID)
+	if err == sql.ErrNoRows {
+		return movement, apierror.NotFound("id", apierror.SupplyNotFound, "id", supplyID.String())
+	}
+	if err != nil {
+		return movement, err
+	}
+
+	m := build(supply)
+	m.SupplyID = supplyID
+	movement, err = inventory.Record(tx, m)
+	if err == inventory.ErrInsufficientStock {
+		return movement, apierror.Conflict("quantity", apierror.InsufficientStock, "stock", strconv.FormatFloat(supply.Stock, 'f', -1, 64), "unit", supply.Unit)
+	}
+	return movement, err
+}
+
+func validateLoadExists(db *sqlx.DB, loadID uuid.UUID) error {
+	var exists bool
+	err := db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM machine_loads WHERE id = $1)", loadID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return apierror.Field("load_id", apierror.LoadNotFound, "id", loadID.String())
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package inventoryhandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/entities"
+	"lavanderia/inventory"
+	"lavanderia/validation"
+)
+
+// SupplyRequest is the request body to register or update a supply. Its
+// stock changes through purchases, consumption and adjustments.
+type SupplyRequest struct {
+	Name         string  `json:"name" validate:"required,max=100"`
+	Unit         string  `json:"unit" validate:"required,oneof=un|kg|g|l|ml"`
+	ReorderLevel float64 `json:"reorder_level" validate:"positive"`
+	UsagePerLoad float64 `json:"usage_per_load" validate:"positive"`
+	UsagePerKg   float64 `json:"usage_per_kg" validate:"positive"`
+	IsActive     *bool   `json:"is_active"`
+}
+
+// validate checks the request and that no other supply than supplyID has
+// the name, ignoring case
+func (req SupplyRequest) validate(db *sqlx.DB, supplyID uuid.UUID) error {
+	if err := validation.Struct(req); err != nil {
+		return err
+	}
+
+	var exists bool
+	err := db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM supplies WHERE LOWER(name) = LOWER($1) AND id <> $2)", req.Name, supplyID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return apierror.Field("name", apierror.AlreadyExists)
+	}
+	return nil
+}
+
+// SuppliesQuery filters GET /supplies to the supplies that fell to their
+// reorder level
+type SuppliesQuery struct {
+	LowStock string `json:"low_stock" validate:"oneof=true|false"`
+}
+
+// ListSuppliesHandler handles the listing of the supplies by name.
+// ?low_stock=true lists only the ones to buy.
+func ListSuppliesHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := SuppliesQuery{LowStock: r.URL.Query().Get("low_stock")}
+		if err := validation.Struct(query); err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		where := ""
+		if query.LowStock == "true" {
+			where = " WHERE reorder_level > 0 AND stock <= reorder_level"
+		}
+
+		supplies := []entities.SupplyEntity{}
+		err := db.Select(&supplies, "SELECT "+inventory.SupplyColumns+" FROM supplies"+where+" ORDER BY name")
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(supplies)
+	}
+}
+
+// CreateSupplyHandler handles the registration of a supply, with no stock
+func CreateSupplyHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req SupplyRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = req.validate(db, uuid.Nil)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		isActive := req.IsActive == nil || *req.IsActive
+
+		var supply entities.SupplyEntity
+		err = db.Get(&supply, `
+			INSERT INTO supplies (name, unit, reorder_level, usage_per_load, usage_per_kg, is_active)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING `+inventory.SupplyColumns,
+			req.Name, req.Unit, req.ReorderLevel, req.UsagePerLoad, req.UsagePerKg, isActive)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(supply)
+	}
+}
+
+// UpdateSupplyHandler handles the update of a supply
+func UpdateSupplyHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		supplyID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var req SupplyRequest
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = req.validate(db, supplyID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		isActive := req.IsActive == nil || *req.IsActive
+
+		var supply entities.SupplyEntity
+		err = db.Get(&supply, `
+			UPDATE supplies SET name=$1, unit=$2, reorder_level=$3, usage_per_load=$4, usage_per_kg=$5, is_active=$6
+			WHERE id=$7
+			RETURNING `+inventory.SupplyColumns,
+			req.Name, req.Unit, req.ReorderLevel, req.UsagePerLoad, req.UsagePerKg, isActive, supplyID)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.SupplyNotFound, "id", supplyID.String()))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(supply)
+	}
+}
+
+// DeleteSupplyHandler handles the deletion of a supply without stock
+// movements. Supplies with a history should be disabled instead.
+func DeleteSupplyHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		supplyID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var inUse bool
+		err = db.Get(&inUse, "SELECT EXISTS(SELECT 1 FROM stock_movements WHERE supply_id = $1)", supplyID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if inUse {
+			apierror.Write(w, r, apierror.Conflict("id", apierror.InUse))
+			return
+		}
+
+		result, err := db.Exec("DELETE FROM supplies WHERE id = $1", supplyID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.SupplyNotFound, "id", supplyID.String()))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
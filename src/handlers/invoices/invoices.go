@@ -0,0 +1,269 @@
+package invoiceshandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/entities"
+	middleware "lavanderia/middlewares"
+	"lavanderia/nfse"
+)
+
+// invoiceColumns are the columns of laundry_services read into an
+// InvoiceEntity
+const invoiceColumns = "id, rps_number, invoice_status, invoice_number, invoice_verification_code, invoice_pdf_url, invoice_message, invoiced_at"
+
+// IssueInvoiceHandler handles the issue of the NFS-e of a paid service.
+// The RPS keeps its number when the city rejects it or the provider doesn't
+// answer, so it is sent again with the same number.
+func IssueInvoiceHandler(db *sqlx.DB, issuer *nfse.Issuer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serviceID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		// Checked before the RPS is numbered, so no number is used up
+		if !issuer.Configured() {
+			apierror.Write(w, r, apierror.New(http.StatusServiceUnavailable, apierror.InvoicingDisabled))
+			return
+		}
+
+		rps, previous, err := reserve(db, serviceID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		result, _, err := issuer.Issue(r.Context(), rps)
+		if err != nil {
+			// The RPS number stays with the service: the city may have
+			// received the RPS before the provider failed
+			_, resetErr := db.Exec("UPDATE laundry_services SET invoice_status = $2, invoiced_at = NULL WHERE id = $1 AND invoice_status = $3", serviceID, previous, nfse.Processing)
+			if resetErr != nil {
+				log.Printf("nfse: releasing RPS %d of service %s: %v", rps.Number, serviceID, resetErr)
+			}
+			log.Printf("nfse: issuing RPS %d of service %s: %v", rps.Number, serviceID, err)
+			apierror.Write(w, r, apierror.New(http.StatusBadGateway, apierror.InvoiceProviderError))
+			return
+		}
+
+		var invoice entities.InvoiceEntity
+		err = db.Get(&invoice, `UPDATE laundry_services SET
+				invoice_status = $2,
+				invoice_number = NULLIF($3, ''),
+				invoice_verification_code = NULLIF($4, ''),
+				invoice_pdf_url = NULLIF($5, ''),
+				invoice_message = NULLIF($6, ''),
+				invoiced_at = CASE WHEN $2 = 'issued' THEN CURRENT_TIMESTAMP END
+			WHERE id = $1
+			RETURNING `+invoiceColumns,
+			serviceID, result.Status, result.Number, result.VerificationCode, result.PDFURL, nfse.Truncate(result.Message, 255))
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		if result.Status == nfse.Rejected {
+			apierror.Write(w, r, apierror.New(http.StatusUnprocessableEntity, apierror.InvoiceRejected, "rps", strconv.FormatInt(rps.Number, 10), "reason", result.Message))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(invoice)
+	}
+}
+
+// ShowInvoiceHandler handles the display of the NFS-e of a service, or of
+// the rejection of its RPS. Clients only see the invoices of their own
+// services.
+func ShowInvoiceHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serviceID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		err = middleware.AuthorizeService(db, r, serviceID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		var invoice entities.InvoiceEntity
+		err = db.Get(&invoice, "SELECT "+invoiceColumns+" FROM laundry_services WHERE id = $1", serviceID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if invoice.Status == nil {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.InvoiceNotFound, "id", serviceID.String()))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(invoice)
+	}
+}
+
+// reserve numbers the RPS of the service and marks it processing in a
+// transaction of its own, so no lock is held while the provider answers.
+// previous is the status the service goes back to when the RPS isn't sent.
+// An RPS processing for longer than the provider takes to answer was left
+// by a request that didn't finish, and is sent again.
+func reserve(db *sqlx.DB, serviceID uuid.UUID) (rps nfse.RPS, previous *string, err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return rps, nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var service struct {
+		IsPaid        bool    `db:"is_paid"`
+		TotalPrice    float64 `db:"total_price"`
+		InvoiceStatus *string `db:"invoice_status"`
+		InvoiceNumber *string `db:"invoice_number"`
+		RPSNumber     int64   `db:"rps_number"`
+		Sending       bool    `db:"sending"`
+	}
+	err = tx.Get(&service, `
+		SELECT COALESCE(is_paid, false) AS is_paid, COALESCE(total_price, 0) AS total_price, invoice_status, invoice_number, COALESCE(rps_number, 0) AS rps_number,
+			COALESCE(invoice_status = $2 AND invoiced_at > CURRENT_TIMESTAMP - INTERVAL '5 minutes', false) AS sending
+		FROM laundry_services WHERE id = $1 FOR UPDATE`, serviceID, nfse.Processing)
+	if err == sql.ErrNoRows {
+		err = apierror.NotFound("id", apierror.ServiceNotFound, "id", serviceID.String())
+	}
+	if err == nil && service.InvoiceStatus != nil && *service.InvoiceStatus == nfse.Issued {
+		err = apierror.Conflict("id", apierror.InvoiceAlreadyIssued, "number", value(service.InvoiceNumber))
+	}
+	if err == nil && service.Sending {
+		err = apierror.Conflict("id", apierror.InvoiceProcessing, "rps", strconv.FormatInt(service.RPSNumber, 10))
+	}
+	if err == nil && !service.IsPaid {
+		err = apierror.Conflict("id", apierror.ServiceNotPaid, "id", serviceID.String())
+	}
+	if err == nil && service.TotalPrice <= 0 {
+		err = apierror.Conflict("id", apierror.NothingToInvoice, "id", serviceID.String())
+	}
+	if err != nil {
+		return rps, nil, err
+	}
+
+	rps, err = buildRPS(tx, serviceID, service.TotalPrice)
+	if err != nil {
+		return rps, nil, err
+	}
+
+	_, err = tx.Exec("UPDATE laundry_services SET invoice_status = $2, invoiced_at = CURRENT_TIMESTAMP WHERE id = $1", serviceID, nfse.Processing)
+	if service.InvoiceStatus != nil && *service.InvoiceStatus == nfse.Rejected {
+		previous = service.InvoiceStatus
+	}
+	return rps, previous, err
+}
+
+// buildRPS numbers the RPS of the service, keeping the number of an RPS
+// already sent, and fills it with the client and the items of the service
+func buildRPS(tx *sqlx.Tx, serviceID uuid.UUID, amount float64) (nfse.RPS, error) {
+	rps := nfse.RPS{IssuedAt: time.Now(), Amount: amount}
+	err := tx.Get(&rps.Number, "UPDATE laundry_services SET rps_number = COALESCE(rps_number, nextval('nfse_rps_number')) WHERE id = $1 RETURNING rps_number", serviceID)
+	if err != nil {
+		return rps, err
+	}
+
+	var taker struct {
+		FirstName    string  `db:"first_name"`
+		LastName     string  `db:"last_name"`
+		CPF          string  `db:"cpf"`
+		Phone        string  `db:"phone"`
+		Street       *string `db:"street"`
+		Number       *string `db:"number"`
+		Complement   *string `db:"complement"`
+		Neighborhood *string `db:"neighborhood"`
+		State        *string `db:"state"`
+		PostalCode   *string `db:"postal_code"`
+		TrackingCode string  `db:"tracking_code"`
+		IsWeight     bool    `db:"is_weight"`
+		Weight       float64 `db:"weight"`
+	}
+	err = tx.Get(&taker, `
+		SELECT c.first_name, c.last_name, COALESCE(c.cpf, '') AS cpf, COALESCE(c.phone, '') AS phone,
+			a.street, a.number, a.complement, a.neighborhood, a.state, a.postal_code,
+			ls.tracking_code, COALESCE(ls.is_weight, false) AS is_weight, COALESCE(ls.weight, 0) AS weight
+		FROM laundry_services ls
+		JOIN clients c ON c.id = ls.client_id
+		LEFT JOIN address a ON a.address_id = COALESCE(ls.address_id, c.address_id)
+		WHERE ls.id = $1`, serviceID)
+	if err != nil {
+		return rps, err
+	}
+
+	rps.Taker = nfse.Taker{
+		Name:     strings.TrimSpace(taker.FirstName + " " + taker.LastName),
+		Document: strings.TrimSpace(taker.CPF),
+		Phone:    strings.TrimSpace(taker.Phone),
+	}
+	if taker.Street != nil {
+		rps.Taker.Address = &nfse.Address{
+			Street:       *taker.Street,
+			Number:       value(taker.Number),
+			Complement:   value(taker.Complement),
+			Neighborhood: value(taker.Neighborhood),
+			State:        value(taker.State),
+			PostalCode:   value(taker.PostalCode),
+		}
+	}
+
+	var items []struct {
+		Name     string `db:"name"`
+		Quantity int    `db:"item_quantity"`
+	}
+	err = tx.Select(&items, `
+		SELECT li.name, lis.item_quantity
+		FROM laundry_items_services lis
+		JOIN laundry_items li ON li.id = lis.laundry_item_id
+		WHERE lis.laundry_service_id = $1
+		ORDER BY li.name`, serviceID)
+	if err != nil {
+		return rps, err
+	}
+
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, fmt.Sprintf("%d x %s", item.Quantity, item.Name))
+	}
+	if taker.IsWeight && len(lines) == 0 {
+		lines = append(lines, strings.Replace(fmt.Sprintf("%.2f kg", taker.Weight), ".", ",", 1))
+	}
+	rps.Description = "Serviço de lavanderia " + taker.TrackingCode
+	if len(lines) > 0 {
+		rps.Description += ": " + strings.Join(lines, "; ")
+	}
+	return rps, nil
+}
+
+func value(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
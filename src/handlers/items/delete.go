@@ -2,12 +2,13 @@ package itemshandlers
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
 )
 
 // DeleteItemHandler handles the deletion of a item by ID
@@ -17,35 +18,28 @@ func DeleteItemHandler(db sqlx.ExtContext) http.HandlerFunc {
 		vars := mux.Vars(r)
 		itemIDStr, ok := vars["id"]
 		if !ok {
-			http.Error(w, "item ID not provided in URL", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.Required)))
 			return
 		}
 
 		// Parse itemIDStr as UUID
 		itemID, err := uuid.Parse(itemIDStr)
 		if err != nil {
-			http.Error(w, "Invalid item ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
 			return
 		}
 
 		ctx := r.Context()
 
 		if err := validateDeleteItem(ctx, db, itemIDStr); err != nil {
-			if ve, ok := err.(ValidationError); ok {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(ve.Status)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"details": []ValidationError{ve},
-					"error":   "Validation failed",
-				})
-				return
-			}
+			apierror.Write(w, r, apierror.From(err))
+			return
 		}
 
 		// Execute the delete query
 		_, err = db.ExecContext(ctx, "DELETE FROM laundry_items WHERE id=$1", itemID)
 		if err != nil {
-			http.Error(w, "Error deleting item from database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 		// Return success response
@@ -58,19 +52,19 @@ func validateDeleteItem(ctx context.Context, db sqlx.ExtContext, itemID string)
 	// Fixed the query by adding a closing parenthesis
 	err := sqlx.GetContext(ctx, db, &exists, "SELECT EXISTS(SELECT 1 FROM laundry_items WHERE id=$1)", itemID)
 	if err != nil {
-		return ValidationError{Field: "id", Message: "Failed to validate item existence", Status: http.StatusNotFound}
+		return err
 	}
 	if !exists { // Inverted the logic here to check if the item does not exist
-		return ValidationError{Field: "id", Message: "No item with this ID exists", Status: http.StatusNotFound}
+		return apierror.NotFound("id", apierror.ItemNotFound, "id", itemID)
 	}
 
 	var fkExists bool
 	err = sqlx.GetContext(ctx, db, &fkExists, "SELECT EXISTS(SELECT 1 FROM laundry_items_services WHERE laundry_item_id=$1)", itemID)
 	if err != nil {
-		return ValidationError{Field: "id", Message: "Failed to check foreign key constraints", Status: http.StatusConflict}
+		return err
 	}
 	if fkExists {
-		return ValidationError{Field: "id", Message: "Cannot delete item because it is referenced in other records", Status: http.StatusConflict}
+		return apierror.Conflict("id", apierror.InUse)
 	}
 
 	return nil
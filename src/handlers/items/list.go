@@ -2,71 +2,73 @@ package itemshandlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/jmoiron/sqlx"
 
+	"lavanderia/apierror"
 	"lavanderia/entities"
+	"lavanderia/queryspec"
 )
 
+// ListItemsOptions are the pagination, sort and filter parameters accepted by ListItemsHandler
+var ListItemsOptions = queryspec.Options{
+	SortFields: map[string]string{
+		"name":  "name",
+		"price": "price",
+	},
+	NullSorts:   map[string]string{"price": "0"},
+	DefaultSort: "name",
+	TieBreaker:  "id",
+	Filters: []queryspec.Filter{
+		{Param: "search", Column: "name", Type: queryspec.Contains},
+	},
+}
+
 // ListItemsHandler handles the listing of all items
 func ListItemsHandler(db *sqlx.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Get page and limit from query params, with defaults
-		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-
-		var totalPages int
-		var items []entities.LaundryItemsEntity
-		var response map[string]interface{}
-
-		if page < 1 {
-			err := db.Select(&items, "SELECT id, name, price FROM laundry_items ORDER BY name")
-
-			if err != nil {
-				http.Error(w, "Error retrieving users from database", http.StatusInternalServerError)
-				return
-			}
-
-			response = map[string]interface{}{
-				"items": items,
-			}
-		} else {
-			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-			if limit < 1 {
-				limit = 10 // default limit
-			}
-
-			offset := (page - 1) * limit
-
-			// Query all items from the database
-			err := db.Select(&items, "SELECT id, name, price FROM laundry_items ORDER BY name LIMIT $1 OFFSET $2", limit, offset)
+		spec, err := queryspec.Parse(r.URL.Query(), ListItemsOptions)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
 
-			if err != nil {
-				http.Error(w, "Error retrieving users from database", http.StatusInternalServerError)
-				return
-			}
+		// Count total number of items matching the filters
+		var countArgs queryspec.Args
+		var totalItems int
+		err = db.Get(&totalItems, "SELECT COUNT(*) FROM laundry_items "+spec.CountWhere(&countArgs), countArgs.Values()...)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
 
-			// Count total number of items for pagination
-			var totalItems int
-			err = db.Get(&totalItems, "SELECT COUNT(*) FROM laundry_items")
-			if err != nil {
-				http.Error(w, "Error counting items", http.StatusInternalServerError)
-				return
-			}
+		var args queryspec.Args
+		query := fmt.Sprintf("SELECT id, name, price, %s AS cursor FROM laundry_items %s %s %s",
+			spec.CursorColumns(), spec.Where(&args), spec.OrderBy(), spec.Limit(&args))
 
-			totalPages = (totalItems + limit - 1) / limit
+		var rows []struct {
+			entities.LaundryItemsEntity
+			Cursor string `db:"cursor"`
+		}
+		err = db.Select(&rows, query, args.Values()...)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
 
-			// Format response with clients map and pagination info
-			response = map[string]interface{}{
-				"items":       items,
-				"page":        page,
-				"total_pages": totalPages,
-			}
+		items := make([]entities.LaundryItemsEntity, 0, len(rows))
+		var last string
+		for _, row := range rows[:spec.Trim(len(rows))] {
+			items = append(items, row.LaundryItemsEntity)
+			last = row.Cursor
 		}
 
-		// Return the list of users as JSON
+		// Return the list of items as JSON
+		pagination := spec.Paginate(totalItems, len(rows), last)
+
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		json.NewEncoder(w).Encode(pagination.Response("items", items))
 	}
 }
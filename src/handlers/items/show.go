@@ -3,6 +3,7 @@ package itemshandlers
 import (
 	"context"
 	"encoding/json"
+	"lavanderia/apierror"
 	"lavanderia/entities"
 	"net/http"
 
@@ -18,17 +19,13 @@ func ShowItemHandler(db *sqlx.DB) http.HandlerFunc {
 		vars := mux.Vars(r)
 		itemIDStr, ok := vars["id"]
 		if !ok {
-			http.Error(w, "item ID not provided in URL", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.Required)))
 			return
 		}
 
 		itemID, err := uuid.Parse(itemIDStr)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "id", Message: "Invalid item ID", Status: http.StatusBadRequest},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
 			return
 		}
 
@@ -36,15 +33,8 @@ func ShowItemHandler(db *sqlx.DB) http.HandlerFunc {
 
 		// Validate the input data
 		if err := validateDetailItem(ctx, db, itemID); err != nil {
-			if ve, ok := err.(ValidationError); ok {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(ve.Status)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"details": []ValidationError{ve},
-					"error":   "Validation failed",
-				})
-				return
-			}
+			apierror.Write(w, r, apierror.From(err))
+			return
 		}
 
 		// Query all clients from the database with pagination
@@ -57,7 +47,7 @@ func ShowItemHandler(db *sqlx.DB) http.HandlerFunc {
 		  ORDER BY
 			name`, itemID)
 		if err != nil {
-			http.Error(w, "Error retrieving items from database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
@@ -70,10 +60,10 @@ func validateDetailItem(ctx context.Context, db sqlx.ExtContext, itemID uuid.UUI
 	var exists bool
 	err := sqlx.GetContext(ctx, db, &exists, "SELECT EXISTS(SELECT 1 FROM laundry_items WHERE id=$1)", itemID)
 	if err != nil {
-		return ValidationError{Field: "id", Message: "Failed to validate item existence", Status: http.StatusNotFound}
+		return err
 	}
 	if !exists { // Inverted the logic here to check if the item does not exist
-		return ValidationError{Field: "id", Message: "No item with this ID exists", Status: http.StatusNotFound}
+		return apierror.NotFound("id", apierror.ItemNotFound, "id", itemID.String())
 	}
 
 	return nil
@@ -9,7 +9,9 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
 
+	"lavanderia/apierror"
 	"lavanderia/entities"
+	"lavanderia/validation"
 )
 
 // UpdateItemHandler handles the update of item information
@@ -19,14 +21,14 @@ func UpdateItemHandler(db *sqlx.DB) http.HandlerFunc {
 		vars := mux.Vars(r)
 		itemIDStr, ok := vars["id"]
 		if !ok {
-			http.Error(w, "Item ID not provided in URL", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.Required)))
 			return
 		}
 
 		// Convert itemIDStr to int
 		itemID, err := uuid.Parse(itemIDStr)
 		if err != nil {
-			http.Error(w, "Invalid item ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
 			return
 		}
 
@@ -34,7 +36,7 @@ func UpdateItemHandler(db *sqlx.DB) http.HandlerFunc {
 		var updatedItem entities.LaundryItemsEntity
 		err = json.NewDecoder(r.Body).Decode(&updatedItem)
 		if err != nil {
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
 			return
 		}
 
@@ -42,21 +44,14 @@ func UpdateItemHandler(db *sqlx.DB) http.HandlerFunc {
 
 		// Validate the input data
 		if err := validateUpdateItem(ctx, db, updatedItem, itemID); err != nil {
-			if ve, ok := err.(ValidationError); ok {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"details": []ValidationError{ve},
-					"error":   "Validation failed",
-				})
-				return
-			}
+			apierror.Write(w, r, apierror.From(err))
+			return
 		}
 
 		// Update item information in the database
 		_, err = db.Exec("UPDATE laundry_items SET name=$1, price=$2 WHERE id=$3", updatedItem.Name, updatedItem.Price, itemID)
 		if err != nil {
-			http.Error(w, "Error updating item in the database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
@@ -66,23 +61,17 @@ func UpdateItemHandler(db *sqlx.DB) http.HandlerFunc {
 }
 
 func validateUpdateItem(ctx context.Context, db sqlx.ExtContext, item entities.LaundryItemsEntity, itemID uuid.UUID) error {
-	if item.Name == "" {
-		return ValidationError{Field: "name", Message: "Item name cannot be empty", Status: http.StatusBadRequest}
-	}
-	if len(item.Name) > 100 { // Assuming 100 is the maximum length for a name
-		return ValidationError{Field: "name", Message: "Item name exceeds maximum length of 100 characters", Status: http.StatusBadRequest}
-	}
-	if item.Price <= 0 {
-		return ValidationError{Field: "price", Message: "Item price must be positive", Status: http.StatusBadRequest}
+	if err := validation.Struct(item); err != nil {
+		return err
 	}
 
 	var exists bool
 	err := sqlx.GetContext(ctx, db, &exists, "SELECT EXISTS(SELECT 1 FROM laundry_items WHERE id=$1)", itemID)
 	if err != nil {
-		return ValidationError{Field: "id", Message: "Failed to validate item existence", Status: http.StatusNotFound}
+		return err
 	}
 	if !exists { // Inverted the logic here to check if the item does not exist
-		return ValidationError{Field: "id", Message: "No item with this ID exists", Status: http.StatusNotFound}
+		return apierror.NotFound("id", apierror.ItemNotFound, "id", itemID.String())
 	}
 
 	return nil
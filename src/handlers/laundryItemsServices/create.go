@@ -2,32 +2,26 @@ package itemsserviceshandlers
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
-)
-
-// ValidationError is the struct for the error return
-type ValidationError struct {
-	Field   string `json:"field"`
-	Message string `json:"message"`
-	Status  int    `json:"status"` // HTTP status code
-}
 
-func (ve ValidationError) Error() string {
-	return fmt.Sprintf("%s: %s (status %d)", ve.Field, ve.Message, ve.Status)
-}
+	"lavanderia/apierror"
+	"lavanderia/validation"
+)
 
 // LaundryItemsService is a interface of the request body to add items to service
 type LaundryItemsService struct {
-	Items []struct {
-		LaundryItemID uuid.UUID `json:"laundry_item_id"`
-		ItemQuantity  int       `json:"item_quantity"`
-		Observation   string    `json:"observation"`
-	} `json:"items"`
+	Items []ItemServiceRequest `json:"items" validate:"required,dive"`
+}
+
+// ItemServiceRequest is an item line of the request body to add items to service
+type ItemServiceRequest struct {
+	LaundryItemID uuid.UUID `json:"laundry_item_id" validate:"required"`
+	ItemQuantity  int       `json:"item_quantity" validate:"required,positive"`
+	Observation   string    `json:"observation"`
 }
 
 // AddItemsServicesHandler handles the creation of a laundry services
@@ -36,51 +30,45 @@ func AddItemsServicesHandler(db *sqlx.DB) http.HandlerFunc {
 		var newItemsService LaundryItemsService
 		err := json.NewDecoder(r.Body).Decode(&newItemsService)
 		if err != nil {
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(newItemsService)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
 		vars := mux.Vars(r)
 		serviceIDStr, ok := vars["serviceID"]
 		if !ok {
-			http.Error(w, "Service ID not provided in URL", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("serviceID", apierror.Required)))
 			return
 		}
 
 		serviceID, err := uuid.Parse(serviceIDStr)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "id", Message: "Invalid service ID", Status: http.StatusBadRequest},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.Validation(apierror.Field("serviceID", apierror.InvalidUUID)))
 			return
 		}
 
 		err = validateServiceIDExists(db, serviceIDStr)
 		if err != nil {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "id", Message: err.Error(), Status: http.StatusNotFound},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
 		err = validateLaundryItemsExistence(db, newItemsService.Items)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "items", Message: err.Error(), Status: http.StatusNotFound},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
 		// Start a transaction
 		tx, err := db.Beginx()
 		if err != nil {
-			http.Error(w, "Error starting database transaction", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 		defer func() {
@@ -93,7 +81,7 @@ func AddItemsServicesHandler(db *sqlx.DB) http.HandlerFunc {
 
 		err = insertLaundryItems(tx, serviceIDStr, newItemsService)
 		if err != nil {
-			http.Error(w, "Error to insert items in service_items", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
@@ -101,12 +89,16 @@ func AddItemsServicesHandler(db *sqlx.DB) http.HandlerFunc {
 		err = tx.Get(&isPiece, "SELECT is_piece FROM laundry_services WHERE id=$1", serviceID)
 
 		if err != nil {
-			http.Error(w, "Error to find service in the database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
 		if isPiece {
-			calculateUpdatedTotalPrice(tx, serviceIDStr)
+			err = calculateUpdatedTotalPrice(tx, serviceID)
+			if err != nil {
+				apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+				return
+			}
 		}
 
 		// Return success response
@@ -129,11 +121,7 @@ func insertLaundryItems(tx *sqlx.Tx, serviceID string, itemsService LaundryItems
 	return nil
 }
 
-func validateLaundryItemsExistence(db *sqlx.DB, items []struct {
-	LaundryItemID uuid.UUID `json:"laundry_item_id"`
-	ItemQuantity  int       `json:"item_quantity"`
-	Observation   string    `json:"observation"`
-}) error {
+func validateLaundryItemsExistence(db *sqlx.DB, items []ItemServiceRequest) error {
 	for _, item := range items {
 		var exists bool
 		err := db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM laundry_items WHERE id = $1)", item.LaundryItemID)
@@ -141,7 +129,7 @@ func validateLaundryItemsExistence(db *sqlx.DB, items []struct {
 			return err
 		}
 		if !exists {
-			return fmt.Errorf("laundry item with ID %s does not exist", item.LaundryItemID)
+			return apierror.Field("items", apierror.ItemNotFound, "id", item.LaundryItemID.String())
 		}
 	}
 	return nil
@@ -154,7 +142,7 @@ func validateServiceIDExists(db *sqlx.DB, serviceID string) error {
 		return err
 	}
 	if !exists {
-		return fmt.Errorf("Service with ID %s does not exist", serviceID)
+		return apierror.NotFound("serviceID", apierror.ServiceNotFound, "id", serviceID)
 	}
 	return nil
 }
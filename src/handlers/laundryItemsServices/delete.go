@@ -1,8 +1,9 @@
 package itemsserviceshandlers
 
 import (
-	"lavanderia/entities"
+	"lavanderia/apierror"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -16,7 +17,7 @@ func DeleteItemServiceHandler(db *sqlx.DB) http.HandlerFunc {
 		vars := mux.Vars(r)
 		serviceIDStr, ok := vars["serviceID"]
 		if !ok {
-			http.Error(w, "Service ID not provided in URL", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("serviceID", apierror.Required)))
 			return
 		}
 
@@ -24,77 +25,79 @@ func DeleteItemServiceHandler(db *sqlx.DB) http.HandlerFunc {
 		itemVars := mux.Vars(r)
 		itemIDStr, ok := itemVars["itemID"]
 		if !ok {
-			http.Error(w, "Service ID not provided in URL", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("itemID", apierror.Required)))
 			return
 		}
 
 		// Parse serviceIDStr as UUID
 		serviceID, err := uuid.Parse(serviceIDStr)
 		if err != nil {
-			http.Error(w, "Invalid service ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("serviceID", apierror.InvalidUUID)))
 			return
 		}
 
 		// Parse itemIDStr as UUID
 		itemID, err := uuid.Parse(itemIDStr)
 		if err != nil {
-			http.Error(w, "Invalid item ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("itemID", apierror.InvalidUUID)))
+			return
+		}
+
+		// Start a transaction
+		tx, err := db.Beginx()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			tx.Commit()
+		}()
+
+		// Lines with incidents are kept as the record of the incident. The
+		// line is locked first, so no incident is recorded on it meanwhile.
+		var incidents []string
+		_, err = tx.Exec("SELECT 1 FROM laundry_items_services WHERE laundry_service_id=$1 AND laundry_item_id=$2 FOR UPDATE", serviceID, itemID)
+		if err == nil {
+			err = tx.Select(&incidents, "SELECT id FROM incidents WHERE laundry_service_id=$1 AND laundry_item_id=$2 ORDER BY created_at", serviceID, itemID)
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if len(incidents) > 0 {
+			err = apierror.Conflict("itemID", apierror.LineHasIncidents, "ids", strings.Join(incidents, ", "))
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
 		// Execute the delete query
-		_, err = db.Exec("DELETE FROM laundry_items_services WHERE laundry_service_id=$1 AND laundry_item_id=$2", serviceID, itemID)
+		_, err = tx.Exec("DELETE FROM laundry_items_services WHERE laundry_service_id=$1 AND laundry_item_id=$2", serviceID, itemID)
 		if err != nil {
-			http.Error(w, "Error deleting service from the database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
 		var isPiece bool
-		err = db.Get(&isPiece, "SELECT is_piece FROM laundry_services WHERE id=$1", serviceID)
+		err = tx.Get(&isPiece, "SELECT is_piece FROM laundry_services WHERE id=$1", serviceID)
 
 		if err != nil {
-			http.Error(w, "Error to find service in the database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
 		if isPiece {
-			calculateTotalPriceWhenDeleted(db, serviceID.String())
+			err = calculateUpdatedTotalPrice(tx, serviceID)
+			if err != nil {
+				apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+				return
+			}
 		}
 
 		// Return success response
 		w.WriteHeader(http.StatusOK)
 	}
 }
-
-func calculateTotalPriceWhenDeleted(db *sqlx.DB, serviceID string) error {
-	var items []entities.LaundryItemsServicesEntity
-
-	var price float64
-	var totalPrice float64
-
-	query := `
-	SELECT price
-	FROM laundry_items
-	WHERE id = $1
-	`
-
-	err := db.Select(&items, "SELECT * FROM laundry_items_services WHERE laundry_service_id=$1", serviceID)
-
-	for _, item := range items {
-		db.Get(&price, query, item.LaundryItemID)
-
-		totalPrice += (price * float64(item.ItemQuantity))
-	}
-
-	// Update service total_price in the database
-	_, err = db.Exec(
-		"UPDATE laundry_services SET total_price=$1 WHERE id=$2",
-		totalPrice,
-		serviceID)
-
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
@@ -2,8 +2,11 @@ package itemsserviceshandlers
 
 import (
 	"encoding/json"
-	"fmt"
+	"lavanderia/apierror"
 	"lavanderia/entities"
+	"lavanderia/events"
+	"lavanderia/production"
+	"lavanderia/validation"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -13,7 +16,7 @@ import (
 
 // LaundryItemsServiceUpdate is a interface of the request body to update items
 type LaundryItemsServiceUpdate struct {
-	ItemQuantity int `json:"item_quantity"`
+	ItemQuantity int `json:"item_quantity" validate:"required,positive"`
 }
 
 // UpdateItemServiceHandler handles the update of items in the service
@@ -23,49 +26,28 @@ func UpdateItemServiceHandler(db *sqlx.DB) http.HandlerFunc {
 		vars := mux.Vars(r)
 		serviceIDStr, ok := vars["serviceID"]
 		if !ok {
-			http.Error(w, "Service ID not provided in URL", http.StatusBadRequest)
-			return
-		}
-
-		err := validateServiceIDExists(db, serviceIDStr)
-		if err != nil {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "serviceID", Message: err.Error(), Status: http.StatusNotFound},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.Validation(apierror.Field("serviceID", apierror.Required)))
 			return
 		}
 
 		// Get item ID from URL parameters
-		itemVars := mux.Vars(r)
-		itemIDStr, ok := itemVars["itemID"]
+		itemIDStr, ok := vars["itemID"]
 		if !ok {
-			http.Error(w, "Service ID not provided in URL", http.StatusBadRequest)
-			return
-		}
-
-		err = validateItemServiceIDExists(db, itemIDStr)
-		if err != nil {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "itemServiceID", Message: err.Error(), Status: http.StatusNotFound},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.Validation(apierror.Field("itemID", apierror.Required)))
 			return
 		}
 
 		// Parse serviceIDStr as UUID
 		serviceID, err := uuid.Parse(serviceIDStr)
 		if err != nil {
-			http.Error(w, "Invalid service ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("serviceID", apierror.InvalidUUID)))
 			return
 		}
 
 		// Parse itemIDStr as UUID
 		itemID, err := uuid.Parse(itemIDStr)
 		if err != nil {
-			http.Error(w, "Invalid item ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("itemID", apierror.InvalidUUID)))
 			return
 		}
 
@@ -73,14 +55,32 @@ func UpdateItemServiceHandler(db *sqlx.DB) http.HandlerFunc {
 		var updatedItemService LaundryItemsServiceUpdate
 		err = json.NewDecoder(r.Body).Decode(&updatedItemService)
 		if err != nil {
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(updatedItemService)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		err = validateServiceIDExists(db, serviceIDStr)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		err = validateItemServiceIDExists(db, itemIDStr)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
 		// Start a transaction
 		tx, err := db.Beginx()
 		if err != nil {
-			http.Error(w, "Error starting database transaction", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 		defer func() {
@@ -95,7 +95,7 @@ func UpdateItemServiceHandler(db *sqlx.DB) http.HandlerFunc {
 		_, err = tx.Exec("UPDATE laundry_items_services SET item_quantity=$1 WHERE laundry_service_id=$2 AND laundry_item_id=$3", updatedItemService.ItemQuantity, serviceID, itemID)
 
 		if err != nil {
-			http.Error(w, "Error updating service in the database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
@@ -103,37 +103,54 @@ func UpdateItemServiceHandler(db *sqlx.DB) http.HandlerFunc {
 		err = tx.Get(&isPiece, "SELECT is_piece FROM laundry_services WHERE id=$1", serviceID)
 
 		if err != nil {
-			http.Error(w, "Error to find service in the database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
 		if isPiece {
-			calculateUpdatedTotalPrice(tx, serviceID.String())
+			err = calculateUpdatedTotalPrice(tx, serviceID)
+			if err != nil {
+				apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+				return
+			}
 		}
 		// Return success response
 		w.WriteHeader(http.StatusOK)
 	}
 }
 
-func calculateUpdatedTotalPrice(tx *sqlx.Tx, serviceID string) error {
+// calculateUpdatedTotalPrice sums the items of a service, with the
+// surcharge of its priority, into its total price, publishing
+// ServiceRepriced when it changes
+func calculateUpdatedTotalPrice(tx *sqlx.Tx, serviceID uuid.UUID) error {
 	var items []entities.LaundryItemsServicesEntity
 
 	var price float64
 	var totalPrice float64
 
+	var current struct {
+		TotalPrice float64 `db:"total_price"`
+		Surcharge  float64 `db:"surcharge"`
+	}
+	err := tx.Get(&current, "SELECT COALESCE(total_price, 0) AS total_price, surcharge FROM laundry_services WHERE id=$1", serviceID)
+	if err != nil {
+		return err
+	}
+
 	query := `
 	SELECT price
 	FROM laundry_items
 	WHERE id = $1
 	`
 
-	err := tx.Select(&items, "SELECT * FROM laundry_items_services WHERE laundry_service_id=$1", serviceID)
+	err = tx.Select(&items, "SELECT * FROM laundry_items_services WHERE laundry_service_id=$1", serviceID)
 
 	for _, item := range items {
 		tx.Get(&price, query, item.LaundryItemID)
 
 		totalPrice += (price * float64(item.ItemQuantity))
 	}
+	totalPrice = production.WithSurcharge(totalPrice, current.Surcharge)
 
 	// Update service total_price in the database
 	_, err = tx.Exec(
@@ -145,7 +162,14 @@ func calculateUpdatedTotalPrice(tx *sqlx.Tx, serviceID string) error {
 		return err
 	}
 
-	return nil
+	if totalPrice == current.TotalPrice {
+		return nil
+	}
+	return events.Publish(tx, events.ServiceRepriced, serviceID, events.Reprice{
+		ServiceID:  serviceID,
+		From:       current.TotalPrice,
+		TotalPrice: totalPrice,
+	})
 }
 
 func validateItemServiceIDExists(db *sqlx.DB, itemServiceID string) error {
@@ -155,7 +179,7 @@ func validateItemServiceIDExists(db *sqlx.DB, itemServiceID string) error {
 		return err
 	}
 	if !exists {
-		return fmt.Errorf("Service with ID %s does not exist", itemServiceID)
+		return apierror.NotFound("itemID", apierror.ItemServiceNotFound, "id", itemServiceID)
 	}
 	return nil
 }
@@ -2,40 +2,49 @@ package serviceshandlers
 
 import (
 	"encoding/json"
-	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
-)
-
-// ValidationError is the struct for the error return
-type ValidationError struct {
-	Field   string `json:"field"`
-	Message string `json:"message"`
-	Status  int    `json:"status"` // HTTP status code
-}
 
-func (ve ValidationError) Error() string {
-	return fmt.Sprintf("%s: %s (status %d)", ve.Field, ve.Message, ve.Status)
-}
+	"lavanderia/apierror"
+	"lavanderia/calendar"
+	"lavanderia/events"
+	"lavanderia/loyalty"
+	"lavanderia/notify"
+	"lavanderia/production"
+	"lavanderia/tracking"
+	"lavanderia/validation"
+)
 
 // LaundryService is a interface of the request body to create service
 type LaundryService struct {
-	ID                      string    `json:"id"`
-	EstimatedCompletionDate time.Time `json:"estimated_completion_date"`
-	Items                   []struct {
-		LaundryItemID uuid.UUID `json:"laundry_item_id"`
-		ItemQuantity  int       `json:"item_quantity"`
-		Observation   string    `json:"observation"`
-	} `json:"items"`
-	Weight    float64   `json:"weight"`
-	IsWeight  bool      `json:"is_weight"`
-	IsPiece   bool      `json:"is_piece"`
-	ClientID  uuid.UUID `json:"client_id"`
-	IsPaid    bool      `json:"is_paid"`
-	IsMonthly bool      `json:"is_monthly"`
+	ID                      string               `json:"id"`
+	EstimatedCompletionDate time.Time            `json:"estimated_completion_date" validate:"future"`
+	Items                   []ServiceItemRequest `json:"items" validate:"required,dive"`
+	Weight                  float64              `json:"weight" validate:"required_if=IsWeight,positive"`
+	IsWeight                bool                 `json:"is_weight"`
+	IsPiece                 bool                 `json:"is_piece"`
+	ClientID                uuid.UUID            `json:"client_id" validate:"required"`
+	IsPaid                  bool                 `json:"is_paid"`
+	IsMonthly               bool                 `json:"is_monthly"`
+	AddressID               *uuid.UUID           `json:"address_id"`
+	TrackingCode            string               `json:"tracking_code"`
+	Priority                string               `json:"priority" validate:"oneof=normal|express|same_day"`
+	Surcharge               float64              `json:"surcharge"`
+	RedeemPoints            int                  `json:"redeem_points" validate:"positive"`
+	Discount                float64              `json:"discount"`
+	Warnings                []string             `json:"warnings,omitempty"`
+}
+
+// ServiceItemRequest is an item line of the request body to create service
+type ServiceItemRequest struct {
+	LaundryItemID uuid.UUID `json:"laundry_item_id" validate:"required"`
+	ItemQuantity  int       `json:"item_quantity" validate:"required,positive"`
+	Observation   string    `json:"observation"`
 }
 
 // CreateServicesHandler handles the creation of a laundry services
@@ -44,91 +53,71 @@ func CreateServicesHandler(db *sqlx.DB) http.HandlerFunc {
 		var newService LaundryService
 		err := json.NewDecoder(r.Body).Decode(&newService)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Message: "Invalid request payload", Status: http.StatusBadRequest},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
 			return
 		}
 
-		if newService.IsWeight && newService.Weight <= 0 {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "weight", Message: "Quando opção por peso está marcado, o peso deve ser positivo.", Status: http.StatusBadRequest},
-				"error":   "Validation failed",
-			})
+		err = validation.Struct(newService)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
-		if len(newService.Items) == 0 {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "items", Message: "Serviço não pode ser cadastrado sem itens", Status: http.StatusBadRequest},
-				"error":   "Validation failed",
-			})
+		err = validateLaundryItemsExistence(db, newService.Items)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
-		if newService.IsPiece {
-			for _, item := range newService.Items {
-				if item.ItemQuantity <= 0 {
-					w.WriteHeader(http.StatusBadRequest)
-					json.NewEncoder(w).Encode(map[string]interface{}{
-						"details": ValidationError{Field: "item_quantity", Message: "A quantidade de cada item deve ser positiva", Status: http.StatusBadRequest},
-						"error":   "Validation failed",
-					})
-					return
-				}
-			}
+		err = validateClientIDExists(db, newService.ClientID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
 		}
 
-		err = validateLaundryItemsExistence(db, newService.Items)
+		newService.AddressID, err = deliveryAddress(db, newService.ClientID, newService.AddressID)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "items", Message: err.Error(), Status: http.StatusNotFound},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
-		err = validateClientIDExists(db, newService.ClientID)
+		cal, err := calendar.Load(db)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		settings, err := production.LoadSettings(db)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		if newService.Priority == "" {
+			newService.Priority = production.Normal
+		}
+		newService.Surcharge = production.Surcharge(settings, newService.Priority)
+
+		// Propose the completion date when the attendant doesn't set one,
+		// and check the day has room for the service
+		newService.Warnings, err = scheduleService(db, cal, settings, &newService)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "client_id", Message: err.Error(), Status: http.StatusNotFound},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
 		if newService.IsMonthly {
 			err = validateClientIsMensal(db, newService.ClientID)
 			if err != nil {
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"details": ValidationError{Field: "client_id", Message: err.Error(), Status: http.StatusBadRequest},
-					"error":   "Validation failed",
-				})
+				apierror.Write(w, r, apierror.From(err))
 				return
 			}
 			newService.IsPaid = true
 		}
 
-		err = validateEstimatedCompletionDate(newService.EstimatedCompletionDate)
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "estimated_completion_date", Message: err.Error(), Status: http.StatusBadRequest},
-				"error":   "Validation failed",
-			})
-			return
-		}
-
 		tx, err := db.Beginx()
 		if err != nil {
-			http.Error(w, "Error starting database transaction", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 		defer func() {
@@ -139,35 +128,69 @@ func CreateServicesHandler(db *sqlx.DB) http.HandlerFunc {
 			tx.Commit()
 		}()
 
-		newService.ID = uuid.New().String()
+		serviceID := uuid.New()
+		newService.ID = serviceID.String()
+
+		newService.TrackingCode, err = tracking.NewCode()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.InternalError))
+			return
+		}
+
+		serviceTotalPrice, err := calculateTotalPrice(db, newService)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
 
-		serviceTotalPrice, err := calculateTotalPrice(db, newService, w)
+		// Points redeemed are taken from the total price
+		newService.Discount, err = loyaltyDiscount(db, newService.RedeemPoints, serviceTotalPrice)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "total_price", Message: "Erro ao calcular preço total.", Status: http.StatusInternalServerError},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
+		serviceTotalPrice = math.Round((serviceTotalPrice-newService.Discount)*100) / 100
 
 		err = insertLaundryService(tx, newService, newService.ID, serviceTotalPrice)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Message: "Error inserting laundry service into database", Status: http.StatusInternalServerError},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		err = redeemPoints(tx, newService, serviceID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
 		err = insertLaundryItems(tx, newService.ID, newService)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Message: "Error inserting laundry service items into database", Status: http.StatusInternalServerError},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		err = notify.Enqueue(tx, newService.ClientID, notify.ServiceCreated, notify.Data{
+			ServiceID:    newService.ID,
+			Total:        serviceTotalPrice,
+			Date:         newService.EstimatedCompletionDate,
+			TrackingCode: tracking.Format(newService.TrackingCode),
+		}, newService.ID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		err = events.Publish(tx, events.ServiceCreated, serviceID, events.Service{
+			ServiceID:               serviceID,
+			ClientID:                newService.ClientID,
+			Status:                  statusSeparated,
+			TotalPrice:              serviceTotalPrice,
+			IsPaid:                  newService.IsPaid,
+			EstimatedCompletionDate: newService.EstimatedCompletionDate,
+			Priority:                newService.Priority,
+		})
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
@@ -178,12 +201,10 @@ func CreateServicesHandler(db *sqlx.DB) http.HandlerFunc {
 }
 
 func insertLaundryService(tx *sqlx.Tx, service LaundryService, serviceID string, totalPrice float64) error {
-	var serviceStatus = "Separado"
-
 	_, err := tx.Exec(`
-		INSERT INTO laundry_services (id, status, estimated_completion_date, total_price, weight, is_weight, is_piece, client_id, is_paid)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
-		serviceID, serviceStatus, service.EstimatedCompletionDate, totalPrice, service.Weight, service.IsWeight, service.IsPiece, service.ClientID, service.IsPaid)
+		INSERT INTO laundry_services (id, status, estimated_completion_date, total_price, weight, is_weight, is_piece, client_id, is_paid, address_id, tracking_code, priority, surcharge, loyalty_discount, paid_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, CASE WHEN $9 THEN CURRENT_TIMESTAMP END)`,
+		serviceID, statusSeparated, service.EstimatedCompletionDate, totalPrice, service.Weight, service.IsWeight, service.IsPiece, service.ClientID, service.IsPaid, service.AddressID, service.TrackingCode, service.Priority, service.Surcharge, service.Discount)
 
 	return err
 }
@@ -201,7 +222,7 @@ func insertLaundryItems(tx *sqlx.Tx, serviceID string, service LaundryService) e
 	return nil
 }
 
-func calculateTotalPrice(db *sqlx.DB, service LaundryService, w http.ResponseWriter) (float64, error) {
+func calculateTotalPrice(db *sqlx.DB, service LaundryService) (float64, error) {
 	if service.IsMonthly {
 		return 0, nil
 	}
@@ -217,24 +238,7 @@ func calculateTotalPrice(db *sqlx.DB, service LaundryService, w http.ResponseWri
     `
 
 	if service.IsPiece {
-		if len(service.Items) == 0 {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Message: "When 'IsPiece' is true, 'Items' should not be empty", Status: http.StatusBadRequest},
-				"error":   "Validation failed",
-			})
-			return 0, nil
-		}
-
 		for _, item := range service.Items {
-			if item.ItemQuantity <= 0 {
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"details": ValidationError{Message: "Item quantities should be positive numbers", Status: http.StatusBadRequest},
-					"error":   "Validation failed",
-				})
-				return 0, nil
-			}
 			err := db.Get(&price, query, item.LaundryItemID)
 			if err != nil {
 				return 0, err
@@ -248,14 +252,50 @@ func calculateTotalPrice(db *sqlx.DB, service LaundryService, w http.ResponseWri
 		totalPrice = 0
 	}
 
-	return totalPrice, nil
+	return production.WithSurcharge(totalPrice, service.Surcharge), nil
 }
 
-func validateLaundryItemsExistence(db *sqlx.DB, items []struct {
-	LaundryItemID uuid.UUID `json:"laundry_item_id"`
-	ItemQuantity  int       `json:"item_quantity"`
-	Observation   string    `json:"observation"`
-}) error {
+// loyaltyDiscount returns the discount of redeeming points on a service of
+// the given price, under the current loyalty settings
+func loyaltyDiscount(db *sqlx.DB, points int, price float64) (float64, error) {
+	if points == 0 {
+		return 0, nil
+	}
+
+	settings, err := loyalty.LoadSettings(db)
+	if err != nil {
+		return 0, err
+	}
+
+	discount, err := loyalty.Discount(settings, points, price)
+	switch err {
+	case loyalty.ErrInactive:
+		return 0, apierror.Field("redeem_points", apierror.LoyaltyInactive)
+	case loyalty.ErrBelowMinimum:
+		return 0, apierror.Field("redeem_points", apierror.BelowMinRedemption, "min", strconv.Itoa(settings.MinRedemption))
+	case loyalty.ErrDiscountTooHigh:
+		return 0, apierror.Field("redeem_points", apierror.DiscountTooHigh,
+			"max", strconv.FormatFloat(settings.MaxDiscount, 'f', -1, 64),
+			"amount", strconv.FormatFloat(price*settings.MaxDiscount/100, 'f', 2, 64))
+	}
+	return discount, err
+}
+
+// redeemPoints takes the points redeemed on a service from its client
+func redeemPoints(tx *sqlx.Tx, service LaundryService, serviceID uuid.UUID) error {
+	if service.RedeemPoints == 0 {
+		return nil
+	}
+
+	err := loyalty.RedeemPoints(tx, service.ClientID, serviceID, service.RedeemPoints)
+	if err != loyalty.ErrInsufficientPoints {
+		return err
+	}
+	points, _ := loyalty.Balance(tx, service.ClientID)
+	return apierror.Conflict("redeem_points", apierror.InsufficientPoints, "points", strconv.Itoa(points))
+}
+
+func validateLaundryItemsExistence(db *sqlx.DB, items []ServiceItemRequest) error {
 	for _, item := range items {
 		var exists bool
 		err := db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM laundry_items WHERE id = $1)", item.LaundryItemID)
@@ -263,7 +303,7 @@ func validateLaundryItemsExistence(db *sqlx.DB, items []struct {
 			return err
 		}
 		if !exists {
-			return fmt.Errorf("laundry item with ID %s does not exist", item.LaundryItemID)
+			return apierror.Field("items", apierror.ItemNotFound, "id", item.LaundryItemID.String())
 		}
 	}
 	return nil
@@ -276,26 +316,30 @@ func validateClientIDExists(db *sqlx.DB, clientID uuid.UUID) error {
 		return err
 	}
 	if !exists {
-		return fmt.Errorf("client with ID %s does not exist", clientID)
+		return apierror.Field("client_id", apierror.ClientNotFound, "id", clientID.String())
 	}
 	return nil
 }
 
-func validateEstimatedCompletionDate(date time.Time) error {
-	currentTime := time.Now()
-
-	// Check if the date is in the past
-	if date.Before(currentTime) {
-		return fmt.Errorf("A data estimada %s está no passado", date.Format("2006-01-02"))
+// deliveryAddress returns the address where the service is picked up and
+// delivered: addressID when it belongs to the client, or the client's
+// default address when addressID is nil
+func deliveryAddress(db *sqlx.DB, clientID uuid.UUID, addressID *uuid.UUID) (*uuid.UUID, error) {
+	if addressID == nil {
+		var defaultID *uuid.UUID
+		err := db.Get(&defaultID, "SELECT address_id FROM clients WHERE id = $1", clientID)
+		return defaultID, err
 	}
 
-	// Assuming a threshold of 30 days in the future for example
-	maxAllowedDate := currentTime.AddDate(0, 0, 30)
-	if date.After(maxAllowedDate) {
-		return fmt.Errorf("estimated completion date %s is beyond the acceptable threshold of 30 days", date.Format("2006-01-02"))
+	var exists bool
+	err := db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM client_addresses WHERE client_id = $1 AND address_id = $2)", clientID, *addressID)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil
+	if !exists {
+		return nil, apierror.Field("address_id", apierror.ClientAddressNotFound, "id", addressID.String())
+	}
+	return addressID, nil
 }
 
 // ClientDetails holds the relevant details fetched from the database.
@@ -310,11 +354,11 @@ func validateClientIsMensal(db *sqlx.DB, clientID uuid.UUID) error {
 
 	err := db.Get(&details, query, clientID)
 	if err != nil {
-		return fmt.Errorf("error checking client's details: %w", err)
+		return err
 	}
 
 	if !details.IsMensal {
-		return fmt.Errorf("o cliente não está cadastrado como mensal")
+		return apierror.Field("client_id", apierror.ClientNotMonthly)
 	}
 
 	// if details.MonthlyDate.Before(time.Now()) {
@@ -6,6 +6,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
 )
 
 // DeleteServiceHandler handles the deletion of a user by ID
@@ -15,21 +17,21 @@ func DeleteServiceHandler(db *sqlx.DB) http.HandlerFunc {
 		vars := mux.Vars(r)
 		serviceIDStr, ok := vars["id"]
 		if !ok {
-			http.Error(w, "Service ID not provided in URL", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.Required)))
 			return
 		}
 
 		// Parse serviceIDStr as UUID
 		serviceID, err := uuid.Parse(serviceIDStr)
 		if err != nil {
-			http.Error(w, "Invalid service ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
 			return
 		}
 
 		// Execute the delete query
 		_, err = db.Exec("DELETE FROM laundry_services WHERE id=$1", serviceID)
 		if err != nil {
-			http.Error(w, "Error deleting service from the database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
@@ -0,0 +1,123 @@
+package serviceshandlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/calendar"
+	"lavanderia/estimate"
+	"lavanderia/production"
+	"lavanderia/validation"
+)
+
+// EstimateRequest describes the service whose completion date is estimated
+type EstimateRequest struct {
+	Items    []ServiceItemRequest `json:"items" validate:"dive"`
+	Weight   float64              `json:"weight" validate:"required_if=IsWeight,positive"`
+	IsWeight bool                 `json:"is_weight"`
+	IsPiece  bool                 `json:"is_piece"`
+	Priority string               `json:"priority" validate:"oneof=normal|express|same_day"`
+}
+
+// maxBusinessDays is how many days the shop opens, at most, until the
+// completion date of a new service
+const maxBusinessDays = 30
+
+// EstimateServiceHandler handles the estimate of when a new service would
+// be ready, proposed to the attendant before the service is created
+func EstimateServiceHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request EstimateRequest
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(request)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		err = validateLaundryItemsExistence(db, request.Items)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		cal, err := calendar.Load(db)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		result, err := estimateCompletion(db, cal, request.Items, request.Weight, request.IsWeight, request.Priority)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		// Same-day services are due when the shop closes today
+		if request.Priority == production.SameDay {
+			settings, err := production.LoadSettings(db)
+			if err != nil {
+				apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+				return
+			}
+			result.EstimatedCompletionDate, err = sameDayDueDate(cal, settings, time.Now())
+			if err != nil {
+				apierror.Write(w, r, apierror.From(err))
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// estimateCompletion estimates the completion date of a service with the
+// given items, or weight, created now. Working time is counted on the
+// business calendar, and the date is kept within maxBusinessDays. Express
+// services skip the queue, so only their own turnaround counts.
+func estimateCompletion(db *sqlx.DB, cal *calendar.Calendar, items []ServiceItemRequest, weight float64, isWeight bool, priority string) (estimate.Result, error) {
+	order := estimate.Order{IsWeight: isWeight, Weight: weight}
+	for _, item := range items {
+		order.Pieces += item.ItemQuantity
+		order.Items = append(order.Items, item.LaundryItemID.String())
+	}
+
+	now := time.Now()
+	in, err := estimate.Load(db, order, now)
+	if err != nil {
+		return estimate.Result{}, err
+	}
+
+	if priority == production.Express {
+		in.Queue = nil
+	}
+
+	result := estimate.Estimate(in, cal)
+	if limit := cal.AddDays(now, maxBusinessDays); result.EstimatedCompletionDate.After(limit) {
+		result.EstimatedCompletionDate = limit
+	}
+	return result, nil
+}
+
+// validateEstimatedCompletionDate checks that the shop opens on the
+// completion date of a new service, at most maxBusinessDays from now
+func validateEstimatedCompletionDate(cal *calendar.Calendar, date time.Time) error {
+	if reason, closed := cal.Closed(date); closed {
+		return apierror.Field("estimated_completion_date", apierror.ShopClosed, "date", date.Format("2006-01-02"), "reason", reason)
+	}
+	if date.After(cal.AddDays(time.Now(), maxBusinessDays)) {
+		return apierror.Field("estimated_completion_date", apierror.BusinessDaysTooFar, "date", date.Format("2006-01-02"), "days", strconv.Itoa(maxBusinessDays))
+	}
+	return nil
+}
@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strconv"
-	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/production"
+	"lavanderia/queryspec"
 )
 
 // ServiceItem represents an item in a laundry service
@@ -18,173 +21,116 @@ type ServiceItem struct {
 	ItemQuantity int    `json:"item_quantity"`
 }
 
+// ServiceItems is the list of items of a service, aggregated as JSON by the database
+type ServiceItems []ServiceItem
+
+// Scan implements the sql.Scanner interface for the aggregated items
+func (items *ServiceItems) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, items)
+	case string:
+		return json.Unmarshal([]byte(v), items)
+	case nil:
+		*items = ServiceItems{}
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into service items", src)
+}
+
 // Service represents a laundry service including client and estimated completion date
 type Service struct {
-	ID                      string        `json:"id"`
-	Items                   []ServiceItem `json:"items"`
-	Status                  string        `json:"status"`
-	TotalPrice              float64       `json:"total_price"`
-	IsPaid                  bool          `json:"is_paid"`
-	ClientFirstName         string        `json:"client_first_name"`
-	ClientLastName          string        `json:"client_last_name"`
-	EstimatedCompletionDate string        `json:"estimated_completion_date"`
+	ID                      string       `json:"id" db:"id"`
+	TrackingCode            string       `json:"tracking_code" db:"tracking_code"`
+	Items                   ServiceItems `json:"items" db:"items"`
+	Status                  string       `json:"status" db:"status"`
+	Type                    string       `json:"type" db:"type"`
+	Priority                string       `json:"priority" db:"priority"`
+	Surcharge               float64      `json:"surcharge" db:"surcharge"`
+	CreatedAt               time.Time    `json:"created_at" db:"created_at"`
+	CompletedAt             *time.Time   `json:"completed_at" db:"completed_at"`
+	EstimatedCompletionDate *time.Time   `json:"estimated_completion_date" db:"estimated_completion_date"`
+	TotalPrice              float64      `json:"total_price" db:"total_price"`
+	Weight                  float64      `json:"weight" db:"weight"`
+	IsWeight                bool         `json:"is_weight" db:"is_weight"`
+	IsPiece                 bool         `json:"is_piece" db:"is_piece"`
+	IsMonthly               bool         `json:"is_monthly" db:"is_monthly"`
+	IsPaid                  bool         `json:"is_paid" db:"is_paid"`
+	ClientID                string       `json:"client_id" db:"client_id"`
+	ClientFirstName         string       `json:"client_first_name" db:"client_first_name"`
+	ClientLastName          string       `json:"client_last_name" db:"client_last_name"`
+	AddressID               *string      `json:"address_id" db:"address_id"`
 }
 
-// ListServicesHandler handles the listing of all services with pagination
-func ListServicesHandler(db *sqlx.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Parse pagination parameters from query string
-		pageStr := r.URL.Query().Get("page")
-		pageSizeStr := r.URL.Query().Get("pageSize")
-		searchTerm := r.URL.Query().Get("searchTerm")
-		status := r.URL.Query().Get("status")
-
-		// Default values for page and pageSize
-		page, pageSize := 1, 10
-
-		// Override defaults if parameters are provided
-		if pageStr != "" {
-			if p, err := strconv.Atoi(pageStr); err == nil {
-				page = p
-			}
-		}
-		if pageSizeStr != "" {
-			if ps, err := strconv.Atoi(pageSizeStr); err == nil {
-				pageSize = ps
-			}
-		}
-
-		// Calculate total number of records (consider adding a condition based on firstName and lastName)
-		var totalRecords int
-		countQuery := "SELECT COUNT(*) FROM laundry_services"
-		err := db.Get(&totalRecords, countQuery)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+// serviceStatuses are the statuses a laundry service goes through
+var serviceStatuses = []string{statusSeparated, "Lavando", "Secando", "Passando", statusFinished}
 
-		// Calculate total number of pages
-		totalPages := (totalRecords + pageSize - 1) / pageSize // Ensure rounding up
+// Service statuses with special meaning: new services start separated, and
+// finished services are ready to be picked up or delivered
+const (
+	statusSeparated = "Separado"
+	statusFinished  = "Finalizado"
+)
 
-		// Validate requested page number
-		if page > totalPages {
-			msg := fmt.Sprintf("Requested page exceeds total pages. Total pages available: %d", totalPages)
-			http.Error(w, msg, http.StatusBadRequest)
-			return
-		}
+// serviceSortFields are the fields services can be sorted by
+var serviceSortFields = map[string]string{
+	"created_at":                "ls.created_at",
+	"estimated_completion_date": "ls.estimated_completion_date",
+	"total_price":               "ls.total_price",
+	"status":                    "ls.status",
+	"client_name":               "cli.first_name",
+}
 
-		// Construct the WHERE clause and arguments dynamically
-		whereClause := ""
-		whereConditions := []string{}
-		args := []interface{}{pageSize, (page - 1) * pageSize}
-		if searchTerm != "" {
-			whereConditions = append(whereConditions, "to_tsvector('english', cli.first_name || ' ' || cli.last_name) @@ plainto_tsquery('english', $3)")
-			args = append(args, searchTerm)
-		}
+// serviceNullSorts stand in for the NULLs of the nullable sort fields, so
+// services without a date sort last and cursors move past them
+var serviceNullSorts = map[string]string{
+	"estimated_completion_date": "'infinity'::timestamp",
+	"total_price":               "0",
+}
 
-		if status != "" {
-			if searchTerm != "" {
-				// If searchTerm is also provided, status will use the next placeholder number
-				whereConditions = append(whereConditions, fmt.Sprintf("ls.status = $%d", len(args)+1))
-			} else {
-				// If only status is provided, it will use $3
-				whereConditions = append(whereConditions, "ls.status = $3")
-			}
-			args = append(args, status)
-		}
+// ListServicesOptions are the pagination, sort and filter parameters accepted by ListServicesHandler
+var ListServicesOptions = queryspec.Options{
+	SortFields:  serviceSortFields,
+	NullSorts:   serviceNullSorts,
+	DefaultSort: "-created_at",
+	TieBreaker:  "ls.id",
+	Filters: []queryspec.Filter{
+		{Param: "status", Column: "ls.status", Type: queryspec.Equals, Allowed: serviceStatuses},
+		{Param: "priority", Column: "ls.priority", Type: queryspec.Equals, Allowed: production.Priorities},
+		{Param: "is_paid", Column: "ls.is_paid", Type: queryspec.Bool},
+		{Param: "created_at", Column: "ls.created_at", Type: queryspec.DateRange},
+		{Param: "client_id", Column: "ls.client_id", Type: queryspec.UUID},
+		{Param: "search", Column: "to_tsvector('english', cli.first_name || ' ' || cli.last_name)", Type: queryspec.Search},
+	},
+}
 
-		if len(whereConditions) > 0 {
-			whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
+// ListServicesHandler handles the listing of all services with pagination
+func ListServicesHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		spec, err := queryspec.Parse(r.URL.Query(), ListServicesOptions)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
 		}
 
-		// Construct the final query
-		query := fmt.Sprintf(`
-            WITH TopServices AS (
-                SELECT ls.id
-                FROM laundry_services ls
-                JOIN clients cli ON ls.client_id = cli.id
-                %s
-                ORDER BY ls.created_at DESC
-                LIMIT $1 OFFSET $2
-            )
-            SELECT ls.id, li.id as item_id, li.name, lis.item_quantity, lis.observation, ls.status, ls.is_paid, ls.total_price,
-                   cli.first_name AS client_first_name, cli.last_name AS client_last_name, ls.estimated_completion_date
-            FROM laundry_items_services lis
-            JOIN TopServices ON lis.laundry_service_id = TopServices.id
-            LEFT JOIN laundry_services ls ON lis.laundry_service_id = ls.id
-            LEFT JOIN laundry_items li ON lis.laundry_item_id = li.id
-            LEFT JOIN clients cli ON ls.client_id = cli.id
-            ORDER BY ls.created_at DESC
-        `, whereClause)
-
-		// Execute the SQL query with dynamic arguments
-		rows, err := db.Queryx(query, args...)
+		result, pagination, err := queryServices(db, spec, nil)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
-		defer rows.Close()
-
-		// Map to store services and their items
-		serviceMap := make(map[string]*Service)
-
-		// Slice to keep track of the order of service IDs
-		var orderedServiceIDs []string
-
-		// Iterate through the rows and build the result
-		for rows.Next() {
-			var serviceID string
-			var item ServiceItem
-			var service Service
-
-			err := rows.Scan(
-				&serviceID,
-				&item.ID,
-				&item.Name,
-				&item.ItemQuantity,
-				&item.Observation,
-				&service.Status,
-				&service.IsPaid,
-				&service.TotalPrice,
-				&service.ClientFirstName,
-				&service.ClientLastName,
-				&service.EstimatedCompletionDate)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			// Check if the service already exists in the map
-			if existingService, ok := serviceMap[serviceID]; ok {
-				// Service exists, append the item to its items
-				existingService.Items = append(existingService.Items, item)
-			} else {
-				// Service doesn't exist, create a new service with the item
-				service.ID = serviceID
-				service.Items = []ServiceItem{item}
-				serviceMap[serviceID] = &service
-				// Record the order of this new service ID
-				orderedServiceIDs = append(orderedServiceIDs, serviceID)
-			}
-		}
 
-		// Use the orderedServiceIDs slice to extract services in the correct order
-		var result []Service
-		for _, serviceID := range orderedServiceIDs {
-			if service, ok := serviceMap[serviceID]; ok {
-				result = append(result, *service)
-			}
+		searchTerm := r.URL.Query().Get("search")
+		if searchTerm == "" {
+			searchTerm = r.URL.Query().Get("searchTerm")
 		}
 
+		response := pagination.Response("services", result)
+		response["search_term"] = searchTerm
+
 		// Convert the result to JSON
-		responseJSON, err := json.Marshal(map[string]interface{}{
-			"services":    result,
-			"page":        page,
-			"total_pages": totalPages,
-			"search_term": searchTerm,
-		})
+		responseJSON, err := json.Marshal(response)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.InternalError))
 			return
 		}
 
@@ -194,3 +140,80 @@ func ListServicesHandler(db *sqlx.DB) http.HandlerFunc {
 		w.Write(responseJSON)
 	}
 }
+
+// queryServices counts and fetches the page of services described by spec.
+// base adds conditions that are always applied, such as the client of the route.
+func queryServices(db *sqlx.DB, spec *queryspec.Spec, base func(args *queryspec.Args) []string) ([]Service, queryspec.Pagination, error) {
+	if base == nil {
+		base = func(args *queryspec.Args) []string { return nil }
+	}
+
+	// Calculate total number of records matching the same filters as the page
+	var countArgs queryspec.Args
+	var totalRecords int
+	countQuery := "SELECT COUNT(*) FROM laundry_services ls JOIN clients cli ON ls.client_id = cli.id " + spec.CountWhere(&countArgs, base(&countArgs)...)
+	err := db.Get(&totalRecords, countQuery, countArgs.Values()...)
+	if err != nil {
+		return nil, queryspec.Pagination{}, err
+	}
+
+	// Construct the final query, driven by laundry_services so services
+	// without items are listed too, with their items aggregated as JSON
+	var args queryspec.Args
+	query := fmt.Sprintf(`
+            SELECT ls.id,
+                   ls.tracking_code,
+                   ls.status,
+                   CASE WHEN ls.is_piece THEN 'piece' WHEN ls.is_weight THEN 'weight' ELSE '' END AS type,
+                   ls.priority,
+                   ls.surcharge,
+                   ls.created_at,
+                   ls.completed_at,
+                   ls.estimated_completion_date,
+                   COALESCE(ls.total_price, 0) AS total_price,
+                   COALESCE(ls.weight, 0) AS weight,
+                   COALESCE(ls.is_weight, FALSE) AS is_weight,
+                   COALESCE(ls.is_piece, FALSE) AS is_piece,
+                   COALESCE(cli.is_mensal, FALSE) AS is_monthly,
+                   COALESCE(ls.is_paid, FALSE) AS is_paid,
+                   ls.client_id,
+                   cli.first_name AS client_first_name,
+                   cli.last_name AS client_last_name,
+                   COALESCE(ls.address_id, cli.address_id) AS address_id,
+                   COALESCE((
+                       SELECT json_agg(json_build_object(
+                           'id', li.id,
+                           'name', li.name,
+                           'observation', COALESCE(lis.observation, ''),
+                           'item_quantity', lis.item_quantity
+                       ) ORDER BY li.name)
+                       FROM laundry_items_services lis
+                       JOIN laundry_items li ON lis.laundry_item_id = li.id
+                       WHERE lis.laundry_service_id = ls.id
+                   ), '[]') AS items,
+                   %s AS cursor
+            FROM laundry_services ls
+            JOIN clients cli ON ls.client_id = cli.id
+            %s
+            %s
+            %s
+        `, spec.CursorColumns(), spec.Where(&args, base(&args)...), spec.OrderBy(), spec.Limit(&args))
+
+	var rows []struct {
+		Service
+		Cursor string `db:"cursor"`
+	}
+	err = db.Select(&rows, query, args.Values()...)
+	if err != nil {
+		return nil, queryspec.Pagination{}, err
+	}
+
+	result := make([]Service, 0, len(rows))
+	var last string
+	for _, row := range rows[:spec.Trim(len(rows))] {
+		result = append(result, row.Service)
+		last = row.Cursor
+	}
+
+	return result, spec.Paginate(totalRecords, len(rows), last), nil
+}
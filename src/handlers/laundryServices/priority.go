@@ -0,0 +1,116 @@
+package serviceshandlers
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/calendar"
+	"lavanderia/entities"
+	"lavanderia/production"
+)
+
+// scheduleService sets the completion date of a new service for its
+// priority and checks that the day has room for it. Same-day services are
+// due when the shop closes today. Without a date, the estimate is proposed,
+// and a normal service that doesn't fit is moved to the next day with room.
+// Express and same-day services that don't fit are refused; normal ones
+// with a date are accepted with a warning.
+func scheduleService(db *sqlx.DB, cal *calendar.Calendar, settings entities.ProductionSettingsEntity, service *LaundryService) ([]string, error) {
+	now := time.Now()
+	proposed := service.EstimatedCompletionDate.IsZero()
+
+	switch {
+	case service.Priority == production.SameDay:
+		closes, err := sameDayDueDate(cal, settings, now)
+		if err != nil {
+			return nil, err
+		}
+		if proposed {
+			service.EstimatedCompletionDate = closes
+		} else if service.EstimatedCompletionDate.Format("2006-01-02") != now.Format("2006-01-02") {
+			return nil, apierror.Field("estimated_completion_date", apierror.SameDayNotToday)
+		}
+	case proposed:
+		proposal, err := estimateCompletion(db, cal, service.Items, service.Weight, service.IsWeight, service.Priority)
+		if err != nil {
+			return nil, err
+		}
+		service.EstimatedCompletionDate = proposal.EstimatedCompletionDate
+	default:
+		err := validateEstimatedCompletionDate(cal, service.EstimatedCompletionDate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if settings.MaxKgPerDay == 0 && settings.MaxPiecesPerDay == 0 {
+		return nil, nil
+	}
+
+	kg, pieces := orderSize(service)
+	load, err := production.DayLoad(db, service.EstimatedCompletionDate, settings)
+	if err != nil {
+		return nil, err
+	}
+	if load.Fits(kg, pieces) {
+		return nil, nil
+	}
+	if production.Urgent(service.Priority) {
+		return nil, apierror.Conflict("estimated_completion_date", apierror.CapacityExceeded, "date", load.Date)
+	}
+
+	if proposed {
+		limit := cal.AddDays(now, maxBusinessDays)
+		for day := cal.AddDays(service.EstimatedCompletionDate, 1); !day.After(limit); day = cal.AddDays(day, 1) {
+			load, err := production.DayLoad(db, day, settings)
+			if err != nil {
+				return nil, err
+			}
+			if load.Fits(kg, pieces) {
+				if closes, ok := cal.Closes(day); ok && day.After(closes) {
+					day = closes
+				}
+				service.EstimatedCompletionDate = day
+				return nil, nil
+			}
+		}
+	}
+	return []string{string(apierror.CapacityExceeded)}, nil
+}
+
+// sameDayDueDate returns when a same-day service created at now is due:
+// when the shop closes today, as long as it is taken before the cutoff
+func sameDayDueDate(cal *calendar.Calendar, settings entities.ProductionSettingsEntity, now time.Time) (time.Time, error) {
+	closes, open := cal.Closes(now)
+	if !open {
+		reason, _ := cal.Closed(now)
+		return time.Time{}, apierror.Field("priority", apierror.ShopClosed, "date", now.Format("2006-01-02"), "reason", reason)
+	}
+
+	cutoff, err := time.Parse("15:04", settings.SameDayCutoff)
+	if err != nil {
+		return time.Time{}, err
+	}
+	limit := time.Date(now.Year(), now.Month(), now.Day(), cutoff.Hour(), cutoff.Minute(), 0, 0, now.Location())
+	if now.After(limit) || !now.Before(closes) {
+		return time.Time{}, apierror.Field("priority", apierror.SameDayCutoffPassed, "cutoff", settings.SameDayCutoff)
+	}
+	return closes, nil
+}
+
+// orderSize is what a service takes of the daily capacity: its kilos when
+// it is charged by weight, or its pieces when it is charged by piece
+func orderSize(service *LaundryService) (float64, int) {
+	if service.IsWeight {
+		return service.Weight, 0
+	}
+	pieces := 0
+	if service.IsPiece {
+		for _, item := range service.Items {
+			pieces += item.ItemQuantity
+		}
+	}
+	return 0, pieces
+}
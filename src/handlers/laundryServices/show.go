@@ -7,13 +7,19 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	middleware "lavanderia/middlewares"
 )
 
 // ServiceDetail represents a laundry service
 type ServiceDetail struct {
 	ID                      string        `json:"id"`
+	TrackingCode            string        `json:"tracking_code"`
 	Items                   []ServiceItem `json:"items"`
 	Status                  string        `json:"status"`
+	Priority                string        `json:"priority"`
+	Surcharge               float64       `json:"surcharge"`
 	TotalPrice              float64       `json:"total_price"`
 	IsMonthly               bool          `json:"is_monthly"`
 	IsPaid                  bool          `json:"is_paid"`
@@ -41,31 +47,20 @@ func ShowServiceHandler(db *sqlx.DB) http.HandlerFunc {
 		vars := mux.Vars(r)
 		serviceIDStr, ok := vars["id"]
 		if !ok {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "id", Message: "Service ID not provided in URL", Status: http.StatusBadRequest},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.Required)))
 			return
 		}
 
 		serviceID, err := uuid.Parse(serviceIDStr)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "id", Message: "Invalid service ID", Status: http.StatusBadRequest},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
 			return
 		}
 
-		err = validateServiceIDExists(db, serviceID)
+		// Clients only see their own services
+		err = middleware.AuthorizeService(db, r, serviceID)
 		if err != nil {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "id", Message: err.Error(), Status: http.StatusNotFound},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
@@ -84,17 +79,17 @@ func ShowServiceHandler(db *sqlx.DB) http.HandlerFunc {
 			ls.total_price, 
 			cli.id, 
 			cli.first_name, 
-			cli.last_name, ls.estimated_completion_date, ls.completed_at, ad.address_id, ad.street, ad.city, ad.state, ad.postal_code, ad.number, cli.phone, cli.is_mensal
+			cli.last_name, ls.estimated_completion_date, ls.completed_at, ad.address_id, ad.street, ad.city, ad.state, ad.postal_code, ad.number, cli.phone, cli.is_mensal, ls.tracking_code, ls.priority, ls.surcharge
 		FROM laundry_items_services lis
 			LEFT JOIN laundry_services ls ON lis.laundry_service_id = ls.id
 			LEFT JOIN laundry_items li ON lis.laundry_item_id = li.id
 			LEFT JOIN clients cli ON ls.client_id = cli.id
-			LEFT JOIN address ad ON cli.address_id = ad.address_id
+			LEFT JOIN address ad ON ad.address_id = COALESCE(ls.address_id, cli.address_id)
 		WHERE ls.id = $1
 		`, serviceID)
 
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 		defer rows.Close()
@@ -132,9 +127,12 @@ func ShowServiceHandler(db *sqlx.DB) http.HandlerFunc {
 				&service.Number,
 				&service.Phone,
 				&service.IsMonthly,
+				&service.TrackingCode,
+				&service.Priority,
+				&service.Surcharge,
 			)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 				return
 			}
 
@@ -145,7 +143,7 @@ func ShowServiceHandler(db *sqlx.DB) http.HandlerFunc {
 		// Convert the service to JSON
 		responseJSON, err := json.Marshal(map[string]*ServiceDetail{"service": &service})
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.InternalError))
 			return
 		}
 
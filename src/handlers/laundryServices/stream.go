@@ -0,0 +1,113 @@
+package serviceshandlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lavanderia/apierror"
+	middleware "lavanderia/middlewares"
+	"lavanderia/stream"
+)
+
+// streamHeartbeat is how often an idle stream sends a comment, so proxies
+// don't close the connection
+const streamHeartbeat = 25 * time.Second
+
+// StreamServicesHandler handles the Server-Sent Events stream of service
+// changes shown on the order board. Each change is sent as an event named
+// after its type with the service as JSON data. Clients only receive their
+// own services. Missed changes are not replayed: boards reload the list
+// when they reconnect.
+func StreamServicesHandler(broker *stream.Broker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := streamFilter(r)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			apierror.Write(w, r, apierror.Internal(apierror.InternalError))
+			return
+		}
+
+		messages, cancel := broker.Subscribe(filter)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "retry: 3000\n\n")
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(message)
+				if err != nil {
+					return
+				}
+				fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", message.EventID, message.Type, data)
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// streamFilter reads the status and client_id parameters. A client can only
+// follow their own services, whatever client_id they send.
+func streamFilter(r *http.Request) (stream.Filter, error) {
+	var filter stream.Filter
+	values := r.URL.Query()
+
+	if status := values.Get("status"); status != "" {
+		valid := false
+		for _, s := range serviceStatuses {
+			valid = valid || s == status
+		}
+		if !valid {
+			return filter, apierror.Field("status", apierror.OneOf, "values", strings.Join(serviceStatuses, ", "))
+		}
+		filter.Status = status
+	}
+
+	if clientID := values.Get("client_id"); clientID != "" {
+		id, err := uuid.Parse(clientID)
+		if err != nil {
+			return filter, apierror.Field("client_id", apierror.InvalidUUID)
+		}
+		filter.ClientID = id
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		return filter, apierror.New(http.StatusUnauthorized, apierror.Unauthorized)
+	}
+	if user.Role == "Client" {
+		id, err := uuid.Parse(user.ID)
+		if err != nil {
+			return filter, apierror.New(http.StatusUnauthorized, apierror.InvalidToken)
+		}
+		filter.ClientID = id
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,65 @@
+package serviceshandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/tracking"
+)
+
+// OrderStatus is what anyone holding the receipt can see about an order.
+// It carries nothing that identifies the client.
+type OrderStatus struct {
+	TrackingCode            string     `json:"tracking_code" db:"tracking_code"`
+	Status                  string     `json:"status" db:"status"`
+	Ready                   bool       `json:"ready"`
+	EstimatedCompletionDate *time.Time `json:"estimated_completion_date" db:"estimated_completion_date"`
+	CompletedAt             *time.Time `json:"completed_at" db:"completed_at"`
+	AmountDue               float64    `json:"amount_due" db:"amount_due"`
+}
+
+// TrackServiceHandler handles the public lookup of an order by the tracking
+// code printed on its receipt. Malformed and unknown codes get the same
+// answer, so the response doesn't help guessing codes.
+func TrackServiceHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		typed := mux.Vars(r)["code"]
+		code, ok := tracking.Normalize(typed)
+		if !ok {
+			apierror.Write(w, r, apierror.NotFound("code", apierror.TrackingCodeNotFound, "code", typed))
+			return
+		}
+
+		var order OrderStatus
+		err := db.Get(&order, `
+			SELECT
+				tracking_code,
+				status,
+				estimated_completion_date,
+				completed_at,
+				CASE WHEN COALESCE(is_paid, false) THEN 0 ELSE COALESCE(total_price, 0) END AS amount_due
+			FROM laundry_services
+			WHERE tracking_code = $1`, code)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("code", apierror.TrackingCodeNotFound, "code", typed))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		order.TrackingCode = tracking.Format(order.TrackingCode)
+		order.Ready = order.Status == statusFinished
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(order)
+	}
+}
@@ -1,8 +1,9 @@
 package serviceshandlers
 
 import (
+	"database/sql"
 	"encoding/json"
-	"fmt"
+	"math"
 	"net/http"
 	"time"
 
@@ -10,7 +11,13 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
 
+	"lavanderia/apierror"
+	"lavanderia/calendar"
 	"lavanderia/entities"
+	"lavanderia/events"
+	"lavanderia/notify"
+	"lavanderia/production"
+	"lavanderia/validation"
 )
 
 // UpdateServiceHandler handles the update of service information
@@ -19,155 +26,167 @@ func UpdateServiceHandler(db *sqlx.DB) http.HandlerFunc {
 		vars := mux.Vars(r)
 		serviceIDStr, ok := vars["id"]
 		if !ok {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "id", Message: "Service ID not provided in URL", Status: http.StatusBadRequest},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.Required)))
 			return
 		}
 
 		serviceID, err := uuid.Parse(serviceIDStr)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "id", Message: "Invalid service ID", Status: http.StatusBadRequest},
-				"error":   "Validation failed",
-			})
-			return
-		}
-
-		err = validateServiceIDExists(db, serviceID)
-		if err != nil {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "id", Message: err.Error(), Status: http.StatusNotFound},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
 			return
 		}
 
 		var updatedService entities.LaundryServicesEntity
 		err = json.NewDecoder(r.Body).Decode(&updatedService)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Message: "Invalid request payload", Status: http.StatusBadRequest},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
 			return
 		}
 
-		// Retrieve the CreatedAt date for the service
-		var createdAt time.Time
-		err = db.Get(&createdAt, "SELECT created_at FROM laundry_services WHERE id=$1", serviceID)
+		err = validation.Struct(updatedService)
 		if err != nil {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Message: "Service not found", Status: http.StatusNotFound},
-				"error":   "Service retrieval failed",
-			})
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
-		// Validate CompletedAt date
-		if updatedService.CompletedAt != nil && updatedService.CompletedAt.Before(createdAt) {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "completed_at", Message: "'CompletedAt' should not be before the 'CreatedAt' date", Status: http.StatusBadRequest},
-				"error":   "Validation failed",
-			})
+		err = validateServiceIDExists(db, serviceID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
-		// Validate EstimatedCompletionDate
-		if !updatedService.EstimatedCompletionDate.IsZero() && updatedService.EstimatedCompletionDate.Before(createdAt) {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "estimated_completion_date", Message: "'EstimatedCompletionDate' should not be before the 'CreatedAt' date", Status: http.StatusBadRequest},
-				"error":   "Validation failed",
-			})
+		// Start a transaction
+		tx, err := db.Beginx()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			tx.Commit()
+		}()
 
-		// Verify weight when is_weight is true
-		if updatedService.IsWeight && updatedService.Weight <= 0 {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "weight", Message: "When 'IsWeight' is true, 'Weight' should be positive number", Status: http.StatusBadRequest},
-				"error":   "Validation failed",
-			})
+		// Retrieve the CreatedAt date and the current state of the service,
+		// locked so concurrent updates publish each change once
+		var current struct {
+			CreatedAt               time.Time  `db:"created_at"`
+			EstimatedCompletionDate *time.Time `db:"estimated_completion_date"`
+			Status                  string     `db:"status"`
+			IsPaid                  bool       `db:"is_paid"`
+			TotalPrice              float64    `db:"total_price"`
+			Priority                string     `db:"priority"`
+			Surcharge               float64    `db:"surcharge"`
+			LoyaltyDiscount         float64    `db:"loyalty_discount"`
+			IsWeight                bool       `db:"is_weight"`
+			IsPiece                 bool       `db:"is_piece"`
+			Weight                  float64    `db:"weight"`
+		}
+		err = tx.Get(&current, `SELECT created_at, estimated_completion_date, status, COALESCE(is_paid, false) AS is_paid, COALESCE(total_price, 0) AS total_price, priority, surcharge, loyalty_discount,
+				COALESCE(is_weight, false) AS is_weight, COALESCE(is_piece, false) AS is_piece, COALESCE(weight, 0) AS weight
+			FROM laundry_services WHERE id=$1 FOR UPDATE`, serviceID)
+		if err == sql.ErrNoRows {
+			err = apierror.NotFound("id", apierror.ServiceNotFound, "id", serviceID.String())
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
-		// Define valid statuses
-		validStatuses := map[string]bool{
-			"Separado":   true,
-			"Lavando":    true,
-			"Secando":    true,
-			"Passando":   true,
-			"Finalizado": true,
+		// The payment of a paid service is in the wallet or the cash drawer,
+		// so it can't be undone nor its price changed here
+		if current.IsPaid && (!updatedService.IsPaid || repriced(updatedService, current.IsWeight, current.IsPiece, current.Weight, current.Priority, current.TotalPrice)) {
+			err = apierror.Conflict("is_paid", apierror.PaidServiceChanged, "id", serviceID.String())
+			apierror.Write(w, r, apierror.From(err))
+			return
 		}
 
-		// Validate the provided status
-		if _, ok := validStatuses[updatedService.Status]; !ok {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Field: "status", Message: "Invalid status. Must be one of: Separado, Lavando, Secando, Passando, Finalizado", Status: http.StatusBadRequest},
-				"error":   "Validation failed",
-			})
+		// Validate CompletedAt date
+		if updatedService.CompletedAt != nil && updatedService.CompletedAt.Before(current.CreatedAt) {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("completed_at", apierror.BeforeCreatedAt)))
 			return
 		}
 
-		// Start a transaction
-		tx, err := db.Beginx()
-		if err != nil {
-			http.Error(w, "Error starting database transaction", http.StatusInternalServerError)
+		// Validate EstimatedCompletionDate
+		if !updatedService.EstimatedCompletionDate.IsZero() && updatedService.EstimatedCompletionDate.Before(current.CreatedAt) {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("estimated_completion_date", apierror.BeforeCreatedAt)))
 			return
 		}
-		defer func() {
+
+		// A new completion date must fall on a day the shop opens
+		if !updatedService.EstimatedCompletionDate.IsZero() && (current.EstimatedCompletionDate == nil || !updatedService.EstimatedCompletionDate.Equal(*current.EstimatedCompletionDate)) {
+			cal, err := calendar.Load(db)
 			if err != nil {
-				tx.Rollback()
+				apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 				return
 			}
-			tx.Commit()
-		}()
-
-		var totalPrice float64
+			if reason, closed := cal.Closed(updatedService.EstimatedCompletionDate); closed {
+				apierror.Write(w, r, apierror.Validation(apierror.Field("estimated_completion_date", apierror.ShopClosed, "date", updatedService.EstimatedCompletionDate.Format("2006-01-02"), "reason", reason)))
+				return
+			}
+		}
 
-		// Check if 'is_piece' has changed to 'is_weight'
-		if updatedService.IsWeight && !updatedService.IsPiece {
-			totalPrice = updatedService.Weight * 20
+		// Keep the priority and its surcharge when the priority isn't sent
+		// or doesn't change; a new priority is charged at the current rate
+		if updatedService.Priority == "" || updatedService.Priority == current.Priority {
+			updatedService.Priority = current.Priority
+			updatedService.Surcharge = current.Surcharge
 		} else {
-			totalPrice, err = calculateUpdatedTotalPrice(tx, serviceID.String())
-
+			settings, err := production.LoadSettings(db)
 			if err != nil {
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"details": ValidationError{Field: "total_price", Message: "When 'IsWeight' is true, 'Weight' should be positive number", Status: http.StatusBadRequest},
-					"error":   "Validation failed",
-				})
+				apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 				return
 			}
+			updatedService.Surcharge = production.Surcharge(settings, updatedService.Priority)
 		}
 
-		if updatedService.IsMonthly {
-			err = validateClientIsMensal(db, updatedService.ClientID)
+		// Validate the delivery address, keeping the current one when it isn't sent
+		if updatedService.AddressID != nil {
+			updatedService.AddressID, err = deliveryAddress(db, updatedService.ClientID, updatedService.AddressID)
 			if err != nil {
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"details": ValidationError{Field: "client_id", Message: err.Error(), Status: http.StatusBadRequest},
-					"error":   "Validation failed",
-				})
+				apierror.Write(w, r, apierror.From(err))
 				return
 			}
+		}
+
+		// A paid service keeps the price it was paid at
+		totalPrice := current.TotalPrice
+		if !current.IsPaid {
+			// Check if 'is_piece' has changed to 'is_weight'
+			if updatedService.IsWeight && !updatedService.IsPiece {
+				totalPrice = updatedService.Weight * 20
+			} else {
+				totalPrice, err = calculateUpdatedTotalPrice(tx, serviceID.String())
+
+				if err != nil {
+					apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+					return
+				}
+			}
+
+			if updatedService.IsMonthly {
+				err = validateClientIsMensal(db, updatedService.ClientID)
+				if err != nil {
+					apierror.Write(w, r, apierror.From(err))
+					return
+				}
 
-			totalPrice = 0
+				totalPrice = 0
+			}
+			totalPrice = production.WithSurcharge(totalPrice, updatedService.Surcharge)
+
+			// The points redeemed when the service was created stay redeemed
+			totalPrice = math.Max(math.Round((totalPrice-current.LoyaltyDiscount)*100)/100, 0)
 		}
 
 		// Update service information in the database
-		_, err = db.Exec(
-			"UPDATE laundry_services SET status=$1, is_paid=$2, completed_at=$3, estimated_completion_date=$4, is_weight=$5, is_piece=$6, total_price=$7, weight=$8, client_id=$9 WHERE id=$10",
+		_, err = tx.Exec(
+			`UPDATE laundry_services SET status=$1, is_paid=$2, completed_at=$3, estimated_completion_date=$4, is_weight=$5, is_piece=$6, total_price=$7, weight=$8, client_id=$9,
+				address_id=COALESCE($10, CASE WHEN client_id = $9 THEN address_id END, (SELECT address_id FROM clients WHERE id = $9)),
+				priority=$12, surcharge=$13, paid_at=COALESCE(paid_at, CASE WHEN $2 THEN CURRENT_TIMESTAMP END)
+			WHERE id=$11`,
 			updatedService.Status,
 			updatedService.IsPaid,
 			updatedService.CompletedAt,
@@ -177,14 +196,30 @@ func UpdateServiceHandler(db *sqlx.DB) http.HandlerFunc {
 			totalPrice,
 			updatedService.Weight,
 			updatedService.ClientID,
-			serviceID)
+			updatedService.AddressID,
+			serviceID,
+			updatedService.Priority,
+			updatedService.Surcharge)
+
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		if updatedService.Status == statusFinished && current.Status != statusFinished {
+			err = notify.Enqueue(tx, updatedService.ClientID, notify.ServiceReady, notify.Data{
+				ServiceID: serviceID.String(),
+				Total:     totalPrice,
+			}, serviceID.String())
+			if err != nil {
+				apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+				return
+			}
+		}
 
+		err = publishServiceChanges(tx, serviceID, updatedService, current.Status, current.IsPaid, current.TotalPrice, totalPrice)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"details": ValidationError{Message: "Error updating service in the database", Status: http.StatusInternalServerError},
-				"error":   "Validation failed",
-			})
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
@@ -193,6 +228,49 @@ func UpdateServiceHandler(db *sqlx.DB) http.HandlerFunc {
 	}
 }
 
+// repriced reports whether the update changes what the price of the
+// service is computed from
+func repriced(updated entities.LaundryServicesEntity, isWeight, isPiece bool, weight float64, priority string, price float64) bool {
+	return updated.IsWeight != isWeight || updated.IsPiece != isPiece || math.Abs(updated.Weight-weight) >= 0.005 ||
+		(updated.Priority != "" && updated.Priority != priority) || (updated.IsMonthly && price != 0)
+}
+
+// publishServiceChanges publishes an event for each change of status,
+// payment and price made by an update
+func publishServiceChanges(tx *sqlx.Tx, serviceID uuid.UUID, updated entities.LaundryServicesEntity, status string, isPaid bool, price, totalPrice float64) error {
+	if updated.Status != status {
+		err := events.Publish(tx, events.ServiceStatusChanged, serviceID, events.StatusChange{
+			ServiceID: serviceID,
+			ClientID:  updated.ClientID,
+			From:      status,
+			To:        updated.Status,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if totalPrice != price {
+		err := events.Publish(tx, events.ServiceRepriced, serviceID, events.Reprice{
+			ServiceID:  serviceID,
+			From:       price,
+			TotalPrice: totalPrice,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if updated.IsPaid && !isPaid {
+		return events.Publish(tx, events.ServicePaid, serviceID, events.Payment{
+			ServiceID:  serviceID,
+			ClientID:   updated.ClientID,
+			TotalPrice: totalPrice,
+		})
+	}
+	return nil
+}
+
 func calculateUpdatedTotalPrice(tx *sqlx.Tx, serviceID string) (float64, error) {
 
 	var items []entities.LaundryItemsServicesEntity
@@ -227,7 +305,7 @@ func validateServiceIDExists(db *sqlx.DB, serviceID uuid.UUID) error {
 		return err
 	}
 	if !exists {
-		return fmt.Errorf("Service with ID %s does not exist", serviceID)
+		return apierror.NotFound("id", apierror.ServiceNotFound, "id", serviceID.String())
 	}
 	return nil
 }
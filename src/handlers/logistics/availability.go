@@ -0,0 +1,61 @@
+package logisticshandlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/entities"
+	"lavanderia/validation"
+)
+
+// DateQuery is the query string of endpoints that work on a single day
+type DateQuery struct {
+	Date string `json:"date" validate:"required,date"`
+}
+
+// SlotAvailability is an active time slot with its bookings on a day
+type SlotAvailability struct {
+	entities.TimeSlotEntity
+	Booked    int `json:"booked" db:"booked"`
+	Available int `json:"available" db:"available"`
+}
+
+// SlotAvailabilityHandler handles the listing of the active time slots of a
+// day with how many bookings each one can still take
+func SlotAvailabilityHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := DateQuery{Date: r.URL.Query().Get("date")}
+		if err := validation.Struct(query); err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		slots := []SlotAvailability{}
+		err := db.Select(&slots, `
+			SELECT
+				ts.id,
+				to_char(ts.start_time, 'HH24:MI') AS start_time,
+				to_char(ts.end_time, 'HH24:MI') AS end_time,
+				ts.capacity,
+				ts.is_active,
+				COUNT(sb.id) AS booked,
+				GREATEST(ts.capacity - COUNT(sb.id), 0) AS available
+			FROM time_slots ts
+			LEFT JOIN service_bookings sb
+				ON sb.time_slot_id = ts.id AND sb.date = $1 AND sb.status <> $2
+			WHERE ts.is_active
+			GROUP BY ts.id
+			ORDER BY ts.start_time, ts.end_time`,
+			query.Date, StatusFailed)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(slots)
+	}
+}
@@ -0,0 +1,290 @@
+package logisticshandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/entities"
+	"lavanderia/validation"
+)
+
+// Booking types
+const (
+	Pickup   = "pickup"
+	Delivery = "delivery"
+)
+
+// Booking statuses
+const (
+	StatusScheduled = "scheduled"
+	StatusEnRoute   = "en_route"
+	StatusDone      = "done"
+	StatusFailed    = "failed"
+)
+
+// transitions lists the statuses a booking can move to from each status.
+// A failed booking is scheduled again through the booking endpoints.
+var transitions = map[string][]string{
+	StatusScheduled: {StatusEnRoute, StatusFailed},
+	StatusEnRoute:   {StatusDone, StatusFailed},
+}
+
+const bookingColumns = `id, laundry_service_id, type, to_char(date, 'YYYY-MM-DD') AS date, time_slot_id, driver_id, status, COALESCE(notes, '') AS notes, created_at, updated_at`
+
+// BookingRequest is the request body to schedule a pickup or delivery
+type BookingRequest struct {
+	Date       string     `json:"date" validate:"required,date,future"`
+	TimeSlotID uuid.UUID  `json:"time_slot_id" validate:"required"`
+	DriverID   *uuid.UUID `json:"driver_id"`
+	Notes      string     `json:"notes" validate:"max=500"`
+}
+
+// BookingUpdate is the request body to follow up a booking
+type BookingUpdate struct {
+	Status   string     `json:"status" validate:"oneof=scheduled|en_route|done|failed"`
+	DriverID *uuid.UUID `json:"driver_id"`
+	Notes    *string    `json:"notes" validate:"max=500"`
+}
+
+// BookPickupHandler handles scheduling the pickup of a service at the client's address
+func BookPickupHandler(db *sqlx.DB) http.HandlerFunc {
+	return bookHandler(db, Pickup)
+}
+
+// BookDeliveryHandler handles scheduling the delivery of a service to the client's address
+func BookDeliveryHandler(db *sqlx.DB) http.HandlerFunc {
+	return bookHandler(db, Delivery)
+}
+
+// bookHandler schedules a booking of bookingType. Posting again reschedules
+// a booking that is still scheduled or has failed.
+func bookHandler(db *sqlx.DB, bookingType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serviceID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var req BookingRequest
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			tx.Commit()
+		}()
+
+		booking, created, err := book(tx, serviceID, bookingType, req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(booking)
+	}
+}
+
+// book creates or reschedules the booking of a service, locking the time
+// slot so concurrent bookings can't exceed its capacity
+func book(tx *sqlx.Tx, serviceID uuid.UUID, bookingType string, req BookingRequest) (entities.ServiceBookingEntity, bool, error) {
+	var booking entities.ServiceBookingEntity
+
+	var serviceExists bool
+	err := tx.Get(&serviceExists, "SELECT EXISTS(SELECT 1 FROM laundry_services WHERE id = $1)", serviceID)
+	if err != nil {
+		return booking, false, err
+	}
+	if !serviceExists {
+		return booking, false, apierror.NotFound("id", apierror.ServiceNotFound, "id", serviceID.String())
+	}
+
+	var slot entities.TimeSlotEntity
+	err = tx.Get(&slot, "SELECT "+timeSlotColumns+" FROM time_slots WHERE id = $1 FOR UPDATE", req.TimeSlotID)
+	if err == sql.ErrNoRows {
+		return booking, false, apierror.Field("time_slot_id", apierror.TimeSlotNotFound, "id", req.TimeSlotID.String())
+	}
+	if err != nil {
+		return booking, false, err
+	}
+	if !slot.IsActive {
+		return booking, false, apierror.Field("time_slot_id", apierror.TimeSlotInactive, "id", req.TimeSlotID.String())
+	}
+
+	if req.DriverID != nil {
+		if err := validateDriver(tx, *req.DriverID); err != nil {
+			return booking, false, err
+		}
+	}
+
+	var existing entities.ServiceBookingEntity
+	err = tx.Get(&existing, "SELECT "+bookingColumns+" FROM service_bookings WHERE laundry_service_id = $1 AND type = $2", serviceID, bookingType)
+	if err != nil && err != sql.ErrNoRows {
+		return booking, false, err
+	}
+	exists := err == nil
+	if exists && existing.Status != StatusScheduled && existing.Status != StatusFailed {
+		return booking, false, apierror.Conflict("type", apierror.AlreadyBooked, "type", bookingType)
+	}
+
+	var booked int
+	err = tx.Get(&booked, `
+		SELECT COUNT(*) FROM service_bookings
+		WHERE time_slot_id = $1 AND date = $2 AND status <> $3 AND id <> $4`,
+		slot.ID, req.Date, StatusFailed, existing.ID)
+	if err != nil {
+		return booking, false, err
+	}
+	if booked >= slot.Capacity {
+		return booking, false, apierror.Conflict("time_slot_id", apierror.TimeSlotFull, "date", req.Date)
+	}
+
+	if exists {
+		err = tx.Get(&booking, `
+			UPDATE service_bookings
+			SET date = $1, time_slot_id = $2, driver_id = $3, notes = $4, status = $5, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $6
+			RETURNING `+bookingColumns,
+			req.Date, slot.ID, req.DriverID, req.Notes, StatusScheduled, existing.ID)
+		return booking, false, err
+	}
+
+	err = tx.Get(&booking, `
+		INSERT INTO service_bookings (laundry_service_id, type, date, time_slot_id, driver_id, notes, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+bookingColumns,
+		serviceID, bookingType, req.Date, slot.ID, req.DriverID, req.Notes, StatusScheduled)
+	return booking, true, err
+}
+
+// UpdateBookingHandler handles status changes, driver assignment and notes of a booking
+func UpdateBookingHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookingID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var req BookingUpdate
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			tx.Commit()
+		}()
+
+		booking, err := updateBooking(tx, bookingID, req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(booking)
+	}
+}
+
+func updateBooking(tx *sqlx.Tx, bookingID uuid.UUID, req BookingUpdate) (entities.ServiceBookingEntity, error) {
+	var booking entities.ServiceBookingEntity
+	err := tx.Get(&booking, "SELECT "+bookingColumns+" FROM service_bookings WHERE id = $1 FOR UPDATE", bookingID)
+	if err == sql.ErrNoRows {
+		return booking, apierror.NotFound("id", apierror.BookingNotFound, "id", bookingID.String())
+	}
+	if err != nil {
+		return booking, err
+	}
+
+	if req.Status != "" && req.Status != booking.Status {
+		if !canTransition(booking.Status, req.Status) {
+			return booking, apierror.Validation(apierror.Field("status", apierror.InvalidTransition, "from", booking.Status, "to", req.Status))
+		}
+		booking.Status = req.Status
+	}
+
+	if req.DriverID != nil {
+		if err := validateDriver(tx, *req.DriverID); err != nil {
+			return booking, err
+		}
+		booking.DriverID = req.DriverID
+	}
+
+	if req.Notes != nil {
+		booking.Notes = *req.Notes
+	}
+
+	err = tx.Get(&booking, `
+		UPDATE service_bookings
+		SET status = $1, driver_id = $2, notes = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+		RETURNING `+bookingColumns,
+		booking.Status, booking.DriverID, booking.Notes, booking.ID)
+	return booking, err
+}
+
+func canTransition(from, to string) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// validateDriver checks the driver is a staff user, not a client
+func validateDriver(tx *sqlx.Tx, driverID uuid.UUID) error {
+	var exists bool
+	err := tx.Get(&exists, "SELECT EXISTS(SELECT 1 FROM ONLY users WHERE id = $1)", driverID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return apierror.Field("driver_id", apierror.DriverNotFound, "id", driverID.String())
+	}
+	return nil
+}
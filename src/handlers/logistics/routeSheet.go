@@ -0,0 +1,125 @@
+package logisticshandlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/validation"
+)
+
+// RouteSheetQuery is the query string of the route sheet
+type RouteSheetQuery struct {
+	Date     string `json:"date" validate:"required,date"`
+	Type     string `json:"type" validate:"oneof=pickup|delivery"`
+	DriverID string `json:"driver_id"`
+}
+
+// RouteStop is a pickup or delivery in the route sheet, with everything
+// the driver needs at the door
+type RouteStop struct {
+	BookingID       string  `json:"booking_id" db:"booking_id"`
+	Type            string  `json:"type" db:"type"`
+	Status          string  `json:"status" db:"status"`
+	StartTime       string  `json:"start_time" db:"start_time"`
+	EndTime         string  `json:"end_time" db:"end_time"`
+	DriverID        *string `json:"driver_id" db:"driver_id"`
+	DriverName      string  `json:"driver_name" db:"driver_name"`
+	Notes           string  `json:"notes" db:"notes"`
+	ServiceID       string  `json:"service_id" db:"service_id"`
+	ServiceStatus   string  `json:"service_status" db:"service_status"`
+	TotalPrice      float64 `json:"total_price" db:"total_price"`
+	IsPaid          bool    `json:"is_paid" db:"is_paid"`
+	ClientFirstName string  `json:"client_first_name" db:"client_first_name"`
+	ClientLastName  string  `json:"client_last_name" db:"client_last_name"`
+	Phone           string  `json:"phone" db:"phone"`
+	AddressLabel    string  `json:"address_label" db:"address_label"`
+	Street          string  `json:"street" db:"street"`
+	Number          string  `json:"number" db:"number"`
+	Complement      string  `json:"complement" db:"complement"`
+	Neighborhood    string  `json:"neighborhood" db:"neighborhood"`
+	City            string  `json:"city" db:"city"`
+	State           string  `json:"state" db:"state"`
+	PostalCode      string  `json:"postal_code" db:"postal_code"`
+	Landmark        string  `json:"landmark" db:"landmark"`
+}
+
+// RouteSheet is the list of stops of a day
+type RouteSheet struct {
+	Date  string      `json:"date"`
+	Stops []RouteStop `json:"stops"`
+}
+
+// RouteSheetHandler handles the route sheet of a day: its pickups and
+// deliveries grouped by neighbourhood and postal code, then by time slot.
+// Each stop is at the address chosen for its service.
+func RouteSheetHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		values := r.URL.Query()
+		query := RouteSheetQuery{Date: values.Get("date"), Type: values.Get("type"), DriverID: values.Get("driver_id")}
+		if err := validation.Struct(query); err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		var driverID interface{}
+		if query.DriverID != "" {
+			id, err := uuid.Parse(query.DriverID)
+			if err != nil {
+				apierror.Write(w, r, apierror.Validation(apierror.Field("driver_id", apierror.InvalidUUID)))
+				return
+			}
+			driverID = id
+		}
+
+		stops := []RouteStop{}
+		err := db.Select(&stops, `
+			SELECT
+				sb.id AS booking_id,
+				sb.type,
+				sb.status,
+				to_char(ts.start_time, 'HH24:MI') AS start_time,
+				to_char(ts.end_time, 'HH24:MI') AS end_time,
+				sb.driver_id,
+				COALESCE(drv.first_name || ' ' || drv.last_name, '') AS driver_name,
+				COALESCE(sb.notes, '') AS notes,
+				ls.id AS service_id,
+				ls.status AS service_status,
+				COALESCE(ls.total_price, 0) AS total_price,
+				COALESCE(ls.is_paid, false) AS is_paid,
+				cli.first_name AS client_first_name,
+				cli.last_name AS client_last_name,
+				COALESCE(TRIM(cli.phone), '') AS phone,
+				COALESCE(ca.label, '') AS address_label,
+				COALESCE(ad.street, '') AS street,
+				COALESCE(ad.number, '') AS number,
+				COALESCE(ad.complement, '') AS complement,
+				COALESCE(ad.neighborhood, '') AS neighborhood,
+				COALESCE(ad.city, '') AS city,
+				COALESCE(ad.state, '') AS state,
+				COALESCE(ad.postal_code, '') AS postal_code,
+				COALESCE(ad.landmark, '') AS landmark
+			FROM service_bookings sb
+			JOIN time_slots ts ON ts.id = sb.time_slot_id
+			JOIN laundry_services ls ON ls.id = sb.laundry_service_id
+			JOIN clients cli ON cli.id = ls.client_id
+			LEFT JOIN address ad ON ad.address_id = COALESCE(ls.address_id, cli.address_id)
+			LEFT JOIN client_addresses ca ON ca.address_id = ad.address_id
+			LEFT JOIN ONLY users drv ON drv.id = sb.driver_id
+			WHERE sb.date = $1
+				AND ($2::text = '' OR sb.type = $2::text)
+				AND ($3::uuid IS NULL OR sb.driver_id = $3::uuid)
+			ORDER BY ad.neighborhood NULLS LAST, ad.postal_code NULLS LAST, ts.start_time, sb.type`,
+			query.Date, query.Type, driverID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(RouteSheet{Date: query.Date, Stops: stops})
+	}
+}
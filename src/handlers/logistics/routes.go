@@ -0,0 +1,222 @@
+package logisticshandlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/routing"
+	"lavanderia/validation"
+)
+
+// PlannedStop is a pickup or delivery in the optimized visiting order
+type PlannedStop struct {
+	Order           int     `json:"order"`
+	BookingID       string  `json:"booking_id" db:"booking_id"`
+	Type            string  `json:"type" db:"type"`
+	Status          string  `json:"status" db:"status"`
+	StartTime       string  `json:"start_time" db:"start_time"`
+	EndTime         string  `json:"end_time" db:"end_time"`
+	ServiceID       string  `json:"service_id" db:"service_id"`
+	ClientFirstName string  `json:"client_first_name" db:"client_first_name"`
+	ClientLastName  string  `json:"client_last_name" db:"client_last_name"`
+	Phone           string  `json:"phone" db:"phone"`
+	Street          string  `json:"street" db:"street"`
+	Number          string  `json:"number" db:"number"`
+	Neighborhood    string  `json:"neighborhood" db:"neighborhood"`
+	City            string  `json:"city" db:"city"`
+	Latitude        float64 `json:"latitude" db:"-"`
+	Longitude       float64 `json:"longitude" db:"-"`
+	DistanceKm      float64 `json:"distance_km"`
+	EstimatedAt     string  `json:"estimated_at"`
+	Late            bool    `json:"late"`
+}
+
+// OptimizedRoute is the visiting order of a day starting at the shop.
+// Unrouted lists the stops whose address has no coordinates.
+type OptimizedRoute struct {
+	Date       string        `json:"date"`
+	Origin     routing.Point `json:"origin"`
+	DistanceKm float64       `json:"distance_km"`
+	Departure  string        `json:"departure"`
+	Return     string        `json:"return"`
+	LateStops  int           `json:"late_stops"`
+	Stops      []PlannedStop `json:"stops"`
+	Unrouted   []PlannedStop `json:"unrouted"`
+}
+
+// routeCandidate is a booking as read from the database, before planning
+type routeCandidate struct {
+	PlannedStop
+	Latitude  *float64 `db:"latitude"`
+	Longitude *float64 `db:"longitude"`
+}
+
+// RoutesHandler handles the optimized visiting order of the pickups and
+// deliveries still to be done on a day, starting and ending at the shop.
+// Each booking's time slot is its time window.
+func RoutesHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		values := r.URL.Query()
+		query := RouteSheetQuery{Date: values.Get("date"), Type: values.Get("type"), DriverID: values.Get("driver_id")}
+		if err := validation.Struct(query); err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		var driverID interface{}
+		if query.DriverID != "" {
+			id, err := uuid.Parse(query.DriverID)
+			if err != nil {
+				apierror.Write(w, r, apierror.Validation(apierror.Field("driver_id", apierror.InvalidUUID)))
+				return
+			}
+			driverID = id
+		}
+
+		origin, ok := shopLocation()
+		if !ok {
+			apierror.Write(w, r, apierror.Internal(apierror.ShopLocationNotSet))
+			return
+		}
+
+		candidates := []routeCandidate{}
+		err := db.Select(&candidates, `
+			SELECT
+				sb.id AS booking_id,
+				sb.type,
+				sb.status,
+				to_char(ts.start_time, 'HH24:MI') AS start_time,
+				to_char(ts.end_time, 'HH24:MI') AS end_time,
+				ls.id AS service_id,
+				cli.first_name AS client_first_name,
+				cli.last_name AS client_last_name,
+				COALESCE(TRIM(cli.phone), '') AS phone,
+				COALESCE(ad.street, '') AS street,
+				COALESCE(ad.number, '') AS number,
+				COALESCE(ad.neighborhood, '') AS neighborhood,
+				COALESCE(ad.city, '') AS city,
+				ad.latitude,
+				ad.longitude
+			FROM service_bookings sb
+			JOIN time_slots ts ON ts.id = sb.time_slot_id
+			JOIN laundry_services ls ON ls.id = sb.laundry_service_id
+			JOIN clients cli ON cli.id = ls.client_id
+			LEFT JOIN address ad ON ad.address_id = COALESCE(ls.address_id, cli.address_id)
+			WHERE sb.date = $1
+				AND sb.status IN ($2, $3)
+				AND ($4::text = '' OR sb.type = $4::text)
+				AND ($5::uuid IS NULL OR sb.driver_id = $5::uuid)
+			ORDER BY ts.start_time, sb.created_at`,
+			query.Date, StatusScheduled, StatusEnRoute, query.Type, driverID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		route, err := planRoute(query.Date, origin, candidates)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.InternalError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(route)
+	}
+}
+
+// shopLocation reads the starting point of the routes from SHOP_LATITUDE
+// and SHOP_LONGITUDE
+func shopLocation() (routing.Point, bool) {
+	lat, err := strconv.ParseFloat(os.Getenv("SHOP_LATITUDE"), 64)
+	if err != nil {
+		return routing.Point{}, false
+	}
+	lng, err := strconv.ParseFloat(os.Getenv("SHOP_LONGITUDE"), 64)
+	if err != nil {
+		return routing.Point{}, false
+	}
+	return routing.Point{Lat: lat, Lng: lng}, true
+}
+
+// planRoute orders the candidates that have coordinates. The driver leaves
+// the shop when the earliest time slot opens.
+func planRoute(day string, origin routing.Point, candidates []routeCandidate) (OptimizedRoute, error) {
+	route := OptimizedRoute{Date: day, Origin: origin, Stops: []PlannedStop{}, Unrouted: []PlannedStop{}}
+
+	date, err := time.ParseInLocation("2006-01-02", day[:10], time.Local)
+	if err != nil {
+		return route, err
+	}
+
+	stops := make([]routing.Stop, 0, len(candidates))
+	byID := make(map[string]PlannedStop, len(candidates))
+	var departure time.Time
+	for _, candidate := range candidates {
+		if candidate.Latitude == nil || candidate.Longitude == nil {
+			route.Unrouted = append(route.Unrouted, candidate.PlannedStop)
+			continue
+		}
+
+		start, err := atClock(date, candidate.StartTime)
+		if err != nil {
+			return route, err
+		}
+		end, err := atClock(date, candidate.EndTime)
+		if err != nil {
+			return route, err
+		}
+		if departure.IsZero() || start.Before(departure) {
+			departure = start
+		}
+
+		stop := candidate.PlannedStop
+		stop.Latitude, stop.Longitude = *candidate.Latitude, *candidate.Longitude
+		byID[stop.BookingID] = stop
+		stops = append(stops, routing.Stop{
+			ID:          stop.BookingID,
+			Location:    routing.Point{Lat: stop.Latitude, Lng: stop.Longitude},
+			WindowStart: start,
+			WindowEnd:   end,
+		})
+	}
+	if len(stops) == 0 {
+		return route, nil
+	}
+
+	plan := routing.Plan(origin, stops, routing.Options{Start: departure, ReturnToOrigin: true})
+
+	route.DistanceKm = roundKm(plan.DistanceKm)
+	route.Departure = departure.Format("15:04")
+	route.Return = plan.Finish.Format("15:04")
+	route.LateStops = plan.LateStops
+	for i, visit := range plan.Visits {
+		stop := byID[visit.ID]
+		stop.Order = i + 1
+		stop.DistanceKm = roundKm(visit.DistanceKm)
+		stop.EstimatedAt = visit.Start.Format("15:04")
+		stop.Late = visit.Late
+		route.Stops = append(route.Stops, stop)
+	}
+
+	return route, nil
+}
+
+func atClock(date time.Time, clock string) (time.Time, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return date.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute), nil
+}
+
+func roundKm(km float64) float64 {
+	return math.Round(km*100) / 100
+}
@@ -0,0 +1,170 @@
+package logisticshandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/entities"
+	"lavanderia/validation"
+)
+
+// timeSlotColumns selects a time slot with its times formatted as HH:MM
+const timeSlotColumns = `id, to_char(start_time, 'HH24:MI') AS start_time, to_char(end_time, 'HH24:MI') AS end_time, capacity, is_active`
+
+// TimeSlotRequest is the request body to create or update a time slot
+type TimeSlotRequest struct {
+	StartTime string `json:"start_time" validate:"required,clock"`
+	EndTime   string `json:"end_time" validate:"required,clock"`
+	Capacity  int    `json:"capacity" validate:"required,positive"`
+	IsActive  *bool  `json:"is_active"`
+}
+
+// validate checks the request and normalizes its times to HH:MM
+func (req *TimeSlotRequest) validate() error {
+	if err := validation.Struct(req); err != nil {
+		return err
+	}
+
+	start, _ := time.Parse("15:04", req.StartTime)
+	end, _ := time.Parse("15:04", req.EndTime)
+	if !end.After(start) {
+		return apierror.Field("end_time", apierror.EndBeforeStart)
+	}
+
+	req.StartTime = start.Format("15:04")
+	req.EndTime = end.Format("15:04")
+	return nil
+}
+
+// ListTimeSlotsHandler handles the listing of all time slots ordered by start time
+func ListTimeSlotsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slots := []entities.TimeSlotEntity{}
+		err := db.Select(&slots, "SELECT "+timeSlotColumns+" FROM time_slots ORDER BY start_time, end_time")
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(slots)
+	}
+}
+
+// CreateTimeSlotHandler handles the creation of a time slot
+func CreateTimeSlotHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req TimeSlotRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = req.validate()
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		isActive := req.IsActive == nil || *req.IsActive
+
+		var slot entities.TimeSlotEntity
+		err = db.Get(&slot,
+			"INSERT INTO time_slots (start_time, end_time, capacity, is_active) VALUES ($1, $2, $3, $4) RETURNING "+timeSlotColumns,
+			req.StartTime, req.EndTime, req.Capacity, isActive)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(slot)
+	}
+}
+
+// UpdateTimeSlotHandler handles the update of a time slot. Lowering the
+// capacity doesn't cancel bookings already made.
+func UpdateTimeSlotHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slotID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var req TimeSlotRequest
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = req.validate()
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		isActive := req.IsActive == nil || *req.IsActive
+
+		var slot entities.TimeSlotEntity
+		err = db.Get(&slot,
+			"UPDATE time_slots SET start_time=$1, end_time=$2, capacity=$3, is_active=$4 WHERE id=$5 RETURNING "+timeSlotColumns,
+			req.StartTime, req.EndTime, req.Capacity, isActive, slotID)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.TimeSlotNotFound, "id", slotID.String()))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(slot)
+	}
+}
+
+// DeleteTimeSlotHandler handles the deletion of a time slot without bookings.
+// Slots that were already booked should be disabled instead.
+func DeleteTimeSlotHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slotID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var inUse bool
+		err = db.Get(&inUse, "SELECT EXISTS(SELECT 1 FROM service_bookings WHERE time_slot_id = $1)", slotID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if inUse {
+			apierror.Write(w, r, apierror.Conflict("id", apierror.InUse))
+			return
+		}
+
+		result, err := db.Exec("DELETE FROM time_slots WHERE id = $1", slotID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.TimeSlotNotFound, "id", slotID.String()))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
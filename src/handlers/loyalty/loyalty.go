@@ -0,0 +1,220 @@
+package loyaltyhandlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/entities"
+	"lavanderia/loyalty"
+	middleware "lavanderia/middlewares"
+	"lavanderia/validation"
+)
+
+// historySize is how many of the latest entries a loyalty view lists
+const historySize = 50
+
+// SettingsRequest is the request body to replace the loyalty settings.
+// PointValue is in reais, MaxDiscount a percentage and an ExpiryDays of 0
+// keeps points forever.
+type SettingsRequest struct {
+	IsActive      bool    `json:"is_active"`
+	PointsPerReal float64 `json:"points_per_real"`
+	PointValue    float64 `json:"point_value"`
+	MinRedemption int     `json:"min_redemption"`
+	MaxDiscount   float64 `json:"max_discount"`
+	ExpiryDays    int     `json:"expiry_days"`
+}
+
+// validate checks the request
+func (req *SettingsRequest) validate() error {
+	if err := validation.Struct(req); err != nil {
+		return err
+	}
+
+	var errs apierror.FieldErrors
+	if req.PointsPerReal < 0 || req.PointsPerReal > 100 {
+		errs = append(errs, apierror.Field("points_per_real", apierror.OutOfRange, "min", "0", "max", "100"))
+	}
+	if req.PointValue < 0 || req.PointValue > 100 {
+		errs = append(errs, apierror.Field("point_value", apierror.OutOfRange, "min", "0", "max", "100"))
+	}
+	if req.MinRedemption < 0 || req.MinRedemption > 1000000 {
+		errs = append(errs, apierror.Field("min_redemption", apierror.OutOfRange, "min", "0", "max", "1000000"))
+	}
+	if req.MaxDiscount < 0 || req.MaxDiscount > 100 {
+		errs = append(errs, apierror.Field("max_discount", apierror.OutOfRange, "min", "0", "max", "100"))
+	}
+	if req.ExpiryDays < 0 || req.ExpiryDays > 3650 {
+		errs = append(errs, apierror.Field("expiry_days", apierror.OutOfRange, "min", "0", "max", "3650"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// LoyaltyResponse is the points of a client, what they are worth, when
+// they expire and the latest entries, newest first, with the rules of the
+// program
+type LoyaltyResponse struct {
+	ClientID uuid.UUID                      `json:"client_id"`
+	Points   int                            `json:"points"`
+	Value    float64                        `json:"value"`
+	Expiring []loyalty.Lot                  `json:"expiring"`
+	Entries  []entities.LoyaltyEntryEntity  `json:"entries"`
+	Rules    entities.LoyaltySettingsEntity `json:"rules"`
+}
+
+// ShowSettingsHandler handles the display of the loyalty settings. Until
+// they are saved, the defaults are shown.
+func ShowSettingsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		settings, err := loyalty.LoadSettings(db)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(settings)
+	}
+}
+
+// UpdateSettingsHandler handles the replacement of the loyalty settings.
+// Points already earned keep their expiry date.
+func UpdateSettingsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req SettingsRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = req.validate()
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		var settings entities.LoyaltySettingsEntity
+		err = db.Get(&settings, `
+			INSERT INTO loyalty_settings (id, is_active, points_per_real, point_value, min_redemption, max_discount, expiry_days, updated_at)
+			VALUES (1, $1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP)
+			ON CONFLICT (id) DO UPDATE SET
+				is_active = EXCLUDED.is_active,
+				points_per_real = EXCLUDED.points_per_real,
+				point_value = EXCLUDED.point_value,
+				min_redemption = EXCLUDED.min_redemption,
+				max_discount = EXCLUDED.max_discount,
+				expiry_days = EXCLUDED.expiry_days,
+				updated_at = EXCLUDED.updated_at
+			RETURNING `+loyalty.SettingsColumns,
+			req.IsActive, req.PointsPerReal, req.PointValue, req.MinRedemption, req.MaxDiscount, req.ExpiryDays)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(settings)
+	}
+}
+
+// ShowClientLoyaltyHandler handles the display of the points of a client.
+// Clients only see their own points.
+func ShowClientLoyaltyHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		err = middleware.AuthorizeClient(db, r, clientID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		show(db, w, r, clientID)
+	}
+}
+
+// MyLoyaltyHandler handles the display of the points of the logged in
+// client
+func MyLoyaltyHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := middleware.UserFromContext(r.Context())
+		if !ok {
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.Unauthorized))
+			return
+		}
+		clientID, err := uuid.Parse(user.ID)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.InvalidToken))
+			return
+		}
+
+		show(db, w, r, clientID)
+	}
+}
+
+// show writes the points of a client, first recording the expiry of the
+// points past their date
+func show(db *sqlx.DB, w http.ResponseWriter, r *http.Request, clientID uuid.UUID) {
+	var exists bool
+	err := db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM clients WHERE id = $1)", clientID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+		return
+	}
+	if !exists {
+		apierror.Write(w, r, apierror.NotFound("id", apierror.ClientNotFound, "id", clientID.String()))
+		return
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	err = loyalty.ExpirePoints(tx, clientID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+		return
+	}
+
+	response := LoyaltyResponse{ClientID: clientID, Entries: []entities.LoyaltyEntryEntity{}}
+	response.Rules, err = loyalty.LoadSettings(tx)
+	if err == nil {
+		response.Points, err = loyalty.Balance(tx, clientID)
+	}
+	if err == nil {
+		response.Expiring, err = loyalty.Expiring(tx, clientID)
+	}
+	if err == nil {
+		err = tx.Select(&response.Entries, "SELECT "+loyalty.EntryColumns+" FROM loyalty_entries WHERE client_id = $1 ORDER BY created_at DESC, points LIMIT $2", clientID, historySize)
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+		return
+	}
+	response.Value = loyalty.Value(response.Rules, response.Points)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
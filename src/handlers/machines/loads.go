@@ -0,0 +1,407 @@
+package machineshandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"lavanderia/apierror"
+	"lavanderia/entities"
+	"lavanderia/events"
+	"lavanderia/inventory"
+	"lavanderia/machines"
+	middleware "lavanderia/middlewares"
+	"lavanderia/production"
+	"lavanderia/queryspec"
+	"lavanderia/validation"
+)
+
+// loadQuery selects loads with their machine, operator and services
+const loadQuery = `
+	SELECT ml.id, ml.machine_id, ml.operator_id, ml.stage, ml.weight, ml.notes, ml.started_at, ml.ended_at,
+	       m.name AS machine_name,
+	       m.type AS machine_type,
+	       COALESCE(u.first_name || ' ' || u.last_name, '') AS operator_name,
+	       COALESCE(array_agg(mls.laundry_service_id::text) FILTER (WHERE mls.laundry_service_id IS NOT NULL), '{}') AS service_ids
+	FROM machine_loads ml
+	JOIN machines m ON m.id = ml.machine_id
+	LEFT JOIN users u ON u.id = ml.operator_id
+	LEFT JOIN machine_load_services mls ON mls.load_id = ml.id`
+
+// Load is a load of a machine with the services it processed
+type Load struct {
+	entities.MachineLoadEntity
+	MachineName  string         `json:"machine_name" db:"machine_name"`
+	MachineType  string         `json:"machine_type" db:"machine_type"`
+	OperatorName string         `json:"operator_name" db:"operator_name"`
+	ServiceIDs   pq.StringArray `json:"service_ids" db:"service_ids"`
+}
+
+// LoadRequest is the request body to start a load of a machine. Without a
+// weight, the weight of the services charged by weight is used; without
+// an operator, the logged in user is.
+type LoadRequest struct {
+	ServiceIDs []uuid.UUID `json:"service_ids" validate:"required"`
+	Weight     float64     `json:"weight" validate:"positive"`
+	OperatorID *uuid.UUID  `json:"operator_id"`
+	Notes      string      `json:"notes" validate:"max=255"`
+}
+
+// StartLoadHandler handles the start of a load of a machine. The services
+// still in an earlier status move to the stage the machine works on.
+func StartLoadHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		machineID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var req LoadRequest
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		var machine entities.MachineEntity
+		err = db.Get(&machine, "SELECT "+machineColumns+" FROM machines WHERE id = $1", machineID)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.MachineNotFound, "id", machineID.String()))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if !machine.IsActive {
+			apierror.Write(w, r, apierror.Conflict("id", apierror.MachineInactive))
+			return
+		}
+
+		operatorID, err := loadOperator(db, r, req.OperatorID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			tx.Commit()
+		}()
+
+		err = validateMachineIdle(tx, machineID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		stage := machines.Stage(machine.Type)
+		services, err := loadServices(tx, req.ServiceIDs)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		weight := req.Weight
+		if weight == 0 {
+			for _, service := range services {
+				if service.IsWeight {
+					weight += service.Weight
+				}
+			}
+		}
+		if weight > machine.CapacityKg {
+			err = apierror.Field("weight", apierror.LoadTooHeavy, "capacity", strconv.FormatFloat(machine.CapacityKg, 'f', -1, 64))
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		var loadID uuid.UUID
+		err = tx.Get(&loadID,
+			"INSERT INTO machine_loads (machine_id, operator_id, stage, weight, notes) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+			machineID, operatorID, stage, weight, req.Notes)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		// Wash loads take detergent and softener from the stock, estimated
+		// from the usage registered for each supply
+		if machine.Type == machines.Washer {
+			err = inventory.ConsumeLoad(tx, loadID, weight)
+			if err != nil {
+				apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+				return
+			}
+		}
+
+		for _, service := range services {
+			_, err = tx.Exec("INSERT INTO machine_load_services (load_id, laundry_service_id) VALUES ($1, $2)", loadID, service.ID)
+			if err != nil {
+				apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+				return
+			}
+
+			if statusIndex(service.Status) >= statusIndex(stage) {
+				continue
+			}
+			_, err = tx.Exec("UPDATE laundry_services SET status = $1 WHERE id = $2", stage, service.ID)
+			if err != nil {
+				apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+				return
+			}
+			err = events.Publish(tx, events.ServiceStatusChanged, service.ID, events.StatusChange{
+				ServiceID: service.ID,
+				ClientID:  service.ClientID,
+				From:      service.Status,
+				To:        stage,
+			})
+			if err != nil {
+				apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+				return
+			}
+		}
+
+		var load Load
+		err = tx.Get(&load, loadQuery+" WHERE ml.id = $1 GROUP BY ml.id, m.id, u.id", loadID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(load)
+	}
+}
+
+// FinishLoadHandler handles the end of a running load
+func FinishLoadHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loadID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var endedAt *time.Time
+		err = db.Get(&endedAt, "SELECT ended_at FROM machine_loads WHERE id = $1", loadID)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.LoadNotFound, "id", loadID.String()))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if endedAt != nil {
+			apierror.Write(w, r, apierror.Conflict("id", apierror.LoadFinished))
+			return
+		}
+
+		_, err = db.Exec("UPDATE machine_loads SET ended_at = GREATEST(started_at, $1) WHERE id = $2 AND ended_at IS NULL", time.Now(), loadID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		var load Load
+		err = db.Get(&load, loadQuery+" WHERE ml.id = $1 GROUP BY ml.id, m.id, u.id", loadID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(load)
+	}
+}
+
+// ListMachineLoadsHandler handles the listing of the loads a machine
+// started in a period, the last 30 days by default, most recent first
+func ListMachineLoadsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		machineID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		from, to, err := queryspec.Period(r.URL.Query(), queryspec.MaxPeriodDays)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		err = validateMachineExists(db, machineID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		loads := []Load{}
+		err = db.Select(&loads, loadQuery+`
+			WHERE ml.machine_id = $1 AND ml.started_at >= $2 AND ml.started_at < $3
+			GROUP BY ml.id, m.id, u.id
+			ORDER BY ml.started_at DESC`, machineID, from, to)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(loads)
+	}
+}
+
+// ListServiceLoadsHandler handles the listing of the loads that processed
+// a service, in the order they ran, to tell which machines handled it
+func ListServiceLoadsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serviceID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var exists bool
+		err = db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM laundry_services WHERE id = $1)", serviceID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if !exists {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.ServiceNotFound, "id", serviceID.String()))
+			return
+		}
+
+		loads := []Load{}
+		err = db.Select(&loads, loadQuery+`
+			WHERE ml.id IN (SELECT load_id FROM machine_load_services WHERE laundry_service_id = $1)
+			GROUP BY ml.id, m.id, u.id
+			ORDER BY ml.started_at`, serviceID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(loads)
+	}
+}
+
+// loadService is a service put into a load
+type loadService struct {
+	ID       uuid.UUID `db:"id"`
+	ClientID uuid.UUID `db:"client_id"`
+	Status   string    `db:"status"`
+	IsWeight bool      `db:"is_weight"`
+	Weight   float64   `db:"weight"`
+}
+
+// loadServices reads the services of a load, which must exist and be
+// unfinished. Repeated ids are read once.
+func loadServices(tx *sqlx.Tx, ids []uuid.UUID) ([]loadService, error) {
+	services := make([]loadService, 0, len(ids))
+	seen := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		var service loadService
+		var completedAt *time.Time
+		row := tx.QueryRowx(`
+			SELECT id, client_id, status, COALESCE(is_weight, FALSE), COALESCE(weight, 0), completed_at
+			FROM laundry_services WHERE id = $1 FOR UPDATE`, id)
+		err := row.Scan(&service.ID, &service.ClientID, &service.Status, &service.IsWeight, &service.Weight, &completedAt)
+		if err == sql.ErrNoRows {
+			return nil, apierror.Field("service_ids", apierror.ServiceNotFound, "id", id.String())
+		}
+		if err != nil {
+			return nil, err
+		}
+		if completedAt != nil || service.Status == "Finalizado" {
+			return nil, apierror.Field("service_ids", apierror.ServiceFinished, "id", id.String())
+		}
+		services = append(services, service)
+	}
+	return services, nil
+}
+
+// validateMachineIdle checks the machine isn't running another load
+func validateMachineIdle(tx *sqlx.Tx, machineID uuid.UUID) error {
+	var running uuid.UUID
+	err := tx.Get(&running, "SELECT id FROM machine_loads WHERE machine_id = $1 AND ended_at IS NULL", machineID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return apierror.Conflict("id", apierror.MachineBusy, "id", running.String())
+}
+
+// loadOperator returns the staff user who runs a load: operatorID when it
+// is given, or else the logged in user when they are staff
+func loadOperator(db *sqlx.DB, r *http.Request, operatorID *uuid.UUID) (*uuid.UUID, error) {
+	if operatorID == nil {
+		return middleware.StaffID(db, r), nil
+	}
+
+	var exists bool
+	err := db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM ONLY users WHERE id = $1)", *operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, apierror.Field("operator_id", apierror.OperatorNotFound, "id", operatorID.String())
+	}
+	return operatorID, nil
+}
+
+// statusIndex is the position of a status in the production stages;
+// finished and unknown statuses come after every stage
+func statusIndex(status string) int {
+	for i, s := range production.Statuses {
+		if s == status {
+			return i
+		}
+	}
+	return len(production.Statuses)
+}
+
+func validateMachineExists(db *sqlx.DB, machineID uuid.UUID) error {
+	var exists bool
+	err := db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM machines WHERE id = $1)", machineID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return apierror.NotFound("id", apierror.MachineNotFound, "id", machineID.String())
+	}
+	return nil
+}
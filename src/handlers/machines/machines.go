@@ -0,0 +1,181 @@
+package machineshandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/entities"
+	"lavanderia/validation"
+)
+
+// machineColumns selects a machine
+const machineColumns = `id, name, type, capacity_kg, is_active, created_at`
+
+// MachineRequest is the request body to register or update a machine
+type MachineRequest struct {
+	Name       string  `json:"name" validate:"required,max=100"`
+	Type       string  `json:"type" validate:"required,oneof=washer|dryer|iron"`
+	CapacityKg float64 `json:"capacity_kg" validate:"required,positive"`
+	IsActive   *bool   `json:"is_active"`
+}
+
+// validate checks the request and that no other machine than machineID
+// has the name, ignoring case
+func (req MachineRequest) validate(db *sqlx.DB, machineID uuid.UUID) error {
+	if err := validation.Struct(req); err != nil {
+		return err
+	}
+
+	var exists bool
+	err := db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM machines WHERE LOWER(name) = LOWER($1) AND id <> $2)", req.Name, machineID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return apierror.Field("name", apierror.AlreadyExists)
+	}
+	return nil
+}
+
+// MachinesQuery filters GET /machines by type
+type MachinesQuery struct {
+	Type string `json:"type" validate:"oneof=washer|dryer|iron"`
+}
+
+// ListMachinesHandler handles the listing of the machines by type and name
+func ListMachinesHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := MachinesQuery{Type: r.URL.Query().Get("type")}
+		if err := validation.Struct(query); err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		machines := []entities.MachineEntity{}
+		err := db.Select(&machines, "SELECT "+machineColumns+" FROM machines WHERE $1 = '' OR type = $1 ORDER BY type, name", query.Type)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(machines)
+	}
+}
+
+// CreateMachineHandler handles the registration of a machine
+func CreateMachineHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req MachineRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = req.validate(db, uuid.Nil)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		isActive := req.IsActive == nil || *req.IsActive
+
+		var machine entities.MachineEntity
+		err = db.Get(&machine,
+			"INSERT INTO machines (name, type, capacity_kg, is_active) VALUES ($1, $2, $3, $4) RETURNING "+machineColumns,
+			req.Name, req.Type, req.CapacityKg, isActive)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(machine)
+	}
+}
+
+// UpdateMachineHandler handles the update of a machine. Its past loads
+// keep the stage they were worked in.
+func UpdateMachineHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		machineID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var req MachineRequest
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = req.validate(db, machineID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		isActive := req.IsActive == nil || *req.IsActive
+
+		var machine entities.MachineEntity
+		err = db.Get(&machine,
+			"UPDATE machines SET name=$1, type=$2, capacity_kg=$3, is_active=$4 WHERE id=$5 RETURNING "+machineColumns,
+			req.Name, req.Type, req.CapacityKg, isActive, machineID)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.MachineNotFound, "id", machineID.String()))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(machine)
+	}
+}
+
+// DeleteMachineHandler handles the deletion of a machine without loads.
+// Machines that were used should be disabled instead, keeping their history.
+func DeleteMachineHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		machineID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var inUse bool
+		err = db.Get(&inUse, "SELECT EXISTS(SELECT 1 FROM machine_loads WHERE machine_id = $1)", machineID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if inUse {
+			apierror.Write(w, r, apierror.Conflict("id", apierror.InUse))
+			return
+		}
+
+		result, err := db.Exec("DELETE FROM machines WHERE id = $1", machineID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.MachineNotFound, "id", machineID.String()))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,78 @@
+package machineshandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/calendar"
+	"lavanderia/entities"
+	"lavanderia/machines"
+	"lavanderia/queryspec"
+)
+
+// UtilizationResponse is how a machine was used over a period
+type UtilizationResponse struct {
+	Machine entities.MachineEntity `json:"machine"`
+	machines.Utilization
+}
+
+// UtilizationHandler handles the report of how a machine was used over a
+// period, the last 30 days by default: its loads, the hours it ran against
+// the opening hours of the shop, the kilos it processed and how full its
+// loads were, by day
+func UtilizationHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		machineID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		from, to, err := queryspec.Period(r.URL.Query(), queryspec.MaxPeriodDays)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		var machine entities.MachineEntity
+		err = db.Get(&machine, "SELECT "+machineColumns+" FROM machines WHERE id = $1", machineID)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.MachineNotFound, "id", machineID.String()))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		// Loads that ran during the period, including those started before it
+		var runs []machines.Run
+		err = db.Select(&runs, `
+			SELECT started_at, ended_at, weight FROM machine_loads
+			WHERE machine_id = $1 AND started_at < $3 AND (ended_at IS NULL OR ended_at > $2)`,
+			machineID, from, to)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		cal, err := calendar.Load(db)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(UtilizationResponse{
+			Machine:     machine,
+			Utilization: machines.Report(runs, machine.CapacityKg, from, to, time.Now(), cal),
+		})
+	}
+}
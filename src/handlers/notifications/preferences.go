@@ -0,0 +1,104 @@
+package notificationshandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/entities"
+	"lavanderia/validation"
+)
+
+// ShowPreferencesHandler handles the display of the notification channels
+// of a client, with the defaults when they were never changed
+func ShowPreferencesHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var preferences entities.NotificationPreferencesEntity
+		err = db.Get(&preferences, `
+			SELECT
+				cli.id AS client_id,
+				COALESCE(np.email, '') AS email,
+				COALESCE(np.email_enabled, FALSE) AS email_enabled,
+				COALESCE(np.sms_enabled, FALSE) AS sms_enabled,
+				COALESCE(np.whatsapp_enabled, TRUE) AS whatsapp_enabled
+			FROM clients cli
+			LEFT JOIN notification_preferences np ON np.client_id = cli.id
+			WHERE cli.id = $1`, clientID)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.ClientNotFound, "id", clientID.String()))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(preferences)
+	}
+}
+
+// UpdatePreferencesHandler handles the update of the notification channels
+// of a client
+func UpdatePreferencesHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var preferences entities.NotificationPreferencesEntity
+		err = json.NewDecoder(r.Body).Decode(&preferences)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(preferences)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		var exists bool
+		err = db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM clients WHERE id = $1)", clientID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if !exists {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.ClientNotFound, "id", clientID.String()))
+			return
+		}
+
+		preferences.ClientID = clientID
+		_, err = db.NamedExec(`
+			INSERT INTO notification_preferences (client_id, email, email_enabled, sms_enabled, whatsapp_enabled)
+			VALUES (:client_id, NULLIF(:email, ''), :email_enabled, :sms_enabled, :whatsapp_enabled)
+			ON CONFLICT (client_id) DO UPDATE SET
+				email = EXCLUDED.email,
+				email_enabled = EXCLUDED.email_enabled,
+				sms_enabled = EXCLUDED.sms_enabled,
+				whatsapp_enabled = EXCLUDED.whatsapp_enabled,
+				updated_at = CURRENT_TIMESTAMP`, preferences)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(preferences)
+	}
+}
@@ -0,0 +1,87 @@
+package productionhandlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/production"
+	"lavanderia/validation"
+)
+
+// QueueResponse is the work of the laundry, by stage
+type QueueResponse struct {
+	Stages []production.Stage `json:"stages"`
+}
+
+// QueueQuery limits GET /production/queue to a stage
+type QueueQuery struct {
+	Status string `json:"status" validate:"oneof=Separado|Lavando|Secando|Passando"`
+}
+
+// CapacityQuery is the day of GET /production/capacity, as YYYY-MM-DD
+type CapacityQuery struct {
+	Date string `json:"date" validate:"date"`
+}
+
+// QueueHandler handles the listing of the unfinished services by status,
+// each stage in the order it should be worked: same-day, express and
+// normal services, each by due date. ?status limits it to one stage.
+func QueueHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := QueueQuery{Status: r.URL.Query().Get("status")}
+		if err := validation.Struct(query); err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		statuses := production.Statuses
+		if query.Status != "" {
+			statuses = []string{query.Status}
+		}
+
+		stages, err := production.Queue(db, statuses, time.Now())
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(QueueResponse{Stages: stages})
+	}
+}
+
+// CapacityHandler handles the load of a day, today by default, against
+// the daily capacity
+func CapacityHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := CapacityQuery{Date: r.URL.Query().Get("date")}
+		if err := validation.Struct(query); err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		day := time.Now()
+		if query.Date != "" {
+			day, _ = time.ParseInLocation("2006-01-02", query.Date[:10], time.Local)
+		}
+
+		settings, err := production.LoadSettings(db)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		load, err := production.DayLoad(db, day, settings)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(load)
+	}
+}
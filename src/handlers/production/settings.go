@@ -0,0 +1,107 @@
+package productionhandlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/entities"
+	"lavanderia/production"
+	"lavanderia/validation"
+)
+
+// SettingsRequest is the request body to replace the production settings.
+// A zero capacity is unlimited; surcharges are percentages.
+type SettingsRequest struct {
+	MaxKgPerDay      float64 `json:"max_kg_per_day"`
+	MaxPiecesPerDay  int     `json:"max_pieces_per_day"`
+	ExpressSurcharge float64 `json:"express_surcharge"`
+	SameDaySurcharge float64 `json:"same_day_surcharge"`
+	SameDayCutoff    string  `json:"same_day_cutoff" validate:"required,clock"`
+}
+
+// validate checks the request and normalizes the cutoff to HH:MM
+func (req *SettingsRequest) validate() error {
+	if err := validation.Struct(req); err != nil {
+		return err
+	}
+
+	var errs apierror.FieldErrors
+	if req.MaxKgPerDay < 0 || req.MaxKgPerDay > 100000 {
+		errs = append(errs, apierror.Field("max_kg_per_day", apierror.OutOfRange, "min", "0", "max", "100000"))
+	}
+	if req.MaxPiecesPerDay < 0 || req.MaxPiecesPerDay > 100000 {
+		errs = append(errs, apierror.Field("max_pieces_per_day", apierror.OutOfRange, "min", "0", "max", "100000"))
+	}
+	if req.ExpressSurcharge < 0 || req.ExpressSurcharge > 999 {
+		errs = append(errs, apierror.Field("express_surcharge", apierror.OutOfRange, "min", "0", "max", "999"))
+	}
+	if req.SameDaySurcharge < 0 || req.SameDaySurcharge > 999 {
+		errs = append(errs, apierror.Field("same_day_surcharge", apierror.OutOfRange, "min", "0", "max", "999"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	cutoff, _ := time.Parse("15:04", req.SameDayCutoff)
+	req.SameDayCutoff = cutoff.Format("15:04")
+	return nil
+}
+
+// ShowSettingsHandler handles the display of the production settings.
+// Until they are saved, the defaults are shown.
+func ShowSettingsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		settings, err := production.LoadSettings(db)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(settings)
+	}
+}
+
+// UpdateSettingsHandler handles the replacement of the production
+// settings. New surcharges apply to the services created afterwards.
+func UpdateSettingsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req SettingsRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = req.validate()
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		var settings entities.ProductionSettingsEntity
+		err = db.Get(&settings, `
+			INSERT INTO production_settings (id, max_kg_per_day, max_pieces_per_day, express_surcharge, same_day_surcharge, same_day_cutoff, updated_at)
+			VALUES (1, $1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+			ON CONFLICT (id) DO UPDATE SET
+				max_kg_per_day = EXCLUDED.max_kg_per_day,
+				max_pieces_per_day = EXCLUDED.max_pieces_per_day,
+				express_surcharge = EXCLUDED.express_surcharge,
+				same_day_surcharge = EXCLUDED.same_day_surcharge,
+				same_day_cutoff = EXCLUDED.same_day_cutoff,
+				updated_at = EXCLUDED.updated_at
+			RETURNING `+production.SettingsColumns,
+			req.MaxKgPerDay, req.MaxPiecesPerDay, req.ExpressSurcharge, req.SameDaySurcharge, req.SameDayCutoff)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(settings)
+	}
+}
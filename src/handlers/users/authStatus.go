@@ -9,6 +9,8 @@ import (
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/joho/godotenv"
+
+	"lavanderia/apierror"
 )
 
 // AuthStatusResponse represents the structure of the response for the auth status check.
@@ -29,7 +31,7 @@ func AuthStatusHandler(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(AuthStatusResponse{IsAuthenticated: false})
 			return
 		}
-		w.WriteHeader(http.StatusBadRequest)
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidToken))
 		return
 	}
 
@@ -54,15 +56,15 @@ func AuthStatusHandler(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		if err == jwt.ErrSignatureInvalid {
-			w.WriteHeader(http.StatusUnauthorized)
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.InvalidToken))
 			return
 		}
-		w.WriteHeader(http.StatusBadRequest)
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidToken))
 		return
 	}
 
 	if !tkn.Valid {
-		w.WriteHeader(http.StatusUnauthorized)
+		apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.InvalidToken))
 		return
 	}
 
@@ -70,11 +72,11 @@ func AuthStatusHandler(w http.ResponseWriter, r *http.Request) {
 	if exp, ok := (*claims)["exp"].(float64); ok {
 		now := time.Now().Unix()
 		if int64(exp) < now {
-			w.WriteHeader(http.StatusUnauthorized)
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.InvalidToken))
 			return
 		}
 	} else {
-		w.WriteHeader(http.StatusBadRequest)
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidToken))
 		return
 	}
 
@@ -7,35 +7,57 @@ import (
 	"github.com/jmoiron/sqlx"
 	"golang.org/x/crypto/bcrypt"
 
+	"lavanderia/apierror"
 	"lavanderia/entities"
+	"lavanderia/validation"
 )
 
+// CreateUser represents the request body to create an admin user
+type CreateUser struct {
+	FirstName string `json:"first_name" validate:"required,max=255"`
+	LastName  string `json:"last_name" validate:"required,max=255"`
+	Username  string `json:"username" validate:"required,max=255"`
+	Password  string `json:"password" validate:"required,max=72"`
+}
+
 // CreateUserHandler handles the creation of a new user
 func CreateUserHandler(db *sqlx.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Parse request body
-		var newUser entities.UserEntity
-		err := json.NewDecoder(r.Body).Decode(&newUser)
+		var request CreateUser
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(request)
 		if err != nil {
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
+		newUser := entities.UserEntity{
+			FirstName: request.FirstName,
+			LastName:  request.LastName,
+			Username:  request.Username,
+		}
+
 		// Insert the new user into the database
 
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newUser.Password), bcrypt.DefaultCost)
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
 		if err != nil {
-			http.Error(w, "Failed to encrypt password", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.InternalError))
 			return
 		}
 
 		err = db.QueryRow(
-			"INSERT INTO users (first_name, last_name, username, password, role) VALUES ($1, $2, $3, $4) RETURNING id, first_name, last_name",
+			"INSERT INTO users (first_name, last_name, username, password, role) VALUES ($1, $2, $3, $4, $5) RETURNING id, first_name, last_name",
 			newUser.FirstName, newUser.LastName, newUser.Username, hashedPassword, "Admin",
 		).Scan(&newUser.ID, &newUser.FirstName, &newUser.LastName)
 
 		if err != nil {
-			http.Error(w, "Error inserting user into database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
@@ -6,6 +6,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
 )
 
 // DeleteUserHandler handles the deletion of a user by ID
@@ -15,21 +17,21 @@ func DeleteUserHandler(db *sqlx.DB) http.HandlerFunc {
 		vars := mux.Vars(r)
 		userIDStr, ok := vars["id"]
 		if !ok {
-			http.Error(w, "User ID not provided in URL", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.Required)))
 			return
 		}
 
 		// Parse userIDStr as UUID
 		userID, err := uuid.Parse(userIDStr)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
 			return
 		}
 
 		// Execute the delete query
 		_, err = db.Exec("DELETE FROM users WHERE id=$1", userID)
 		if err != nil {
-			http.Error(w, "Error deleting user from the database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
@@ -6,6 +6,7 @@ import (
 
 	"github.com/jmoiron/sqlx"
 
+	"lavanderia/apierror"
 	"lavanderia/entities"
 )
 
@@ -17,7 +18,7 @@ func ListUsersHandler(db *sqlx.DB) http.HandlerFunc {
 		err := db.Select(&users, "SELECT id, first_name, last_name  FROM users")
 
 		if err != nil {
-			http.Error(w, "Error retrieving users from database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
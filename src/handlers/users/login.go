@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -12,13 +11,16 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
 	"golang.org/x/crypto/bcrypt"
+
+	"lavanderia/apierror"
+	"lavanderia/validation"
 )
 
 // LoginRequest represents the required information for a user login attempt,
 // including the username and password fields.
 type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
 }
 
 // LoginResponse represents the required information returned
@@ -35,17 +37,6 @@ type User struct {
 	// Include other fields as necessary
 }
 
-// ValidationError is the struct for the error return
-type ValidationError struct {
-	Field   string `json:"field"`
-	Message string `json:"message"`
-	Status  int    `json:"status"` // HTTP status code
-}
-
-func (ve ValidationError) Error() string {
-	return fmt.Sprintf("%s: %s (status %d)", ve.Field, ve.Message, ve.Status)
-}
-
 // LoginHandler handles with login
 func LoginHandler(db *sqlx.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -59,19 +50,24 @@ func LoginHandler(db *sqlx.DB) http.HandlerFunc {
 
 		var req LoginRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		if err := validation.Struct(req); err != nil {
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
 		var user User
 		err = db.Get(&user, "SELECT id, username, password, role FROM users WHERE username = $1", req.Username)
 		if err != nil {
-			http.Error(w, "Invalid username or password", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidCredentials))
 			return
 		}
 
 		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-			http.Error(w, "Invalid username or password", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidCredentials))
 			return
 		}
 
@@ -85,7 +81,7 @@ func LoginHandler(db *sqlx.DB) http.HandlerFunc {
 
 		tokenString, err := token.SignedString(jwtKey)
 		if err != nil {
-			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.InternalError))
 			return
 		}
 
@@ -3,12 +3,14 @@ package handlers
 import (
 	"net/http"
 	"time"
+
+	"lavanderia/apierror"
 )
 
 // LogoutHandler remove cookies
 func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		apierror.Write(w, r, apierror.New(http.StatusMethodNotAllowed, apierror.MethodNotAllowed))
 		return
 	}
 
@@ -8,9 +8,16 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
 
-	"lavanderia/entities"
+	"lavanderia/apierror"
+	"lavanderia/validation"
 )
 
+// UpdateUser represents the fields allowed to be updated for a user
+type UpdateUser struct {
+	FirstName string `json:"first_name" validate:"required,max=255"`
+	LastName  string `json:"last_name" validate:"required,max=255"`
+}
+
 // UpdateUserHandler handles the update of user information
 func UpdateUserHandler(db *sqlx.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -18,29 +25,35 @@ func UpdateUserHandler(db *sqlx.DB) http.HandlerFunc {
 		vars := mux.Vars(r)
 		userIDStr, ok := vars["id"]
 		if !ok {
-			http.Error(w, "User ID not provided in URL", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.Required)))
 			return
 		}
 
 		// Convert userIDStr to int
 		userID, err := uuid.Parse(userIDStr)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
 			return
 		}
 
 		// Parse request body
-		var updatedUser entities.UserEntity
+		var updatedUser UpdateUser
 		err = json.NewDecoder(r.Body).Decode(&updatedUser)
 		if err != nil {
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = validation.Struct(updatedUser)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
 			return
 		}
 
 		// Update user information in the database
 		_, err = db.Exec("UPDATE users SET first_name=$1, last_name=$2 WHERE id=$3", updatedUser.FirstName, updatedUser.LastName, userID)
 		if err != nil {
-			http.Error(w, "Error updating user in the database", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
 			return
 		}
 
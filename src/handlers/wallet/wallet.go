@@ -0,0 +1,266 @@
+package wallethandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/events"
+	middleware "lavanderia/middlewares"
+	"lavanderia/queryspec"
+	"lavanderia/validation"
+	"lavanderia/wallet"
+)
+
+// WalletResponse is the balance of the wallet of a client with its
+// statement over a period. Opening is the balance before the period.
+type WalletResponse struct {
+	ClientID  uuid.UUID              `json:"client_id"`
+	Balance   float64                `json:"balance"`
+	From      string                 `json:"from"`
+	To        string                 `json:"to"`
+	Opening   float64                `json:"opening"`
+	Statement []wallet.StatementLine `json:"statement"`
+}
+
+// TopUpRequest is the request body of a prepayment into a wallet
+type TopUpRequest struct {
+	Amount float64 `json:"amount" validate:"required,positive"`
+	Reason string  `json:"reason" validate:"max=255"`
+}
+
+// AdjustmentRequest is the request body of a correction of a wallet.
+// Amount is added to the balance, so it is negative to take from it.
+type AdjustmentRequest struct {
+	Amount float64 `json:"amount" validate:"required"`
+	Reason string  `json:"reason" validate:"required,max=255"`
+}
+
+// TransactionResponse is the transaction posted to a wallet, with the
+// balance it left
+type TransactionResponse struct {
+	TransactionID uuid.UUID `json:"transaction_id"`
+	Balance       float64   `json:"balance"`
+}
+
+// ShowWalletHandler handles the display of the wallet of a client with its
+// statement. Clients only see their own wallet.
+func ShowWalletHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		err = middleware.AuthorizeClient(db, r, clientID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		from, to, err := queryspec.Period(r.URL.Query(), queryspec.MaxPeriodDays)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		response := WalletResponse{ClientID: clientID, From: from.Format("2006-01-02"), To: to.AddDate(0, 0, -1).Format("2006-01-02")}
+		response.Balance, err = wallet.Balance(db, clientID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		response.Opening, response.Statement, err = wallet.Statement(db, clientID, from, to)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// TopUpHandler handles a prepayment into the wallet of a client
+func TopUpHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req TopUpRequest
+		clientID, err := decodeClientRequest(db, r, &req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		post(db, w, r, wallet.Transaction{Type: wallet.TopUp, ClientID: clientID, Amount: req.Amount, Reason: req.Reason, CreatedBy: middleware.StaffID(db, r)})
+	}
+}
+
+// AdjustmentHandler handles a correction of the wallet of a client
+func AdjustmentHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req AdjustmentRequest
+		clientID, err := decodeClientRequest(db, r, &req)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		post(db, w, r, wallet.Transaction{Type: wallet.Adjustment, ClientID: clientID, Amount: req.Amount, Reason: req.Reason, CreatedBy: middleware.StaffID(db, r)})
+	}
+}
+
+// PayServiceHandler handles the payment of a service with the balance of
+// the wallet of its client. Clients only pay their own services.
+func PayServiceHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serviceID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			tx.Commit()
+		}()
+
+		var service struct {
+			ClientID   uuid.UUID `db:"client_id"`
+			IsPaid     bool      `db:"is_paid"`
+			TotalPrice float64   `db:"total_price"`
+		}
+		err = tx.Get(&service, "SELECT client_id, COALESCE(is_paid, false) AS is_paid, COALESCE(total_price, 0) AS total_price FROM laundry_services WHERE id = $1 FOR UPDATE", serviceID)
+		if err == sql.ErrNoRows {
+			err = apierror.NotFound("id", apierror.ServiceNotFound, "id", serviceID.String())
+		}
+		if err == nil {
+			err = middleware.AuthorizeClient(db, r, service.ClientID)
+		}
+		if err == nil && service.IsPaid {
+			err = apierror.Conflict("id", apierror.ServiceAlreadyPaid, "id", serviceID.String())
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		var response TransactionResponse
+		if service.TotalPrice > 0 {
+			response.TransactionID, err = wallet.Post(tx, wallet.Transaction{
+				Type:      wallet.Payment,
+				ClientID:  service.ClientID,
+				Amount:    -service.TotalPrice,
+				ServiceID: &serviceID,
+				Reason:    "Pagamento do serviço",
+				CreatedBy: middleware.StaffID(db, r),
+			})
+			if err != nil {
+				apierror.Write(w, r, apierror.From(walletError(tx, service.ClientID, err)))
+				return
+			}
+		}
+
+		_, err = tx.Exec("UPDATE laundry_services SET is_paid = true, payment_method = 'wallet', paid_at = CURRENT_TIMESTAMP WHERE id = $1", serviceID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		err = events.Publish(tx, events.ServicePaid, serviceID, events.Payment{
+			ServiceID:  serviceID,
+			ClientID:   service.ClientID,
+			TotalPrice: service.TotalPrice,
+			Method:     "wallet",
+		})
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		response.Balance, err = wallet.Balance(tx, service.ClientID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// post posts the transaction in its own database transaction and writes
+// the response
+func post(db *sqlx.DB, w http.ResponseWriter, r *http.Request, t wallet.Transaction) {
+	tx, err := db.Beginx()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		tx.Commit()
+	}()
+
+	var response TransactionResponse
+	response.TransactionID, err = wallet.Post(tx, t)
+	if err != nil {
+		apierror.Write(w, r, apierror.From(walletError(tx, t.ClientID, err)))
+		return
+	}
+	response.Balance, err = wallet.Balance(tx, t.ClientID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// walletError tells the balance when the wallet can't pay
+func walletError(tx *sqlx.Tx, clientID uuid.UUID, err error) error {
+	if err != wallet.ErrInsufficientBalance {
+		return err
+	}
+	balance, _ := wallet.Balance(tx, clientID)
+	return apierror.Conflict("amount", apierror.InsufficientBalance, "balance", strconv.FormatFloat(balance, 'f', 2, 64))
+}
+
+// decodeClientRequest reads the client of the URL and the request body
+// into req, validating both
+func decodeClientRequest(db *sqlx.DB, r *http.Request, req interface{}) (uuid.UUID, error) {
+	clientID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		return uuid.Nil, apierror.Field("id", apierror.InvalidUUID)
+	}
+
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		return uuid.Nil, apierror.New(http.StatusBadRequest, apierror.InvalidPayload)
+	}
+
+	err = validation.Struct(req)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return clientID, middleware.ValidateClientExists(db, clientID)
+}
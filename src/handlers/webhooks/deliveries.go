@@ -0,0 +1,111 @@
+package webhookshandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+	"lavanderia/entities"
+	"lavanderia/validation"
+	"lavanderia/webhooks"
+)
+
+// deliveryColumns selects a delivery of the delivery log
+const deliveryColumns = `id, webhook_id, event_id, event_type, status, attempts, next_attempt_at, response_code, response_body, last_error, created_at, delivered_at`
+
+// maxDeliveries is how many deliveries the log shows
+const maxDeliveries = 100
+
+// DeliveriesQuery filters the delivery log of a webhook
+type DeliveriesQuery struct {
+	Status string `json:"status" validate:"oneof=pending|succeeded|failed"`
+}
+
+// ListDeliveriesHandler handles the delivery log of a webhook, newest first
+func ListDeliveriesHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		webhookID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		query := DeliveriesQuery{Status: r.URL.Query().Get("status")}
+		if err := validation.Struct(query); err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		err = validateWebhookExists(db, webhookID)
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		deliveries := []entities.WebhookDeliveryEntity{}
+		err = db.Select(&deliveries, "SELECT "+deliveryColumns+` FROM webhook_deliveries
+			WHERE webhook_id = $1 AND ($2 = '' OR status = $2)
+			ORDER BY created_at DESC, event_id DESC
+			LIMIT $3`, webhookID, query.Status, maxDeliveries)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(deliveries)
+	}
+}
+
+// RedeliverHandler handles a manual redelivery: the delivery is queued
+// again, whatever its status, with a fresh number of attempts
+func RedeliverHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		webhookID, err := uuid.Parse(vars["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+		deliveryID, err := uuid.Parse(vars["deliveryID"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("deliveryID", apierror.InvalidUUID)))
+			return
+		}
+
+		var delivery entities.WebhookDeliveryEntity
+		err = db.Get(&delivery, `
+			UPDATE webhook_deliveries SET status = $1, attempts = 0, next_attempt_at = NOW(), last_error = NULL
+			WHERE id = $2 AND webhook_id = $3
+			RETURNING `+deliveryColumns, webhooks.StatusPending, deliveryID, webhookID)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("deliveryID", apierror.DeliveryNotFound, "id", deliveryID.String()))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(delivery)
+	}
+}
+
+func validateWebhookExists(db *sqlx.DB, webhookID uuid.UUID) error {
+	var exists bool
+	err := db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM webhooks WHERE id = $1)", webhookID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return apierror.NotFound("id", apierror.WebhookNotFound, "id", webhookID.String())
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package webhookshandlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"lavanderia/apierror"
+	"lavanderia/entities"
+	"lavanderia/events"
+	"lavanderia/validation"
+	"lavanderia/webhooks"
+)
+
+// webhookColumns selects a webhook without its secret
+const webhookColumns = `id, url, '' AS secret, event_types, description, is_active, created_at, updated_at`
+
+// WebhookRequest is the request body to create or update a webhook. A
+// secret is generated when none is sent on creation; on update, an empty
+// secret keeps the current one.
+type WebhookRequest struct {
+	URL         string   `json:"url" validate:"required,max=2048,url"`
+	Secret      string   `json:"secret" validate:"max=255"`
+	EventTypes  []string `json:"event_types" validate:"required"`
+	Description string   `json:"description" validate:"max=255"`
+	IsActive    *bool    `json:"is_active"`
+}
+
+// validate checks the request, including that every event type exists
+func (req *WebhookRequest) validate() error {
+	if err := validation.Struct(req); err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(events.Types))
+	names := make([]string, 0, len(events.Types))
+	for _, typ := range events.Types {
+		known[string(typ)] = true
+		names = append(names, string(typ))
+	}
+	for _, typ := range req.EventTypes {
+		if !known[typ] {
+			return apierror.Field("event_types", apierror.OneOf, "values", strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// ListWebhooksHandler handles the listing of the webhooks, newest first
+func ListWebhooksHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hooks := []entities.WebhookEntity{}
+		err := db.Select(&hooks, "SELECT "+webhookColumns+" FROM webhooks ORDER BY created_at DESC")
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hooks)
+	}
+}
+
+// ShowWebhookHandler handles the display of a webhook
+func ShowWebhookHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		webhookID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var hook entities.WebhookEntity
+		err = db.Get(&hook, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", webhookID)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.WebhookNotFound, "id", webhookID.String()))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hook)
+	}
+}
+
+// CreateWebhookHandler handles the registration of a webhook. The response
+// is the only one that includes the secret.
+func CreateWebhookHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req WebhookRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = req.validate()
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		if req.Secret == "" {
+			req.Secret, err = webhooks.NewSecret()
+			if err != nil {
+				apierror.Write(w, r, apierror.Internal(apierror.InternalError))
+				return
+			}
+		}
+		isActive := req.IsActive == nil || *req.IsActive
+
+		var hook entities.WebhookEntity
+		err = db.Get(&hook, `
+			INSERT INTO webhooks (url, secret, event_types, description, is_active) VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, url, secret, event_types, description, is_active, created_at, updated_at`,
+			req.URL, req.Secret, pq.StringArray(req.EventTypes), req.Description, isActive)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(hook)
+	}
+}
+
+// UpdateWebhookHandler handles the update of a webhook. Deliveries already
+// queued are still sent after the event types change.
+func UpdateWebhookHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		webhookID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		var req WebhookRequest
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidPayload))
+			return
+		}
+
+		err = req.validate()
+		if err != nil {
+			apierror.Write(w, r, apierror.From(err))
+			return
+		}
+
+		isActive := req.IsActive == nil || *req.IsActive
+
+		var hook entities.WebhookEntity
+		err = db.Get(&hook, `
+			UPDATE webhooks SET url=$1, secret=COALESCE(NULLIF($2, ''), secret), event_types=$3, description=$4, is_active=$5, updated_at=CURRENT_TIMESTAMP
+			WHERE id=$6
+			RETURNING id, url, CASE WHEN $2 = '' THEN '' ELSE secret END AS secret, event_types, description, is_active, created_at, updated_at`,
+			req.URL, req.Secret, pq.StringArray(req.EventTypes), req.Description, isActive, webhookID)
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.WebhookNotFound, "id", webhookID.String()))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hook)
+	}
+}
+
+// DeleteWebhookHandler handles the deletion of a webhook and its deliveries
+func DeleteWebhookHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		webhookID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.Field("id", apierror.InvalidUUID)))
+			return
+		}
+
+		result, err := db.Exec("DELETE FROM webhooks WHERE id = $1", webhookID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(apierror.DatabaseError))
+			return
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			apierror.Write(w, r, apierror.NotFound("id", apierror.WebhookNotFound, "id", webhookID.String()))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,93 @@
+// Package incidents follows the garments damaged or lost and the complaints
+// on the services, from their report to their resolution, when the
+// compensation is credited to the client's wallet.
+package incidents
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Types of incident
+const (
+	Damage    = "damage"
+	Loss      = "loss"
+	Complaint = "complaint"
+)
+
+// Types lists the types of incident
+var Types = []string{Damage, Loss, Complaint}
+
+// Statuses of an incident. It is reported open, may be investigated and is
+// closed either resolved or rejected.
+const (
+	Open          = "open"
+	Investigating = "investigating"
+	Resolved      = "resolved"
+	Rejected      = "rejected"
+)
+
+// Statuses lists the statuses of an incident, in the order of the workflow
+var Statuses = []string{Open, Investigating, Resolved, Rejected}
+
+// Columns selects an incident
+const Columns = `id, laundry_service_id, laundry_item_id, type, description, photos, compensation, status, resolution, created_at, resolved_at`
+
+// transitions are the statuses each status can move to
+var transitions = map[string][]string{
+	Open:          {Investigating, Resolved, Rejected},
+	Investigating: {Open, Resolved, Rejected},
+}
+
+// Closed tells whether the incident reached the end of the workflow, so it
+// can't change anymore
+func Closed(status string) bool {
+	return status == Resolved || status == Rejected
+}
+
+// CanMove tells whether an incident can move from a status to another
+func CanMove(from, to string) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportLine sums the incidents of a laundry item over a period. Incidents
+// not about an item are summed on a line without LaundryItemID.
+type ReportLine struct {
+	LaundryItemID *uuid.UUID `json:"laundry_item_id" db:"laundry_item_id"`
+	Name          string     `json:"name" db:"name"`
+	Incidents     int        `json:"incidents" db:"incidents"`
+	Damages       int        `json:"damages" db:"damages"`
+	Losses        int        `json:"losses" db:"losses"`
+	Complaints    int        `json:"complaints" db:"complaints"`
+	Pending       int        `json:"pending" db:"pending"`
+	Compensation  float64    `json:"compensation" db:"compensation"`
+}
+
+// Report sums the incidents reported from from until to by laundry item,
+// the items with the most incidents first. Compensation is what was paid
+// on the resolved ones.
+func Report(db sqlx.Queryer, from, to time.Time) ([]ReportLine, error) {
+	lines := []ReportLine{}
+	err := sqlx.Select(db, &lines, `
+		SELECT i.laundry_item_id,
+		       COALESCE(li.name, '') AS name,
+		       COUNT(*) AS incidents,
+		       COUNT(*) FILTER (WHERE i.type = 'damage') AS damages,
+		       COUNT(*) FILTER (WHERE i.type = 'loss') AS losses,
+		       COUNT(*) FILTER (WHERE i.type = 'complaint') AS complaints,
+		       COUNT(*) FILTER (WHERE i.status IN ('open', 'investigating')) AS pending,
+		       COALESCE(SUM(i.compensation) FILTER (WHERE i.status = 'resolved'), 0) AS compensation
+		FROM incidents i
+		LEFT JOIN laundry_items li ON li.id = i.laundry_item_id
+		WHERE i.created_at >= $1 AND i.created_at < $2
+		GROUP BY i.laundry_item_id, li.name
+		ORDER BY incidents DESC, name`, from, to)
+	return lines, err
+}
@@ -0,0 +1,124 @@
+// Package inventory keeps the stock of the consumables of the laundry, such
+// as detergent, softener and bags: what was bought, what was consumed,
+// recorded or estimated from wash loads, and when to buy more.
+package inventory
+
+import (
+	"errors"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/entities"
+	"lavanderia/events"
+)
+
+// Types of stock movement
+const (
+	Purchase    = "purchase"
+	Consumption = "consumption"
+	Adjustment  = "adjustment"
+)
+
+// Units a supply is counted in
+var Units = []string{"un", "kg", "g", "l", "ml"}
+
+// SupplyColumns selects a supply, telling whether it fell to its reorder level
+const SupplyColumns = `id, name, unit, stock, reorder_level, usage_per_load, usage_per_kg, is_active,
+	reorder_level > 0 AND stock <= reorder_level AS low_stock, created_at`
+
+// MovementColumns selects a stock movement
+const MovementColumns = `id, supply_id, type, quantity, unit_cost, load_id, estimated, notes, created_at`
+
+// ErrInsufficientStock is returned when a movement would take more than
+// the stock of the supply
+var ErrInsufficientStock = errors.New("inventory: insufficient stock")
+
+// Movement is a change of the stock of a supply. Quantity is positive when
+// it adds to the stock.
+type Movement struct {
+	SupplyID  uuid.UUID
+	Type      string
+	Quantity  float64
+	UnitCost  *float64
+	LoadID    *uuid.UUID
+	Estimated bool
+	Notes     string
+}
+
+// Record writes the movement and updates the stock of its supply. When the
+// stock falls to the reorder level, SupplyLowStock is published. A missing
+// supply returns sql.ErrNoRows.
+func Record(tx *sqlx.Tx, m Movement) (entities.StockMovementEntity, error) {
+	var supply entities.SupplyEntity
+	err := tx.Get(&supply, "SELECT "+SupplyColumns+" FROM supplies WHERE id = $1 FOR UPDATE", m.SupplyID)
+	if err != nil {
+		return entities.StockMovementEntity{}, err
+	}
+
+	stock := round(supply.Stock + m.Quantity)
+	if stock < 0 {
+		return entities.StockMovementEntity{}, ErrInsufficientStock
+	}
+
+	var movement entities.StockMovementEntity
+	err = tx.Get(&movement, `
+		INSERT INTO stock_movements (supply_id, type, quantity, unit_cost, load_id, estimated, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+MovementColumns,
+		m.SupplyID, m.Type, round(m.Quantity), m.UnitCost, m.LoadID, m.Estimated, m.Notes)
+	if err != nil {
+		return entities.StockMovementEntity{}, err
+	}
+
+	_, err = tx.Exec("UPDATE supplies SET stock = $1 WHERE id = $2", stock, m.SupplyID)
+	if err != nil {
+		return entities.StockMovementEntity{}, err
+	}
+
+	if supply.ReorderLevel > 0 && supply.Stock > supply.ReorderLevel && stock <= supply.ReorderLevel {
+		err = events.Publish(tx, events.SupplyLowStock, supply.ID, events.Supply{
+			SupplyID:     supply.ID,
+			Name:         supply.Name,
+			Unit:         supply.Unit,
+			Stock:        stock,
+			ReorderLevel: supply.ReorderLevel,
+		})
+	}
+	return movement, err
+}
+
+// ConsumeLoad records the consumption estimated for a wash load of weight
+// kilos from the usage of each active supply. Estimates never take more
+// than the stock.
+func ConsumeLoad(tx *sqlx.Tx, loadID uuid.UUID, weight float64) error {
+	var supplies []entities.SupplyEntity
+	err := tx.Select(&supplies, "SELECT "+SupplyColumns+" FROM supplies WHERE is_active AND (usage_per_load > 0 OR usage_per_kg > 0) ORDER BY name")
+	if err != nil {
+		return err
+	}
+
+	for _, supply := range supplies {
+		quantity := math.Min(round(supply.UsagePerLoad+supply.UsagePerKg*weight), supply.Stock)
+		if quantity <= 0 {
+			continue
+		}
+		_, err = Record(tx, Movement{
+			SupplyID:  supply.ID,
+			Type:      Consumption,
+			Quantity:  -quantity,
+			LoadID:    &loadID,
+			Estimated: true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// round keeps the precision of the stock columns
+func round(quantity float64) float64 {
+	return math.Round(quantity*1000) / 1000
+}
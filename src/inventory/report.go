@@ -0,0 +1,45 @@
+package inventory
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// ReportLine is how the stock of a supply moved over a period: from the
+// opening stock, what was purchased, consumed and adjusted, to the closing
+// stock. Estimated is the part of the consumption estimated from wash loads.
+type ReportLine struct {
+	SupplyID     uuid.UUID `json:"supply_id" db:"supply_id"`
+	Name         string    `json:"name" db:"name"`
+	Unit         string    `json:"unit" db:"unit"`
+	Opening      float64   `json:"opening" db:"opening"`
+	Purchased    float64   `json:"purchased" db:"purchased"`
+	Consumed     float64   `json:"consumed" db:"consumed"`
+	Estimated    float64   `json:"estimated" db:"estimated"`
+	Adjusted     float64   `json:"adjusted" db:"adjusted"`
+	Closing      float64   `json:"closing" db:"closing"`
+	PurchaseCost float64   `json:"purchase_cost" db:"purchase_cost"`
+}
+
+// Report sums the stock movements of every supply from from until to
+func Report(db sqlx.Queryer, from, to time.Time) ([]ReportLine, error) {
+	lines := []ReportLine{}
+	err := sqlx.Select(db, &lines, `
+		SELECT s.id AS supply_id,
+		       s.name,
+		       s.unit,
+		       s.stock - COALESCE(SUM(m.quantity), 0) AS opening,
+		       COALESCE(SUM(m.quantity) FILTER (WHERE m.type = 'purchase' AND m.created_at < $2), 0) AS purchased,
+		       COALESCE(-SUM(m.quantity) FILTER (WHERE m.type = 'consumption' AND m.created_at < $2), 0) AS consumed,
+		       COALESCE(-SUM(m.quantity) FILTER (WHERE m.type = 'consumption' AND m.estimated AND m.created_at < $2), 0) AS estimated,
+		       COALESCE(SUM(m.quantity) FILTER (WHERE m.type = 'adjustment' AND m.created_at < $2), 0) AS adjusted,
+		       s.stock - COALESCE(SUM(m.quantity) FILTER (WHERE m.created_at >= $2), 0) AS closing,
+		       COALESCE(SUM(m.quantity * m.unit_cost) FILTER (WHERE m.type = 'purchase' AND m.created_at < $2), 0) AS purchase_cost
+		FROM supplies s
+		LEFT JOIN stock_movements m ON m.supply_id = s.id AND m.created_at >= $1
+		GROUP BY s.id
+		ORDER BY s.name`, from, to)
+	return lines, err
+}
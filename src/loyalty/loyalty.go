@@ -0,0 +1,225 @@
+// Package loyalty rewards clients with points for the services they pay,
+// which they later redeem as a discount on new services. Each payment
+// earns a lot of points that expires on its own; redemptions and expiry
+// take from the lots that expire first.
+package loyalty
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/entities"
+	"lavanderia/events"
+)
+
+// Types of entry
+const (
+	Earn   = "earn"
+	Redeem = "redeem"
+	Expire = "expire"
+)
+
+// Types lists the types of entry
+var Types = []string{Earn, Redeem, Expire}
+
+// EntryColumns selects a loyalty entry
+const EntryColumns = `id, client_id, type, points, remaining, laundry_service_id, expires_at, created_at`
+
+var (
+	// ErrInactive is returned when points are redeemed while the program
+	// is turned off
+	ErrInactive = errors.New("loyalty: program inactive")
+	// ErrBelowMinimum is returned when fewer points than the minimum are
+	// redeemed
+	ErrBelowMinimum = errors.New("loyalty: below the minimum redemption")
+	// ErrDiscountTooHigh is returned when the points are worth more than
+	// the share of the price that may be paid with points
+	ErrDiscountTooHigh = errors.New("loyalty: discount above the maximum")
+	// ErrInsufficientPoints is returned when more points are redeemed than
+	// the client has
+	ErrInsufficientPoints = errors.New("loyalty: insufficient points")
+)
+
+// Lot is what is left of the points earned on a payment
+type Lot struct {
+	Points    int        `json:"points" db:"remaining"`
+	ExpiresAt *time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// Points returns the points earned by paying amount, rounded down
+func Points(settings entities.LoyaltySettingsEntity, amount float64) int {
+	if !settings.IsActive || amount <= 0 {
+		return 0
+	}
+	return int(math.Floor(amount*settings.PointsPerReal + 1e-9))
+}
+
+// Value returns what points are worth, in reais
+func Value(settings entities.LoyaltySettingsEntity, points int) float64 {
+	return math.Round(float64(points)*settings.PointValue*100) / 100
+}
+
+// Discount returns the discount of redeeming points on a service of the
+// given price, checking the redemption against the settings
+func Discount(settings entities.LoyaltySettingsEntity, points int, price float64) (float64, error) {
+	if points <= 0 {
+		return 0, nil
+	}
+	if !settings.IsActive {
+		return 0, ErrInactive
+	}
+	if points < settings.MinRedemption {
+		return 0, ErrBelowMinimum
+	}
+
+	discount := Value(settings, points)
+	if discount > math.Round(price*settings.MaxDiscount)/100 {
+		return 0, ErrDiscountTooHigh
+	}
+	return discount, nil
+}
+
+// Award records the points the client of a service earned paying amount
+// for it at paidAt. A service earns points once, so awarding it again, or
+// awarding a service since deleted, does nothing.
+func Award(q sqlx.Execer, settings entities.LoyaltySettingsEntity, serviceID uuid.UUID, amount float64, paidAt time.Time) error {
+	points := Points(settings, amount)
+	if points <= 0 {
+		return nil
+	}
+
+	var expiresAt *time.Time
+	if settings.ExpiryDays > 0 {
+		expiry := paidAt.AddDate(0, 0, settings.ExpiryDays)
+		expiresAt = &expiry
+	}
+
+	_, err := q.Exec(`
+		INSERT INTO loyalty_entries (client_id, type, points, remaining, laundry_service_id, expires_at, created_at)
+		SELECT client_id, $1, $2, $2, id, $3, $4 FROM laundry_services WHERE id = $5
+		ON CONFLICT (laundry_service_id) WHERE type = 'earn' DO NOTHING`,
+		Earn, points, expiresAt, paidAt, serviceID)
+	return err
+}
+
+// ExpirePoints records the expiry of what is left of the lots of a client
+// past their date
+func ExpirePoints(q sqlx.Execer, clientID uuid.UUID) error {
+	_, err := q.Exec(`
+		WITH due AS (
+			SELECT id, remaining, expires_at FROM loyalty_entries
+			WHERE client_id = $1 AND type = 'earn' AND remaining > 0 AND expires_at <= NOW()
+			FOR UPDATE
+		), cleared AS (
+			UPDATE loyalty_entries le SET remaining = 0 FROM due WHERE le.id = due.id
+		)
+		INSERT INTO loyalty_entries (client_id, type, points, expires_at)
+		SELECT $1, $2, -remaining, expires_at FROM due`, clientID, Expire)
+	return err
+}
+
+// RedeemPoints takes points from the lots of a client that expire first,
+// recording them as redeemed on a service
+func RedeemPoints(tx *sqlx.Tx, clientID, serviceID uuid.UUID, points int) error {
+	err := ExpirePoints(tx, clientID)
+	if err != nil {
+		return err
+	}
+
+	lots := []struct {
+		ID        uuid.UUID `db:"id"`
+		Remaining int       `db:"remaining"`
+	}{}
+	err = tx.Select(&lots, `
+		SELECT id, remaining FROM loyalty_entries
+		WHERE client_id = $1 AND type = 'earn' AND remaining > 0
+		ORDER BY expires_at NULLS LAST, created_at
+		FOR UPDATE`, clientID)
+	if err != nil {
+		return err
+	}
+
+	available := 0
+	for _, lot := range lots {
+		available += lot.Remaining
+	}
+	if available < points {
+		return ErrInsufficientPoints
+	}
+
+	left := points
+	for _, lot := range lots {
+		if left == 0 {
+			break
+		}
+		taken := min(lot.Remaining, left)
+		_, err = tx.Exec("UPDATE loyalty_entries SET remaining = remaining - $1 WHERE id = $2", taken, lot.ID)
+		if err != nil {
+			return err
+		}
+		left -= taken
+	}
+
+	_, err = tx.Exec("INSERT INTO loyalty_entries (client_id, type, points, laundry_service_id) VALUES ($1, $2, $3, $4)",
+		clientID, Redeem, -points, serviceID)
+	return err
+}
+
+// Balance returns the points a client has that haven't expired
+func Balance(q sqlx.Queryer, clientID uuid.UUID) (int, error) {
+	var balance int
+	err := sqlx.Get(q, &balance, `
+		SELECT COALESCE(SUM(remaining), 0) FROM loyalty_entries
+		WHERE client_id = $1 AND type = 'earn' AND (expires_at IS NULL OR expires_at > NOW())`, clientID)
+	return balance, err
+}
+
+// Expiring lists the lots of a client left to redeem that expire, the
+// first to expire first
+func Expiring(q sqlx.Queryer, clientID uuid.UUID) ([]Lot, error) {
+	lots := []Lot{}
+	err := sqlx.Select(q, &lots, `
+		SELECT remaining, expires_at FROM loyalty_entries
+		WHERE client_id = $1 AND type = 'earn' AND remaining > 0 AND expires_at > NOW()
+		ORDER BY expires_at`, clientID)
+	return lots, err
+}
+
+// Subscriber is the events.Handler awarding points for the services paid,
+// whether created paid or paid later, at the current settings
+func Subscriber(db *sqlx.DB) events.Handler {
+	return func(ctx context.Context, event events.Event) error {
+		var amount float64
+		switch event.Type {
+		case events.ServiceCreated:
+			var service events.Service
+			if err := json.Unmarshal(event.Payload, &service); err != nil {
+				return err
+			}
+			if !service.IsPaid {
+				return nil
+			}
+			amount = service.TotalPrice
+		case events.ServicePaid:
+			var payment events.Payment
+			if err := json.Unmarshal(event.Payload, &payment); err != nil {
+				return err
+			}
+			amount = payment.TotalPrice
+		default:
+			return nil
+		}
+
+		settings, err := LoadSettings(db)
+		if err != nil {
+			return err
+		}
+		return Award(db, settings, event.AggregateID, amount, event.OccurredAt)
+	}
+}
@@ -0,0 +1,34 @@
+package loyalty
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/entities"
+)
+
+// SettingsColumns selects the loyalty settings
+const SettingsColumns = `is_active, points_per_real, point_value, min_redemption, max_discount, expiry_days, updated_at`
+
+// DefaultSettings are used until the settings are saved: a point per real
+// paid, worth 5 cents, redeemed from 100 points for up to half of a
+// service, lasting a year
+var DefaultSettings = entities.LoyaltySettingsEntity{
+	IsActive:      true,
+	PointsPerReal: 1,
+	PointValue:    0.05,
+	MinRedemption: 100,
+	MaxDiscount:   50,
+	ExpiryDays:    365,
+}
+
+// LoadSettings reads the loyalty settings from the database
+func LoadSettings(db sqlx.Queryer) (entities.LoyaltySettingsEntity, error) {
+	var settings entities.LoyaltySettingsEntity
+	err := sqlx.Get(db, &settings, "SELECT "+SettingsColumns+" FROM loyalty_settings WHERE id = 1")
+	if err == sql.ErrNoRows {
+		return DefaultSettings, nil
+	}
+	return settings, err
+}
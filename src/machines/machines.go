@@ -0,0 +1,145 @@
+// Package machines describes the washers, dryers and irons of the laundry
+// and reports how much each one is used.
+package machines
+
+import (
+	"math"
+	"time"
+
+	"lavanderia/calendar"
+)
+
+// Types of machine
+const (
+	Washer = "washer"
+	Dryer  = "dryer"
+	Iron   = "iron"
+)
+
+// Types are the types of machine
+var Types = []string{Washer, Dryer, Iron}
+
+// Stage returns the status of the services a machine of the type works on
+func Stage(machineType string) string {
+	switch machineType {
+	case Washer:
+		return "Lavando"
+	case Dryer:
+		return "Secando"
+	}
+	return "Passando"
+}
+
+// Run is a load of a machine. EndedAt is nil while the machine runs.
+type Run struct {
+	StartedAt time.Time  `db:"started_at"`
+	EndedAt   *time.Time `db:"ended_at"`
+	Weight    float64    `db:"weight"`
+}
+
+// Day is how a machine was used on a day. Loads and kilos count on the day
+// the load started.
+type Day struct {
+	Date      string  `json:"date"`
+	Loads     int     `json:"loads"`
+	BusyHours float64 `json:"busy_hours"`
+	Kg        float64 `json:"kg"`
+}
+
+// Utilization is how a machine was used over a period. Utilization is the
+// share of the opening hours the machine ran, and AverageFill the share of
+// its capacity the loads with a weight took on average.
+type Utilization struct {
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Loads       int       `json:"loads"`
+	BusyHours   float64   `json:"busy_hours"`
+	OpenHours   float64   `json:"open_hours"`
+	Utilization float64   `json:"utilization"`
+	Kg          float64   `json:"kg"`
+	AverageFill float64   `json:"average_fill"`
+	Days        []Day     `json:"days"`
+}
+
+// Report sums runs over the period from from until to, by day. Loads still
+// running count until now.
+func Report(runs []Run, capacityKg float64, from, to, now time.Time, cal *calendar.Calendar) Utilization {
+	report := Utilization{From: from, To: to, Days: []Day{}}
+	var days []time.Time
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+		report.Days = append(report.Days, Day{Date: day.Format("2006-01-02")})
+	}
+
+	var busy, worked time.Duration
+	var fill float64
+	filled := 0
+	for _, run := range runs {
+		if !run.StartedAt.Before(from) && run.StartedAt.Before(to) {
+			report.Loads++
+			report.Kg += run.Weight
+			if run.Weight > 0 && capacityKg > 0 {
+				fill += run.Weight / capacityKg
+				filled++
+			}
+			for i, day := range days {
+				if !run.StartedAt.Before(day) && run.StartedAt.Before(day.AddDate(0, 0, 1)) {
+					report.Days[i].Loads++
+					report.Days[i].Kg += run.Weight
+				}
+			}
+		}
+
+		end := now
+		if run.EndedAt != nil {
+			end = *run.EndedAt
+		}
+		start, stop := later(run.StartedAt, from), earlier(end, to)
+		if !stop.After(start) {
+			continue
+		}
+		busy += stop.Sub(start)
+		worked += cal.Between(start, stop)
+		for i, day := range days {
+			dayStart, dayStop := later(start, day), earlier(stop, day.AddDate(0, 0, 1))
+			if dayStop.After(dayStart) {
+				report.Days[i].BusyHours += dayStop.Sub(dayStart).Hours()
+			}
+		}
+	}
+
+	open := cal.Between(from, to)
+	report.BusyHours = round(busy.Hours(), 1)
+	report.OpenHours = round(open.Hours(), 1)
+	if open > 0 {
+		report.Utilization = round(float64(worked)/float64(open), 3)
+	}
+	report.Kg = round(report.Kg, 2)
+	if filled > 0 {
+		report.AverageFill = round(fill/float64(filled), 3)
+	}
+	for i := range report.Days {
+		report.Days[i].BusyHours = round(report.Days[i].BusyHours, 1)
+		report.Days[i].Kg = round(report.Days[i].Kg, 2)
+	}
+	return report
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func round(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
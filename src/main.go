@@ -1,11 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"lavanderia/cep"
+	"lavanderia/events"
+	"lavanderia/loyalty"
+	"lavanderia/notify"
 	router "lavanderia/routes"
+	"lavanderia/stream"
+	"lavanderia/webhooks"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
@@ -36,6 +44,35 @@ func main() {
 	}
 	defer db.Close()
 
+	// Carrega a base local de CEPs usada para preencher e conferir endereços
+	if path := os.Getenv("CEP_DATASET"); path != "" {
+		provider, err := cep.LoadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cep.Use(provider)
+		log.Printf("Loaded %d CEPs from %s", provider.Len(), path)
+	}
+
+	// Envia as notificações da fila em segundo plano
+	worker := &notify.Worker{DB: db, Channels: notify.ChannelsFromEnv()}
+	go worker.Run(context.Background())
+
+	// Entrega os eventos de domínio aos assinantes em segundo plano. O
+	// intervalo curto mantém o painel de pedidos em tempo real.
+	dispatcher := &events.Dispatcher{DB: db, Interval: time.Second}
+	if os.Getenv("EVENTS_LOG") == "true" {
+		dispatcher.Subscribe("log", events.Log)
+	}
+	dispatcher.Subscribe("webhooks", webhooks.Subscriber(db))
+	dispatcher.Subscribe("stream", stream.Default.Subscriber(db))
+	dispatcher.Subscribe("loyalty", loyalty.Subscriber(db), events.ServiceCreated, events.ServicePaid)
+	go dispatcher.Run(context.Background())
+
+	// Envia os eventos aos webhooks cadastrados
+	webhookWorker := &webhooks.Worker{DB: db}
+	go webhookWorker.Run(context.Background())
+
 	routes := router.SetupRoutes(db)
 
 	env := os.Getenv("ENV") // 'development' or 'production'
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/apierror"
+)
+
+// StaffID returns the logged in user when they are staff, to record who
+// made a change, and nil for clients
+func StaffID(db sqlx.Queryer, r *http.Request) *uuid.UUID {
+	user, ok := UserFromContext(r.Context())
+	if !ok {
+		return nil
+	}
+	id, err := uuid.Parse(user.ID)
+	if err != nil {
+		return nil
+	}
+	var exists bool
+	err = sqlx.Get(db, &exists, "SELECT EXISTS(SELECT 1 FROM ONLY users WHERE id = $1)", id)
+	if err != nil || !exists {
+		return nil
+	}
+	return &id
+}
+
+// ValidateClientExists answers 404 for a client that doesn't exist
+func ValidateClientExists(db sqlx.Queryer, clientID uuid.UUID) error {
+	var exists bool
+	err := sqlx.Get(db, &exists, "SELECT EXISTS(SELECT 1 FROM clients WHERE id = $1)", clientID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return apierror.NotFound("id", apierror.ClientNotFound, "id", clientID.String())
+	}
+	return nil
+}
+
+// AuthorizeClient checks the client exists and lets the staff reach every
+// client, and clients only themselves
+func AuthorizeClient(db sqlx.Queryer, r *http.Request, clientID uuid.UUID) error {
+	err := ValidateClientExists(db, clientID)
+	if err != nil {
+		return err
+	}
+	return authorizeOwner(r, clientID)
+}
+
+// AuthorizeService checks the service exists and lets the staff reach every
+// service, and clients only their own
+func AuthorizeService(db sqlx.Queryer, r *http.Request, serviceID uuid.UUID) error {
+	var clientID uuid.UUID
+	err := sqlx.Get(db, &clientID, "SELECT client_id FROM laundry_services WHERE id = $1", serviceID)
+	if err == sql.ErrNoRows {
+		return apierror.NotFound("id", apierror.ServiceNotFound, "id", serviceID.String())
+	}
+	if err != nil {
+		return err
+	}
+	return authorizeOwner(r, clientID)
+}
+
+func authorizeOwner(r *http.Request, clientID uuid.UUID) error {
+	user, ok := UserFromContext(r.Context())
+	if !ok {
+		return apierror.New(http.StatusUnauthorized, apierror.Unauthorized)
+	}
+	if user.Role == "Client" && user.ID != clientID.String() {
+		return apierror.New(http.StatusForbidden, apierror.Forbidden)
+	}
+	return nil
+}
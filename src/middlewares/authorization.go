@@ -7,9 +7,12 @@ import (
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/joho/godotenv"
+
+	"lavanderia/apierror"
 )
 
-// RoleAuthorization checks if the user role has access to the route
+// RoleAuthorization checks if the user role has access to the route and
+// makes the user available to the handler through UserFromContext
 func RoleAuthorization(allowedRoles ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -25,11 +28,11 @@ func RoleAuthorization(allowedRoles ...string) func(http.Handler) http.Handler {
 			if err != nil {
 				if err == http.ErrNoCookie {
 					// If the cookie is not set, return an unauthorized status
-					http.Error(w, "No authentication cookie", http.StatusForbidden)
+					apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.Unauthorized))
 					return
 				}
 				// For any other type of error, return a bad request status
-				http.Error(w, "Bad request", http.StatusBadRequest)
+				apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidToken))
 				return
 			}
 
@@ -43,7 +46,7 @@ func RoleAuthorization(allowedRoles ...string) func(http.Handler) http.Handler {
 			})
 
 			if err != nil {
-				http.Error(w, "Invalid token", http.StatusForbidden)
+				apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.InvalidToken))
 				return
 			}
 
@@ -57,11 +60,11 @@ func RoleAuthorization(allowedRoles ...string) func(http.Handler) http.Handler {
 			}
 
 			if !isAllowed {
-				http.Error(w, "Forbidden", http.StatusForbidden)
+				apierror.Write(w, r, apierror.New(http.StatusForbidden, apierror.Forbidden))
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), userFromClaims(*claims))))
 		})
 	}
 }
@@ -8,6 +8,8 @@ import (
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/joho/godotenv"
+
+	"lavanderia/apierror"
 )
 
 // JWTAuthentication authenticates the user
@@ -23,10 +25,10 @@ func JWTAuthentication(next http.Handler) http.Handler {
 		cookie, err := r.Cookie("auth_token")
 		if err != nil {
 			if err == http.ErrNoCookie {
-				http.Error(w, "No authentication cookie", http.StatusUnauthorized)
+				apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.Unauthorized))
 				return
 			}
-			http.Error(w, "Bad request", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.InvalidToken))
 			return
 		}
 
@@ -41,14 +43,14 @@ func JWTAuthentication(next http.Handler) http.Handler {
 		})
 
 		if err != nil {
-			http.Error(w, "Invalid Authentication Token", http.StatusUnauthorized)
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.InvalidToken))
 			return
 		}
 
 		if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 			next.ServeHTTP(w, r)
 		} else {
-			http.Error(w, "Invalid Authentication Token", http.StatusUnauthorized)
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.InvalidToken))
 		}
 	})
 }
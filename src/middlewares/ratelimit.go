@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"lavanderia/apierror"
+)
+
+// bucket holds the requests left to a client. It refills continuously, a
+// whole limit per window.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter keeps one bucket per client IP
+type limiter struct {
+	mu      sync.Mutex
+	limit   float64
+	window  time.Duration
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// allow takes a token from the bucket of ip and, when there is none, tells
+// how long until the next one
+func (l *limiter) allow(ip string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Buckets idle for a whole window are full again, so they can go
+	if now.Sub(l.swept) > l.window {
+		for key, b := range l.buckets {
+			if now.Sub(b.last) > l.window {
+				delete(l.buckets, key)
+			}
+		}
+		l.swept = now
+	}
+
+	b, ok := l.buckets[ip]
+	if !ok {
+		b = &bucket{tokens: l.limit, last: now}
+		l.buckets[ip] = b
+	}
+
+	rate := l.limit / l.window.Seconds()
+	b.tokens = math.Min(l.limit, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// RateLimit allows each client IP limit requests per window, in bursts of up
+// to limit, and answers 429 with Retry-After beyond that. Counters are kept
+// in memory by each instance. Behind trustedProxies reverse proxies, the
+// client IP is the address the outermost of them appended to
+// X-Forwarded-For; the addresses before it are sent by the client and can't
+// be trusted.
+func RateLimit(limit int, window time.Duration, trustedProxies int) func(http.Handler) http.Handler {
+	l := &limiter{limit: float64(limit), window: window, buckets: map[string]*bucket{}}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, wait := l.allow(clientIP(r, trustedProxies), time.Now())
+			if !allowed {
+				seconds := strconv.Itoa(int(math.Ceil(wait.Seconds())))
+				w.Header().Set("Retry-After", seconds)
+				apierror.Write(w, r, apierror.New(http.StatusTooManyRequests, apierror.TooManyRequests, "seconds", seconds))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TrustedProxies reads the number of reverse proxies in front of the API
+// from TRUST_PROXY: a number of hops, or "true" for a single proxy
+func TrustedProxies(value string) int {
+	if value == "true" {
+		return 1
+	}
+	hops, err := strconv.Atoi(value)
+	if err != nil || hops < 0 {
+		return 0
+	}
+	return hops
+}
+
+func clientIP(r *http.Request, trustedProxies int) string {
+	if trustedProxies > 0 {
+		var forwarded []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, address := range strings.Split(header, ",") {
+				if address = strings.TrimSpace(address); address != "" {
+					forwarded = append(forwarded, address)
+				}
+			}
+		}
+		// Each proxy appends the address it received the request from,
+		// so the client is trustedProxies entries from the right
+		if len(forwarded) > 0 {
+			return forwarded[max(len(forwarded)-trustedProxies, 0)]
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// User is the authenticated user of a request, taken from the claims of its
+// auth_token cookie
+type User struct {
+	ID       string
+	Username string
+	Role     string
+}
+
+type userKey struct{}
+
+// WithUser returns a copy of ctx carrying the authenticated user
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the user set by RoleAuthorization, if any
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(userKey{}).(User)
+	return user, ok
+}
+
+func userFromClaims(claims jwt.MapClaims) User {
+	id, _ := claims["id"].(string)
+	username, _ := claims["username"].(string)
+	role, _ := claims["role"].(string)
+	return User{ID: id, Username: username, Role: role}
+}
@@ -0,0 +1,209 @@
+package nfse
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Namespace is the namespace of the ABRASF 2.02 documents
+const Namespace = "http://www.abrasf.org.br/nfse.xsd"
+
+// node is an element of the XML written. Elements are written in their
+// canonical form (C14N), so the bytes signed are the bytes sent.
+type node struct {
+	name     string
+	xmlns    string
+	attrs    [][2]string
+	text     string
+	children []*node
+}
+
+func element(name string, children ...*node) *node {
+	return &node{name: name, children: children}
+}
+
+func leaf(name, text string) *node {
+	return &node{name: name, text: text}
+}
+
+// add appends the children that aren't nil
+func (n *node) add(children ...*node) *node {
+	for _, child := range children {
+		if child != nil {
+			n.children = append(n.children, child)
+		}
+	}
+	return n
+}
+
+// canonical writes n in its canonical form, declaring ns, the namespace it
+// inherits, when n doesn't declare its own
+func (n *node) canonical(ns string) []byte {
+	var buf bytes.Buffer
+	n.write(&buf, ns)
+	return buf.Bytes()
+}
+
+func (n *node) write(buf *bytes.Buffer, ns string) {
+	buf.WriteString("<" + n.name)
+	if n.xmlns != "" {
+		ns = n.xmlns
+	}
+	if ns != "" {
+		buf.WriteString(` xmlns="` + escapeAttr(ns) + `"`)
+	}
+	for _, attr := range n.attrs {
+		buf.WriteString(" " + attr[0] + `="` + escapeAttr(attr[1]) + `"`)
+	}
+	buf.WriteString(">")
+	buf.WriteString(escapeText(n.text))
+	for _, child := range n.children {
+		child.write(buf, "")
+	}
+	buf.WriteString("</" + n.name + ">")
+}
+
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+
+var attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+
+func escapeText(s string) string { return textEscaper.Replace(s) }
+
+func escapeAttr(s string) string { return attrEscaper.Replace(s) }
+
+// Build writes the GerarNfseEnvio document of rps, signed by signer when
+// it isn't nil
+func Build(cfg Config, rps RPS, signer *Signer) ([]byte, error) {
+	inf := declaration(cfg, rps)
+	signed := element("Rps", inf)
+	if signer != nil {
+		signature, err := signer.sign(inf, Namespace)
+		if err != nil {
+			return nil, err
+		}
+		signed.add(signature)
+	}
+
+	envio := element("GerarNfseEnvio", signed)
+	envio.xmlns = Namespace
+	return append([]byte(`<?xml version="1.0" encoding="UTF-8"?>`), envio.canonical("")...), nil
+}
+
+// declaration is the InfDeclaracaoPrestacaoServico of rps, the element
+// signed
+func declaration(cfg Config, rps RPS) *node {
+	date := rps.IssuedAt.Format("2006-01-02")
+
+	inf := element("InfDeclaracaoPrestacaoServico",
+		element("Rps",
+			element("IdentificacaoRps",
+				leaf("Numero", strconv.FormatInt(rps.Number, 10)),
+				leaf("Serie", cfg.Series),
+				leaf("Tipo", "1"), // RPS
+			),
+			leaf("DataEmissao", date),
+			leaf("Status", "1"), // normal
+		),
+		leaf("Competencia", date),
+		element("Servico",
+			element("Valores",
+				leaf("ValorServicos", amount(rps.Amount)),
+				leaf("Aliquota", amount(cfg.ISSRate)),
+			),
+			leaf("IssRetido", "2"), // not withheld by the taker
+			leaf("ItemListaServico", cfg.ServiceItem),
+			leaf("Discriminacao", Truncate(rps.Description, 2000)),
+			leaf("CodigoMunicipio", cfg.CityCode),
+			leaf("ExigibilidadeISS", "1"), // payable
+			leaf("MunicipioIncidencia", cfg.CityCode),
+		),
+		provider(cfg),
+		taker(rps.Taker),
+		leaf("OptanteSimplesNacional", yesNo(cfg.SimplesNacional)),
+		leaf("IncentivoFiscal", "2"),
+	)
+	inf.attrs = [][2]string{{"Id", "rps" + strconv.FormatInt(rps.Number, 10)}}
+	return inf
+}
+
+func provider(cfg Config) *node {
+	prestador := element("Prestador", element("CpfCnpj", leaf("Cnpj", cfg.CNPJ)))
+	if cfg.MunicipalRegistration != "" {
+		prestador.add(leaf("InscricaoMunicipal", cfg.MunicipalRegistration))
+	}
+	return prestador
+}
+
+func taker(t Taker) *node {
+	tomador := element("Tomador")
+	if document := document(t.Document); document != nil {
+		tomador.add(element("IdentificacaoTomador", element("CpfCnpj", document)))
+	}
+	tomador.add(leaf("RazaoSocial", Truncate(t.Name, 150)))
+
+	if a := t.Address; a != nil {
+		endereco := element("Endereco",
+			leaf("Endereco", Truncate(a.Street, 125)),
+			leaf("Numero", Truncate(a.Number, 10)),
+		)
+		if a.Complement != "" {
+			endereco.add(leaf("Complemento", Truncate(a.Complement, 60)))
+		}
+		endereco.add(
+			leaf("Bairro", Truncate(a.Neighborhood, 60)),
+			leaf("Uf", a.State),
+			leaf("Cep", digits(a.PostalCode)),
+		)
+		tomador.add(endereco)
+	}
+
+	if phone := digits(t.Phone); phone != "" {
+		tomador.add(element("Contato", leaf("Telefone", Truncate(phone, 20))))
+	}
+	return tomador
+}
+
+// document is the Cpf or Cnpj element of a document, nil when it is
+// neither
+func document(s string) *node {
+	s = digits(s)
+	switch len(s) {
+	case 11:
+		return leaf("Cpf", s)
+	case 14:
+		return leaf("Cnpj", s)
+	}
+	return nil
+}
+
+func amount(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "1"
+	}
+	return "2"
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// Truncate cuts s, trimmed of spaces, to the size of its field, in
+// characters
+func Truncate(s string, size int) string {
+	s = strings.TrimSpace(s)
+	if runes := []rune(s); len(runes) > size {
+		return string(runes[:size])
+	}
+	return s
+}
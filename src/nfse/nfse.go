@@ -0,0 +1,154 @@
+// Package nfse issues the NFS-e, the Brazilian service invoice, of the
+// services paid. Each service becomes an RPS (Recibo Provisório de
+// Serviços) in the ABRASF standard XML, signed with the certificate of the
+// laundry and submitted through a Provider, which answers with the number
+// of the invoice issued by the city.
+package nfse
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Status of an invoice. An RPS is processing while it is sent to the
+// provider.
+const (
+	Processing = "processing"
+	Issued     = "issued"
+	Rejected   = "rejected"
+)
+
+// ErrNotConfigured is returned when invoices are issued without the CNPJ
+// and city of the laundry
+var ErrNotConfigured = errors.New("nfse: issuer not configured")
+
+// Config identifies the laundry to the city. ServiceItem is the item of
+// the list of services of LC 116, 14.10 (laundry and dyeing) by default,
+// and ISSRate the ISS percentage.
+type Config struct {
+	CNPJ                  string
+	MunicipalRegistration string
+	CityCode              string // IBGE code of the city of the laundry
+	ServiceItem           string
+	ISSRate               float64
+	SimplesNacional       bool
+	Series                string
+}
+
+// RPS is a service to invoice
+type RPS struct {
+	Number      int64
+	IssuedAt    time.Time
+	Amount      float64
+	Description string
+	Taker       Taker
+}
+
+// Taker is the client the service was provided to. Document is their CPF
+// or CNPJ, digits only, and may be empty for clients who didn't give one.
+type Taker struct {
+	Name     string
+	Document string
+	Phone    string
+	Address  *Address
+}
+
+// Address is the address of the taker
+type Address struct {
+	Street       string
+	Number       string
+	Complement   string
+	Neighborhood string
+	State        string
+	PostalCode   string
+}
+
+// Result is what the city answered to an RPS
+type Result struct {
+	Status           string `json:"status"`
+	Number           string `json:"number"`
+	VerificationCode string `json:"verification_code"`
+	PDFURL           string `json:"pdf_url"`
+	Message          string `json:"message"`
+}
+
+// Provider submits signed RPS to the city
+type Provider interface {
+	// Submit sends the signed GerarNfseEnvio document of rps. A rejection
+	// by the city is a Result with the Rejected status, not an error.
+	Submit(ctx context.Context, doc []byte, rps RPS) (Result, error)
+}
+
+// Issuer builds, signs and submits the RPS of the laundry
+type Issuer struct {
+	Config   Config
+	Signer   *Signer // leaves the RPS unsigned when nil
+	Provider Provider
+}
+
+// Configured reports whether the CNPJ and city of the laundry are set, so
+// an RPS can be issued
+func (i *Issuer) Configured() bool {
+	return i.Config.CNPJ != "" && i.Config.CityCode != ""
+}
+
+// Issue builds the GerarNfseEnvio document of rps, signs it and submits
+// it, returning the answer of the city and the document sent
+func (i *Issuer) Issue(ctx context.Context, rps RPS) (Result, []byte, error) {
+	if !i.Configured() {
+		return Result{}, nil, ErrNotConfigured
+	}
+
+	doc, err := Build(i.Config, rps, i.Signer)
+	if err != nil {
+		return Result{}, nil, err
+	}
+
+	result, err := i.Provider.Submit(ctx, doc, rps)
+	return result, doc, err
+}
+
+// FromEnv builds the issuer configured in the environment: NFSE_CNPJ,
+// NFSE_MUNICIPAL_REGISTRATION and NFSE_CITY_CODE identify the laundry,
+// NFSE_SERVICE_ITEM, NFSE_ISS_RATE, NFSE_SIMPLES_NACIONAL and NFSE_SERIES
+// describe its RPS. NFSE_CERT_FILE is the A1 certificate signing them, a
+// PKCS#12 file opened with NFSE_CERT_PASSWORD or a PEM file with the key.
+// RPS are submitted to the gateway at NFSE_PROVIDER_URL with
+// NFSE_PROVIDER_TOKEN; without one they go to a Fake provider, writing
+// them under NFSE_FAKE_DIR when set.
+func FromEnv() (*Issuer, error) {
+	rate, _ := strconv.ParseFloat(os.Getenv("NFSE_ISS_RATE"), 64)
+	issuer := &Issuer{Config: Config{
+		CNPJ:                  os.Getenv("NFSE_CNPJ"),
+		MunicipalRegistration: os.Getenv("NFSE_MUNICIPAL_REGISTRATION"),
+		CityCode:              os.Getenv("NFSE_CITY_CODE"),
+		ServiceItem:           os.Getenv("NFSE_SERVICE_ITEM"),
+		ISSRate:               rate,
+		SimplesNacional:       os.Getenv("NFSE_SIMPLES_NACIONAL") == "true",
+		Series:                os.Getenv("NFSE_SERIES"),
+	}}
+	if issuer.Config.ServiceItem == "" {
+		issuer.Config.ServiceItem = "14.10"
+	}
+	if issuer.Config.Series == "" {
+		issuer.Config.Series = "A"
+	}
+
+	if path := os.Getenv("NFSE_CERT_FILE"); path != "" {
+		signer, err := LoadSigner(path, os.Getenv("NFSE_CERT_PASSWORD"))
+		if err != nil {
+			return nil, err
+		}
+		issuer.Signer = signer
+	}
+
+	if url := os.Getenv("NFSE_PROVIDER_URL"); url != "" {
+		issuer.Provider = &HTTPProvider{URL: url, Token: os.Getenv("NFSE_PROVIDER_TOKEN")}
+	} else {
+		issuer.Provider = &Fake{Dir: os.Getenv("NFSE_FAKE_DIR")}
+	}
+	return issuer, nil
+}
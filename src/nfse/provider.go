@@ -0,0 +1,102 @@
+package nfse
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPProvider submits RPS through an NFS-e gateway, which relays them to
+// the web service of the city. The signed document is posted as
+// application/xml with a bearer token and the gateway answers the Result
+// as JSON, with 422 when the city rejected the RPS.
+type HTTPProvider struct {
+	URL    string
+	Token  string
+	Client *http.Client // defaults to a client with a 30 second timeout
+}
+
+// Submit implements Provider
+func (p *HTTPProvider) Submit(ctx context.Context, doc []byte, rps RPS) (Result, error) {
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(doc))
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.Token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode != http.StatusUnprocessableEntity && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return Result{}, fmt.Errorf("nfse: %s answered %d: %s", p.URL, resp.StatusCode, strings.TrimSpace(string(body[:min(len(body), 512)])))
+	}
+
+	var result Result
+	if err := json.Unmarshal(body, &result); err != nil {
+		return Result{}, fmt.Errorf("nfse: %s answered an invalid result: %w", p.URL, err)
+	}
+	if resp.StatusCode == http.StatusUnprocessableEntity {
+		result.Status = Rejected
+	}
+	if result.Status != Rejected && result.Number == "" {
+		return Result{}, fmt.Errorf("nfse: %s answered without the invoice number", p.URL)
+	}
+	if result.Status != Rejected {
+		result.Status = Issued
+	}
+	return result, nil
+}
+
+// Fake is a provider for development and tests. It issues every RPS with
+// its own number, or rejects them all with the Reject message when set,
+// and writes the documents submitted under Dir when set.
+type Fake struct {
+	Dir    string
+	Reject string
+}
+
+// Submit implements Provider
+func (f *Fake) Submit(_ context.Context, doc []byte, rps RPS) (Result, error) {
+	number := strconv.FormatInt(rps.Number, 10)
+	if f.Dir != "" {
+		err := os.MkdirAll(f.Dir, 0o755)
+		if err == nil {
+			err = os.WriteFile(filepath.Join(f.Dir, "rps"+number+".xml"), doc, 0o644)
+		}
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	if f.Reject != "" {
+		return Result{Status: Rejected, Message: f.Reject}, nil
+	}
+
+	sum := sha1.Sum(doc)
+	return Result{
+		Status:           Issued,
+		Number:           number,
+		VerificationCode: strings.ToUpper(fmt.Sprintf("%x", sum[:4])),
+		PDFURL:           "fake://nfse/" + number + ".pdf",
+	}, nil
+}
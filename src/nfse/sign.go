@@ -0,0 +1,144 @@
+package nfse
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// Algorithms of the XML signatures required by the ABRASF standard
+const (
+	xmldsig       = "http://www.w3.org/2000/09/xmldsig#"
+	c14n          = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315"
+	enveloped     = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	rsaSHA1       = "http://www.w3.org/2000/09/xmldsig#rsa-sha1"
+	sha1Algorithm = "http://www.w3.org/2000/09/xmldsig#sha1"
+)
+
+// ErrInvalidCertificate is returned when a certificate file holds no RSA
+// key with its certificate
+var ErrInvalidCertificate = errors.New("nfse: certificate file without an RSA key and its certificate")
+
+// Signer signs RPS with the A1 certificate of the laundry
+type Signer struct {
+	Key         *rsa.PrivateKey
+	Certificate *x509.Certificate
+}
+
+// LoadSigner reads the certificate at path: a PKCS#12 (.pfx) file opened
+// with password, or a PEM file with the certificate and its key
+func LoadSigner(path, password string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("nfse: reading certificate: %w", err)
+	}
+
+	var blocks []*pem.Block
+	if strings.Contains(string(data), "-----BEGIN") {
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			blocks = append(blocks, block)
+		}
+	} else {
+		blocks, err = pkcs12.ToPEM(data, password)
+		if err != nil {
+			return nil, fmt.Errorf("nfse: opening certificate: %w", err)
+		}
+	}
+
+	signer := &Signer{}
+	var certificates []*x509.Certificate
+	for _, block := range blocks {
+		switch block.Type {
+		case "CERTIFICATE":
+			certificate, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("nfse: parsing certificate: %w", err)
+			}
+			certificates = append(certificates, certificate)
+		case "PRIVATE KEY", "RSA PRIVATE KEY":
+			signer.Key = parseKey(block.Bytes)
+		}
+	}
+	if signer.Key == nil {
+		return nil, ErrInvalidCertificate
+	}
+
+	// A PKCS#12 file also carries the chain of the certificate
+	for _, certificate := range certificates {
+		if key, ok := certificate.PublicKey.(*rsa.PublicKey); ok && key.Equal(&signer.Key.PublicKey) {
+			signer.Certificate = certificate
+		}
+	}
+	if signer.Certificate == nil {
+		return nil, ErrInvalidCertificate
+	}
+	return signer, nil
+}
+
+// parseKey reads an RSA key in PKCS#1, as converted from PKCS#12, or in
+// PKCS#8
+func parseKey(der []byte) *rsa.PrivateKey {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil
+	}
+	rsaKey, _ := key.(*rsa.PrivateKey)
+	return rsaKey
+}
+
+// sign writes the enveloped XML signature of the element, which inherits
+// the namespace ns, referenced by its Id
+func (s *Signer) sign(n *node, ns string) (*node, error) {
+	id := ""
+	for _, attr := range n.attrs {
+		if attr[0] == "Id" {
+			id = attr[1]
+		}
+	}
+
+	digest := sha1.Sum(n.canonical(ns))
+
+	signedInfo := element("SignedInfo",
+		algorithm("CanonicalizationMethod", c14n),
+		algorithm("SignatureMethod", rsaSHA1),
+		element("Reference",
+			element("Transforms",
+				algorithm("Transform", enveloped),
+				algorithm("Transform", c14n),
+			),
+			algorithm("DigestMethod", sha1Algorithm),
+			leaf("DigestValue", base64.StdEncoding.EncodeToString(digest[:])),
+		),
+	)
+	signedInfo.children[2].attrs = [][2]string{{"URI", "#" + id}}
+
+	hash := sha1.Sum(signedInfo.canonical(xmldsig))
+	value, err := rsa.SignPKCS1v15(nil, s.Key, crypto.SHA1, hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("nfse: signing: %w", err)
+	}
+
+	signature := element("Signature",
+		signedInfo,
+		leaf("SignatureValue", base64.StdEncoding.EncodeToString(value)),
+		element("KeyInfo", element("X509Data", leaf("X509Certificate", base64.StdEncoding.EncodeToString(s.Certificate.Raw)))),
+	)
+	signature.xmlns = xmldsig
+	return signature, nil
+}
+
+func algorithm(name, uri string) *node {
+	return &node{name: name, attrs: [][2]string{{"Algorithm", uri}}}
+}
@@ -0,0 +1,185 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMTPChannel sends e-mails through an SMTP server
+type SMTPChannel struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send implements Channel
+func (c SMTPChannel) Send(_ context.Context, msg Message) error {
+	headers := []string{
+		"From: " + c.From,
+		"To: " + msg.Recipient,
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding: 8bit",
+	}
+	body := strings.Join(headers, "\r\n") + "\r\n\r\n" + msg.Body + "\r\n"
+
+	var auth smtp.Auth
+	if c.Username != "" {
+		auth = smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	}
+	return smtp.SendMail(c.Host+":"+c.Port, auth, c.From, []string{msg.Recipient}, []byte(body))
+}
+
+// SMSChannel sends text messages through an HTTP SMS gateway that accepts
+// {"to": "...", "message": "..."} with a bearer token
+type SMSChannel struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+// Send implements Channel
+func (c SMSChannel) Send(ctx context.Context, msg Message) error {
+	return postJSON(ctx, c.Client, c.URL, c.Token, map[string]string{"to": msg.Recipient, "message": msg.Body})
+}
+
+// WhatsAppChannel sends text messages through the WhatsApp Business Cloud
+// API. Free-form text is only delivered inside the 24 hour customer service
+// window; outside it Meta requires an approved template.
+type WhatsAppChannel struct {
+	BaseURL       string // defaults to https://graph.facebook.com/v19.0
+	PhoneNumberID string
+	Token         string
+	Client        *http.Client
+}
+
+// Send implements Channel
+func (c WhatsAppChannel) Send(ctx context.Context, msg Message) error {
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = "https://graph.facebook.com/v19.0"
+	}
+	return postJSON(ctx, c.Client, baseURL+"/"+c.PhoneNumberID+"/messages", c.Token, map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"to":                msg.Recipient,
+		"type":              "text",
+		"text":              map[string]string{"body": msg.Body},
+	})
+}
+
+func postJSON(ctx context.Context, client *http.Client, url, token string, payload interface{}) error {
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("notify: %s answered %d: %s", url, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// FileChannel is a fake channel for development. It appends each message
+// as a JSON line to Path, or writes it to the log when Path is empty.
+type FileChannel struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Send implements Channel
+func (c *FileChannel) Send(_ context.Context, msg Message) error {
+	line, err := json.Marshal(map[string]string{
+		"sent_at":   time.Now().Format(time.RFC3339),
+		"channel":   msg.Channel,
+		"recipient": msg.Recipient,
+		"subject":   msg.Subject,
+		"body":      msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	if c.Path == "" {
+		log.Printf("notification %s", line)
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, err := os.OpenFile(c.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// ChannelsFromEnv builds the channels configured in the environment:
+// SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD and SMTP_FROM for
+// e-mail, SMS_GATEWAY_URL and SMS_GATEWAY_TOKEN for SMS and
+// WHATSAPP_PHONE_NUMBER_ID and WHATSAPP_TOKEN for WhatsApp. When
+// NOTIFY_FAKE is set every channel is replaced by a FileChannel writing to
+// that file, or to the log when it is "log".
+func ChannelsFromEnv() map[string]Channel {
+	if path := os.Getenv("NOTIFY_FAKE"); path != "" {
+		if path == "log" {
+			path = ""
+		}
+		fake := &FileChannel{Path: path}
+		return map[string]Channel{Email: fake, SMS: fake, WhatsApp: fake}
+	}
+
+	channels := make(map[string]Channel)
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		channels[Email] = SMTPChannel{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		}
+	}
+	if url := os.Getenv("SMS_GATEWAY_URL"); url != "" {
+		channels[SMS] = SMSChannel{URL: url, Token: os.Getenv("SMS_GATEWAY_TOKEN")}
+	}
+	if id := os.Getenv("WHATSAPP_PHONE_NUMBER_ID"); id != "" {
+		channels[WhatsApp] = WhatsAppChannel{PhoneNumberID: id, Token: os.Getenv("WHATSAPP_TOKEN")}
+	}
+	return channels
+}
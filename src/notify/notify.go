@@ -0,0 +1,30 @@
+// Package notify sends messages to clients about their orders. Messages are
+// rendered from pt-BR templates and written to the notification_outbox table
+// in the same transaction as the change that caused them; a Worker then
+// delivers them through the channels each client enabled, retrying failures.
+package notify
+
+import "context"
+
+// Channels a client can enable
+const (
+	Email    = "email"
+	SMS      = "sms"
+	WhatsApp = "whatsapp"
+)
+
+// Message is a notification ready to be sent
+type Message struct {
+	ID        string
+	Channel   string
+	Recipient string
+	Subject   string
+	Body      string
+}
+
+// Channel delivers messages through one medium. Recipient is an e-mail
+// address for Email and a phone number with country code, digits only, for
+// SMS and WhatsApp.
+type Channel interface {
+	Send(ctx context.Context, msg Message) error
+}
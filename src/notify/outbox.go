@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Outbox statuses
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+// recipient is what Enqueue needs to know about a client
+type recipient struct {
+	FirstName       string `db:"first_name"`
+	Phone           string `db:"phone"`
+	Email           string `db:"email"`
+	EmailEnabled    bool   `db:"email_enabled"`
+	SMSEnabled      bool   `db:"sms_enabled"`
+	WhatsAppEnabled bool   `db:"whatsapp_enabled"`
+}
+
+// Enqueue renders a notification and writes it to the outbox once for each
+// channel the client enabled. Clients without preferences are notified by
+// WhatsApp. Pass the transaction of the change that caused the
+// notification, so it is only sent if that change is committed.
+//
+// A non-empty key makes the notification unique: enqueueing the same kind
+// with the same key again does nothing.
+func Enqueue(q sqlx.Ext, clientID uuid.UUID, kind Kind, data Data, key string) error {
+	var to recipient
+	err := sqlx.Get(q, &to, `
+		SELECT
+			cli.first_name,
+			COALESCE(TRIM(cli.phone), '') AS phone,
+			COALESCE(np.email, '') AS email,
+			COALESCE(np.email_enabled, FALSE) AS email_enabled,
+			COALESCE(np.sms_enabled, FALSE) AS sms_enabled,
+			COALESCE(np.whatsapp_enabled, TRUE) AS whatsapp_enabled
+		FROM clients cli
+		LEFT JOIN notification_preferences np ON np.client_id = cli.id
+		WHERE cli.id = $1`, clientID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if data.FirstName == "" {
+		data.FirstName = to.FirstName
+	}
+	subject, body, err := Render(kind, data)
+	if err != nil {
+		return err
+	}
+
+	recipients := map[string]string{}
+	if to.EmailEnabled && to.Email != "" {
+		recipients[Email] = to.Email
+	}
+	if to.SMSEnabled && to.Phone != "" {
+		recipients[SMS] = internationalPhone(to.Phone)
+	}
+	if to.WhatsAppEnabled && to.Phone != "" {
+		recipients[WhatsApp] = internationalPhone(to.Phone)
+	}
+
+	for channel, address := range recipients {
+		var dedupeKey interface{}
+		if key != "" {
+			dedupeKey = strings.Join([]string{string(kind), key, channel}, ":")
+		}
+
+		_, err = q.Exec(`
+			INSERT INTO notification_outbox (client_id, channel, kind, recipient, subject, body, dedupe_key)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (dedupe_key) DO NOTHING`,
+			clientID, channel, kind, address, subject, body, dedupeKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// internationalPhone prefixes a Brazilian phone number stored without
+// country code with 55
+func internationalPhone(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+	if len(digits) <= 11 {
+		return "55" + digits
+	}
+	return digits
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lavanderia/calendar"
+)
+
+// scan enqueues the reminders that are due. Each reminder has a key, so
+// scanning again doesn't repeat it. Days are counted on the business
+// calendar, so days the shop is closed don't count.
+func (w *Worker) scan() {
+	cal, err := calendar.Load(w.DB)
+	if err != nil {
+		log.Printf("notify: loading the business calendar: %v", err)
+		return
+	}
+
+	now := time.Now()
+	if err := w.remindOverduePayments(cal.AddDays(now, -w.OverdueDays)); err != nil {
+		log.Printf("notify: scanning overdue payments: %v", err)
+	}
+	if err := w.remindExpiringSubscriptions(cal.AddDays(now, w.ExpiryDays)); err != nil {
+		log.Printf("notify: scanning expiring subscriptions: %v", err)
+	}
+}
+
+// remindOverduePayments notifies clients of finished services that are
+// still unpaid since before the overdue date, OverdueDays business days ago
+func (w *Worker) remindOverduePayments(overdue time.Time) error {
+	var services []struct {
+		ID       uuid.UUID `db:"id"`
+		ClientID uuid.UUID `db:"client_id"`
+		Total    float64   `db:"total_price"`
+		Date     time.Time `db:"date"`
+	}
+	err := w.DB.Select(&services, `
+		SELECT id, client_id, total_price, COALESCE(completed_at, estimated_completion_date) AS date
+		FROM laundry_services
+		WHERE status = 'Finalizado'
+			AND NOT COALESCE(is_paid, FALSE)
+			AND COALESCE(total_price, 0) > 0
+			AND COALESCE(completed_at, estimated_completion_date) < $1`,
+		overdue)
+	if err != nil {
+		return err
+	}
+
+	for _, service := range services {
+		data := Data{ServiceID: service.ID.String(), Total: service.Total, Date: service.Date}
+		if err := Enqueue(w.DB, service.ClientID, PaymentOverdue, data, service.ID.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remindExpiringSubscriptions notifies monthly clients whose renewal date
+// comes before the limit, ExpiryDays business days away
+func (w *Worker) remindExpiringSubscriptions(limit time.Time) error {
+	var clients []struct {
+		ID   uuid.UUID `db:"id"`
+		Date time.Time `db:"monthly_date"`
+	}
+	err := w.DB.Select(&clients, `
+		SELECT id, monthly_date
+		FROM clients
+		WHERE is_mensal AND monthly_date BETWEEN CURRENT_DATE AND $1::date`,
+		limit.Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+
+	for _, client := range clients {
+		key := client.ID.String() + ":" + client.Date.Format("2006-01-02")
+		if err := Enqueue(w.DB, client.ID, SubscriptionExpiring, Data{Date: client.Date}, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Kind identifies why a client is notified
+type Kind string
+
+// Kinds of notification
+const (
+	ServiceCreated       Kind = "service_created"
+	ServiceReady         Kind = "service_ready"
+	PaymentOverdue       Kind = "payment_overdue"
+	SubscriptionExpiring Kind = "subscription_expiring"
+)
+
+// Data fills the templates. Date is the estimated completion date for
+// ServiceCreated, the completion date for PaymentOverdue and the renewal
+// date for SubscriptionExpiring. TrackingCode is the formatted code of
+// the receipt, given with ServiceCreated.
+type Data struct {
+	FirstName    string
+	ServiceID    string
+	Total        float64
+	Date         time.Time
+	TrackingCode string
+}
+
+var funcs = template.FuncMap{
+	"brl":    formatBRL,
+	"data":   func(t time.Time) string { return t.Format("02/01/2006") },
+	"codigo": shortCode,
+}
+
+var templates = map[Kind]struct{ subject, body *template.Template }{
+	ServiceCreated: parse(
+		"Recebemos o seu pedido {{codigo .ServiceID}}",
+		"Olá, {{.FirstName}}! Recebemos o seu pedido {{codigo .ServiceID}}. A previsão de entrega é {{data .Date}}{{if .Total}} e o valor é {{brl .Total}}{{end}}.{{if .TrackingCode}} Acompanhe pelo código {{.TrackingCode}}.{{end}} Obrigado pela preferência!",
+	),
+	ServiceReady: parse(
+		"Seu pedido {{codigo .ServiceID}} está pronto",
+		"Olá, {{.FirstName}}! Seu pedido {{codigo .ServiceID}} está pronto{{if .Total}}. Valor: {{brl .Total}}{{end}}. Estamos à sua espera!",
+	),
+	PaymentOverdue: parse(
+		"Pagamento pendente do pedido {{codigo .ServiceID}}",
+		"Olá, {{.FirstName}}. O pagamento do pedido {{codigo .ServiceID}}, no valor de {{brl .Total}}, está pendente desde {{data .Date}}. Se você já pagou, desconsidere esta mensagem.",
+	),
+	SubscriptionExpiring: parse(
+		"Sua mensalidade vence em {{data .Date}}",
+		"Olá, {{.FirstName}}! Sua mensalidade vence em {{data .Date}}. Renove para continuar aproveitando o plano mensal.",
+	),
+}
+
+func parse(subject, body string) struct{ subject, body *template.Template } {
+	return struct{ subject, body *template.Template }{
+		template.Must(template.New("subject").Funcs(funcs).Parse(subject)),
+		template.Must(template.New("body").Funcs(funcs).Parse(body)),
+	}
+}
+
+// Render returns the subject and body of a notification
+func Render(kind Kind, data Data) (string, string, error) {
+	t, ok := templates[kind]
+	if !ok {
+		return "", "", fmt.Errorf("notify: unknown kind %q", kind)
+	}
+
+	var subject, body bytes.Buffer
+	if err := t.subject.Execute(&subject, data); err != nil {
+		return "", "", err
+	}
+	if err := t.body.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+	return subject.String(), body.String(), nil
+}
+
+// formatBRL formats a value as R$ 1.234,56
+func formatBRL(value float64) string {
+	cents := int64(math.Round(value * 100))
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+
+	integer := fmt.Sprint(cents / 100)
+	var groups []string
+	for len(integer) > 3 {
+		groups = append([]string{integer[len(integer)-3:]}, groups...)
+		integer = integer[:len(integer)-3]
+	}
+	groups = append([]string{integer}, groups...)
+
+	return fmt.Sprintf("%sR$ %s,%02d", sign, strings.Join(groups, "."), cents%100)
+}
+
+// shortCode is how orders are referred to in messages: the first 8
+// characters of their ID in upper case
+func shortCode(id string) string {
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return strings.ToUpper(id)
+}
@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Worker delivers the outbox and periodically enqueues the reminders that
+// don't come from a request: overdue payments and expiring subscriptions
+type Worker struct {
+	DB       *sqlx.DB
+	Channels map[string]Channel
+
+	Interval     time.Duration // between outbox polls, defaults to 30 seconds
+	ScanInterval time.Duration // between reminder scans, defaults to 1 hour
+	BatchSize    int           // messages sent per poll, defaults to 20
+	MaxAttempts  int           // before a message is marked failed, defaults to 5
+	OverdueDays  int           // business days after completion to remind an unpaid service, defaults to 3
+	ExpiryDays   int           // business days before the renewal date to remind a subscription, defaults to 3
+}
+
+// RetryDelay is how long to wait before the next attempt after attempt
+// failed ones: 1 minute doubling up to 6 hours
+func RetryDelay(attempt int) time.Duration {
+	delay := time.Minute
+	for i := 1; i < attempt && delay < 6*time.Hour; i++ {
+		delay *= 2
+	}
+	return min(delay, 6*time.Hour)
+}
+
+// Run delivers the outbox until ctx is done
+func (w *Worker) Run(ctx context.Context) {
+	w.defaults()
+
+	poll := time.NewTicker(w.Interval)
+	defer poll.Stop()
+	scan := time.NewTicker(w.ScanInterval)
+	defer scan.Stop()
+
+	w.scan()
+	for {
+		for {
+			sent, err := w.Deliver(ctx)
+			if err != nil {
+				log.Printf("notify: delivering outbox: %v", err)
+			}
+			if sent < w.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-scan.C:
+			w.scan()
+		case <-poll.C:
+		}
+	}
+}
+
+func (w *Worker) defaults() {
+	if w.Interval <= 0 {
+		w.Interval = 30 * time.Second
+	}
+	if w.ScanInterval <= 0 {
+		w.ScanInterval = time.Hour
+	}
+	if w.BatchSize <= 0 {
+		w.BatchSize = 20
+	}
+	if w.MaxAttempts <= 0 {
+		w.MaxAttempts = 5
+	}
+	if w.OverdueDays <= 0 {
+		w.OverdueDays = 3
+	}
+	if w.ExpiryDays <= 0 {
+		w.ExpiryDays = 3
+	}
+}
+
+// outboxRow is a message claimed for delivery
+type outboxRow struct {
+	ID        string `db:"id"`
+	Channel   string `db:"channel"`
+	Recipient string `db:"recipient"`
+	Subject   string `db:"subject"`
+	Body      string `db:"body"`
+	Attempts  int    `db:"attempts"`
+}
+
+// Deliver sends one batch of due messages and returns how many were
+// attempted. Rows are locked while being sent, so several workers can run
+// at once without sending a message twice.
+func (w *Worker) Deliver(ctx context.Context) (int, error) {
+	w.defaults()
+
+	tx, err := w.DB.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	// Rolls back on the early returns; a no-op after Commit
+	defer tx.Rollback()
+
+	rows := []outboxRow{}
+	err = tx.Select(&rows, `
+		SELECT id, channel, recipient, subject, body, attempts
+		FROM notification_outbox
+		WHERE status = $1 AND next_attempt_at <= NOW()
+		ORDER BY next_attempt_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`, StatusPending, w.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, row := range rows {
+		sendErr := w.send(ctx, row)
+		if sendErr == nil {
+			_, err = tx.Exec("UPDATE notification_outbox SET status = $1, attempts = attempts + 1, sent_at = NOW(), last_error = NULL WHERE id = $2", StatusSent, row.ID)
+		} else {
+			status, attempts := StatusPending, row.Attempts+1
+			if attempts >= w.MaxAttempts {
+				status = StatusFailed
+			}
+			_, err = tx.Exec("UPDATE notification_outbox SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4 WHERE id = $5",
+				status, attempts, time.Now().Add(RetryDelay(attempts)), sendErr.Error(), row.ID)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(rows), tx.Commit()
+}
+
+func (w *Worker) send(ctx context.Context, row outboxRow) error {
+	channel, ok := w.Channels[row.Channel]
+	if !ok {
+		return fmt.Errorf("notify: channel %s is not configured", row.Channel)
+	}
+	return channel.Send(ctx, Message{ID: row.ID, Channel: row.Channel, Recipient: row.Recipient, Subject: row.Subject, Body: row.Body})
+}
@@ -0,0 +1,264 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"lavanderia/apierror"
+)
+
+// Parameter is a query string parameter accepted by an operation
+type Parameter struct {
+	Name        string
+	Description string
+	Type        string // string, integer, boolean or number
+	Format      string // e.g. date, uuid
+	Enum        []string
+	Required    bool
+}
+
+// Page describes a paginated list response, with the records under Key
+// next to the pagination fields
+type Page struct {
+	Key  string
+	Item interface{}
+}
+
+// File documents a file part of a multipart/form-data request
+type File struct{}
+
+// Operation documents a single route
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Query       []Parameter
+	Request     interface{} // request body DTO, nil when the route takes no body
+	RequestType string      // request content type, defaults to application/json
+	Response    interface{} // response body, a DTO value, a slice of DTOs or a Page
+	Status      int         // success status, defaults to 200
+	ContentType string      // success content type, defaults to application/json
+	Roles       []string    // roles allowed by RoleAuthorization, empty for public routes
+}
+
+// Spec collects the operations of the API and renders them as an OpenAPI 3
+// document
+type Spec struct {
+	Title   string
+	Version string
+
+	mu         sync.Mutex
+	operations map[string]map[string]Operation // path -> method -> operation
+	document   []byte
+}
+
+// New creates an empty specification
+func New(title, version string) *Spec {
+	return &Spec{Title: title, Version: version, operations: map[string]map[string]Operation{}}
+}
+
+// Add documents the route method path
+func (s *Spec) Add(method, path string, op Operation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.operations[path] == nil {
+		s.operations[path] = map[string]Operation{}
+	}
+	s.operations[path][strings.ToLower(method)] = op
+	s.document = nil
+}
+
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// Document renders the OpenAPI 3 document as JSON
+func (s *Spec) Document() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.document != nil {
+		return s.document, nil
+	}
+
+	schemas := newRegistry()
+	schemas.schema(reflect.TypeOf(apierror.Problem{}))
+
+	// Visit routes in a stable order so component names never change
+	// between runs
+	var routes []string
+	for path := range s.operations {
+		routes = append(routes, path)
+	}
+	sort.Strings(routes)
+
+	paths := map[string]interface{}{}
+	for _, path := range routes {
+		item := map[string]interface{}{}
+		for _, method := range []string{"get", "post", "put", "patch", "delete"} {
+			if op, ok := s.operations[path][method]; ok {
+				item[method] = s.operation(schemas, method, path, op)
+			}
+		}
+		paths[pathParam.ReplaceAllString(path, "{$1}")] = item
+	}
+
+	components := map[string]interface{}{
+		"schemas": schemas.components(),
+		"securitySchemes": map[string]interface{}{
+			"cookieAuth": map[string]interface{}{
+				"type":        "apiKey",
+				"in":          "cookie",
+				"name":        "auth_token",
+				"description": "JWT issued by POST /login",
+			},
+		},
+	}
+
+	document, err := json.MarshalIndent(map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   s.Title,
+			"version": s.Version,
+		},
+		"paths":      paths,
+		"components": components,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	s.document = document
+	return document, nil
+}
+
+func (s *Spec) operation(schemas *registry, method, path string, op Operation) map[string]interface{} {
+	operation := map[string]interface{}{
+		"summary":     op.Summary,
+		"operationId": operationID(method, path),
+	}
+	if op.Description != "" {
+		operation["description"] = op.Description
+	}
+	if len(op.Tags) > 0 {
+		operation["tags"] = op.Tags
+	}
+
+	var parameters []interface{}
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, p := range op.Query {
+		parameters = append(parameters, p.document())
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if op.Request != nil {
+		requestType := op.RequestType
+		if requestType == "" {
+			requestType = "application/json"
+		}
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				requestType: map[string]interface{}{"schema": schemas.body(op.Request)},
+			},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	if op.Response != nil {
+		contentType := op.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		success["content"] = map[string]interface{}{
+			contentType: map[string]interface{}{"schema": schemas.body(op.Response)},
+		}
+	}
+
+	responses := map[string]interface{}{strconv.Itoa(status): success}
+	if op.Request != nil || len(parameters) > 0 {
+		responses["400"] = problemResponse("Invalid request")
+	}
+	if len(op.Roles) > 0 {
+		operation["security"] = []interface{}{map[string]interface{}{"cookieAuth": []string{}}}
+		operation["x-roles"] = op.Roles
+		responses["401"] = problemResponse("Missing or invalid authentication cookie")
+		responses["403"] = problemResponse("Allowed roles: " + strings.Join(op.Roles, ", "))
+	}
+	if strings.Contains(path, "{") {
+		responses["404"] = problemResponse("Not found")
+	}
+	responses["500"] = problemResponse("Unexpected error")
+	operation["responses"] = responses
+
+	return operation
+}
+
+func (p Parameter) document() map[string]interface{} {
+	schema := map[string]interface{}{"type": p.Type}
+	if p.Type == "" {
+		schema["type"] = "string"
+	}
+	if p.Format != "" {
+		schema["format"] = p.Format
+	}
+	if len(p.Enum) > 0 {
+		schema["enum"] = p.Enum
+	}
+
+	parameter := map[string]interface{}{
+		"name":   p.Name,
+		"in":     "query",
+		"schema": schema,
+	}
+	if p.Required {
+		parameter["required"] = true
+	}
+	if p.Description != "" {
+		parameter["description"] = p.Description
+	}
+	return parameter
+}
+
+// operationID builds a stable identifier such as getServicesId from the
+// method and path
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(pathParam.ReplaceAllString(path, "$1"), func(r rune) bool {
+		return r == '/' || r == '-' || r == '_' || r == '.'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// Handler serves the document as JSON
+func (s *Spec) Handler(w http.ResponseWriter, r *http.Request) {
+	document, err := s.Document()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(apierror.InternalError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(document)
+}
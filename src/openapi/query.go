@@ -0,0 +1,58 @@
+package openapi
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"lavanderia/queryspec"
+)
+
+// ListParameters documents the pagination, sort and filter parameters of a
+// list endpoint built on the queryspec package
+func ListParameters(opts queryspec.Options) []Parameter {
+	maxPageSize := opts.MaxPageSize
+	if maxPageSize < 1 {
+		maxPageSize = 100
+	}
+
+	var sortFields []string
+	for field := range opts.SortFields {
+		sortFields = append(sortFields, field)
+	}
+	sort.Strings(sortFields)
+
+	parameters := []Parameter{
+		{Name: "page", Type: "integer", Description: "Page number, starting at 1"},
+		{Name: "page_size", Type: "integer", Description: "Records per page, at most " + strconv.Itoa(maxPageSize)},
+		{Name: "cursor", Description: "Keyset pagination cursor; send it empty to start and then the next_cursor of the previous page"},
+	}
+
+	if len(sortFields) > 0 {
+		description := "Comma separated fields, prefixed with - for descending order. Allowed: " + strings.Join(sortFields, ", ")
+		if opts.DefaultSort != "" {
+			description += ". Default: " + opts.DefaultSort
+		}
+		parameters = append(parameters, Parameter{Name: "sort", Description: description})
+	}
+
+	for _, filter := range opts.Filters {
+		switch filter.Type {
+		case queryspec.DateRange:
+			parameters = append(parameters,
+				Parameter{Name: filter.Param + "_from", Format: "date", Description: "Inclusive lower bound, a date or RFC 3339 timestamp"},
+				Parameter{Name: filter.Param + "_to", Format: "date", Description: "Upper bound, a date includes the whole day"},
+			)
+		case queryspec.Bool:
+			parameters = append(parameters, Parameter{Name: filter.Param, Type: "boolean"})
+		case queryspec.UUID:
+			parameters = append(parameters, Parameter{Name: filter.Param, Format: "uuid"})
+		case queryspec.Search, queryspec.Contains:
+			parameters = append(parameters, Parameter{Name: filter.Param, Description: "Search term"})
+		default:
+			parameters = append(parameters, Parameter{Name: filter.Param, Enum: filter.Allowed})
+		}
+	}
+
+	return parameters
+}
@@ -0,0 +1,281 @@
+package openapi
+
+import (
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type schema = map[string]interface{}
+
+// registry builds JSON schemas from Go types, keeping named structs under
+// components/schemas so they are shared between operations
+type registry struct {
+	schemas map[string]schema
+	names   map[reflect.Type]string
+}
+
+func newRegistry() *registry {
+	return &registry{schemas: map[string]schema{}, names: map[reflect.Type]string{}}
+}
+
+func (r *registry) components() map[string]interface{} {
+	components := map[string]interface{}{}
+	for name, s := range r.schemas {
+		components[name] = s
+	}
+	return components
+}
+
+// body returns the schema of a request or response body
+func (r *registry) body(v interface{}) schema {
+	if page, ok := v.(Page); ok {
+		return schema{
+			"type":     "object",
+			"required": []string{page.Key, "page_size", "total_items", "total_pages"},
+			"properties": schema{
+				page.Key:      schema{"type": "array", "items": r.schema(reflect.TypeOf(page.Item))},
+				"page":        schema{"type": "integer", "description": "Current page, omitted in cursor mode"},
+				"page_size":   schema{"type": "integer"},
+				"total_items": schema{"type": "integer"},
+				"total_pages": schema{"type": "integer"},
+				"next_cursor": schema{"type": "string", "description": "Cursor of the next page, only in cursor mode; empty on the last page"},
+			},
+		}
+	}
+	return r.schema(reflect.TypeOf(v))
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	uuidType      = reflect.TypeOf(uuid.UUID{})
+	rawType       = reflect.TypeOf(json.RawMessage{})
+	fileType      = reflect.TypeOf(File{})
+	nullableTypes = map[reflect.Type]schema{
+		reflect.TypeOf(sql.NullString{}):  {"type": "string", "nullable": true},
+		reflect.TypeOf(sql.NullTime{}):    {"type": "string", "format": "date-time", "nullable": true},
+		reflect.TypeOf(sql.NullBool{}):    {"type": "boolean", "nullable": true},
+		reflect.TypeOf(sql.NullInt64{}):   {"type": "integer", "nullable": true},
+		reflect.TypeOf(sql.NullFloat64{}): {"type": "number", "nullable": true},
+	}
+)
+
+func (r *registry) schema(t reflect.Type) schema {
+	if t == nil {
+		return schema{}
+	}
+
+	if t.Kind() == reflect.Ptr {
+		s := copySchema(r.schema(t.Elem()))
+		if _, ref := s["$ref"]; ref {
+			return schema{"allOf": []interface{}{s}, "nullable": true}
+		}
+		s["nullable"] = true
+		return s
+	}
+
+	if s, ok := nullableTypes[t]; ok {
+		return copySchema(s)
+	}
+
+	switch t {
+	case timeType:
+		return schema{"type": "string", "format": "date-time"}
+	case uuidType:
+		return schema{"type": "string", "format": "uuid"}
+	case rawType:
+		return schema{}
+	case fileType:
+		return schema{"type": "string", "format": "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return schema{"type": "number"}
+	case reflect.String:
+		return schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return schema{"type": "string", "format": "byte"}
+		}
+		return schema{"type": "array", "items": r.schema(t.Elem())}
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": r.schema(t.Elem())}
+	case reflect.Struct:
+		return r.structSchema(t)
+	}
+	return schema{}
+}
+
+func (r *registry) structSchema(t reflect.Type) schema {
+	if t.Name() == "" {
+		return r.object(t)
+	}
+
+	name, ok := r.names[t]
+	if !ok {
+		name = r.name(t)
+		r.names[t] = name
+		r.schemas[name] = schema{}
+		r.schemas[name] = r.object(t)
+	}
+	return schema{"$ref": "#/components/schemas/" + name}
+}
+
+// name picks the component name of a struct, qualifying it with its
+// package when two packages declare a struct with the same name
+func (r *registry) name(t reflect.Type) string {
+	name := t.Name()
+	if _, taken := r.schemas[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()
+	return pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+}
+
+func (r *registry) object(t reflect.Type) schema {
+	properties := schema{}
+	var required []string
+	r.fields(t, properties, &required)
+
+	s := schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func (r *registry) fields(t reflect.Type, properties schema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if tag == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				r.fields(embedded, properties, required)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		property := r.schema(f.Type)
+		if rules := f.Tag.Get("validate"); rules != "" {
+			property = applyRules(property, rules, t)
+			if hasRule(rules, "required") {
+				*required = append(*required, name)
+			}
+		}
+		properties[name] = property
+	}
+}
+
+// applyRules documents the validation package rules of a field of parent
+func applyRules(s schema, rules string, parent reflect.Type) schema {
+	s = copySchema(s)
+	var notes []string
+
+	for _, rule := range strings.Split(rules, ",") {
+		ruleName, param, _ := strings.Cut(rule, "=")
+		switch ruleName {
+		case "max":
+			if n, err := strconv.Atoi(param); err == nil {
+				s["maxLength"] = n
+			}
+		case "positive":
+			s["minimum"] = 0
+			s["exclusiveMinimum"] = true
+		case "oneof":
+			s["enum"] = strings.Split(param, "|")
+		case "cep":
+			s["pattern"] = `^\d{5}-?\d{3}$`
+		case "uf":
+			s["pattern"] = `^[A-Z]{2}$`
+			notes = append(notes, "Brazilian state abbreviation (UF)")
+		case "phone":
+			notes = append(notes, "Brazilian phone number with area code")
+		case "cpf":
+			s["pattern"] = `^\d{3}\.?\d{3}\.?\d{3}-?\d{2}$`
+		case "email":
+			s["format"] = "email"
+		case "url":
+			s["format"] = "uri"
+		case "lat":
+			s["minimum"], s["maximum"] = -90, 90
+		case "lng":
+			s["minimum"], s["maximum"] = -180, 180
+		case "clock":
+			s["pattern"] = `^\d{2}:\d{2}$`
+		case "date":
+			if s["type"] == "string" && s["format"] == nil {
+				s["format"] = "date"
+			}
+		case "future":
+			notes = append(notes, "cannot be in the past")
+		case "max_days":
+			notes = append(notes, "at most "+param+" days ahead")
+		case "required_if":
+			condition := param
+			if f, ok := parent.FieldByName(param); ok {
+				if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" {
+					condition = name
+				}
+			}
+			notes = append(notes, "required when "+condition+" is true")
+		}
+	}
+
+	if len(notes) > 0 {
+		s["description"] = strings.Join(notes, "; ")
+	}
+	return s
+}
+
+func hasRule(rules, name string) bool {
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == name {
+			return true
+		}
+	}
+	return false
+}
+
+func copySchema(s schema) schema {
+	c := make(schema, len(s))
+	for k, v := range s {
+		c[k] = v
+	}
+	return c
+}
+
+func problemResponse(description string) schema {
+	return schema{
+		"description": description,
+		"content": schema{
+			"application/problem+json": schema{"schema": schema{"$ref": "#/components/schemas/Problem"}},
+		},
+	}
+}
@@ -0,0 +1,16 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed swagger.html
+var swaggerUI []byte
+
+// UIHandler serves a Swagger UI page that loads the document from
+// /openapi.json
+func UIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(swaggerUI)
+}
@@ -0,0 +1,57 @@
+package production
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/entities"
+)
+
+// Load is the work due on a day against the capacity of the laundry.
+// Weight services count their kilos and piece services their pieces. A
+// zero limit is unlimited.
+type Load struct {
+	Date      string  `json:"date"`
+	Services  int     `json:"services"`
+	Kg        float64 `json:"kg"`
+	Pieces    int     `json:"pieces"`
+	MaxKg     float64 `json:"max_kg"`
+	MaxPieces int     `json:"max_pieces"`
+	Exceeded  bool    `json:"exceeded"`
+}
+
+// Fits tells whether kg and pieces more still fit the day
+func (l Load) Fits(kg float64, pieces int) bool {
+	if l.MaxKg > 0 && kg > 0 && l.Kg+kg > l.MaxKg {
+		return false
+	}
+	if l.MaxPieces > 0 && pieces > 0 && l.Pieces+pieces > l.MaxPieces {
+		return false
+	}
+	return true
+}
+
+// DayLoad sums the unfinished services due on the date of day
+func DayLoad(db sqlx.Queryer, day time.Time, settings entities.ProductionSettingsEntity) (Load, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	load := Load{Date: start.Format("2006-01-02"), MaxKg: settings.MaxKgPerDay, MaxPieces: settings.MaxPiecesPerDay}
+	err := sqlx.Get(db, &load, `
+		SELECT COUNT(*) AS services,
+		       COALESCE(SUM(ls.weight) FILTER (WHERE ls.is_weight), 0) AS kg,
+		       COALESCE(SUM(p.pieces) FILTER (WHERE ls.is_piece), 0) AS pieces
+		FROM laundry_services ls
+		LEFT JOIN (
+			SELECT laundry_service_id, SUM(item_quantity) AS pieces
+			FROM laundry_items_services
+			GROUP BY laundry_service_id
+		) p ON p.laundry_service_id = ls.id
+		WHERE ls.completed_at IS NULL
+		  AND ls.estimated_completion_date >= $1 AND ls.estimated_completion_date < $2`,
+		start, start.AddDate(0, 0, 1))
+	if err != nil {
+		return Load{}, err
+	}
+	load.Exceeded = (load.MaxKg > 0 && load.Kg > load.MaxKg) || (load.MaxPieces > 0 && load.Pieces > load.MaxPieces)
+	return load, nil
+}
@@ -0,0 +1,56 @@
+// Package production orders the work of the laundry by priority and due
+// date, prices the priorities and keeps the work due each day within the
+// capacity of the laundry.
+package production
+
+import (
+	"math"
+
+	"lavanderia/entities"
+)
+
+// Priorities of a service. Express services skip the queue; same-day
+// services are due when the shop closes on the day they are created.
+const (
+	Normal  = "normal"
+	Express = "express"
+	SameDay = "same_day"
+)
+
+// Priorities are the priorities of a service, from the least to the most
+// urgent
+var Priorities = []string{Normal, Express, SameDay}
+
+// Rank orders the priorities for the queue: the most urgent comes first
+func Rank(priority string) int {
+	switch priority {
+	case SameDay:
+		return 0
+	case Express:
+		return 1
+	}
+	return 2
+}
+
+// Urgent tells whether the due date of a priority is promised to the
+// client, so it can't be moved to fit the capacity
+func Urgent(priority string) bool {
+	return priority == Express || priority == SameDay
+}
+
+// Surcharge returns the percentage added to the price of a service of the
+// priority
+func Surcharge(settings entities.ProductionSettingsEntity, priority string) float64 {
+	switch priority {
+	case SameDay:
+		return settings.SameDaySurcharge
+	case Express:
+		return settings.ExpressSurcharge
+	}
+	return 0
+}
+
+// WithSurcharge adds percent to price, rounded to cents
+func WithSurcharge(price, percent float64) float64 {
+	return math.Round(price*(100+percent)) / 100
+}
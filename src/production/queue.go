@@ -0,0 +1,107 @@
+package production
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Statuses are the stages a service is worked through, in order.
+// Finished services leave the queue.
+var Statuses = []string{"Separado", "Lavando", "Secando", "Passando"}
+
+// Item is a service waiting in the queue
+type Item struct {
+	ServiceID               uuid.UUID  `json:"service_id" db:"id"`
+	ClientID                uuid.UUID  `json:"client_id" db:"client_id"`
+	ClientFirstName         string     `json:"client_first_name" db:"client_first_name"`
+	ClientLastName          string     `json:"client_last_name" db:"client_last_name"`
+	Status                  string     `json:"status" db:"status"`
+	Priority                string     `json:"priority" db:"priority"`
+	EstimatedCompletionDate *time.Time `json:"estimated_completion_date" db:"estimated_completion_date"`
+	CreatedAt               time.Time  `json:"created_at" db:"created_at"`
+	IsWeight                bool       `json:"is_weight" db:"is_weight"`
+	Weight                  float64    `json:"weight" db:"weight"`
+	Pieces                  int        `json:"pieces" db:"pieces"`
+	Overdue                 bool       `json:"overdue" db:"-"`
+}
+
+// Stage is the queue of a status
+type Stage struct {
+	Status   string `json:"status"`
+	Count    int    `json:"count"`
+	Services []Item `json:"services"`
+}
+
+// Sort orders items as they should be worked: the most urgent priority
+// first, then the earliest due date, services without one last, then the
+// oldest
+func Sort(items []Item) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if Rank(a.Priority) != Rank(b.Priority) {
+			return Rank(a.Priority) < Rank(b.Priority)
+		}
+		if (a.EstimatedCompletionDate == nil) != (b.EstimatedCompletionDate == nil) {
+			return a.EstimatedCompletionDate != nil
+		}
+		if a.EstimatedCompletionDate != nil && !a.EstimatedCompletionDate.Equal(*b.EstimatedCompletionDate) {
+			return a.EstimatedCompletionDate.Before(*b.EstimatedCompletionDate)
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+}
+
+// Group splits items into the stages of statuses, each one sorted, and
+// flags the items due before now
+func Group(items []Item, statuses []string, now time.Time) []Stage {
+	stages := make([]Stage, len(statuses))
+	index := make(map[string]int, len(statuses))
+	for i, status := range statuses {
+		stages[i] = Stage{Status: status, Services: []Item{}}
+		index[status] = i
+	}
+
+	for _, item := range items {
+		i, ok := index[item.Status]
+		if !ok {
+			continue
+		}
+		item.Overdue = item.EstimatedCompletionDate != nil && item.EstimatedCompletionDate.Before(now)
+		stages[i].Services = append(stages[i].Services, item)
+	}
+
+	for i := range stages {
+		Sort(stages[i].Services)
+		stages[i].Count = len(stages[i].Services)
+	}
+	return stages
+}
+
+// Queue reads the unfinished services of statuses and groups them into
+// their stages
+func Queue(db sqlx.Queryer, statuses []string, now time.Time) ([]Stage, error) {
+	var items []Item
+	err := sqlx.Select(db, &items, `
+		SELECT ls.id,
+		       ls.client_id,
+		       cli.first_name AS client_first_name,
+		       cli.last_name AS client_last_name,
+		       ls.status,
+		       ls.priority,
+		       ls.estimated_completion_date,
+		       ls.created_at,
+		       COALESCE(ls.is_weight, FALSE) AS is_weight,
+		       COALESCE(ls.weight, 0) AS weight,
+		       COALESCE((SELECT SUM(item_quantity) FROM laundry_items_services WHERE laundry_service_id = ls.id), 0) AS pieces
+		FROM laundry_services ls
+		JOIN clients cli ON cli.id = ls.client_id
+		WHERE ls.completed_at IS NULL AND ls.status = ANY($1)`, pq.StringArray(statuses))
+	if err != nil {
+		return nil, err
+	}
+	return Group(items, statuses, now), nil
+}
@@ -0,0 +1,31 @@
+package production
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/entities"
+)
+
+// SettingsColumns selects the production settings with the same-day
+// cutoff formatted as HH:MM
+const SettingsColumns = `max_kg_per_day, max_pieces_per_day, express_surcharge, same_day_surcharge, to_char(same_day_cutoff, 'HH24:MI') AS same_day_cutoff, updated_at`
+
+// DefaultSettings are used until the settings are saved: unlimited
+// capacity, express at 50% and same-day at 100% more, taken until noon
+var DefaultSettings = entities.ProductionSettingsEntity{
+	ExpressSurcharge: 50,
+	SameDaySurcharge: 100,
+	SameDayCutoff:    "12:00",
+}
+
+// LoadSettings reads the production settings from the database
+func LoadSettings(db sqlx.Queryer) (entities.ProductionSettingsEntity, error) {
+	var settings entities.ProductionSettingsEntity
+	err := sqlx.Get(db, &settings, "SELECT "+SettingsColumns+" FROM production_settings WHERE id = 1")
+	if err == sql.ErrNoRows {
+		return DefaultSettings, nil
+	}
+	return settings, err
+}
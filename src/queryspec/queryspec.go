@@ -0,0 +1,483 @@
+package queryspec
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lavanderia/apierror"
+	"lavanderia/validation"
+)
+
+// FilterType defines how a query string filter is parsed and compared
+type FilterType int
+
+const (
+	// Equals compares the column with the raw value
+	Equals FilterType = iota
+	// Bool parses the value as a boolean ("true"/"false")
+	Bool
+	// UUID parses the value as an UUID
+	UUID
+	// DateRange reads <param>_from and <param>_to and compares the column with both bounds
+	DateRange
+	// Search matches the value against a full text search expression
+	Search
+	// Contains matches the column case-insensitively against a substring
+	Contains
+)
+
+// Filter describes a filter accepted by a list endpoint
+type Filter struct {
+	Param   string
+	Column  string
+	Type    FilterType
+	Allowed []string // optional whitelist for Equals filters
+}
+
+// Options describes which parameters a list endpoint accepts
+type Options struct {
+	DefaultPageSize int
+	MaxPageSize     int
+	SortFields      map[string]string // sort parameter name -> SQL column
+	NullSorts       map[string]string // sort parameter name -> value sorting in place of NULL, e.g. "'infinity'::timestamp"
+	DefaultSort     string            // e.g. "-created_at" or "first_name,last_name"
+	TieBreaker      string            // unique column used to keep ordering stable, e.g. "ls.id"
+	Filters         []Filter
+}
+
+// aliases keeps the parameter names used before the query spec layer working
+var aliases = map[string]string{
+	"pageSize":   "page_size",
+	"limit":      "page_size",
+	"searchTerm": "search",
+}
+
+// Sort is a validated sort field
+type Sort struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+type condition struct {
+	column string
+	op     string
+	value  interface{}
+}
+
+// Spec is the validated pagination, sort and filter specification of a list request
+type Spec struct {
+	Page       int
+	PageSize   int
+	Sort       []Sort
+	UseCursor  bool
+	cursor     []string
+	conditions []condition
+	tieBreaker string
+}
+
+// Pagination is the pagination metadata returned by list endpoints
+type Pagination struct {
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"page_size"`
+	TotalItems int    `json:"total_items"`
+	TotalPages int    `json:"total_pages"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	cursor     bool
+}
+
+// Response builds the list response body, keeping the records under key
+// next to the pagination fields
+func (p Pagination) Response(key string, records interface{}) map[string]interface{} {
+	response := map[string]interface{}{
+		key:           records,
+		"page_size":   p.PageSize,
+		"total_items": p.TotalItems,
+		"total_pages": p.TotalPages,
+	}
+	if p.cursor {
+		response["next_cursor"] = p.NextCursor
+	} else {
+		response["page"] = p.Page
+	}
+	return response
+}
+
+// Parse validates the query string against the endpoint options
+func Parse(values url.Values, opts Options) (*Spec, error) {
+	values = normalize(values)
+
+	if opts.DefaultPageSize < 1 {
+		opts.DefaultPageSize = 10
+	}
+	if opts.MaxPageSize < 1 {
+		opts.MaxPageSize = 100
+	}
+
+	var errs apierror.FieldErrors
+	spec := &Spec{Page: 1, PageSize: opts.DefaultPageSize, tieBreaker: opts.TieBreaker}
+
+	if v := values.Get("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			errs = append(errs, apierror.Field("page", apierror.MustBePositive))
+		} else {
+			spec.Page = page
+		}
+	}
+
+	if v := values.Get("page_size"); v != "" {
+		pageSize, err := strconv.Atoi(v)
+		if err != nil || pageSize < 1 || pageSize > opts.MaxPageSize {
+			errs = append(errs, apierror.Field("page_size", apierror.OutOfRange, "min", "1", "max", strconv.Itoa(opts.MaxPageSize)))
+		} else {
+			spec.PageSize = pageSize
+		}
+	}
+
+	sortParam := values.Get("sort")
+	if sortParam == "" {
+		sortParam = opts.DefaultSort
+	}
+	for _, field := range strings.Split(sortParam, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		desc := strings.HasPrefix(field, "-")
+		name := strings.TrimPrefix(field, "-")
+		column, ok := opts.SortFields[name]
+		if !ok {
+			errs = append(errs, apierror.Field("sort", apierror.InvalidSortField, "value", name))
+			continue
+		}
+		// A NULL would make the cursor comparison NULL and end the pages
+		if fallback, ok := opts.NullSorts[name]; ok {
+			column = fmt.Sprintf("COALESCE(%s, %s)", column, fallback)
+		}
+		spec.Sort = append(spec.Sort, Sort{Field: name, Column: column, Desc: desc})
+	}
+
+	for _, filter := range opts.Filters {
+		errs = append(errs, spec.parseFilter(values, filter)...)
+	}
+
+	if v, ok := values["cursor"]; ok {
+		spec.UseCursor = true
+		if values.Get("page") != "" {
+			errs = append(errs, apierror.Field("cursor", apierror.CursorWithPage))
+		}
+		for _, s := range spec.Sort {
+			if s.Desc != spec.Sort[0].Desc {
+				errs = append(errs, apierror.Field("cursor", apierror.CursorMixedDirections))
+				break
+			}
+		}
+		if v[0] != "" {
+			cursor, err := decodeCursor(v[0])
+			if err != nil || len(cursor) != len(spec.Sort)+1 {
+				errs = append(errs, apierror.Field("cursor", apierror.InvalidCursor))
+			} else {
+				spec.cursor = cursor
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return spec, nil
+}
+
+func normalize(values url.Values) url.Values {
+	normalized := url.Values{}
+	for key, v := range values {
+		if alias, ok := aliases[key]; ok {
+			if _, exists := values[alias]; exists {
+				continue
+			}
+			key = alias
+		}
+		normalized[key] = v
+	}
+	return normalized
+}
+
+func (s *Spec) parseFilter(values url.Values, filter Filter) apierror.FieldErrors {
+	if filter.Type == DateRange {
+		var errs apierror.FieldErrors
+		if v := values.Get(filter.Param + "_from"); v != "" {
+			from, err := parseDate(v)
+			if err != nil {
+				errs = append(errs, apierror.Field(filter.Param+"_from", apierror.InvalidDate))
+			} else {
+				s.conditions = append(s.conditions, condition{column: filter.Column, op: ">=", value: from})
+			}
+		}
+		if v := values.Get(filter.Param + "_to"); v != "" {
+			to, err := parseDate(v)
+			if err != nil {
+				errs = append(errs, apierror.Field(filter.Param+"_to", apierror.InvalidDate))
+			} else {
+				// A date without time includes the whole day
+				if len(v) == len("2006-01-02") {
+					to = to.AddDate(0, 0, 1)
+				}
+				s.conditions = append(s.conditions, condition{column: filter.Column, op: "<", value: to})
+			}
+		}
+		return errs
+	}
+
+	v := values.Get(filter.Param)
+	if v == "" {
+		return nil
+	}
+
+	switch filter.Type {
+	case Bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return apierror.FieldErrors{apierror.Field(filter.Param, apierror.InvalidBoolean)}
+		}
+		s.conditions = append(s.conditions, condition{column: filter.Column, op: "=", value: b})
+	case UUID:
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return apierror.FieldErrors{apierror.Field(filter.Param, apierror.InvalidUUID)}
+		}
+		s.conditions = append(s.conditions, condition{column: filter.Column, op: "=", value: id})
+	case Search:
+		s.conditions = append(s.conditions, condition{column: filter.Column, op: "@@", value: v})
+	case Contains:
+		s.conditions = append(s.conditions, condition{column: filter.Column, op: "ILIKE", value: "%" + v + "%"})
+	default:
+		if len(filter.Allowed) > 0 && !contains(filter.Allowed, v) {
+			return apierror.FieldErrors{apierror.Field(filter.Param, apierror.OneOf, "values", strings.Join(filter.Allowed, ", "))}
+		}
+		s.conditions = append(s.conditions, condition{column: filter.Column, op: "=", value: v})
+	}
+
+	return nil
+}
+
+func parseDate(v string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Args accumulates positional arguments and hands out their placeholders
+type Args struct {
+	values []interface{}
+}
+
+// Add appends a value and returns its placeholder ($1, $2, ...)
+func (a *Args) Add(v interface{}) string {
+	a.values = append(a.values, v)
+	return fmt.Sprintf("$%d", len(a.values))
+}
+
+// Values returns the accumulated arguments
+func (a *Args) Values() []interface{} {
+	return a.values
+}
+
+// Filters returns the SQL conditions of the requested filters
+func (s *Spec) Filters(args *Args) []string {
+	conditions := make([]string, 0, len(s.conditions))
+	for _, c := range s.conditions {
+		if c.op == "@@" {
+			conditions = append(conditions, fmt.Sprintf("%s @@ plainto_tsquery('english', %s)", c.column, args.Add(c.value)))
+			continue
+		}
+		conditions = append(conditions, fmt.Sprintf("%s %s %s", c.column, c.op, args.Add(c.value)))
+	}
+	return conditions
+}
+
+// CountWhere builds the WHERE clause used to count the filtered records
+func (s *Spec) CountWhere(args *Args, base ...string) string {
+	conditions := append([]string{}, base...)
+	return where(append(conditions, s.Filters(args)...))
+}
+
+// Where builds the WHERE clause of the page query, including the cursor position
+func (s *Spec) Where(args *Args, base ...string) string {
+	conditions := append([]string{}, base...)
+	conditions = append(conditions, s.Filters(args)...)
+
+	if s.UseCursor && s.cursor != nil {
+		columns := make([]string, 0, len(s.Sort)+1)
+		placeholders := make([]string, 0, len(s.Sort)+1)
+		for i, sort := range s.Sort {
+			columns = append(columns, sort.Column)
+			placeholders = append(placeholders, args.Add(s.cursor[i]))
+		}
+		columns = append(columns, s.tieBreaker)
+		placeholders = append(placeholders, args.Add(s.cursor[len(s.Sort)]))
+
+		op := ">"
+		if s.desc() {
+			op = "<"
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), op, strings.Join(placeholders, ", ")))
+	}
+
+	return where(conditions)
+}
+
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
+
+// OrderBy builds the ORDER BY clause, always ending with the tie breaker
+func (s *Spec) OrderBy() string {
+	parts := make([]string, 0, len(s.Sort)+1)
+	for _, sort := range s.Sort {
+		parts = append(parts, sort.Column+direction(sort.Desc))
+	}
+	if s.tieBreaker != "" {
+		parts = append(parts, s.tieBreaker+direction(s.desc()))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "ORDER BY " + strings.Join(parts, ", ")
+}
+
+// desc reports the direction of the cursor comparison, which is the
+// direction of the first sort field, as cursors require a single direction.
+// The tie breaker follows it, so the row comparison matches the order.
+func (s *Spec) desc() bool {
+	return len(s.Sort) > 0 && s.Sort[0].Desc
+}
+
+func direction(desc bool) string {
+	if desc {
+		return " DESC"
+	}
+	return " ASC"
+}
+
+// Limit builds the LIMIT/OFFSET clause. In cursor mode one extra row is
+// fetched so the handler knows whether there is a next page.
+func (s *Spec) Limit(args *Args) string {
+	if s.UseCursor {
+		return "LIMIT " + args.Add(s.PageSize+1)
+	}
+	return fmt.Sprintf("LIMIT %s OFFSET %s", args.Add(s.PageSize), args.Add((s.Page-1)*s.PageSize))
+}
+
+// CursorColumns returns an expression with the sort values of a row, to be
+// selected alongside each row and passed back to Paginate
+func (s *Spec) CursorColumns() string {
+	columns := make([]string, 0, len(s.Sort)+1)
+	for _, sort := range s.Sort {
+		columns = append(columns, fmt.Sprintf("(%s)::text", sort.Column))
+	}
+	columns = append(columns, fmt.Sprintf("(%s)::text", s.tieBreaker))
+	return fmt.Sprintf("json_build_array(%s)::text", strings.Join(columns, ", "))
+}
+
+// Paginate builds the pagination metadata. rows is the number of rows
+// fetched and last is the CursorColumns value of the last row kept.
+func (s *Spec) Paginate(total int, rows int, last string) Pagination {
+	pagination := Pagination{
+		PageSize:   s.PageSize,
+		TotalItems: total,
+		TotalPages: (total + s.PageSize - 1) / s.PageSize,
+	}
+
+	if !s.UseCursor {
+		pagination.Page = s.Page
+		return pagination
+	}
+
+	pagination.cursor = true
+	if rows > s.PageSize && last != "" {
+		pagination.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(last))
+	}
+	return pagination
+}
+
+// Trim reports how many rows of the fetched page must be returned
+func (s *Spec) Trim(rows int) int {
+	if rows > s.PageSize {
+		return s.PageSize
+	}
+	return rows
+}
+
+func decodeCursor(cursor string) ([]string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// MaxPeriodDays is the longest period, in days, of the reports and listings
+// over a period
+const MaxPeriodDays = 366
+
+// PeriodQuery is the period of a report or listing, as YYYY-MM-DD, both
+// days included
+type PeriodQuery struct {
+	From string `json:"from" validate:"date"`
+	To   string `json:"to" validate:"date"`
+}
+
+// Period reads the from and to parameters of a report or listing: from its
+// first day to the day after its last one, the last 30 days by default and
+// at most maxDays long
+func Period(values url.Values, maxDays int) (time.Time, time.Time, error) {
+	query := PeriodQuery{From: values.Get("from"), To: values.Get("to")}
+	if err := validation.Struct(query); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, 1)
+	if query.To != "" {
+		to, _ = time.ParseInLocation("2006-01-02", query.To[:10], time.Local)
+		to = to.AddDate(0, 0, 1)
+	}
+	from := to.AddDate(0, 0, -30)
+	if query.From != "" {
+		from, _ = time.ParseInLocation("2006-01-02", query.From[:10], time.Local)
+	}
+
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, apierror.Field("to", apierror.EndBeforeStart)
+	}
+	if to.Sub(from) > time.Duration(maxDays)*24*time.Hour+time.Hour {
+		return time.Time{}, time.Time{}, apierror.Field("to", apierror.DateTooFar, "date", to.AddDate(0, 0, -1).Format("2006-01-02"), "days", strconv.Itoa(maxDays))
+	}
+	return from, to, nil
+}
@@ -1,57 +1,719 @@
 package routes
 
 import (
+	"lavanderia/calendar"
+	"lavanderia/cash"
+	"lavanderia/cep"
+	"lavanderia/entities"
+	"lavanderia/estimate"
+	addresseshandlers "lavanderia/handlers/addresses"
+	attachmentshandlers "lavanderia/handlers/attachments"
+	calendarhandlers "lavanderia/handlers/calendar"
+	cashhandlers "lavanderia/handlers/cash"
 	clientshandlers "lavanderia/handlers/clients"
+	incidentshandlers "lavanderia/handlers/incidents"
+	inventoryhandlers "lavanderia/handlers/inventory"
+	invoiceshandlers "lavanderia/handlers/invoices"
 	itemshandlers "lavanderia/handlers/items"
 	itemsserviceshandlers "lavanderia/handlers/laundryItemsServices"
 	serviceshandlers "lavanderia/handlers/laundryServices"
+	logisticshandlers "lavanderia/handlers/logistics"
+	loyaltyhandlers "lavanderia/handlers/loyalty"
+	machineshandlers "lavanderia/handlers/machines"
+	notificationshandlers "lavanderia/handlers/notifications"
+	productionhandlers "lavanderia/handlers/production"
 	handlers "lavanderia/handlers/users"
+	wallethandlers "lavanderia/handlers/wallet"
+	webhookshandlers "lavanderia/handlers/webhooks"
+	"lavanderia/incidents"
+	"lavanderia/inventory"
+	"lavanderia/machines"
 	middleware "lavanderia/middlewares"
+	"lavanderia/nfse"
+	"lavanderia/openapi"
+	"lavanderia/production"
+	"lavanderia/storage"
+	"lavanderia/stream"
+	"lavanderia/webhooks"
+	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
 )
 
+// router registers each route together with its OpenAPI documentation, so
+// the document served at /openapi.json always matches the routes
+type router struct {
+	public    *mux.Router
+	protected *mux.Router
+	spec      *openapi.Spec
+}
+
+// handle registers a route that doesn't require authentication
+func (r *router) handle(method, path string, handler http.HandlerFunc, op openapi.Operation) {
+	r.public.Handle(path, handler).Methods(method)
+	r.spec.Add(method, path, op)
+}
+
+// handleAnyMethod registers a public route that answers every method, as
+// it always has, documented under method
+func (r *router) handleAnyMethod(method, path string, handler http.HandlerFunc, op openapi.Operation) {
+	r.public.Handle(path, handler)
+	r.spec.Add(method, path, op)
+}
+
+// handleAuth registers a route that requires the auth_token cookie of a user
+// with one of the given roles
+func (r *router) handleAuth(method, path string, handler http.HandlerFunc, op openapi.Operation, roles ...string) {
+	r.protected.Handle(path, middleware.RoleAuthorization(roles...)(handler)).Methods(method)
+	op.Roles = roles
+	r.spec.Add(method, path, op)
+}
+
 // SetupRoutes configura as rotas HTTP para a aplicação.
 func SetupRoutes(db *sqlx.DB) *mux.Router {
-	router := mux.NewRouter()
+	mainRouter := mux.NewRouter()
 
 	// Wrap the routes you want to protect with JWTAuthentication middleware
-	protectedRoutes := router.PathPrefix("").Subrouter()
+	protectedRoutes := mainRouter.PathPrefix("").Subrouter()
 	protectedRoutes.Use(middleware.JWTAuthentication)
 
-	protectedRoutes.Handle("/services/{serviceID}/items", middleware.RoleAuthorization("Admin")(http.HandlerFunc(itemsserviceshandlers.AddItemsServicesHandler(db)))).Methods("POST")
-	protectedRoutes.Handle("/services/{serviceID}/items/{itemID}", middleware.RoleAuthorization("Admin")(http.HandlerFunc(itemsserviceshandlers.DeleteItemServiceHandler(db)))).Methods("DELETE")
-	protectedRoutes.Handle("/services/{serviceID}/items/{itemID}", middleware.RoleAuthorization("Admin")(http.HandlerFunc(itemsserviceshandlers.UpdateItemServiceHandler(db)))).Methods("PATCH")
-
-	protectedRoutes.Handle("/items", middleware.RoleAuthorization("Admin")(http.HandlerFunc(itemshandlers.CreateItemHandler(db)))).Methods("POST")
-	router.HandleFunc("/items", itemshandlers.ListItemsHandler(db)).Methods("GET")
-	router.HandleFunc("/items/{id}", itemshandlers.ShowItemHandler(db)).Methods("GET")
-	protectedRoutes.Handle("/items/{id}", middleware.RoleAuthorization("Admin")(http.HandlerFunc(itemshandlers.DeleteItemHandler(db)))).Methods("DELETE")
-	protectedRoutes.Handle("/items/{id}", middleware.RoleAuthorization("Admin")(http.HandlerFunc(itemshandlers.UpdateItemHandler(db)))).Methods("PUT")
-
-	protectedRoutes.Handle("/clients", middleware.RoleAuthorization("Admin")(http.Handler(clientshandlers.CreateClientHandler(db)))).Methods("POST")
-	protectedRoutes.Handle("/clients", middleware.RoleAuthorization("Admin")(http.HandlerFunc(clientshandlers.ListClientsHandler(db)))).Methods("GET")
-	protectedRoutes.Handle("/clients/{id}", middleware.RoleAuthorization("Admin")(http.HandlerFunc(clientshandlers.ShowClientHandler(db)))).Methods("GET")
-	protectedRoutes.Handle("/clients/{id}", middleware.RoleAuthorization("Admin")(http.HandlerFunc(clientshandlers.DeleteClientHandler(db)))).Methods("DELETE")
-	protectedRoutes.Handle("/clients/{id}", middleware.RoleAuthorization("Admin")(http.HandlerFunc(clientshandlers.UpdateClientHandler(db)))).Methods("PUT")
-	protectedRoutes.Handle("/clients/{id}/renew", middleware.RoleAuthorization("Admin")(http.HandlerFunc(clientshandlers.RenewMonthlyFeeHandler(db)))).Methods("PATCH")
-
-	protectedRoutes.Handle("/services", middleware.RoleAuthorization("Admin")(http.HandlerFunc(serviceshandlers.CreateServicesHandler(db)))).Methods("POST")
-	protectedRoutes.Handle("/services", middleware.RoleAuthorization("Admin")(http.HandlerFunc(serviceshandlers.ListServicesHandler(db)))).Methods("GET")
-	protectedRoutes.Handle("/services/client/{id}", middleware.RoleAuthorization("Admin", "Client")(http.HandlerFunc(serviceshandlers.ListServicesByClientHandler(db)))).Methods("GET")
-	protectedRoutes.Handle("/services/{id}", middleware.RoleAuthorization("Admin", "Client")(http.HandlerFunc(serviceshandlers.ShowServiceHandler(db)))).Methods("GET")
-	protectedRoutes.Handle("/services/{id}", middleware.RoleAuthorization("Admin")(http.HandlerFunc(serviceshandlers.UpdateServiceHandler(db)))).Methods("PUT")
-	protectedRoutes.Handle("/services/{id}", middleware.RoleAuthorization("Admin")(http.HandlerFunc(serviceshandlers.DeleteServiceHandler(db)))).Methods("DELETE")
-
-	router.HandleFunc("/login", handlers.LoginHandler(db)).Methods("POST")
-	protectedRoutes.Handle("/users", middleware.RoleAuthorization("Admin")(http.HandlerFunc(handlers.CreateUserHandler(db)))).Methods("POST")
-	protectedRoutes.Handle("/users", middleware.RoleAuthorization("Admin")(http.HandlerFunc(handlers.ListUsersHandler(db)))).Methods("GET")
-	protectedRoutes.Handle("/users/{id}", middleware.RoleAuthorization("Admin")(http.HandlerFunc(handlers.UpdateUserHandler(db)))).Methods("PATCH")
-	protectedRoutes.Handle("/users/{id}", middleware.RoleAuthorization("Admin")(http.HandlerFunc(handlers.DeleteUserHandler(db)))).Methods("DELETE")
-	router.HandleFunc("/api/auth/status", handlers.AuthStatusHandler)
-	router.HandleFunc("/api/auth/logout", handlers.LogoutHandler)
-
-	return router
+	spec := openapi.New("Lavanderia API", "1.0.0")
+	r := &router{public: mainRouter, protected: protectedRoutes, spec: spec}
+	store := storage.FromEnv()
+	issuer, err := nfse.FromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	r.handleAuth("POST", "/services/{serviceID}/items", itemsserviceshandlers.AddItemsServicesHandler(db), openapi.Operation{
+		Summary: "Add items to a service", Tags: []string{"services"},
+		Request: itemsserviceshandlers.LaundryItemsService{}, Response: itemsserviceshandlers.LaundryItemsService{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("DELETE", "/services/{serviceID}/items/{itemID}", itemsserviceshandlers.DeleteItemServiceHandler(db), openapi.Operation{
+		Summary: "Remove an item from a service", Tags: []string{"services"},
+	}, "Admin")
+	r.handleAuth("PATCH", "/services/{serviceID}/items/{itemID}", itemsserviceshandlers.UpdateItemServiceHandler(db), openapi.Operation{
+		Summary: "Change the quantity of an item in a service", Tags: []string{"services"},
+		Request: itemsserviceshandlers.LaundryItemsServiceUpdate{},
+	}, "Admin")
+
+	r.handleAuth("POST", "/items", itemshandlers.CreateItemHandler(db), openapi.Operation{
+		Summary: "Create an item", Tags: []string{"items"},
+		Request: entities.LaundryItemsEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handle("GET", "/items", itemshandlers.ListItemsHandler(db), openapi.Operation{
+		Summary: "List items", Tags: []string{"items"},
+		Query: openapi.ListParameters(itemshandlers.ListItemsOptions), Response: openapi.Page{Key: "items", Item: entities.LaundryItemsEntity{}},
+	})
+	r.handle("GET", "/items/{id}", itemshandlers.ShowItemHandler(db), openapi.Operation{
+		Summary: "Show an item", Tags: []string{"items"},
+		Response: entities.LaundryItemsEntity{},
+	})
+	r.handleAuth("DELETE", "/items/{id}", itemshandlers.DeleteItemHandler(db), openapi.Operation{
+		Summary: "Delete an item", Tags: []string{"items"},
+	}, "Admin")
+	r.handleAuth("PUT", "/items/{id}", itemshandlers.UpdateItemHandler(db), openapi.Operation{
+		Summary: "Update an item", Tags: []string{"items"},
+		Request: entities.LaundryItemsEntity{},
+	}, "Admin")
+
+	r.handleAuth("POST", "/clients", clientshandlers.CreateClientHandler(db), openapi.Operation{
+		Summary: "Create a client", Tags: []string{"clients"},
+		Description: "The client password is set to the phone number.",
+		Request:     clientshandlers.CreateClient{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("GET", "/clients", clientshandlers.ListClientsHandler(db), openapi.Operation{
+		Summary: "List clients", Tags: []string{"clients"},
+		Query: openapi.ListParameters(clientshandlers.ListClientsOptions), Response: openapi.Page{Key: "clients", Item: clientshandlers.ClientList{}},
+	}, "Admin")
+	r.handleAuth("GET", "/clients/{id}", clientshandlers.ShowClientHandler(db), openapi.Operation{
+		Summary: "Show a client with its address", Tags: []string{"clients"},
+		Response: clientshandlers.ClientDetail{},
+	}, "Admin")
+	r.handleAuth("DELETE", "/clients/{id}", clientshandlers.DeleteClientHandler(db), openapi.Operation{
+		Summary: "Delete a client", Tags: []string{"clients"},
+	}, "Admin")
+	r.handleAuth("PUT", "/clients/{id}", clientshandlers.UpdateClientHandler(db), openapi.Operation{
+		Summary: "Update a client and its address", Tags: []string{"clients"},
+		Request: clientshandlers.UpdateClient{},
+	}, "Admin")
+	r.handleAuth("PATCH", "/clients/{id}/renew", clientshandlers.RenewMonthlyFeeHandler(db), openapi.Operation{
+		Summary: "Renew the monthly plan of a client", Tags: []string{"clients"},
+		Request: clientshandlers.Update{},
+	}, "Admin")
+	r.handleAuth("GET", "/clients/{id}/addresses", clientshandlers.ListClientAddressesHandler(db), openapi.Operation{
+		Summary: "List the addresses of a client", Tags: []string{"clients"},
+		Response: []entities.ClientAddressEntity{},
+	}, "Admin")
+	r.handleAuth("POST", "/clients/{id}/addresses", clientshandlers.CreateClientAddressHandler(db), openapi.Operation{
+		Summary: "Add an address to a client", Tags: []string{"clients"},
+		Description: "The first address of a client is always its default.",
+		Request:     clientshandlers.ClientAddressRequest{}, Response: entities.ClientAddressEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("PUT", "/clients/{id}/addresses/{addressID}", clientshandlers.UpdateClientAddressHandler(db), openapi.Operation{
+		Summary: "Update an address of a client", Tags: []string{"clients"},
+		Description: "Marking an address as default unmarks the previous one.",
+		Request:     clientshandlers.ClientAddressRequest{}, Response: entities.ClientAddressEntity{},
+	}, "Admin")
+	r.handleAuth("DELETE", "/clients/{id}/addresses/{addressID}", clientshandlers.DeleteClientAddressHandler(db), openapi.Operation{
+		Summary: "Delete an address of a client", Tags: []string{"clients"},
+		Description: "The default address and addresses used by services cannot be deleted.",
+	}, "Admin")
+
+	r.handleAuth("GET", "/clients/{id}/wallet", wallethandlers.ShowWalletHandler(db), openapi.Operation{
+		Summary: "Balance and statement of the wallet of a client", Tags: []string{"wallet"},
+		Description: "The wallet holds the prepaid top-ups and the compensations of incidents. Each statement line tells the " +
+			"balance it left; opening is the balance before the period. Clients only see their own wallet.",
+		Query: []openapi.Parameter{
+			{Name: "from", Format: "date", Description: "Defaults to 30 days before to"},
+			{Name: "to", Format: "date", Description: "Defaults to today"},
+		},
+		Response: wallethandlers.WalletResponse{},
+	}, "Admin", "Client")
+	r.handleAuth("POST", "/clients/{id}/wallet/top-ups", wallethandlers.TopUpHandler(db), openapi.Operation{
+		Summary: "Add a prepayment to the wallet of a client", Tags: []string{"wallet"},
+		Request: wallethandlers.TopUpRequest{}, Response: wallethandlers.TransactionResponse{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("POST", "/clients/{id}/wallet/adjustments", wallethandlers.AdjustmentHandler(db), openapi.Operation{
+		Summary: "Correct the balance of the wallet of a client", Tags: []string{"wallet"},
+		Description: "amount is added to the balance, so it is negative to take from it. The balance can't go below zero.",
+		Request:     wallethandlers.AdjustmentRequest{}, Response: wallethandlers.TransactionResponse{}, Status: http.StatusCreated,
+	}, "Admin")
+
+	r.handleAuth("GET", "/clients/{id}/loyalty", loyaltyhandlers.ShowClientLoyaltyHandler(db), openapi.Operation{
+		Summary: "Loyalty points of a client", Tags: []string{"loyalty"},
+		Description: "Points are earned on each paid service and expire on their own; expiring lists them by date. " +
+			"value is what the points take off a service. Clients only see their own points.",
+		Response: loyaltyhandlers.LoyaltyResponse{},
+	}, "Admin", "Client")
+	r.handleAuth("GET", "/me/loyalty", loyaltyhandlers.MyLoyaltyHandler(db), openapi.Operation{
+		Summary: "Loyalty points of the logged in client", Tags: []string{"loyalty"},
+		Response: loyaltyhandlers.LoyaltyResponse{},
+	}, "Client")
+	r.handleAuth("GET", "/loyalty/settings", loyaltyhandlers.ShowSettingsHandler(db), openapi.Operation{
+		Summary: "Earning and redemption rules of the loyalty program", Tags: []string{"loyalty"},
+		Response: entities.LoyaltySettingsEntity{},
+	}, "Admin")
+	r.handleAuth("PUT", "/loyalty/settings", loyaltyhandlers.UpdateSettingsHandler(db), openapi.Operation{
+		Summary: "Replace the earning and redemption rules of the loyalty program", Tags: []string{"loyalty"},
+		Description: "Points are earned per real paid and redeemed at point_value each, from min_redemption points, " +
+			"for up to max_discount percent of a service. expiry_days 0 keeps points forever; points already earned keep their date.",
+		Request: loyaltyhandlers.SettingsRequest{}, Response: entities.LoyaltySettingsEntity{},
+	}, "Admin")
+
+	r.handleAuth("POST", "/services", serviceshandlers.CreateServicesHandler(db), openapi.Operation{
+		Summary: "Create a service", Tags: []string{"services"},
+		Description: "When estimated_completion_date is omitted, the date proposed by POST /services/estimate is used. " +
+			"priority (normal, express or same_day) adds its surcharge to the price. Same-day services are due when the shop closes today " +
+			"and are taken until the cutoff. Express and same-day services are refused when their day is at capacity; " +
+			"normal ones are moved to the next day with room, or accepted with the capacity_exceeded warning when their date was set. " +
+			"redeem_points takes the client's loyalty points off the price, shown as discount.",
+		Request: serviceshandlers.LaundryService{}, Response: serviceshandlers.LaundryService{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("POST", "/services/estimate", serviceshandlers.EstimateServiceHandler(db), openapi.Operation{
+		Summary: "Estimate when a new service would be ready", Tags: []string{"services"},
+		Description: "Based on how long similar finished services took in working hours, and on the work queued in each status. " +
+			"basis tells which services were similar enough: mix, size, kind, all or default when there is too little history. " +
+			"Express services skip the queue; same-day services are due when the shop closes today.",
+		Request: serviceshandlers.EstimateRequest{}, Response: estimate.Result{},
+	}, "Admin")
+	r.handleAuth("GET", "/services", serviceshandlers.ListServicesHandler(db), openapi.Operation{
+		Summary: "List services", Tags: []string{"services"},
+		Query: openapi.ListParameters(serviceshandlers.ListServicesOptions), Response: openapi.Page{Key: "services", Item: serviceshandlers.Service{}},
+	}, "Admin")
+	// Registered before /services/{id}, which would match "stream"
+	r.handleAuth("GET", "/services/stream", serviceshandlers.StreamServicesHandler(stream.Default), openapi.Operation{
+		Summary: "Stream service changes as Server-Sent Events", Tags: []string{"services"},
+		Description: "Each creation, status change, repricing or payment of a service is sent as an event named after its type " +
+			"(service.created, service.status_changed, ...) with the service as JSON data. Clients only receive their own services.",
+		Query: []openapi.Parameter{
+			{Name: "status", Description: "Only services that are in this status after the change"},
+			{Name: "client_id", Format: "uuid", Description: "Ignored for clients"},
+		},
+		Response: stream.Message{}, ContentType: "text/event-stream",
+	}, "Admin", "Client")
+	r.handleAuth("GET", "/services/client/{id}", serviceshandlers.ListServicesByClientHandler(db), openapi.Operation{
+		Summary: "List the services of a client", Tags: []string{"services"},
+		Query: openapi.ListParameters(serviceshandlers.ListServicesByClientOptions), Response: openapi.Page{Key: "services", Item: serviceshandlers.Service{}},
+	}, "Admin", "Client")
+	r.handleAuth("GET", "/services/{id}", serviceshandlers.ShowServiceHandler(db), openapi.Operation{
+		Summary: "Show a service with its items", Tags: []string{"services"},
+		Response: struct {
+			Service serviceshandlers.ServiceDetail `json:"service"`
+		}{},
+	}, "Admin", "Client")
+	r.handleAuth("PUT", "/services/{id}", serviceshandlers.UpdateServiceHandler(db), openapi.Operation{
+		Summary: "Update a service", Tags: []string{"services"},
+		Request: entities.LaundryServicesEntity{},
+	}, "Admin")
+	r.handleAuth("DELETE", "/services/{id}", serviceshandlers.DeleteServiceHandler(db), openapi.Operation{
+		Summary: "Delete a service", Tags: []string{"services"},
+	}, "Admin")
+
+	r.handleAuth("POST", "/services/{id}/pickup", logisticshandlers.BookPickupHandler(db), openapi.Operation{
+		Summary: "Schedule the pickup of a service", Tags: []string{"logistics"},
+		Description: "Posting again reschedules a pickup that is still scheduled or has failed.",
+		Request:     logisticshandlers.BookingRequest{}, Response: entities.ServiceBookingEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("POST", "/services/{id}/delivery", logisticshandlers.BookDeliveryHandler(db), openapi.Operation{
+		Summary: "Schedule the delivery of a service", Tags: []string{"logistics"},
+		Description: "Posting again reschedules a delivery that is still scheduled or has failed.",
+		Request:     logisticshandlers.BookingRequest{}, Response: entities.ServiceBookingEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("PATCH", "/logistics/bookings/{id}", logisticshandlers.UpdateBookingHandler(db), openapi.Operation{
+		Summary: "Update the status, driver or notes of a booking", Tags: []string{"logistics"},
+		Request: logisticshandlers.BookingUpdate{}, Response: entities.ServiceBookingEntity{},
+	}, "Admin")
+	r.handleAuth("GET", "/logistics/slots", logisticshandlers.ListTimeSlotsHandler(db), openapi.Operation{
+		Summary: "List time slots", Tags: []string{"logistics"},
+		Response: []entities.TimeSlotEntity{},
+	}, "Admin")
+	r.handleAuth("POST", "/logistics/slots", logisticshandlers.CreateTimeSlotHandler(db), openapi.Operation{
+		Summary: "Create a time slot", Tags: []string{"logistics"},
+		Request: logisticshandlers.TimeSlotRequest{}, Response: entities.TimeSlotEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("GET", "/logistics/slots/availability", logisticshandlers.SlotAvailabilityHandler(db), openapi.Operation{
+		Summary: "Remaining capacity of the active time slots on a day", Tags: []string{"logistics"},
+		Query:    []openapi.Parameter{{Name: "date", Format: "date", Required: true}},
+		Response: []logisticshandlers.SlotAvailability{},
+	}, "Admin")
+	r.handleAuth("PUT", "/logistics/slots/{id}", logisticshandlers.UpdateTimeSlotHandler(db), openapi.Operation{
+		Summary: "Update a time slot", Tags: []string{"logistics"},
+		Request: logisticshandlers.TimeSlotRequest{}, Response: entities.TimeSlotEntity{},
+	}, "Admin")
+	r.handleAuth("DELETE", "/logistics/slots/{id}", logisticshandlers.DeleteTimeSlotHandler(db), openapi.Operation{
+		Summary: "Delete a time slot that was never booked", Tags: []string{"logistics"},
+	}, "Admin")
+	r.handleAuth("GET", "/logistics/route-sheet", logisticshandlers.RouteSheetHandler(db), openapi.Operation{
+		Summary: "Pickups and deliveries of a day ordered by neighbourhood and postal code", Tags: []string{"logistics"},
+		Query: []openapi.Parameter{
+			{Name: "date", Format: "date", Required: true},
+			{Name: "type", Enum: []string{logisticshandlers.Pickup, logisticshandlers.Delivery}},
+			{Name: "driver_id", Format: "uuid"},
+		},
+		Response: logisticshandlers.RouteSheet{},
+	}, "Admin")
+	r.handleAuth("GET", "/logistics/routes", logisticshandlers.RoutesHandler(db), openapi.Operation{
+		Summary: "Optimized visiting order of a day's pickups and deliveries", Tags: []string{"logistics"},
+		Description: "Starts and ends at the shop (SHOP_LATITUDE and SHOP_LONGITUDE) and respects each booking's time slot. " +
+			"Bookings whose address has no coordinates are listed in unrouted.",
+		Query: []openapi.Parameter{
+			{Name: "date", Format: "date", Required: true},
+			{Name: "type", Enum: []string{logisticshandlers.Pickup, logisticshandlers.Delivery}},
+			{Name: "driver_id", Format: "uuid"},
+		},
+		Response: logisticshandlers.OptimizedRoute{},
+	}, "Admin")
+
+	r.handleAuth("GET", "/clients/{id}/notifications", notificationshandlers.ShowPreferencesHandler(db), openapi.Operation{
+		Summary: "Show the notification channels of a client", Tags: []string{"clients"},
+		Response: entities.NotificationPreferencesEntity{},
+	}, "Admin")
+	r.handleAuth("PUT", "/clients/{id}/notifications", notificationshandlers.UpdatePreferencesHandler(db), openapi.Operation{
+		Summary: "Choose the notification channels of a client", Tags: []string{"clients"},
+		Description: "Clients are told when a service is created or finished, when a payment is overdue and before their subscription expires.",
+		Request:     entities.NotificationPreferencesEntity{}, Response: entities.NotificationPreferencesEntity{},
+	}, "Admin")
+	r.handleAuth("GET", "/addresses/lookup", addresseshandlers.LookupAddressHandler(), openapi.Operation{
+		Summary: "Find the address of a CEP", Tags: []string{"clients"},
+		Query:    []openapi.Parameter{{Name: "cep", Description: "8 digits, formatted or not", Required: true}},
+		Response: cep.Address{},
+	}, "Admin")
+
+	r.handleAuth("GET", "/webhooks", webhookshandlers.ListWebhooksHandler(db), openapi.Operation{
+		Summary: "List webhooks", Tags: []string{"webhooks"},
+		Response: []entities.WebhookEntity{},
+	}, "Admin")
+	r.handleAuth("POST", "/webhooks", webhookshandlers.CreateWebhookHandler(db), openapi.Operation{
+		Summary: "Register a webhook", Tags: []string{"webhooks"},
+		Description: "Each event of the chosen types is posted as JSON, signed in the " + webhooks.SignatureHeader + " header as " +
+			"t=<unix time>,v1=<hex HMAC-SHA256 of \"<unix time>.<body>\" with the secret>. " +
+			"Failed deliveries are retried with exponential backoff. The secret is only returned here.",
+		Request: webhookshandlers.WebhookRequest{}, Response: entities.WebhookEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("GET", "/webhooks/{id}", webhookshandlers.ShowWebhookHandler(db), openapi.Operation{
+		Summary: "Show a webhook", Tags: []string{"webhooks"},
+		Response: entities.WebhookEntity{},
+	}, "Admin")
+	r.handleAuth("PUT", "/webhooks/{id}", webhookshandlers.UpdateWebhookHandler(db), openapi.Operation{
+		Summary: "Update a webhook", Tags: []string{"webhooks"},
+		Description: "An empty secret keeps the current one.",
+		Request:     webhookshandlers.WebhookRequest{}, Response: entities.WebhookEntity{},
+	}, "Admin")
+	r.handleAuth("DELETE", "/webhooks/{id}", webhookshandlers.DeleteWebhookHandler(db), openapi.Operation{
+		Summary: "Delete a webhook and its delivery log", Tags: []string{"webhooks"},
+	}, "Admin")
+	r.handleAuth("GET", "/webhooks/{id}/deliveries", webhookshandlers.ListDeliveriesHandler(db), openapi.Operation{
+		Summary: "Delivery log of a webhook", Tags: []string{"webhooks"},
+		Query:    []openapi.Parameter{{Name: "status", Enum: []string{webhooks.StatusPending, webhooks.StatusSucceeded, webhooks.StatusFailed}}},
+		Response: []entities.WebhookDeliveryEntity{},
+	}, "Admin")
+	r.handleAuth("POST", "/webhooks/{id}/deliveries/{deliveryID}/redeliver", webhookshandlers.RedeliverHandler(db), openapi.Operation{
+		Summary: "Send a delivery again", Tags: []string{"webhooks"},
+		Response: entities.WebhookDeliveryEntity{}, Status: http.StatusAccepted,
+	}, "Admin")
+
+	r.handleAuth("GET", "/calendar/hours", calendarhandlers.ListOpeningHoursHandler(db), openapi.Operation{
+		Summary: "Weekly opening hours", Tags: []string{"calendar"},
+		Description: "Weekdays go from 0 (Sunday) to 6 (Saturday); those not listed are closed.",
+		Response:    []entities.OpeningHoursEntity{},
+	}, "Admin", "Client")
+	r.handleAuth("PUT", "/calendar/hours", calendarhandlers.UpdateOpeningHoursHandler(db), openapi.Operation{
+		Summary: "Replace the weekly opening hours", Tags: []string{"calendar"},
+		Description: "Weekdays left out are closed.",
+		Request:     calendarhandlers.OpeningHoursRequest{}, Response: []entities.OpeningHoursEntity{},
+	}, "Admin")
+	r.handleAuth("GET", "/calendar/days", calendarhandlers.ListDaysHandler(db), openapi.Operation{
+		Summary: "Days of a period with the hours the shop opens", Tags: []string{"calendar"},
+		Description: "Closed days tell why: the holiday, the closure or the weekday. At most 62 days.",
+		Query: []openapi.Parameter{
+			{Name: "from", Format: "date", Description: "Defaults to today"},
+			{Name: "to", Format: "date", Description: "Defaults to 13 days after from"},
+		},
+		Response: []calendar.Day{},
+	}, "Admin", "Client")
+	r.handleAuth("GET", "/calendar/holidays", calendarhandlers.ListHolidaysHandler(db), openapi.Operation{
+		Summary: "Holidays of a year", Tags: []string{"calendar"},
+		Description: "The Brazilian national holidays, always closed, and the registered ones.",
+		Query:       []openapi.Parameter{{Name: "year", Description: "Defaults to the current year"}},
+		Response:    []entities.HolidayEntity{},
+	}, "Admin", "Client")
+	r.handleAuth("POST", "/calendar/holidays", calendarhandlers.CreateHolidayHandler(db), openapi.Operation{
+		Summary: "Register a holiday", Tags: []string{"calendar"},
+		Request: calendarhandlers.HolidayRequest{}, Response: entities.HolidayEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("DELETE", "/calendar/holidays/{id}", calendarhandlers.DeleteHolidayHandler(db), openapi.Operation{
+		Summary: "Delete a registered holiday", Tags: []string{"calendar"},
+	}, "Admin")
+	r.handleAuth("GET", "/calendar/closures", calendarhandlers.ListClosuresHandler(db), openapi.Operation{
+		Summary: "Closures that haven't ended", Tags: []string{"calendar"},
+		Response: []entities.ClosureEntity{},
+	}, "Admin")
+	r.handleAuth("POST", "/calendar/closures", calendarhandlers.CreateClosureHandler(db), openapi.Operation{
+		Summary: "Close the shop for a period", Tags: []string{"calendar"},
+		Request: calendarhandlers.ClosureRequest{}, Response: entities.ClosureEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("DELETE", "/calendar/closures/{id}", calendarhandlers.DeleteClosureHandler(db), openapi.Operation{
+		Summary: "Delete a closure", Tags: []string{"calendar"},
+	}, "Admin")
+
+	r.handleAuth("GET", "/production/queue", productionhandlers.QueueHandler(db), openapi.Operation{
+		Summary: "Unfinished services by stage, in the order they should be worked", Tags: []string{"production"},
+		Description: "Each status lists same-day, then express, then normal services, each by due date; services without one come last. " +
+			"overdue flags the services past their due date.",
+		Query:    []openapi.Parameter{{Name: "status", Enum: production.Statuses, Description: "Only this stage"}},
+		Response: productionhandlers.QueueResponse{},
+	}, "Admin")
+	r.handleAuth("GET", "/production/capacity", productionhandlers.CapacityHandler(db), openapi.Operation{
+		Summary: "Work due on a day against the daily capacity", Tags: []string{"production"},
+		Query:    []openapi.Parameter{{Name: "date", Format: "date", Description: "Defaults to today"}},
+		Response: production.Load{},
+	}, "Admin")
+	r.handleAuth("GET", "/production/settings", productionhandlers.ShowSettingsHandler(db), openapi.Operation{
+		Summary: "Daily capacity and priority surcharges", Tags: []string{"production"},
+		Response: entities.ProductionSettingsEntity{},
+	}, "Admin")
+	r.handleAuth("PUT", "/production/settings", productionhandlers.UpdateSettingsHandler(db), openapi.Operation{
+		Summary: "Replace the daily capacity and priority surcharges", Tags: []string{"production"},
+		Description: "A zero capacity is unlimited. New surcharges apply to the services created afterwards.",
+		Request:     productionhandlers.SettingsRequest{}, Response: entities.ProductionSettingsEntity{},
+	}, "Admin")
+
+	r.handleAuth("POST", "/cash/sessions", cashhandlers.OpenSessionHandler(db), openapi.Operation{
+		Summary: "Open the cash drawer with the change float", Tags: []string{"cash"},
+		Description: "Only one session is open at a time.",
+		Request:     cashhandlers.OpenRequest{}, Response: entities.CashSessionEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("GET", "/cash/sessions", cashhandlers.ListSessionsHandler(db), openapi.Operation{
+		Summary: "Cash sessions opened over a period, newest first", Tags: []string{"cash"},
+		Query: []openapi.Parameter{
+			{Name: "from", Format: "date", Description: "Defaults to 30 days before to"},
+			{Name: "to", Format: "date", Description: "Defaults to today"},
+		},
+		Response: []entities.CashSessionEntity{},
+	}, "Admin")
+	r.handleAuth("GET", "/cash/sessions/current", cashhandlers.CurrentSessionHandler(db), openapi.Operation{
+		Summary: "The open cash session with its totals so far", Tags: []string{"cash"},
+		Description: "Answers 404 while the drawer is closed.",
+		Response:    cash.Report{},
+	}, "Admin")
+	r.handleAuth("GET", "/cash/sessions/{id}", cashhandlers.ShowSessionHandler(db), openapi.Operation{
+		Summary: "A cash session with its totals, movements and reconciliation", Tags: []string{"cash"},
+		Description: "totals.expected is the cash the drawer should hold: the opening float and the cash payments, less the withdrawals. " +
+			"reconciliation lists the services paid while the session was open without a payment at the drawer, and the payments " +
+			"taken for another amount than the price of their service. Payments from the wallet don't go through the drawer.",
+		Response: cash.Report{},
+	}, "Admin")
+	r.handleAuth("GET", "/cash/sessions/{id}/z-report", cashhandlers.ZReportHandler(db), openapi.Operation{
+		Summary: "Printable Z-report of a cash session", Tags: []string{"cash"},
+		Description: "Plain text 40 columns wide, for a receipt printer. A session still open prints as a partial X-report.",
+		Response:    openapi.File{}, ContentType: "text/plain",
+	}, "Admin")
+	r.handleAuth("POST", "/cash/sessions/{id}/payments", cashhandlers.PaymentHandler(db), openapi.Operation{
+		Summary: "Take a payment for a service at the counter", Tags: []string{"cash"},
+		Description: "Marks the service paid and sends service.paid with the method. amount defaults to the total price " +
+			"of the service, and 409 when it is short of it; only cash payments go into the drawer.",
+		Request: cashhandlers.PaymentRequest{}, Response: entities.CashMovementEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("POST", "/cash/sessions/{id}/withdrawals", cashhandlers.WithdrawalHandler(db), openapi.Operation{
+		Summary: "Take cash out of the drawer", Tags: []string{"cash"},
+		Request: cashhandlers.WithdrawalRequest{}, Response: entities.CashMovementEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("POST", "/cash/sessions/{id}/close", cashhandlers.CloseSessionHandler(db), openapi.Operation{
+		Summary: "Close a cash session with the cash counted", Tags: []string{"cash"},
+		Description: "Records the cash expected in the drawer; difference is counted less expected, negative when it is short.",
+		Request:     cashhandlers.CloseRequest{}, Response: entities.CashSessionEntity{},
+	}, "Admin")
+	r.handleAuth("GET", "/cash/report", cashhandlers.OverShortHandler(db), openapi.Operation{
+		Summary: "Over/short report of the cash sessions closed over a period", Tags: []string{"cash"},
+		Query: []openapi.Parameter{
+			{Name: "from", Format: "date", Description: "Defaults to 30 days before to"},
+			{Name: "to", Format: "date", Description: "Defaults to today"},
+		},
+		Response: cashhandlers.OverShortResponse{},
+	}, "Admin")
+
+	r.handleAuth("GET", "/machines", machineshandlers.ListMachinesHandler(db), openapi.Operation{
+		Summary: "List machines", Tags: []string{"machines"},
+		Query:    []openapi.Parameter{{Name: "type", Enum: machines.Types}},
+		Response: []entities.MachineEntity{},
+	}, "Admin")
+	r.handleAuth("POST", "/machines", machineshandlers.CreateMachineHandler(db), openapi.Operation{
+		Summary: "Register a washer, dryer or iron", Tags: []string{"machines"},
+		Request: machineshandlers.MachineRequest{}, Response: entities.MachineEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("PUT", "/machines/{id}", machineshandlers.UpdateMachineHandler(db), openapi.Operation{
+		Summary: "Update a machine", Tags: []string{"machines"},
+		Request: machineshandlers.MachineRequest{}, Response: entities.MachineEntity{},
+	}, "Admin")
+	r.handleAuth("DELETE", "/machines/{id}", machineshandlers.DeleteMachineHandler(db), openapi.Operation{
+		Summary: "Delete a machine that was never used", Tags: []string{"machines"},
+		Description: "Machines with loads can't be deleted; disable them instead.",
+	}, "Admin")
+	r.handleAuth("GET", "/machines/{id}/loads", machineshandlers.ListMachineLoadsHandler(db), openapi.Operation{
+		Summary: "Loads a machine started in a period", Tags: []string{"machines"},
+		Query: []openapi.Parameter{
+			{Name: "from", Format: "date", Description: "Defaults to 30 days before to"},
+			{Name: "to", Format: "date", Description: "Defaults to today"},
+		},
+		Response: []machineshandlers.Load{},
+	}, "Admin")
+	r.handleAuth("POST", "/machines/{id}/loads", machineshandlers.StartLoadHandler(db), openapi.Operation{
+		Summary: "Start a load of a machine with one or more services", Tags: []string{"machines"},
+		Description: "A machine runs one load at a time. Washers work on Lavando, dryers on Secando and irons on Passando: " +
+			"services still in an earlier status move to that stage. The weight defaults to the services charged by weight " +
+			"and can't exceed the capacity of the machine; the operator defaults to the logged in user.",
+		Request: machineshandlers.LoadRequest{}, Response: machineshandlers.Load{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("POST", "/loads/{id}/finish", machineshandlers.FinishLoadHandler(db), openapi.Operation{
+		Summary: "Finish a running load", Tags: []string{"machines"},
+		Response: machineshandlers.Load{},
+	}, "Admin")
+	r.handleAuth("GET", "/machines/{id}/utilization", machineshandlers.UtilizationHandler(db), openapi.Operation{
+		Summary: "How a machine was used over a period", Tags: []string{"machines"},
+		Description: "utilization is the share of the opening hours the machine ran, and average_fill the share of its capacity " +
+			"its loads took. Loads still running count until now.",
+		Query: []openapi.Parameter{
+			{Name: "from", Format: "date", Description: "Defaults to 30 days before to"},
+			{Name: "to", Format: "date", Description: "Defaults to today"},
+		},
+		Response: machineshandlers.UtilizationResponse{},
+	}, "Admin")
+	r.handleAuth("GET", "/services/{id}/loads", machineshandlers.ListServiceLoadsHandler(db), openapi.Operation{
+		Summary: "Loads that processed a service", Tags: []string{"machines"},
+		Description: "Tells which machines washed, dried and ironed the service, when and by whom.",
+		Response:    []machineshandlers.Load{},
+	}, "Admin")
+
+	r.handleAuth("GET", "/supplies", inventoryhandlers.ListSuppliesHandler(db), openapi.Operation{
+		Summary: "List the consumables", Tags: []string{"inventory"},
+		Query:    []openapi.Parameter{{Name: "low_stock", Enum: []string{"true", "false"}, Description: "true lists only the supplies at or below their reorder level"}},
+		Response: []entities.SupplyEntity{},
+	}, "Admin")
+	r.handleAuth("POST", "/supplies", inventoryhandlers.CreateSupplyHandler(db), openapi.Operation{
+		Summary: "Register a consumable such as detergent, softener or bags", Tags: []string{"inventory"},
+		Description: "The stock starts empty and changes through purchases, consumptions and adjustments. usage_per_load and " +
+			"usage_per_kg estimate what each wash load takes.",
+		Request: inventoryhandlers.SupplyRequest{}, Response: entities.SupplyEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("PUT", "/supplies/{id}", inventoryhandlers.UpdateSupplyHandler(db), openapi.Operation{
+		Summary: "Update a consumable", Tags: []string{"inventory"},
+		Request: inventoryhandlers.SupplyRequest{}, Response: entities.SupplyEntity{},
+	}, "Admin")
+	r.handleAuth("DELETE", "/supplies/{id}", inventoryhandlers.DeleteSupplyHandler(db), openapi.Operation{
+		Summary: "Delete a consumable that never moved", Tags: []string{"inventory"},
+		Description: "Consumables with stock movements can't be deleted; disable them instead.",
+	}, "Admin")
+	r.handleAuth("GET", "/supplies/report", inventoryhandlers.ReportHandler(db), openapi.Operation{
+		Summary: "Stock movement report", Tags: []string{"inventory"},
+		Description: "Opening and closing stock of each consumable with what was purchased, consumed and adjusted over the period. " +
+			"estimated is the part of the consumption estimated from wash loads.",
+		Query: []openapi.Parameter{
+			{Name: "from", Format: "date", Description: "Defaults to 30 days before to"},
+			{Name: "to", Format: "date", Description: "Defaults to today"},
+		},
+		Response: []inventory.ReportLine{},
+	}, "Admin")
+	r.handleAuth("GET", "/supplies/{id}/movements", inventoryhandlers.ListMovementsHandler(db), openapi.Operation{
+		Summary: "Stock movements of a consumable over a period", Tags: []string{"inventory"},
+		Query: []openapi.Parameter{
+			{Name: "from", Format: "date", Description: "Defaults to 30 days before to"},
+			{Name: "to", Format: "date", Description: "Defaults to today"},
+		},
+		Response: []entities.StockMovementEntity{},
+	}, "Admin")
+	r.handleAuth("POST", "/supplies/{id}/purchases", inventoryhandlers.PurchaseHandler(db), openapi.Operation{
+		Summary: "Enter a purchase of a consumable", Tags: []string{"inventory"},
+		Request: inventoryhandlers.PurchaseRequest{}, Response: entities.StockMovementEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("POST", "/supplies/{id}/consumptions", inventoryhandlers.ConsumptionHandler(db), openapi.Operation{
+		Summary: "Take a consumable from the stock", Tags: []string{"inventory"},
+		Description: "Wash loads record their consumption on their own, estimated from the usage of each consumable; this is " +
+			"for what they don't cover. When the stock falls to the reorder level, the supply.low_stock event is sent.",
+		Request: inventoryhandlers.ConsumptionRequest{}, Response: entities.StockMovementEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("POST", "/supplies/{id}/adjustments", inventoryhandlers.AdjustmentHandler(db), openapi.Operation{
+		Summary: "Set the stock of a consumable to a count", Tags: []string{"inventory"},
+		Description: "Records the difference between the counted and the current stock.",
+		Request:     inventoryhandlers.AdjustmentRequest{}, Response: entities.StockMovementEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+
+	r.handleAuth("GET", "/incidents", incidentshandlers.ListIncidentsHandler(db), openapi.Operation{
+		Summary: "List the incidents reported over a period", Tags: []string{"incidents"},
+		Query: []openapi.Parameter{
+			{Name: "status", Enum: incidents.Statuses},
+			{Name: "type", Enum: incidents.Types},
+			{Name: "from", Format: "date", Description: "Defaults to 30 days before to"},
+			{Name: "to", Format: "date", Description: "Defaults to today"},
+		},
+		Response: []entities.IncidentEntity{},
+	}, "Admin")
+	r.handleAuth("GET", "/incidents/report", incidentshandlers.ReportHandler(db), openapi.Operation{
+		Summary: "Incidents by laundry item over a period", Tags: []string{"incidents"},
+		Description: "compensation sums what was credited on the resolved incidents; pending counts the ones still open or " +
+			"under investigation. Incidents not about an item are summed on a line without laundry_item_id.",
+		Query: []openapi.Parameter{
+			{Name: "from", Format: "date", Description: "Defaults to 30 days before to"},
+			{Name: "to", Format: "date", Description: "Defaults to today"},
+		},
+		Response: []incidents.ReportLine{},
+	}, "Admin")
+	r.handleAuth("PUT", "/incidents/{id}", incidentshandlers.UpdateIncidentHandler(db), openapi.Operation{
+		Summary: "Update an incident still open or under investigation", Tags: []string{"incidents"},
+		Request: incidentshandlers.IncidentRequest{}, Response: entities.IncidentEntity{},
+	}, "Admin")
+	r.handleAuth("POST", "/incidents/{id}/status", incidentshandlers.IncidentStatusHandler(db), openapi.Operation{
+		Summary: "Move an incident along its workflow", Tags: []string{"incidents"},
+		Description: "Incidents go from open to investigating and are closed as resolved or rejected, with the resolution. " +
+			"Resolving one credits its compensation to the client's wallet and sends the incident.resolved event.",
+		Request: incidentshandlers.StatusRequest{}, Response: entities.IncidentEntity{},
+	}, "Admin")
+	r.handleAuth("POST", "/services/{id}/wallet-payment", wallethandlers.PayServiceHandler(db), openapi.Operation{
+		Summary: "Pay a service with the wallet of its client", Tags: []string{"wallet"},
+		Description: "Takes the total price of the service from the wallet and marks it paid, sending service.paid with " +
+			"method wallet. Clients only pay their own services.",
+		Response: wallethandlers.TransactionResponse{},
+	}, "Admin", "Client")
+	r.handleAuth("POST", "/services/{id}/attachments", attachmentshandlers.UploadAttachmentHandler(db, store), openapi.Operation{
+		Summary: "Upload a photo of the garments of a service", Tags: []string{"attachments"},
+		Description: "Photos are taken at intake, when the garments are dropped off, and at delivery. JPEG and PNG photos up to " +
+			"10 MB are accepted; their type is read from the content, and a thumbnail is made on upload.",
+		Request: struct {
+			File openapi.File `json:"file" validate:"required"`
+			attachmentshandlers.AttachmentRequest
+		}{},
+		RequestType: "multipart/form-data",
+		Response:    entities.AttachmentEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("GET", "/services/{id}/attachments", attachmentshandlers.ListAttachmentsHandler(db), openapi.Operation{
+		Summary: "Photos of a service", Tags: []string{"attachments"},
+		Description: "Clients only see the photos of their own services.",
+		Response:    []entities.AttachmentEntity{},
+	}, "Admin", "Client")
+	r.handleAuth("GET", "/attachments/{id}", attachmentshandlers.DownloadAttachmentHandler(db, store), openapi.Operation{
+		Summary: "Download a photo", Tags: []string{"attachments"},
+		Description: "Clients only download the photos of their own services.",
+		Response:    openapi.File{}, ContentType: "image/*",
+	}, "Admin", "Client")
+	r.handleAuth("GET", "/attachments/{id}/thumbnail", attachmentshandlers.ThumbnailHandler(db, store), openapi.Operation{
+		Summary: "Download the thumbnail of a photo", Tags: []string{"attachments"},
+		Description: "A JPEG whose longest side is 320 pixels. Clients only download the thumbnails of their own services.",
+		Response:    openapi.File{}, ContentType: "image/jpeg",
+	}, "Admin", "Client")
+	r.handleAuth("DELETE", "/attachments/{id}", attachmentshandlers.DeleteAttachmentHandler(db, store), openapi.Operation{
+		Summary: "Delete a photo", Tags: []string{"attachments"},
+	}, "Admin")
+	r.handleAuth("POST", "/services/{id}/invoice", invoiceshandlers.IssueInvoiceHandler(db, issuer), openapi.Operation{
+		Summary: "Issue the NFS-e of a paid service", Tags: []string{"invoices"},
+		Description: "Sends the service as a signed ABRASF RPS, to the client's CPF and address, and stores the invoice number, " +
+			"status and PDF link on the service. When the city rejects the RPS the answer is 422 with its reason, and the RPS " +
+			"is sent again with the same number. 409 while the RPS is already being sent; 502 when the provider doesn't answer.",
+		Response: entities.InvoiceEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("GET", "/services/{id}/invoice", invoiceshandlers.ShowInvoiceHandler(db), openapi.Operation{
+		Summary: "NFS-e of a service", Tags: []string{"invoices"},
+		Description: "The invoice issued, or the rejection of its RPS. Clients only see the invoices of their own services.",
+		Response:    entities.InvoiceEntity{},
+	}, "Admin", "Client")
+	r.handleAuth("GET", "/services/{id}/incidents", incidentshandlers.ListServiceIncidentsHandler(db), openapi.Operation{
+		Summary: "Incidents of a service", Tags: []string{"incidents"},
+		Response: []entities.IncidentEntity{},
+	}, "Admin")
+	r.handleAuth("POST", "/services/{id}/incidents", incidentshandlers.CreateIncidentHandler(db), openapi.Operation{
+		Summary: "Report a garment damaged or lost, or a complaint, on a service", Tags: []string{"incidents"},
+		Description: "laundry_item_id points the incident to a line of the service. Sends the incident.reported event.",
+		Request:     incidentshandlers.IncidentRequest{}, Response: entities.IncidentEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+
+	// Public, so limited per IP to slow down the guessing of codes
+	trackLimit := middleware.RateLimit(30, time.Minute, middleware.TrustedProxies(os.Getenv("TRUST_PROXY")))
+	r.handle("GET", "/track/{code}", trackLimit(serviceshandlers.TrackServiceHandler(db)).ServeHTTP, openapi.Operation{
+		Summary: "Status of an order by the tracking code of its receipt", Tags: []string{"tracking"},
+		Description: "No login needed. Codes are case insensitive and may be typed with or without the dash. " +
+			"Limited to 30 requests per minute per IP; beyond that the answer is 429 with Retry-After.",
+		Response: serviceshandlers.OrderStatus{},
+	})
+
+	r.handle("POST", "/login", handlers.LoginHandler(db), openapi.Operation{
+		Summary: "Log in", Tags: []string{"auth"},
+		Description: "Sets the auth_token cookie used by the other routes.",
+		Request:     handlers.LoginRequest{}, Response: struct {
+			Message string `json:"message"`
+		}{},
+	})
+	r.handleAuth("POST", "/users", handlers.CreateUserHandler(db), openapi.Operation{
+		Summary: "Create an admin user", Tags: []string{"users"},
+		Request: handlers.CreateUser{}, Response: entities.UserEntity{}, Status: http.StatusCreated,
+	}, "Admin")
+	r.handleAuth("GET", "/users", handlers.ListUsersHandler(db), openapi.Operation{
+		Summary: "List users", Tags: []string{"users"},
+		Response: []entities.UserEntity{},
+	}, "Admin")
+	r.handleAuth("PATCH", "/users/{id}", handlers.UpdateUserHandler(db), openapi.Operation{
+		Summary: "Update a user", Tags: []string{"users"},
+		Request: handlers.UpdateUser{},
+	}, "Admin")
+	r.handleAuth("DELETE", "/users/{id}", handlers.DeleteUserHandler(db), openapi.Operation{
+		Summary: "Delete a user", Tags: []string{"users"},
+	}, "Admin")
+	r.handleAnyMethod("GET", "/api/auth/status", handlers.AuthStatusHandler, openapi.Operation{
+		Summary: "Check the authentication cookie", Tags: []string{"auth"},
+		Response: handlers.AuthStatusResponse{},
+	})
+	r.handleAnyMethod("POST", "/api/auth/logout", handlers.LogoutHandler, openapi.Operation{
+		Summary: "Log out, removing the authentication cookie", Tags: []string{"auth"},
+	})
+
+	r.handle("GET", "/openapi.json", spec.Handler, openapi.Operation{
+		Summary: "OpenAPI document of this API", Tags: []string{"docs"},
+	})
+	r.handle("GET", "/docs", openapi.UIHandler, openapi.Operation{
+		Summary: "Swagger UI", Tags: []string{"docs"}, ContentType: "text/html",
+	})
+
+	return mainRouter
 }
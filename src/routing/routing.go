@@ -0,0 +1,212 @@
+// Package routing plans the order in which the driver visits the day's
+// pickups and deliveries. It works offline: distances are great-circle
+// distances between stored coordinates and travel times assume a constant
+// average speed.
+package routing
+
+import (
+	"math"
+	"time"
+)
+
+const earthRadiusKm = 6371.0
+
+// Point is a geographic coordinate in decimal degrees
+type Point struct {
+	Lat float64 `json:"latitude"`
+	Lng float64 `json:"longitude"`
+}
+
+// Stop is a place to visit. A zero WindowStart or WindowEnd leaves that side
+// of the time window open.
+type Stop struct {
+	ID          string
+	Location    Point
+	WindowStart time.Time
+	WindowEnd   time.Time
+}
+
+// Options tune the travel time estimates
+type Options struct {
+	Start          time.Time     // departure from the origin
+	SpeedKmh       float64       // average speed, defaults to 30 km/h
+	ServiceTime    time.Duration // time spent at each stop, defaults to 5 minutes
+	ReturnToOrigin bool          // count the way back to the origin in the distance
+}
+
+// Visit is a stop in the planned order with its estimated times
+type Visit struct {
+	Stop
+	DistanceKm float64   // from the previous stop or the origin
+	Arrival    time.Time // when the driver gets there
+	Start      time.Time // when the visit starts, after waiting for the window to open
+	Departure  time.Time
+	Late       bool // the visit starts after the window closes
+}
+
+// Route is the planned visiting order
+type Route struct {
+	Visits     []Visit
+	DistanceKm float64
+	Finish     time.Time
+	LateStops  int
+}
+
+// Haversine returns the great-circle distance between a and b in kilometres
+func Haversine(a, b Point) float64 {
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Plan orders the stops starting at origin. It builds a first route with
+// the nearest-neighbour heuristic, always moving to the closest stop that
+// can still be reached inside its window, and then improves it with 2-opt
+// moves, accepting a move only when it doesn't make any visit later than
+// its window.
+func Plan(origin Point, stops []Stop, opts Options) Route {
+	if opts.SpeedKmh <= 0 {
+		opts.SpeedKmh = 30
+	}
+	if opts.ServiceTime <= 0 {
+		opts.ServiceTime = 5 * time.Minute
+	}
+
+	p := planner{origin: origin, stops: stops, opts: opts}
+	order := p.nearestNeighbour()
+	order = p.twoOpt(order)
+	return p.simulate(order)
+}
+
+type planner struct {
+	origin Point
+	stops  []Stop
+	opts   Options
+}
+
+func (p planner) travel(km float64) time.Duration {
+	return time.Duration(km / p.opts.SpeedKmh * float64(time.Hour))
+}
+
+// nearestNeighbour builds the first route
+func (p planner) nearestNeighbour() []int {
+	visited := make([]bool, len(p.stops))
+	order := make([]int, 0, len(p.stops))
+	position := p.origin
+	now := p.opts.Start
+
+	for len(order) < len(p.stops) {
+		best, fallback := -1, -1
+		bestKm := math.Inf(1)
+		for i, stop := range p.stops {
+			if visited[i] {
+				continue
+			}
+			km := Haversine(position, stop.Location)
+			arrival := now.Add(p.travel(km))
+			if (stop.WindowEnd.IsZero() || !arrival.After(stop.WindowEnd)) && km < bestKm {
+				best, bestKm = i, km
+			}
+			// When every stop would be late, go first to the one whose window
+			// closes first
+			if fallback == -1 || closesBefore(stop, p.stops[fallback]) {
+				fallback = i
+			}
+		}
+		if best == -1 {
+			best = fallback
+		}
+
+		visited[best] = true
+		order = append(order, best)
+		visit := p.visit(p.stops[best], position, now)
+		position, now = p.stops[best].Location, visit.Departure
+	}
+
+	return order
+}
+
+func closesBefore(a, b Stop) bool {
+	if a.WindowEnd.IsZero() {
+		return false
+	}
+	return b.WindowEnd.IsZero() || a.WindowEnd.Before(b.WindowEnd)
+}
+
+// twoOpt reverses segments of the route while that shortens it without
+// adding late visits
+func (p planner) twoOpt(order []int) []int {
+	best := p.simulate(order)
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < len(order)-1; i++ {
+			for j := i + 1; j < len(order); j++ {
+				candidate := reverse(order, i, j)
+				route := p.simulate(candidate)
+				if better(route, best) {
+					order, best, improved = candidate, route, true
+				}
+			}
+		}
+	}
+	return order
+}
+
+func better(a, b Route) bool {
+	if a.LateStops != b.LateStops {
+		return a.LateStops < b.LateStops
+	}
+	return a.DistanceKm < b.DistanceKm-1e-9
+}
+
+func reverse(order []int, i, j int) []int {
+	candidate := make([]int, len(order))
+	copy(candidate, order)
+	for ; i < j; i, j = i+1, j-1 {
+		candidate[i], candidate[j] = candidate[j], candidate[i]
+	}
+	return candidate
+}
+
+func (p planner) visit(stop Stop, from Point, now time.Time) Visit {
+	km := Haversine(from, stop.Location)
+	visit := Visit{Stop: stop, DistanceKm: km, Arrival: now.Add(p.travel(km))}
+
+	visit.Start = visit.Arrival
+	if !stop.WindowStart.IsZero() && visit.Start.Before(stop.WindowStart) {
+		visit.Start = stop.WindowStart
+	}
+	visit.Late = !stop.WindowEnd.IsZero() && visit.Start.After(stop.WindowEnd)
+	visit.Departure = visit.Start.Add(p.opts.ServiceTime)
+	return visit
+}
+
+// simulate computes the visits of the stops in order
+func (p planner) simulate(order []int) Route {
+	route := Route{Visits: make([]Visit, 0, len(order))}
+	position := p.origin
+	now := p.opts.Start
+
+	for _, i := range order {
+		visit := p.visit(p.stops[i], position, now)
+		route.Visits = append(route.Visits, visit)
+		route.DistanceKm += visit.DistanceKm
+		if visit.Late {
+			route.LateStops++
+		}
+		position, now = visit.Location, visit.Departure
+	}
+
+	if p.opts.ReturnToOrigin && len(order) > 0 {
+		km := Haversine(position, p.origin)
+		route.DistanceKm += km
+		now = now.Add(p.travel(km))
+	}
+	route.Finish = now
+
+	return route
+}
@@ -0,0 +1,52 @@
+// Package storage keeps the files uploaded to the laundry, such as the
+// photos of the garments, on the local filesystem or in an S3-compatible
+// bucket.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+)
+
+// ErrNotFound is returned when no file is stored under a key
+var ErrNotFound = errors.New("storage: file not found")
+
+// Storage stores files under keys such as "services/<id>/<file>.jpg"
+type Storage interface {
+	// Put stores the content of r under key, replacing any file there
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the file stored under key. The caller closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the file stored under key. Deleting a missing file
+	// isn't an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// FromEnv builds the storage configured in the environment. When
+// S3_BUCKET is set the files go to that bucket, at S3_ENDPOINT (AWS when
+// empty) in S3_REGION, signed with S3_ACCESS_KEY_ID and
+// S3_SECRET_ACCESS_KEY. Otherwise they are written under STORAGE_DIR,
+// "uploads" by default.
+func FromEnv() Storage {
+	if bucket := os.Getenv("S3_BUCKET"); bucket != "" {
+		region := os.Getenv("S3_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		return &S3{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Bucket:          bucket,
+			Region:          region,
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		}
+	}
+
+	dir := os.Getenv("STORAGE_DIR")
+	if dir == "" {
+		dir = "uploads"
+	}
+	return Local{Dir: dir}
+}
@@ -0,0 +1,157 @@
+// Package stream pushes service changes to the connected order boards. The
+// domain events dispatcher feeds the Broker, which fans each change out to
+// the open connections whose filter matches it.
+//
+// The broker lives in memory: with several API instances, each one only
+// streams the events its own dispatcher claimed.
+package stream
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/events"
+)
+
+// bufferSize is how many messages a connection can fall behind before it is
+// dropped. Dropped boards reconnect and reload the list.
+const bufferSize = 32
+
+// Service is the state of a service after a change
+type Service struct {
+	ID                      uuid.UUID  `json:"id" db:"id"`
+	ClientID                uuid.UUID  `json:"client_id" db:"client_id"`
+	ClientFirstName         string     `json:"client_first_name" db:"client_first_name"`
+	ClientLastName          string     `json:"client_last_name" db:"client_last_name"`
+	Status                  string     `json:"status" db:"status"`
+	Priority                string     `json:"priority" db:"priority"`
+	TotalPrice              float64    `json:"total_price" db:"total_price"`
+	IsPaid                  bool       `json:"is_paid" db:"is_paid"`
+	EstimatedCompletionDate *time.Time `json:"estimated_completion_date" db:"estimated_completion_date"`
+}
+
+// Message is a change of a service as sent to the boards
+type Message struct {
+	EventID int64       `json:"event_id"`
+	Type    events.Type `json:"type"`
+	Service Service     `json:"service"`
+}
+
+// Filter selects the messages of a connection. Empty fields match every
+// service.
+type Filter struct {
+	Status   string
+	ClientID uuid.UUID
+}
+
+func (f Filter) matches(m Message) bool {
+	return (f.Status == "" || f.Status == m.Service.Status) &&
+		(f.ClientID == uuid.Nil || f.ClientID == m.Service.ClientID)
+}
+
+type connection struct {
+	filter   Filter
+	messages chan Message
+}
+
+// Broker fans the messages out to the connections
+type Broker struct {
+	mu          sync.Mutex
+	connections map[*connection]bool
+}
+
+// NewBroker returns a broker without connections
+func NewBroker() *Broker {
+	return &Broker{connections: map[*connection]bool{}}
+}
+
+// Default is the broker fed by the dispatcher and read by the stream route
+var Default = NewBroker()
+
+// Subscribe opens a connection. The channel is closed when cancel is called
+// or when the connection falls too far behind.
+func (b *Broker) Subscribe(filter Filter) (<-chan Message, func()) {
+	c := &connection{filter: filter, messages: make(chan Message, bufferSize)}
+
+	b.mu.Lock()
+	b.connections[c] = true
+	b.mu.Unlock()
+
+	return c.messages, func() { b.remove(c) }
+}
+
+func (b *Broker) remove(c *connection) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.connections[c] {
+		delete(b.connections, c)
+		close(c.messages)
+	}
+}
+
+// Publish sends a message to the matching connections without blocking
+func (b *Broker) Publish(m Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for c := range b.connections {
+		if !c.filter.matches(m) {
+			continue
+		}
+		select {
+		case c.messages <- m:
+		default:
+			delete(b.connections, c)
+			close(c.messages)
+		}
+	}
+}
+
+// Connections returns how many connections are open
+func (b *Broker) Connections() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.connections)
+}
+
+// Subscriber returns the events handler that publishes the current state of
+// the service of each service event. Events of deleted services are skipped.
+func (b *Broker) Subscriber(db *sqlx.DB) events.Handler {
+	return func(ctx context.Context, event events.Event) error {
+		switch event.Type {
+		case events.ServiceCreated, events.ServiceRepriced, events.ServiceStatusChanged, events.ServicePaid:
+		default:
+			return nil
+		}
+
+		var service Service
+		err := db.GetContext(ctx, &service, `
+			SELECT
+				ls.id,
+				ls.client_id,
+				cli.first_name AS client_first_name,
+				cli.last_name AS client_last_name,
+				ls.status,
+				ls.priority,
+				COALESCE(ls.total_price, 0) AS total_price,
+				COALESCE(ls.is_paid, false) AS is_paid,
+				ls.estimated_completion_date
+			FROM laundry_services ls
+			JOIN clients cli ON cli.id = ls.client_id
+			WHERE ls.id = $1`, event.AggregateID)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		b.Publish(Message{EventID: event.ID, Type: event.Type, Service: service})
+		return nil
+	}
+}
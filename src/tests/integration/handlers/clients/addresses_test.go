@@ -0,0 +1,130 @@
+package testhandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"lavanderia/entities"
+	clientshandlers "lavanderia/handlers/clients"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func sendClientAddress(handler http.HandlerFunc, method string, vars map[string]string, body interface{}) *httptest.ResponseRecorder {
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+
+	req, _ := http.NewRequest(method, "/clients/"+vars["id"]+"/addresses", &payload)
+	req = mux.SetURLVars(req, vars)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestClientAddresses(t *testing.T) {
+	var clientID string
+	err := db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		"Marina", "Souza", "marina.souza", "senha_segura", false, "24998548386", false).Scan(&clientID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert client: %v", err)
+	}
+	vars := map[string]string{"id": clientID}
+
+	home := clientshandlers.ClientAddressRequest{
+		Label: clientshandlers.LabelHome, Street: "Rua dos Mineiros", City: "Valença", State: "RJ", PostalCode: "27600-000", Number: "10",
+	}
+	work := home
+	work.Label, work.Street, work.IsDefault = clientshandlers.LabelWork, "Avenida Nilo Peçanha", true
+
+	// The first address becomes the default even without is_default
+	recorder := sendClientAddress(clientshandlers.CreateClientAddressHandler(db), "POST", vars, home)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+	}
+	var homeAddress entities.ClientAddressEntity
+	json.NewDecoder(recorder.Body).Decode(&homeAddress)
+	if !homeAddress.IsDefault || homeAddress.PostalCode != "27600000" {
+		t.Errorf("Expected the first address to be the default with a normalized CEP, got %+v", homeAddress)
+	}
+
+	recorder = sendClientAddress(clientshandlers.CreateClientAddressHandler(db), "POST", vars, work)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+	}
+	var workAddress entities.ClientAddressEntity
+	json.NewDecoder(recorder.Body).Decode(&workAddress)
+
+	var defaultID uuid.UUID
+	if err := db.Get(&defaultID, "SELECT address_id FROM clients WHERE id = $1", clientID); err != nil || defaultID != workAddress.AddressID {
+		t.Errorf("Expected the client default address to be %s, got %s (%v)", workAddress.AddressID, defaultID, err)
+	}
+
+	recorder = sendClientAddress(clientshandlers.ListClientAddressesHandler(db), "GET", vars, nil)
+	var addresses []entities.ClientAddressEntity
+	json.NewDecoder(recorder.Body).Decode(&addresses)
+	if len(addresses) != 2 || addresses[0].AddressID != workAddress.AddressID || addresses[1].IsDefault {
+		t.Errorf("Expected the work address first and only default, got %+v", addresses)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		handler    http.HandlerFunc
+		vars       map[string]string
+		body       interface{}
+		wantStatus int
+	}{
+		{
+			name:       "Invalid label",
+			method:     "POST",
+			handler:    clientshandlers.CreateClientAddressHandler(db),
+			vars:       vars,
+			body:       clientshandlers.ClientAddressRequest{Label: "beach", Street: "Rua A", City: "Valença", State: "RJ", PostalCode: "27600000", Number: "1"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Unknown client",
+			method:     "POST",
+			handler:    clientshandlers.CreateClientAddressHandler(db),
+			vars:       map[string]string{"id": uuid.New().String()},
+			body:       home,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Address of another client",
+			method:     "PUT",
+			handler:    clientshandlers.UpdateClientAddressHandler(db),
+			vars:       map[string]string{"id": uuid.New().String(), "addressID": homeAddress.AddressID.String()},
+			body:       home,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Default address cannot be deleted",
+			method:     "DELETE",
+			handler:    clientshandlers.DeleteClientAddressHandler(db),
+			vars:       map[string]string{"id": clientID, "addressID": workAddress.AddressID.String()},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "Other address is deleted",
+			method:     "DELETE",
+			handler:    clientshandlers.DeleteClientAddressHandler(db),
+			vars:       map[string]string{"id": clientID, "addressID": homeAddress.AddressID.String()},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := sendClientAddress(tc.handler, tc.method, tc.vars, tc.body)
+			if recorder.Code != tc.wantStatus {
+				t.Errorf("Expected status code %d, got %d: %s", tc.wantStatus, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -39,6 +39,7 @@ func TestCreateClientHandler(t *testing.T) {
 		client     CreateClient
 		wantStatus int
 		wantErr    bool
+		wantFields []string
 	}{
 		{
 			name: "Valid client",
@@ -46,7 +47,7 @@ func TestCreateClientHandler(t *testing.T) {
 				FirstName:  "Gabriel",
 				LastName:   "Almeida de Sousa",
 				Username:   "gabriel.almeida",
-				Phone:      "998548386",
+				Phone:      "24998548386",
 				IsMonthly:  false,
 				Street:     "Avenida Nilo Peçanha",
 				City:       "Valença",
@@ -59,6 +60,23 @@ func TestCreateClientHandler(t *testing.T) {
 			wantStatus: http.StatusCreated,
 			wantErr:    false,
 		},
+		{
+			name: "Empty name, invalid state and phone",
+			client: CreateClient{
+				FirstName:  "",
+				LastName:   "Almeida de Sousa",
+				Username:   "gabriel.invalid",
+				Phone:      "12345",
+				Street:     "Avenida Nilo Peçanha",
+				City:       "Valença",
+				State:      "XX",
+				PostalCode: "27600000",
+				Number:     "900",
+			},
+			wantStatus: http.StatusBadRequest,
+			wantErr:    true,
+			wantFields: []string{"first_name", "phone", "state"},
+		},
 	}
 
 	for _, tc := range tests {
@@ -79,6 +97,24 @@ func TestCreateClientHandler(t *testing.T) {
 			if recorder.Code != tc.wantStatus {
 				t.Errorf("Expected status code %d, got %d", tc.wantStatus, recorder.Code)
 			}
+
+			if tc.wantErr {
+				var response struct {
+					Errors []struct {
+						Field string `json:"field"`
+					} `json:"errors"`
+				}
+				json.NewDecoder(recorder.Body).Decode(&response)
+
+				if len(response.Errors) != len(tc.wantFields) {
+					t.Fatalf("Expected errors for %v, got %+v", tc.wantFields, response.Errors)
+				}
+				for i, field := range tc.wantFields {
+					if response.Errors[i].Field != field {
+						t.Errorf("Expected error field '%s', got '%s'", field, response.Errors[i].Field)
+					}
+				}
+			}
 		})
 	}
 }
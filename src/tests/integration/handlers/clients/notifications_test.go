@@ -0,0 +1,81 @@
+package testhandlers
+
+import (
+	"encoding/json"
+	"lavanderia/entities"
+	notificationshandlers "lavanderia/handlers/notifications"
+	"lavanderia/notify"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNotificationPreferences(t *testing.T) {
+	var clientID uuid.UUID
+	err := db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		"Helena", "Prado", "helena.prado", "senha_segura", false, "24998548386", false).Scan(&clientID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert client: %v", err)
+	}
+	vars := map[string]string{"id": clientID.String()}
+
+	// Clients that never changed their preferences are notified by WhatsApp
+	recorder := sendClientAddress(notificationshandlers.ShowPreferencesHandler(db), "GET", vars, nil)
+	var preferences entities.NotificationPreferencesEntity
+	json.NewDecoder(recorder.Body).Decode(&preferences)
+	if recorder.Code != http.StatusOK || !preferences.WhatsAppEnabled || preferences.EmailEnabled {
+		t.Fatalf("Expected the default preferences, got %d: %+v", recorder.Code, preferences)
+	}
+
+	tests := []struct {
+		name       string
+		vars       map[string]string
+		body       entities.NotificationPreferencesEntity
+		wantStatus int
+	}{
+		{
+			name:       "E-mail enabled without an address",
+			vars:       vars,
+			body:       entities.NotificationPreferencesEntity{EmailEnabled: true},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Unknown client",
+			vars:       map[string]string{"id": uuid.New().String()},
+			body:       entities.NotificationPreferencesEntity{WhatsAppEnabled: true},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "E-mail and SMS",
+			vars:       vars,
+			body:       entities.NotificationPreferencesEntity{Email: "helena@example.com", EmailEnabled: true, SMSEnabled: true},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := sendClientAddress(notificationshandlers.UpdatePreferencesHandler(db), "PUT", tc.vars, tc.body)
+			if recorder.Code != tc.wantStatus {
+				t.Errorf("Expected status code %d, got %d: %s", tc.wantStatus, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+
+	// Each enabled channel gets one message, and enqueueing twice is a no-op
+	for i := 0; i < 2; i++ {
+		if err := notify.Enqueue(db, clientID, notify.ServiceReady, notify.Data{ServiceID: uuid.NewString()}, "service-1"); err != nil {
+			t.Fatalf("Failed to enqueue: %v", err)
+		}
+	}
+
+	var recipients []string
+	err = db.Select(&recipients, "SELECT recipient FROM notification_outbox WHERE client_id = $1 ORDER BY channel", clientID)
+	if err != nil {
+		t.Fatalf("Failed to read the outbox: %v", err)
+	}
+	if len(recipients) != 2 || recipients[0] != "helena@example.com" || recipients[1] != "5524998548386" {
+		t.Errorf("Expected one e-mail and one SMS, got %v", recipients)
+	}
+}
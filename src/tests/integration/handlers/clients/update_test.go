@@ -63,7 +63,7 @@ func TestUpdateClientHandler(t *testing.T) {
 				FirstName:  "Vitor",
 				LastName:   "Bastos",
 				Username:   "vitor.bastos",
-				Phone:      "999999999",
+				Phone:      "24999999999",
 				IsMonthly:  false,
 				AddressID:  "",
 				Street:     "Avenida Geraldo Lima",
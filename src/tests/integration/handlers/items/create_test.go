@@ -96,7 +96,7 @@ func TestCreateItemHandler(t *testing.T) {
 			} else {
 				var errResponse map[string]interface{}
 				json.NewDecoder(recorder.Body).Decode(&errResponse)
-				if errResponse["error"] == nil {
+				if errResponse["code"] == nil {
 					t.Errorf("Expected error response, got none")
 				}
 			}
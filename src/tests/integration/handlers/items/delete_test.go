@@ -70,7 +70,7 @@ func TestDeleteItemHandler(t *testing.T) {
 			} else {
 				var errResponse map[string]interface{}
 				if err := json.NewDecoder(recorder.Body).Decode(&errResponse); err == nil {
-					if errResponse["error"] == nil {
+					if errResponse["code"] == nil {
 						t.Errorf("Expected error response, got none")
 					}
 				} else {
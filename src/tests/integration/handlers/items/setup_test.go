@@ -74,13 +74,15 @@ func setupSchemas(db *sqlx.DB) error {
 			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
 			phone CHAR(12),
 			is_mensal boolean,
-			monthly_date DATE
+			monthly_date DATE,
+			cpf CHAR(11)
 		) INHERITS (users);`,
 		`CREATE TABLE IF NOT EXISTS laundry_items (
 			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
 			name VARCHAR(255) NOT NULL,
 			price numeric(10,2)
 		);`,
+		`CREATE SEQUENCE IF NOT EXISTS nfse_rps_number`,
 		`CREATE TABLE IF NOT EXISTS laundry_services (
 			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
 			status VARCHAR(15) NOT NULL,
@@ -93,6 +95,20 @@ func setupSchemas(db *sqlx.DB) error {
 			is_weight boolean,
 			client_id UUID,
 			is_paid boolean,
+			address_id UUID,
+			tracking_code VARCHAR(10) NOT NULL UNIQUE DEFAULT upper(substr(replace(uuid_generate_v4()::text, '-', ''), 1, 10)),
+			priority VARCHAR(10) NOT NULL DEFAULT 'normal' CHECK (priority IN ('normal', 'express', 'same_day')),
+			surcharge NUMERIC(5, 2) NOT NULL DEFAULT 0,
+			loyalty_discount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+			rps_number BIGINT UNIQUE,
+			invoice_status VARCHAR(10) CHECK (invoice_status IN ('processing', 'issued', 'rejected')),
+			invoice_number VARCHAR(20),
+			invoice_verification_code VARCHAR(50),
+			invoice_pdf_url TEXT,
+			invoice_message VARCHAR(255),
+			invoiced_at TIMESTAMP,
+			payment_method VARCHAR(6) CHECK (payment_method IN ('cash', 'card', 'pix', 'wallet')),
+			paid_at TIMESTAMP,
 			FOREIGN KEY (client_id) REFERENCES clients(id)
 		);`,
 		`CREATE TABLE IF NOT EXISTS laundry_items_services (
@@ -107,12 +123,15 @@ func setupSchemas(db *sqlx.DB) error {
 		`CREATE TABLE address (
 			address_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
 			street VARCHAR(255) NOT NULL,
+			neighborhood VARCHAR(100),
 			city VARCHAR(100) NOT NULL,
 			state CHAR(2) NOT NULL,
 			postal_code VARCHAR(20),
 			number VARCHAR(5) NOT NULL,
 			complement VARCHAR(255),
-			landmark VARCHAR(255)
+			landmark VARCHAR(255),
+			latitude NUMERIC(9, 6),
+			longitude NUMERIC(9, 6)
 		);
 		
 		ALTER TABLE clients
@@ -123,6 +142,264 @@ func setupSchemas(db *sqlx.DB) error {
 		FOREIGN KEY (address_id) REFERENCES address(address_id)
 		ON DELETE CASCADE;
 		`,
+		`CREATE TABLE IF NOT EXISTS client_addresses (
+			address_id UUID PRIMARY KEY,
+			client_id UUID NOT NULL,
+			label VARCHAR(10) NOT NULL DEFAULT 'home',
+			is_default boolean NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (address_id) REFERENCES address(address_id) ON DELETE CASCADE,
+			FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+		);
+		
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_client_addresses_default ON client_addresses (client_id) WHERE is_default;`,
+		`CREATE TABLE IF NOT EXISTS notification_preferences (
+			client_id UUID PRIMARY KEY,
+			email VARCHAR(255),
+			email_enabled boolean NOT NULL DEFAULT FALSE,
+			sms_enabled boolean NOT NULL DEFAULT FALSE,
+			whatsapp_enabled boolean NOT NULL DEFAULT TRUE,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS notification_outbox (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			client_id UUID,
+			channel VARCHAR(10) NOT NULL,
+			kind VARCHAR(30) NOT NULL,
+			recipient VARCHAR(255) NOT NULL,
+			subject VARCHAR(255) NOT NULL DEFAULT '',
+			body TEXT NOT NULL,
+			status VARCHAR(10) NOT NULL DEFAULT 'pending',
+			attempts INT NOT NULL DEFAULT 0,
+			next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			last_error TEXT,
+			dedupe_key VARCHAR(255) UNIQUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			sent_at TIMESTAMP,
+			FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE SET NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS domain_events (
+			id BIGSERIAL PRIMARY KEY,
+			type VARCHAR(50) NOT NULL,
+			aggregate_id UUID NOT NULL,
+			payload JSONB NOT NULL,
+			occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			dispatched_at TIMESTAMP,
+			attempts INT NOT NULL DEFAULT 0,
+			next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			last_error TEXT
+		);`,
+		`CREATE TABLE IF NOT EXISTS domain_event_deliveries (
+			event_id BIGINT NOT NULL,
+			subscriber VARCHAR(50) NOT NULL,
+			delivered_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (event_id, subscriber),
+			FOREIGN KEY (event_id) REFERENCES domain_events(id) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS webhooks (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			url VARCHAR(2048) NOT NULL,
+			secret VARCHAR(255) NOT NULL,
+			event_types TEXT[] NOT NULL,
+			description VARCHAR(255) NOT NULL DEFAULT '',
+			is_active boolean NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			webhook_id UUID NOT NULL,
+			event_id BIGINT NOT NULL,
+			event_type VARCHAR(50) NOT NULL,
+			status VARCHAR(10) NOT NULL DEFAULT 'pending',
+			attempts INT NOT NULL DEFAULT 0,
+			next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			response_code INT,
+			response_body TEXT,
+			last_error TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			delivered_at TIMESTAMP,
+			UNIQUE (webhook_id, event_id),
+			FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+			FOREIGN KEY (event_id) REFERENCES domain_events(id) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS opening_hours (
+			weekday SMALLINT PRIMARY KEY,
+			opens_at TIME NOT NULL,
+			closes_at TIME NOT NULL,
+			CHECK (weekday BETWEEN 0 AND 6),
+			CHECK (closes_at > opens_at)
+		);`,
+		`CREATE TABLE IF NOT EXISTS holidays (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			date DATE NOT NULL,
+			name VARCHAR(100) NOT NULL,
+			recurring boolean NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS closures (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			starts_at TIMESTAMP NOT NULL,
+			ends_at TIMESTAMP NOT NULL,
+			reason VARCHAR(255) NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			CHECK (ends_at > starts_at)
+		);`,
+		`CREATE TABLE IF NOT EXISTS production_settings (
+			id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+			max_kg_per_day NUMERIC(10, 2) NOT NULL DEFAULT 0,
+			max_pieces_per_day INT NOT NULL DEFAULT 0,
+			express_surcharge NUMERIC(5, 2) NOT NULL DEFAULT 50,
+			same_day_surcharge NUMERIC(5, 2) NOT NULL DEFAULT 100,
+			same_day_cutoff TIME NOT NULL DEFAULT '12:00',
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS machines (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			name VARCHAR(100) NOT NULL UNIQUE,
+			type VARCHAR(10) NOT NULL CHECK (type IN ('washer', 'dryer', 'iron')),
+			capacity_kg NUMERIC(6, 2) NOT NULL CHECK (capacity_kg > 0),
+			is_active boolean NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS machine_loads (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			machine_id UUID NOT NULL REFERENCES machines(id),
+			operator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+			stage VARCHAR(50) NOT NULL,
+			weight NUMERIC(6, 2) NOT NULL DEFAULT 0,
+			notes VARCHAR(255) NOT NULL DEFAULT '',
+			started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			ended_at TIMESTAMP
+		);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_machine_loads_running ON machine_loads (machine_id) WHERE ended_at IS NULL;`,
+		`CREATE TABLE IF NOT EXISTS machine_load_services (
+			load_id UUID NOT NULL REFERENCES machine_loads(id) ON DELETE CASCADE,
+			laundry_service_id UUID NOT NULL REFERENCES laundry_services(id) ON DELETE CASCADE,
+			PRIMARY KEY (load_id, laundry_service_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS incidents (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			laundry_service_id UUID NOT NULL REFERENCES laundry_services(id) ON DELETE CASCADE,
+			laundry_item_id UUID,
+			type VARCHAR(15) NOT NULL CHECK (type IN ('damage', 'loss', 'complaint')),
+			description TEXT NOT NULL,
+			photos TEXT[] NOT NULL DEFAULT '{}',
+			compensation NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (compensation >= 0),
+			status VARCHAR(15) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'investigating', 'resolved', 'rejected')),
+			resolution TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			resolved_at TIMESTAMP,
+			FOREIGN KEY (laundry_service_id, laundry_item_id) REFERENCES laundry_items_services(laundry_service_id, laundry_item_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS wallet_accounts (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			client_id UUID UNIQUE REFERENCES clients(id) ON DELETE CASCADE,
+			code VARCHAR(20) UNIQUE,
+			balance NUMERIC(12, 2) NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			CHECK ((client_id IS NULL) <> (code IS NULL)),
+			CHECK (client_id IS NULL OR balance >= 0)
+		);`,
+		`INSERT INTO wallet_accounts (code) VALUES ('cash'), ('sales'), ('compensation'), ('adjustments') ON CONFLICT DO NOTHING;`,
+		`CREATE TABLE IF NOT EXISTS wallet_transactions (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			type VARCHAR(15) NOT NULL CHECK (type IN ('top_up', 'payment', 'credit', 'adjustment')),
+			client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+			laundry_service_id UUID REFERENCES laundry_services(id) ON DELETE SET NULL,
+			incident_id UUID REFERENCES incidents(id) ON DELETE SET NULL,
+			reason VARCHAR(255) NOT NULL DEFAULT '',
+			created_by UUID,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS wallet_entries (
+			id BIGSERIAL PRIMARY KEY,
+			transaction_id UUID NOT NULL REFERENCES wallet_transactions(id) ON DELETE CASCADE,
+			account_id UUID NOT NULL REFERENCES wallet_accounts(id),
+			amount NUMERIC(12, 2) NOT NULL CHECK (amount <> 0)
+		);`,
+		`CREATE TABLE IF NOT EXISTS loyalty_settings (
+			id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+			is_active boolean NOT NULL DEFAULT TRUE,
+			points_per_real NUMERIC(6, 2) NOT NULL DEFAULT 1,
+			point_value NUMERIC(6, 4) NOT NULL DEFAULT 0.05,
+			min_redemption INT NOT NULL DEFAULT 100,
+			max_discount NUMERIC(5, 2) NOT NULL DEFAULT 50,
+			expiry_days INT NOT NULL DEFAULT 365,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS loyalty_entries (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+			type VARCHAR(10) NOT NULL CHECK (type IN ('earn', 'redeem', 'expire')),
+			points INT NOT NULL CHECK (points <> 0),
+			remaining INT NOT NULL DEFAULT 0 CHECK (remaining >= 0),
+			laundry_service_id UUID REFERENCES laundry_services(id) ON DELETE SET NULL,
+			expires_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_loyalty_entries_earned ON loyalty_entries (laundry_service_id) WHERE type = 'earn';`,
+		`CREATE TABLE IF NOT EXISTS cash_sessions (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			opening_float NUMERIC(10, 2) NOT NULL DEFAULT 0,
+			opened_by UUID,
+			opened_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			expected NUMERIC(10, 2),
+			counted NUMERIC(10, 2),
+			closed_by UUID,
+			closed_at TIMESTAMP,
+			notes VARCHAR(255) NOT NULL DEFAULT ''
+		);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_cash_sessions_open ON cash_sessions ((closed_at IS NULL)) WHERE closed_at IS NULL;`,
+		`CREATE TABLE IF NOT EXISTS cash_movements (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			session_id UUID NOT NULL REFERENCES cash_sessions(id) ON DELETE CASCADE,
+			type VARCHAR(10) NOT NULL CHECK (type IN ('payment', 'withdrawal')),
+			method VARCHAR(5) NOT NULL DEFAULT 'cash' CHECK (method IN ('cash', 'card', 'pix')),
+			amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+			laundry_service_id UUID REFERENCES laundry_services(id) ON DELETE SET NULL,
+			reason VARCHAR(255) NOT NULL DEFAULT '',
+			created_by UUID,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS service_attachments (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			laundry_service_id UUID NOT NULL REFERENCES laundry_services(id) ON DELETE CASCADE,
+			stage VARCHAR(10) NOT NULL CHECK (stage IN ('intake', 'delivery')),
+			filename VARCHAR(255) NOT NULL DEFAULT '',
+			content_type VARCHAR(50) NOT NULL,
+			size BIGINT NOT NULL,
+			width INT NOT NULL,
+			height INT NOT NULL,
+			storage_key VARCHAR(255) NOT NULL,
+			thumbnail_key VARCHAR(255) NOT NULL,
+			notes VARCHAR(255) NOT NULL DEFAULT '',
+			uploaded_by UUID,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS supplies (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			name VARCHAR(100) NOT NULL UNIQUE,
+			unit VARCHAR(5) NOT NULL CHECK (unit IN ('un', 'kg', 'g', 'l', 'ml')),
+			stock NUMERIC(12, 3) NOT NULL DEFAULT 0 CHECK (stock >= 0),
+			reorder_level NUMERIC(12, 3) NOT NULL DEFAULT 0 CHECK (reorder_level >= 0),
+			usage_per_load NUMERIC(10, 4) NOT NULL DEFAULT 0 CHECK (usage_per_load >= 0),
+			usage_per_kg NUMERIC(10, 4) NOT NULL DEFAULT 0 CHECK (usage_per_kg >= 0),
+			is_active boolean NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS stock_movements (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			supply_id UUID NOT NULL REFERENCES supplies(id),
+			type VARCHAR(15) NOT NULL CHECK (type IN ('purchase', 'consumption', 'adjustment')),
+			quantity NUMERIC(12, 3) NOT NULL,
+			unit_cost NUMERIC(10, 2),
+			load_id UUID REFERENCES machine_loads(id) ON DELETE SET NULL,
+			estimated boolean NOT NULL DEFAULT FALSE,
+			notes VARCHAR(255) NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
 	}
 
 	for _, stmt := range statements {
@@ -100,7 +100,7 @@ func TestUpdateItemHandler(t *testing.T) {
 			} else {
 				var errResponse map[string]interface{}
 				json.NewDecoder(recorder.Body).Decode(&errResponse)
-				if errResponse["error"] == nil {
+				if errResponse["code"] == nil {
 					t.Errorf("Expected error response, got none")
 				}
 			}
@@ -89,6 +89,23 @@ func TestDeleteItemServiceHandler(t *testing.T) {
 		}
 	}
 
+	setupServiceWithIncident := func(db *sqlx.DB) map[string]string {
+		ids := setupInitialService(db)
+
+		// The line of the service, not of another service with an item of the same name
+		var itemID string
+		err := db.Get(&itemID, "SELECT laundry_item_id FROM laundry_items_services WHERE laundry_service_id = $1 LIMIT 1", ids["serviceID"])
+		if err != nil {
+			t.Fatalf("Setup failed: Unable to fetch line of service: %v", err)
+		}
+		ids["itemID"] = itemID
+		_, err = db.Exec("INSERT INTO incidents (laundry_service_id, laundry_item_id, type, description) VALUES ($1, $2, 'damage', 'Rasgo na manga')", ids["serviceID"], ids["itemID"])
+		if err != nil {
+			t.Fatalf("Setup failed: Unable to insert incident: %v", err)
+		}
+		return ids
+	}
+
 	tests := []struct {
 		name           string
 		setupFunc      func(db *sqlx.DB) map[string]string
@@ -103,6 +120,12 @@ func TestDeleteItemServiceHandler(t *testing.T) {
 			wantErr:        false,
 			wantTotalPrice: 50,
 		},
+		{
+			name:       "Item Service with an incident",
+			setupFunc:  setupServiceWithIncident,
+			wantStatus: http.StatusConflict,
+			wantErr:    true,
+		},
 	}
 
 	for _, tc := range tests {
@@ -142,7 +165,12 @@ func TestDeleteItemServiceHandler(t *testing.T) {
 					t.Errorf("Expected total price to be %.2f, got %.2f", tc.wantTotalPrice, totalPrice)
 				}
 			} else {
-				// Verify that an appropriate error message is returned
+				// The line stays as the record of the incident
+				var exists bool
+				db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM laundry_items_services WHERE laundry_service_id = $1 AND laundry_item_id = $2)", serviceID, itemID)
+				if !exists {
+					t.Errorf("Expected the line to be kept")
+				}
 			}
 
 			// Rollback the transaction to ensure the test is not affecting the database
@@ -0,0 +1,198 @@
+package testhandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	logisticshandlers "lavanderia/handlers/logistics"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq" // PostgreSQL driver
+)
+
+func setupService(t *testing.T, db *sqlx.DB, neighborhood, postalCode string) uuid.UUID {
+	var addressID uuid.UUID
+	err := db.QueryRow("INSERT INTO address (street, neighborhood, city, state, postal_code, number) VALUES ($1, $2, $3, $4, $5, $6) RETURNING address_id",
+		"Rua dos Mineiros", neighborhood, "Valença", "RJ", postalCode, "10").Scan(&addressID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert address: %v", err)
+	}
+
+	var clientID uuid.UUID
+	err = db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal, address_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+		"João", "Silva", "joaosilva", "senha123", false, "24987654321", false, addressID).Scan(&clientID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert client: %v", err)
+	}
+
+	var serviceID uuid.UUID
+	err = db.QueryRow("INSERT INTO laundry_services (client_id, estimated_completion_date, is_weight, weight, is_piece, is_paid, status, total_price) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+		clientID, time.Now().Add(48*time.Hour), true, 2, false, false, "Separado", 40).Scan(&serviceID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert service: %v", err)
+	}
+	return serviceID
+}
+
+func setupSlot(t *testing.T, db *sqlx.DB, capacity int, isActive bool) uuid.UUID {
+	var slotID uuid.UUID
+	err := db.QueryRow("INSERT INTO time_slots (start_time, end_time, capacity, is_active) VALUES ($1, $2, $3, $4) RETURNING id",
+		"08:00", "10:00", capacity, isActive).Scan(&slotID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert time slot: %v", err)
+	}
+	return slotID
+}
+
+func book(t *testing.T, handler http.HandlerFunc, serviceID uuid.UUID, req logisticshandlers.BookingRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(req)
+	request, _ := http.NewRequest("POST", "/services/"+serviceID.String()+"/pickup", bytes.NewBuffer(body))
+	request = mux.SetURLVars(request, map[string]string{"id": serviceID.String()})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestBookPickupHandler(t *testing.T) {
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	fullSlot := setupSlot(t, db, 1, true)
+	book(t, logisticshandlers.BookPickupHandler(db), setupService(t, db, "Centro", "27600000"), logisticshandlers.BookingRequest{Date: tomorrow, TimeSlotID: fullSlot})
+
+	tests := []struct {
+		name       string
+		serviceID  uuid.UUID
+		request    logisticshandlers.BookingRequest
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "Valid pickup",
+			serviceID:  setupService(t, db, "Centro", "27600000"),
+			request:    logisticshandlers.BookingRequest{Date: tomorrow, TimeSlotID: setupSlot(t, db, 2, true)},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Slot without capacity",
+			serviceID:  setupService(t, db, "Centro", "27600000"),
+			request:    logisticshandlers.BookingRequest{Date: tomorrow, TimeSlotID: fullSlot},
+			wantStatus: http.StatusConflict,
+			wantCode:   "time_slot_full",
+		},
+		{
+			name:       "Disabled slot",
+			serviceID:  setupService(t, db, "Centro", "27600000"),
+			request:    logisticshandlers.BookingRequest{Date: tomorrow, TimeSlotID: setupSlot(t, db, 2, false)},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "validation_failed",
+		},
+		{
+			name:       "Date in the past",
+			serviceID:  setupService(t, db, "Centro", "27600000"),
+			request:    logisticshandlers.BookingRequest{Date: "2020-01-01", TimeSlotID: setupSlot(t, db, 2, true)},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "validation_failed",
+		},
+		{
+			name:       "Service not found",
+			serviceID:  uuid.New(),
+			request:    logisticshandlers.BookingRequest{Date: tomorrow, TimeSlotID: setupSlot(t, db, 2, true)},
+			wantStatus: http.StatusNotFound,
+			wantCode:   "service_not_found",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := book(t, logisticshandlers.BookPickupHandler(db), tc.serviceID, tc.request)
+
+			if recorder.Code != tc.wantStatus {
+				t.Errorf("Expected status code %d, got %d: %s", tc.wantStatus, recorder.Code, recorder.Body.String())
+			}
+
+			if tc.wantCode != "" {
+				var errResponse map[string]interface{}
+				json.NewDecoder(recorder.Body).Decode(&errResponse)
+				if errResponse["code"] != tc.wantCode {
+					t.Errorf("Expected error code %s, got %v", tc.wantCode, errResponse["code"])
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateBookingHandler(t *testing.T) {
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	recorder := book(t, logisticshandlers.BookPickupHandler(db), setupService(t, db, "Centro", "27600000"),
+		logisticshandlers.BookingRequest{Date: tomorrow, TimeSlotID: setupSlot(t, db, 2, true)})
+
+	var booking struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(recorder.Body).Decode(&booking)
+
+	tests := []struct {
+		name       string
+		status     string
+		wantStatus int
+	}{
+		{name: "Scheduled to done is not allowed", status: "done", wantStatus: http.StatusBadRequest},
+		{name: "Scheduled to en route", status: "en_route", wantStatus: http.StatusOK},
+		{name: "En route to done", status: "done", wantStatus: http.StatusOK},
+		{name: "Done is final", status: "failed", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(logisticshandlers.BookingUpdate{Status: tc.status})
+			req, _ := http.NewRequest("PATCH", "/logistics/bookings/"+booking.ID, bytes.NewBuffer(body))
+			req = mux.SetURLVars(req, map[string]string{"id": booking.ID})
+
+			recorder := httptest.NewRecorder()
+			logisticshandlers.UpdateBookingHandler(db).ServeHTTP(recorder, req)
+
+			if recorder.Code != tc.wantStatus {
+				t.Errorf("Expected status code %d, got %d: %s", tc.wantStatus, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouteSheetHandler(t *testing.T) {
+	date := time.Now().AddDate(0, 0, 2).Format("2006-01-02")
+	slotID := setupSlot(t, db, 5, true)
+
+	for _, neighborhood := range []string{"Centro", "Aparecida", "Belo Horizonte"} {
+		recorder := book(t, logisticshandlers.BookPickupHandler(db), setupService(t, db, neighborhood, "27600000"),
+			logisticshandlers.BookingRequest{Date: date, TimeSlotID: slotID})
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Setup failed: Unable to book pickup: %s", recorder.Body.String())
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/logistics/route-sheet?date="+date, nil)
+	recorder := httptest.NewRecorder()
+	logisticshandlers.RouteSheetHandler(db).ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, recorder.Code)
+	}
+
+	var sheet logisticshandlers.RouteSheet
+	json.NewDecoder(recorder.Body).Decode(&sheet)
+
+	want := []string{"Aparecida", "Belo Horizonte", "Centro"}
+	if len(sheet.Stops) != len(want) {
+		t.Fatalf("Expected %d stops, got %d", len(want), len(sheet.Stops))
+	}
+	for i, stop := range sheet.Stops {
+		if stop.Neighborhood != want[i] {
+			t.Errorf("Expected stop %d in %s, got %s", i, want[i], stop.Neighborhood)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package testhandlers
+
+import (
+	"encoding/json"
+	logisticshandlers "lavanderia/handlers/logistics"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq" // PostgreSQL driver
+)
+
+func TestRoutesHandler(t *testing.T) {
+	t.Setenv("SHOP_LATITUDE", "-22.2453")
+	t.Setenv("SHOP_LONGITUDE", "-43.7003")
+
+	date := time.Now().AddDate(0, 0, 3).Format("2006-01-02")
+	slotID := setupSlot(t, db, 5, true)
+
+	// Stops at increasing distances north of the shop, booked out of order
+	coordinates := map[string][]float64{
+		"Centro":    {-22.2353, -43.7003},
+		"Aparecida": {-22.2153, -43.7003},
+		"Varginha":  {-22.2253, -43.7003},
+		"Osório":    nil,
+	}
+	for _, neighborhood := range []string{"Centro", "Aparecida", "Varginha", "Osório"} {
+		serviceID := setupService(t, db, neighborhood, "27600000")
+		if point := coordinates[neighborhood]; point != nil {
+			setupCoordinates(t, serviceID, point[0], point[1])
+		}
+
+		recorder := book(t, logisticshandlers.BookDeliveryHandler(db), serviceID,
+			logisticshandlers.BookingRequest{Date: date, TimeSlotID: slotID})
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Setup failed: Unable to book delivery: %s", recorder.Body.String())
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/logistics/routes?date="+date, nil)
+	recorder := httptest.NewRecorder()
+	logisticshandlers.RoutesHandler(db).ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+
+	var route logisticshandlers.OptimizedRoute
+	json.NewDecoder(recorder.Body).Decode(&route)
+
+	want := []string{"Centro", "Varginha", "Aparecida"}
+	if len(route.Stops) != len(want) {
+		t.Fatalf("Expected %d stops, got %d", len(want), len(route.Stops))
+	}
+	for i, stop := range route.Stops {
+		if stop.Neighborhood != want[i] || stop.Order != i+1 {
+			t.Errorf("Expected stop %d in %s, got %d in %s", i+1, want[i], stop.Order, stop.Neighborhood)
+		}
+	}
+	if len(route.Unrouted) != 1 || route.Unrouted[0].Neighborhood != "Osório" {
+		t.Errorf("Expected the address without coordinates to be unrouted, got %+v", route.Unrouted)
+	}
+	if route.Departure != "08:00" || route.LateStops != 0 {
+		t.Errorf("Expected to leave at 08:00 without late stops, got %s and %d", route.Departure, route.LateStops)
+	}
+}
+
+func TestRoutesHandlerWithoutShopLocation(t *testing.T) {
+	t.Setenv("SHOP_LATITUDE", "")
+
+	req, _ := http.NewRequest("GET", "/logistics/routes?date=2024-03-01", nil)
+	recorder := httptest.NewRecorder()
+	logisticshandlers.RoutesHandler(db).ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, recorder.Code)
+	}
+}
+
+func setupCoordinates(t *testing.T, serviceID uuid.UUID, latitude, longitude float64) {
+	_, err := db.Exec(`
+		UPDATE address SET latitude = $1, longitude = $2
+		WHERE address_id = (
+			SELECT cli.address_id FROM laundry_services ls JOIN clients cli ON cli.id = ls.client_id WHERE ls.id = $3
+		)`, latitude, longitude, serviceID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to set coordinates: %v", err)
+	}
+}
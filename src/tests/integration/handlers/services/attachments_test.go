@@ -0,0 +1,121 @@
+package testhandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"lavanderia/entities"
+	attachmentshandlers "lavanderia/handlers/attachments"
+	middleware "lavanderia/middlewares"
+	"lavanderia/storage"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestAttachments(t *testing.T) {
+	store := storage.Local{Dir: t.TempDir()}
+
+	var clientID, serviceID string
+	err := db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		"Davi", "Lima", "davi.lima", "senha123", false, "24998548392", false).Scan(&clientID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert client: %v", err)
+	}
+	err = db.QueryRow("INSERT INTO laundry_services (client_id, status, is_weight, is_piece, total_price) VALUES ($1, 'Separado', false, true, 40) RETURNING id",
+		clientID).Scan(&serviceID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert service: %v", err)
+	}
+
+	admin := middleware.User{ID: "admin", Role: "Admin"}
+	send := func(handler http.HandlerFunc, method string, body *bytes.Buffer, contentType, id string, user middleware.User) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/", body)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		req = req.WithContext(middleware.WithUser(req.Context(), user))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+	upload := func(filename string, content []byte, stage string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("stage", stage)
+		part, _ := form.CreateFormFile("file", filename)
+		part.Write(content)
+		form.Close()
+		return send(attachmentshandlers.UploadAttachmentHandler(db, store), "POST", &body, form.FormDataContentType(), serviceID, admin)
+	}
+
+	var photo bytes.Buffer
+	png.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 800, 600)))
+
+	var attachment entities.AttachmentEntity
+	t.Run("Upload", func(t *testing.T) {
+		recorder := upload("nota.pdf", []byte("%PDF-1.4"), "intake")
+		if recorder.Code != http.StatusBadRequest || !bytes.Contains(recorder.Body.Bytes(), []byte("unsupported_file_type")) {
+			t.Errorf("Expected a PDF to be refused, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		recorder = upload("camisa.png", photo.Bytes(), "pickup")
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected an unknown stage to fail, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		recorder = upload("camisa.png", photo.Bytes(), "intake")
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+		json.NewDecoder(recorder.Body).Decode(&attachment)
+		if attachment.ContentType != "image/png" || attachment.Width != 800 || attachment.Height != 600 || attachment.Filename != "camisa.png" {
+			t.Errorf("Unexpected attachment %+v", attachment)
+		}
+	})
+
+	t.Run("Ownership", func(t *testing.T) {
+		owner := middleware.User{ID: clientID, Role: "Client"}
+		stranger := middleware.User{ID: "00000000-0000-0000-0000-000000000001", Role: "Client"}
+
+		recorder := send(attachmentshandlers.ListAttachmentsHandler(db), "GET", &bytes.Buffer{}, "", serviceID, owner)
+		var list []entities.AttachmentEntity
+		json.NewDecoder(recorder.Body).Decode(&list)
+		if recorder.Code != http.StatusOK || len(list) != 1 {
+			t.Errorf("Expected the owner to see the photo, got %d: %+v", recorder.Code, list)
+		}
+
+		recorder = send(attachmentshandlers.ListAttachmentsHandler(db), "GET", &bytes.Buffer{}, "", serviceID, stranger)
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected another client to be forbidden, got %d", recorder.Code)
+		}
+
+		id := attachment.ID.String()
+		recorder = send(attachmentshandlers.ThumbnailHandler(db, store), "GET", &bytes.Buffer{}, "", id, owner)
+		if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "image/jpeg" {
+			t.Errorf("Expected the JPEG thumbnail, got %d %s", recorder.Code, recorder.Header().Get("Content-Type"))
+		}
+
+		recorder = send(attachmentshandlers.DownloadAttachmentHandler(db, store), "GET", &bytes.Buffer{}, "", id, stranger)
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected another client not to download the photo, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		id := attachment.ID.String()
+		recorder := send(attachmentshandlers.DeleteAttachmentHandler(db, store), "DELETE", &bytes.Buffer{}, "", id, admin)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+		if _, err := store.Get(context.Background(), attachment.StorageKey); err != storage.ErrNotFound {
+			t.Errorf("Expected the photo to be removed from the storage, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package testhandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"lavanderia/calendar"
+	"lavanderia/entities"
+	calendarhandlers "lavanderia/handlers/calendar"
+	serviceshandlers "lavanderia/handlers/laundryServices"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestBusinessCalendar(t *testing.T) {
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM opening_hours")
+		db.Exec("DELETE FROM holidays")
+		db.Exec("DELETE FROM closures")
+	})
+
+	send := func(handler http.HandlerFunc, method, path string, body interface{}, vars map[string]string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		if vars != nil {
+			req = mux.SetURLVars(req, vars)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("Opening hours", func(t *testing.T) {
+		recorder := send(calendarhandlers.UpdateOpeningHoursHandler(db), "PUT", "/calendar/hours", calendarhandlers.OpeningHoursRequest{
+			Hours: []calendarhandlers.OpeningHoursDay{
+				{Weekday: 1, OpensAt: "9:00", ClosesAt: "17:00"},
+				{Weekday: 1, OpensAt: "08:00", ClosesAt: "12:00"},
+			},
+		}, nil)
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("Expected a repeated weekday to fail, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		recorder = send(calendarhandlers.UpdateOpeningHoursHandler(db), "PUT", "/calendar/hours", calendarhandlers.OpeningHoursRequest{
+			Hours: []calendarhandlers.OpeningHoursDay{
+				{Weekday: 1, OpensAt: "9:00", ClosesAt: "17:00"},
+				{Weekday: 3, OpensAt: "09:00", ClosesAt: "17:00"},
+			},
+		}, nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		recorder = send(calendarhandlers.ListOpeningHoursHandler(db), "GET", "/calendar/hours", nil, nil)
+		var hours []entities.OpeningHoursEntity
+		json.NewDecoder(recorder.Body).Decode(&hours)
+		if len(hours) != 2 || hours[0].OpensAt != "09:00" || hours[1].Weekday != 3 {
+			t.Errorf("Expected Monday and Wednesday from 09:00, got %+v", hours)
+		}
+
+		recorder = send(calendarhandlers.ListDaysHandler(db), "GET", "/calendar/days?from=2024-03-11&to=2024-03-17", nil, nil)
+		var days []calendar.Day
+		json.NewDecoder(recorder.Body).Decode(&days)
+		if len(days) != 7 || !days[0].IsOpen || days[1].IsOpen || days[1].Reason != "terça-feira" || !days[2].IsOpen {
+			t.Errorf("Expected only Monday and Wednesday open, got %+v", days)
+		}
+
+		db.Exec("DELETE FROM opening_hours")
+	})
+
+	t.Run("Holidays", func(t *testing.T) {
+		day := calendar.Default().AddDays(time.Now(), 2)
+		recorder := send(calendarhandlers.CreateHolidayHandler(db), "POST", "/calendar/holidays", calendarhandlers.HolidayRequest{
+			Date: day.Format("2006-01-02"),
+			Name: "Aniversário da cidade",
+		}, nil)
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+		var holiday entities.HolidayEntity
+		json.NewDecoder(recorder.Body).Decode(&holiday)
+
+		recorder = send(calendarhandlers.ListHolidaysHandler(db), "GET", "/calendar/holidays?year="+day.Format("2006"), nil, nil)
+		var holidays []entities.HolidayEntity
+		json.NewDecoder(recorder.Body).Decode(&holidays)
+		national, registered := 0, 0
+		for _, h := range holidays {
+			if h.National {
+				national++
+			} else if h.Date == holiday.Date {
+				registered++
+			}
+		}
+		if national < 9 || registered != 1 {
+			t.Errorf("Expected the national holidays and the registered one, got %+v", holidays)
+		}
+
+		// A service can't be due on the holiday
+		var clientID string
+		err := db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+			"Vera", "Mota", "vera.mota", "senha123", false, "24998548388", false).Scan(&clientID)
+		if err != nil {
+			t.Fatalf("Setup failed: Unable to insert client: %v", err)
+		}
+		var itemID string
+		err = db.QueryRow("INSERT INTO laundry_items (name, price) VALUES ($1, $2) RETURNING id", "Toalha", 8.00).Scan(&itemID)
+		if err != nil {
+			t.Fatalf("Setup failed: Unable to insert item: %v", err)
+		}
+		recorder = send(serviceshandlers.CreateServicesHandler(db), "POST", "/services", map[string]interface{}{
+			"client_id":                 clientID,
+			"is_piece":                  true,
+			"items":                     []InsertedLaundryItem{{LaundryItemID: itemID, ItemQuantity: 1}},
+			"estimated_completion_date": day,
+		}, nil)
+		if recorder.Code != http.StatusBadRequest || !bytes.Contains(recorder.Body.Bytes(), []byte("shop_closed")) {
+			t.Errorf("Expected the holiday to be refused, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		recorder = send(calendarhandlers.DeleteHolidayHandler(db), "DELETE", "/calendar/holidays/"+holiday.ID.String(), nil, map[string]string{"id": holiday.ID.String()})
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+		recorder = send(calendarhandlers.DeleteHolidayHandler(db), "DELETE", "/calendar/holidays/"+holiday.ID.String(), nil, map[string]string{"id": holiday.ID.String()})
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, got %d: %s", http.StatusNotFound, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("Closures", func(t *testing.T) {
+		start := time.Now().Add(time.Hour)
+		recorder := send(calendarhandlers.CreateClosureHandler(db), "POST", "/calendar/closures", calendarhandlers.ClosureRequest{
+			StartsAt: start, EndsAt: start.Add(-time.Minute), Reason: "Manutenção",
+		}, nil)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected an inverted period to fail, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		recorder = send(calendarhandlers.CreateClosureHandler(db), "POST", "/calendar/closures", calendarhandlers.ClosureRequest{
+			StartsAt: start, EndsAt: start.Add(4 * time.Hour), Reason: "Manutenção",
+		}, nil)
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		recorder = send(calendarhandlers.ListClosuresHandler(db), "GET", "/calendar/closures", nil, nil)
+		var closures []entities.ClosureEntity
+		json.NewDecoder(recorder.Body).Decode(&closures)
+		if len(closures) != 1 || closures[0].Reason != "Manutenção" {
+			t.Errorf("Expected the closure, got %+v", closures)
+		}
+	})
+}
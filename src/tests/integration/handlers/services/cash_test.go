@@ -0,0 +1,167 @@
+package testhandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"lavanderia/cash"
+	"lavanderia/entities"
+	cashhandlers "lavanderia/handlers/cash"
+	middleware "lavanderia/middlewares"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestCashSession(t *testing.T) {
+	send := func(handler http.HandlerFunc, method string, body interface{}, id string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, "/", bytes.NewBuffer(payload))
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		req = req.WithContext(middleware.WithUser(req.Context(), middleware.User{ID: "admin", Role: "Admin"}))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	var clientID string
+	err := db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		"Rita", "Leal", "rita.leal", "senha123", false, "24998548395", false).Scan(&clientID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert client: %v", err)
+	}
+	newService := func(price float64) uuid.UUID {
+		var id uuid.UUID
+		err := db.QueryRow("INSERT INTO laundry_services (client_id, status, is_weight, is_piece, total_price, is_paid) VALUES ($1, 'Separado', false, true, $2, false) RETURNING id",
+			clientID, price).Scan(&id)
+		if err != nil {
+			t.Fatalf("Setup failed: Unable to insert service: %v", err)
+		}
+		return id
+	}
+
+	recorder := send(cashhandlers.OpenSessionHandler(db), "POST", cashhandlers.OpenRequest{OpeningFloat: 50}, "")
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+	}
+	var session entities.CashSessionEntity
+	json.NewDecoder(recorder.Body).Decode(&session)
+	id := session.ID.String()
+
+	if recorder := send(cashhandlers.OpenSessionHandler(db), "POST", cashhandlers.OpenRequest{}, ""); recorder.Code != http.StatusConflict {
+		t.Errorf("Expected a second drawer not to open, got %d", recorder.Code)
+	}
+
+	t.Run("Movements", func(t *testing.T) {
+		paidInCash := newService(45)
+		recorder := send(cashhandlers.PaymentHandler(db), "POST", cashhandlers.PaymentRequest{ServiceID: paidInCash}, id)
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+		var paid struct {
+			IsPaid bool    `db:"is_paid"`
+			Method *string `db:"payment_method"`
+			PaidAt *string `db:"paid_at"`
+		}
+		db.Get(&paid, "SELECT is_paid, payment_method, paid_at::text FROM laundry_services WHERE id = $1", paidInCash)
+		if !paid.IsPaid || paid.Method == nil || *paid.Method != cash.Cash || paid.PaidAt == nil {
+			t.Errorf("Expected the service to be paid in cash, got %+v", paid)
+		}
+		if recorder := send(cashhandlers.PaymentHandler(db), "POST", cashhandlers.PaymentRequest{ServiceID: paidInCash}, id); recorder.Code != http.StatusConflict {
+			t.Errorf("Expected a paid service not to be paid again, got %d", recorder.Code)
+		}
+
+		// Part of the price doesn't pay the service
+		partial := newService(80)
+		if recorder := send(cashhandlers.PaymentHandler(db), "POST", cashhandlers.PaymentRequest{ServiceID: partial, Amount: 1}, id); recorder.Code != http.StatusConflict {
+			t.Errorf("Expected a payment short of the price to be refused, got %d", recorder.Code)
+		}
+		db.Get(&paid, "SELECT is_paid, payment_method, paid_at::text FROM laundry_services WHERE id = $1", partial)
+		if paid.IsPaid || paid.PaidAt != nil {
+			t.Errorf("Expected the service not to be paid, got %+v", paid)
+		}
+
+		// Taken by card for more than the price
+		send(cashhandlers.PaymentHandler(db), "POST", cashhandlers.PaymentRequest{ServiceID: newService(80), Method: cash.Card, Amount: 90}, id)
+
+		// Marked paid without going through the drawer
+		unrecorded := newService(60)
+		db.Exec("UPDATE laundry_services SET is_paid = true, paid_at = CURRENT_TIMESTAMP WHERE id = $1", unrecorded)
+		// Paid from the wallet, which doesn't go through the drawer
+		db.Exec("UPDATE laundry_services SET is_paid = true, payment_method = 'wallet', paid_at = CURRENT_TIMESTAMP WHERE id = $1", newService(30))
+
+		if recorder := send(cashhandlers.WithdrawalHandler(db), "POST", cashhandlers.WithdrawalRequest{Amount: 100, Reason: "Depósito"}, id); recorder.Code != http.StatusConflict {
+			t.Errorf("Expected a withdrawal above the 95 in cash to be refused, got %d", recorder.Code)
+		}
+		if recorder := send(cashhandlers.WithdrawalHandler(db), "POST", cashhandlers.WithdrawalRequest{Amount: 40, Reason: "Depósito"}, id); recorder.Code != http.StatusCreated {
+			t.Errorf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		var report cash.Report
+		json.NewDecoder(send(cashhandlers.CurrentSessionHandler(db), "GET", nil, "").Body).Decode(&report)
+		if report.Totals.Expected != 55 || report.Totals.Card != 90 || report.Totals.Payments != 2 {
+			t.Errorf("Expected 55 in the drawer and 90 by card, got %+v", report.Totals)
+		}
+		if len(report.Reconciliation.Unrecorded) != 1 || report.Reconciliation.Unrecorded[0].ServiceID != unrecorded {
+			t.Errorf("Expected the service paid outside the drawer, got %+v", report.Reconciliation.Unrecorded)
+		}
+		if len(report.Reconciliation.Mismatched) != 1 || report.Reconciliation.Mismatched[0].Amount != 90 {
+			t.Errorf("Expected the card payment over the price, got %+v", report.Reconciliation.Mismatched)
+		}
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		recorder := send(cashhandlers.CloseSessionHandler(db), "POST", map[string]interface{}{"counted": 52.5}, id)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+		var closed entities.CashSessionEntity
+		json.NewDecoder(recorder.Body).Decode(&closed)
+		if closed.Expected == nil || *closed.Expected != 55 || closed.Difference == nil || *closed.Difference != -2.5 {
+			t.Errorf("Expected the drawer 2.50 short of 55, got %+v", closed)
+		}
+
+		if recorder := send(cashhandlers.CloseSessionHandler(db), "POST", map[string]interface{}{"counted": 55}, id); recorder.Code != http.StatusConflict {
+			t.Errorf("Expected a closed session not to close again, got %d", recorder.Code)
+		}
+		if recorder := send(cashhandlers.WithdrawalHandler(db), "POST", cashhandlers.WithdrawalRequest{Amount: 1, Reason: "Troco"}, id); recorder.Code != http.StatusConflict {
+			t.Errorf("Expected a closed session not to take movements, got %d", recorder.Code)
+		}
+
+		recorder = send(cashhandlers.ZReportHandler(db), "GET", nil, id)
+		if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain") || !strings.Contains(recorder.Body.String(), "RELATÓRIO Z") {
+			t.Errorf("Expected a printable Z-report, got %s", recorder.Body.String())
+		}
+
+		var overShort cashhandlers.OverShortResponse
+		json.NewDecoder(send(cashhandlers.OverShortHandler(db), "GET", nil, "").Body).Decode(&overShort)
+		if len(overShort.Sessions) != 1 || overShort.Short != -2.5 || overShort.Net != -2.5 {
+			t.Errorf("Expected the session 2.50 short, got %+v", overShort)
+		}
+	})
+	t.Run("OpenedTogether", func(t *testing.T) {
+		first, _ := db.Beginx()
+		defer first.Rollback()
+		if _, err := cash.Open(first, 10, nil, ""); err != nil {
+			t.Fatalf("Expected the session to open: %v", err)
+		}
+
+		// The second one waits on the first one, and finds it open
+		opened := make(chan error)
+		go func() {
+			second, _ := db.Beginx()
+			defer second.Rollback()
+			_, err := cash.Open(second, 10, nil, "")
+			opened <- err
+		}()
+		first.Commit()
+		if err := <-opened; err != cash.ErrSessionOpen {
+			t.Errorf("Expected %v, got %v", cash.ErrSessionOpen, err)
+		}
+
+		db.Exec("DELETE FROM cash_sessions WHERE closed_at IS NULL")
+	})
+}
@@ -3,6 +3,7 @@ package testhandlers
 import (
 	"bytes"
 	"encoding/json"
+	"lavanderia/calendar"
 	"lavanderia/entities"
 	serviceshandlers "lavanderia/handlers/laundryServices"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq" // PostgreSQL driver
 )
@@ -87,7 +89,7 @@ func TestCreateServicesHandler(t *testing.T) {
 		{
 			name: "Valid Service by weight",
 			service: LaundryService{
-				EstimatedCompletionDate: time.Now().Add(24 * time.Hour),
+				EstimatedCompletionDate: calendar.Default().AddDays(time.Now(), 1),
 				Weight:                  5.0,
 				IsWeight:                true,
 				IsPiece:                 false,
@@ -102,7 +104,7 @@ func TestCreateServicesHandler(t *testing.T) {
 		{
 			name: "Valid Service by piece",
 			service: LaundryService{
-				EstimatedCompletionDate: time.Now().Add(24 * time.Hour),
+				EstimatedCompletionDate: calendar.Default().AddDays(time.Now(), 1),
 				IsWeight:                false,
 				IsPiece:                 true,
 				IsPaid:                  false,
@@ -116,7 +118,7 @@ func TestCreateServicesHandler(t *testing.T) {
 		{
 			name: "Invalid Weight",
 			service: LaundryService{
-				EstimatedCompletionDate: time.Now().Add(24 * time.Hour),
+				EstimatedCompletionDate: calendar.Default().AddDays(time.Now(), 1),
 				Weight:                  -1.0, // Invalid weight
 				IsWeight:                true,
 				IsPiece:                 false,
@@ -131,7 +133,7 @@ func TestCreateServicesHandler(t *testing.T) {
 		{
 			name: "No Items for Piece Based Service",
 			service: LaundryService{
-				EstimatedCompletionDate: time.Now().Add(24 * time.Hour),
+				EstimatedCompletionDate: calendar.Default().AddDays(time.Now(), 1),
 				IsPiece:                 true,
 				IsWeight:                false,
 				IsPaid:                  false,
@@ -144,13 +146,13 @@ func TestCreateServicesHandler(t *testing.T) {
 		{
 			name: "Invalid item for service",
 			service: LaundryService{
-				EstimatedCompletionDate: time.Now().Add(24 * time.Hour),
+				EstimatedCompletionDate: calendar.Default().AddDays(time.Now(), 1),
 				IsPiece:                 true,
 				IsWeight:                false,
 				IsPaid:                  false,
 				ClientID:                setupClient(db),
 				Items: []InsertedLaundryItem{{
-					LaundryItemID: "00000000-0000-0000-0000-000000000000",
+					LaundryItemID: uuid.New().String(),
 					ItemQuantity:  1,
 					Observation:   "No issue",
 				}},
@@ -162,7 +164,7 @@ func TestCreateServicesHandler(t *testing.T) {
 		{
 			name: "Client not found",
 			service: LaundryService{
-				EstimatedCompletionDate: time.Now().Add(24 * time.Hour),
+				EstimatedCompletionDate: calendar.Default().AddDays(time.Now(), 1),
 				Weight:                  1.0,
 				IsWeight:                true,
 				IsPiece:                 false,
@@ -213,20 +215,25 @@ func TestCreateServicesHandler(t *testing.T) {
 				}
 			} else {
 				var response struct { // Define a struct that matches your JSON response structure
-					Details struct {
+					Status int    `json:"status"`
+					Code   string `json:"code"`
+					Errors []struct {
 						Field   string `json:"field"`
+						Code    string `json:"code"`
 						Message string `json:"message"`
-						Status  int    `json:"status"`
-					} `json:"details"`
-					Error string `json:"error"`
+					} `json:"errors"`
 				}
 
 				if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
 					t.Fatalf("Failed to decode response body: %v", err)
 				}
 
-				if response.Details.Field != tc.errField {
-					t.Errorf("Expected error field '%s', got '%s'", tc.errField, response.Details.Field)
+				if response.Status != recorder.Code {
+					t.Errorf("Expected body status %d to match response status %d", response.Status, recorder.Code)
+				}
+
+				if len(response.Errors) == 0 || response.Errors[0].Field != tc.errField {
+					t.Errorf("Expected error field '%s', got %+v", tc.errField, response.Errors)
 				}
 			}
 		})
@@ -0,0 +1,89 @@
+package testhandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"lavanderia/estimate"
+	serviceshandlers "lavanderia/handlers/laundryServices"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEstimateServiceHandler(t *testing.T) {
+	var clientID string
+	err := db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		"Rita", "Lima", "rita.lima", "senha123", false, "24998548387", false).Scan(&clientID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert client: %v", err)
+	}
+
+	// Finished weight services of the last weeks
+	for i := 0; i < estimate.MinSamples; i++ {
+		created := time.Now().AddDate(0, 0, -7*(i+1))
+		_, err := db.Exec("INSERT INTO laundry_services (client_id, created_at, completed_at, estimated_completion_date, is_weight, weight, is_piece, is_paid, status, total_price) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+			clientID, created, created.AddDate(0, 0, 2), created.AddDate(0, 0, 2), true, 5.0, false, true, "Finalizado", 100)
+		if err != nil {
+			t.Fatalf("Setup failed: Unable to insert service: %v", err)
+		}
+	}
+
+	t.Run("Estimate", func(t *testing.T) {
+		body, _ := json.Marshal(map[string]interface{}{"is_weight": true, "weight": 4})
+		req, _ := http.NewRequest("POST", "/services/estimate", bytes.NewBuffer(body))
+		recorder := httptest.NewRecorder()
+		serviceshandlers.EstimateServiceHandler(db).ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+		var result estimate.Result
+		json.NewDecoder(recorder.Body).Decode(&result)
+		if result.Basis == estimate.BasisDefault || result.Samples < estimate.MinSamples || result.HistoryHours <= 0 {
+			t.Errorf("Expected an estimate from the finished services, got %+v", result)
+		}
+		if !result.EstimatedCompletionDate.After(time.Now()) {
+			t.Errorf("Expected a future date, got %s", result.EstimatedCompletionDate)
+		}
+	})
+
+	t.Run("Weight required", func(t *testing.T) {
+		body, _ := json.Marshal(map[string]interface{}{"is_weight": true})
+		req, _ := http.NewRequest("POST", "/services/estimate", bytes.NewBuffer(body))
+		recorder := httptest.NewRecorder()
+		serviceshandlers.EstimateServiceHandler(db).ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("Proposed on create", func(t *testing.T) {
+		var itemID string
+		err := db.QueryRow("INSERT INTO laundry_items (name, price) VALUES ($1, $2) RETURNING id", "Lençol", 15.00).Scan(&itemID)
+		if err != nil {
+			t.Fatalf("Setup failed: Unable to insert item: %v", err)
+		}
+
+		body, _ := json.Marshal(map[string]interface{}{"client_id": clientID, "is_weight": true, "weight": 4, "items": []InsertedLaundryItem{{LaundryItemID: itemID, ItemQuantity: 2}}})
+		req, _ := http.NewRequest("POST", "/services", bytes.NewBuffer(body))
+		recorder := httptest.NewRecorder()
+		serviceshandlers.CreateServicesHandler(db).ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+		var created serviceshandlers.LaundryService
+		json.NewDecoder(recorder.Body).Decode(&created)
+
+		var eta time.Time
+		err = db.Get(&eta, "SELECT estimated_completion_date FROM laundry_services WHERE id = $1", created.ID)
+		if err != nil {
+			t.Fatalf("Failed to fetch created service: %v", err)
+		}
+		if eta.IsZero() || created.EstimatedCompletionDate.IsZero() {
+			t.Errorf("Expected the completion date to be proposed, got %s", eta)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package testhandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"lavanderia/calendar"
+	"lavanderia/entities"
+	"lavanderia/events"
+	serviceshandlers "lavanderia/handlers/laundryServices"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestServiceEvents(t *testing.T) {
+	var clientID, serviceID uuid.UUID
+	err := db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		"Lucas", "Teixeira", "lucas.teixeira", "senha123", false, "24998548386", false).Scan(&clientID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert client: %v", err)
+	}
+	err = db.QueryRow("INSERT INTO laundry_services (client_id, estimated_completion_date, is_weight, weight, is_piece, is_paid, status, total_price) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+		clientID, time.Now().Add(24*time.Hour), true, 5.0, false, false, "Separado", 100).Scan(&serviceID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert service: %v", err)
+	}
+
+	update, _ := json.Marshal(entities.LaundryServicesEntity{
+		EstimatedCompletionDate: calendar.Default().AddDays(time.Now(), 2),
+		Weight:                  2,
+		IsWeight:                true,
+		IsPaid:                  true,
+		Status:                  "Lavando",
+		ClientID:                clientID,
+	})
+	req, _ := http.NewRequest("PUT", "/services/"+serviceID.String(), bytes.NewBuffer(update))
+	req = mux.SetURLVars(req, map[string]string{"id": serviceID.String()})
+	recorder := httptest.NewRecorder()
+	serviceshandlers.UpdateServiceHandler(db).ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+
+	var types []events.Type
+	if err := db.Select(&types, "SELECT type FROM domain_events WHERE aggregate_id = $1 ORDER BY id", serviceID); err != nil {
+		t.Fatalf("Failed to read the events: %v", err)
+	}
+	want := []events.Type{events.ServiceStatusChanged, events.ServiceRepriced, events.ServicePaid}
+	if len(types) != len(want) {
+		t.Fatalf("Expected events %v, got %v", want, types)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("Expected events %v, got %v", want, types)
+		}
+	}
+
+	// A failing subscriber is retried without redelivering to the others
+	received := map[events.Type]int{}
+	failures := 0
+	dispatcher := &events.Dispatcher{DB: db}
+	dispatcher.Subscribe("recorder", func(ctx context.Context, event events.Event) error {
+		if event.AggregateID == serviceID {
+			received[event.Type]++
+		}
+		return nil
+	})
+	dispatcher.Subscribe("payments", func(ctx context.Context, event events.Event) error {
+		if event.AggregateID != serviceID {
+			return nil
+		}
+		if failures == 0 {
+			failures++
+			return errors.New("unavailable")
+		}
+		var payment events.Payment
+		if err := json.Unmarshal(event.Payload, &payment); err != nil || payment.TotalPrice != 40 {
+			t.Errorf("Expected the payment of 40, got %s", event.Payload)
+		}
+		return nil
+	}, events.ServicePaid)
+
+	dispatchAll := func() {
+		for {
+			n, err := dispatcher.Dispatch(context.Background())
+			if err != nil {
+				t.Fatalf("Failed to dispatch: %v", err)
+			}
+			if n == 0 {
+				return
+			}
+		}
+	}
+
+	dispatchAll()
+	var pending int
+	db.Get(&pending, "SELECT COUNT(*) FROM domain_events WHERE aggregate_id = $1 AND dispatched_at IS NULL", serviceID)
+	if pending != 1 || failures != 1 {
+		t.Fatalf("Expected the payment to be retried, got %d pending after %d failures", pending, failures)
+	}
+
+	db.Exec("UPDATE domain_events SET next_attempt_at = NOW() WHERE aggregate_id = $1", serviceID)
+	dispatchAll()
+	db.Get(&pending, "SELECT COUNT(*) FROM domain_events WHERE aggregate_id = $1 AND dispatched_at IS NULL", serviceID)
+	if pending != 0 {
+		t.Errorf("Expected every event to be dispatched, got %d pending", pending)
+	}
+	for _, typ := range want {
+		if received[typ] != 1 {
+			t.Errorf("Expected %s to be received once, got %d", typ, received[typ])
+		}
+	}
+}
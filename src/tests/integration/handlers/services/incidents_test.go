@@ -0,0 +1,118 @@
+package testhandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"lavanderia/entities"
+	incidentshandlers "lavanderia/handlers/incidents"
+	"lavanderia/incidents"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestIncidents(t *testing.T) {
+	send := func(handler http.HandlerFunc, method, path string, body interface{}, id string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		if id != "" {
+			req = mux.SetURLVars(req, map[string]string{"id": id})
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	var clientID, serviceID string
+	var itemID uuid.UUID
+	err := db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		"Rita", "Moura", "rita.moura", "senha123", false, "24998548391", false).Scan(&clientID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert client: %v", err)
+	}
+	err = db.QueryRow("INSERT INTO laundry_items (name, price) VALUES ($1, $2) RETURNING id", "Vestido", 30.00).Scan(&itemID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert item: %v", err)
+	}
+	err = db.QueryRow("INSERT INTO laundry_services (client_id, status, is_weight, is_piece, total_price) VALUES ($1, 'Lavando', false, true, 30) RETURNING id",
+		clientID).Scan(&serviceID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert service: %v", err)
+	}
+	_, err = db.Exec("INSERT INTO laundry_items_services (laundry_service_id, laundry_item_id, item_quantity) VALUES ($1, $2, 1)", serviceID, itemID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert service item: %v", err)
+	}
+
+	var incident entities.IncidentEntity
+	t.Run("Report", func(t *testing.T) {
+		otherItem := uuid.New()
+		recorder := send(incidentshandlers.CreateIncidentHandler(db), "POST", "/services/"+serviceID+"/incidents", incidentshandlers.IncidentRequest{
+			LaundryItemID: &otherItem, Type: incidents.Damage, Description: "Mancha de água sanitária",
+		}, serviceID)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected an item out of the service to fail, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		recorder = send(incidentshandlers.CreateIncidentHandler(db), "POST", "/services/"+serviceID+"/incidents", incidentshandlers.IncidentRequest{
+			LaundryItemID: &itemID, Type: incidents.Damage, Description: "Mancha de água sanitária",
+			Photos: []string{"https://fotos.exemplo.com/vestido.jpg"}, Compensation: 30,
+		}, serviceID)
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+		json.NewDecoder(recorder.Body).Decode(&incident)
+		if incident.Status != incidents.Open || len(incident.Photos) != 1 {
+			t.Errorf("Expected an open incident with the photo, got %+v", incident)
+		}
+	})
+
+	t.Run("Resolve", func(t *testing.T) {
+		id := incident.ID.String()
+		recorder := send(incidentshandlers.IncidentStatusHandler(db), "POST", "/incidents/"+id+"/status", incidentshandlers.StatusRequest{
+			Status: incidents.Resolved,
+		}, id)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected the resolution to be required, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		compensation := 25.0
+		recorder = send(incidentshandlers.IncidentStatusHandler(db), "POST", "/incidents/"+id+"/status", incidentshandlers.StatusRequest{
+			Status: incidents.Resolved, Resolution: "Crédito do valor de mercado", Compensation: &compensation,
+		}, id)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		var balance float64
+		db.Get(&balance, "SELECT balance FROM wallet_accounts WHERE client_id = $1", clientID)
+		if balance != 25 {
+			t.Errorf("Expected the compensation credited to the wallet, got a balance of %v", balance)
+		}
+
+		recorder = send(incidentshandlers.IncidentStatusHandler(db), "POST", "/incidents/"+id+"/status", incidentshandlers.StatusRequest{
+			Status: incidents.Open,
+		}, id)
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected a closed incident not to move, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		var types []string
+		db.Select(&types, "SELECT type FROM domain_events WHERE aggregate_id = $1 AND type LIKE 'incident.%' ORDER BY id", serviceID)
+		if len(types) != 2 || types[1] != "incident.resolved" {
+			t.Errorf("Expected the incident to be reported and resolved, got %v", types)
+		}
+	})
+
+	t.Run("Report by item", func(t *testing.T) {
+		recorder := send(incidentshandlers.ReportHandler(db), "GET", "/incidents/report", nil, "")
+		var lines []incidents.ReportLine
+		json.NewDecoder(recorder.Body).Decode(&lines)
+		if len(lines) != 1 || lines[0].Name != "Vestido" || lines[0].Damages != 1 || lines[0].Compensation != 25 {
+			t.Errorf("Expected one damage compensated on the dress, got %+v", lines)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package testhandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"lavanderia/entities"
+	inventoryhandlers "lavanderia/handlers/inventory"
+	machineshandlers "lavanderia/handlers/machines"
+	"lavanderia/inventory"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestInventory(t *testing.T) {
+	send := func(handler http.HandlerFunc, method, path string, body interface{}, id string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		if id != "" {
+			req = mux.SetURLVars(req, map[string]string{"id": id})
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := send(inventoryhandlers.CreateSupplyHandler(db), "POST", "/supplies", inventoryhandlers.SupplyRequest{
+		Name: "Detergente", Unit: "l", ReorderLevel: 5, UsagePerLoad: 0.1, UsagePerKg: 0.05,
+	}, "")
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+	}
+	var detergent entities.SupplyEntity
+	json.NewDecoder(recorder.Body).Decode(&detergent)
+	if detergent.Stock != 0 || !detergent.IsActive {
+		t.Errorf("Expected an active supply without stock, got %+v", detergent)
+	}
+
+	recorder = send(inventoryhandlers.CreateSupplyHandler(db), "POST", "/supplies", inventoryhandlers.SupplyRequest{
+		Name: "detergente", Unit: "l",
+	}, "")
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected a repeated name to fail, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	id := detergent.ID.String()
+
+	t.Run("Purchase", func(t *testing.T) {
+		cost := 12.5
+		recorder := send(inventoryhandlers.PurchaseHandler(db), "POST", "/supplies/"+id+"/purchases", inventoryhandlers.PurchaseRequest{
+			Quantity: 20, UnitCost: &cost,
+		}, id)
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		var stock float64
+		db.Get(&stock, "SELECT stock FROM supplies WHERE id = $1", id)
+		if stock != 20 {
+			t.Errorf("Expected 20 l in stock, got %v", stock)
+		}
+	})
+
+	t.Run("Consumption", func(t *testing.T) {
+		recorder := send(inventoryhandlers.ConsumptionHandler(db), "POST", "/supplies/"+id+"/consumptions", inventoryhandlers.ConsumptionRequest{
+			Quantity: 30,
+		}, id)
+		if recorder.Code != http.StatusConflict || !bytes.Contains(recorder.Body.Bytes(), []byte("insufficient_stock")) {
+			t.Errorf("Expected taking more than the stock to conflict, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		recorder = send(inventoryhandlers.ConsumptionHandler(db), "POST", "/supplies/"+id+"/consumptions", inventoryhandlers.ConsumptionRequest{
+			Quantity: 15.5,
+		}, id)
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+		var movement entities.StockMovementEntity
+		json.NewDecoder(recorder.Body).Decode(&movement)
+		if movement.Quantity != -15.5 || movement.Type != inventory.Consumption {
+			t.Errorf("Expected a consumption of 15.5, got %+v", movement)
+		}
+
+		var alerts int
+		db.Get(&alerts, "SELECT COUNT(*) FROM domain_events WHERE type = 'supply.low_stock' AND aggregate_id = $1", id)
+		if alerts != 1 {
+			t.Errorf("Expected the low stock alert once, got %d", alerts)
+		}
+
+		recorder = send(inventoryhandlers.ListSuppliesHandler(db), "GET", "/supplies?low_stock=true", nil, "")
+		var supplies []entities.SupplyEntity
+		json.NewDecoder(recorder.Body).Decode(&supplies)
+		if len(supplies) != 1 || !supplies[0].LowStock {
+			t.Errorf("Expected the supply in the low stock list, got %+v", supplies)
+		}
+	})
+
+	t.Run("Wash load", func(t *testing.T) {
+		recorder := send(machineshandlers.CreateMachineHandler(db), "POST", "/machines", machineshandlers.MachineRequest{
+			Name: "Lavadora Estoque", Type: "washer", CapacityKg: 10,
+		}, "")
+		var washer entities.MachineEntity
+		json.NewDecoder(recorder.Body).Decode(&washer)
+
+		recorder = send(machineshandlers.StartLoadHandler(db), "POST", "/machines/"+washer.ID.String()+"/loads", map[string]interface{}{
+			"weight": 8,
+		}, washer.ID.String())
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		var movement entities.StockMovementEntity
+		err := db.Get(&movement, "SELECT "+inventory.MovementColumns+" FROM stock_movements WHERE supply_id = $1 AND estimated", id)
+		if err != nil {
+			t.Fatalf("Expected the load to record its consumption: %v", err)
+		}
+		if movement.Quantity != -0.5 || movement.LoadID == nil {
+			t.Errorf("Expected 0.1 + 8 × 0.05 l taken by the load, got %+v", movement)
+		}
+	})
+
+	t.Run("Adjustment and report", func(t *testing.T) {
+		recorder := send(inventoryhandlers.AdjustmentHandler(db), "POST", "/supplies/"+id+"/adjustments", map[string]interface{}{}, id)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected the count to be required, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		counted := 3.0
+		recorder = send(inventoryhandlers.AdjustmentHandler(db), "POST", "/supplies/"+id+"/adjustments", inventoryhandlers.AdjustmentRequest{
+			Stock: &counted,
+		}, id)
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		recorder = send(inventoryhandlers.ReportHandler(db), "GET", "/supplies/report", nil, "")
+		var lines []inventory.ReportLine
+		json.NewDecoder(recorder.Body).Decode(&lines)
+		if len(lines) != 1 {
+			t.Fatalf("Expected one report line, got %+v", lines)
+		}
+		line := lines[0]
+		if line.Opening != 0 || line.Purchased != 20 || line.Consumed != 16 || line.Estimated != 0.5 || line.Adjusted != -1 || line.Closing != 3 || line.PurchaseCost != 250 {
+			t.Errorf("Unexpected report line %+v", line)
+		}
+
+		recorder = send(inventoryhandlers.DeleteSupplyHandler(db), "DELETE", "/supplies/"+id, nil, id)
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected a supply with movements not to be deleted, got %d", recorder.Code)
+		}
+	})
+}
@@ -0,0 +1,220 @@
+package testhandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"lavanderia/entities"
+	invoiceshandlers "lavanderia/handlers/invoices"
+	middleware "lavanderia/middlewares"
+	"lavanderia/nfse"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestInvoices(t *testing.T) {
+	send := func(handler http.HandlerFunc, method string, id string, user middleware.User) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/", bytes.NewBuffer(nil))
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		req = req.WithContext(middleware.WithUser(req.Context(), user))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	var clientID, otherID, addressID, itemID string
+	err := db.QueryRow("INSERT INTO address (street, neighborhood, city, state, postal_code, number) VALUES ($1, $2, $3, $4, $5, $6) RETURNING address_id",
+		"Rua Sete de Setembro", "Centro", "Petrópolis", "RJ", "25610-000", "120").Scan(&addressID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert address: %v", err)
+	}
+	err = db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal, cpf, address_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id",
+		"Nair", "Campos", "nair.campos", "senha123", false, "24998541111", false, "52998224725", addressID).Scan(&clientID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert client: %v", err)
+	}
+	err = db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		"Rui", "Mota", "rui.mota", "senha123", false, "24998542222", false).Scan(&otherID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert client: %v", err)
+	}
+	err = db.QueryRow("INSERT INTO laundry_items (name, price) VALUES ($1, $2) RETURNING id", "Cortina", 45).Scan(&itemID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert item: %v", err)
+	}
+
+	service := func(paid bool) string {
+		var serviceID string
+		err := db.QueryRow("INSERT INTO laundry_services (client_id, status, is_weight, is_piece, total_price, is_paid) VALUES ($1, 'Finalizado', false, true, 90, $2) RETURNING id",
+			clientID, paid).Scan(&serviceID)
+		if err != nil {
+			t.Fatalf("Setup failed: Unable to insert service: %v", err)
+		}
+		_, err = db.Exec("INSERT INTO laundry_items_services (laundry_service_id, laundry_item_id, item_quantity) VALUES ($1, $2, 2)", serviceID, itemID)
+		if err != nil {
+			t.Fatalf("Setup failed: Unable to insert item of service: %v", err)
+		}
+		return serviceID
+	}
+
+	config := nfse.Config{CNPJ: "12345678000199", CityCode: "3303906", ServiceItem: "14.10", ISSRate: 2, Series: "A"}
+	fake := &nfse.Fake{Dir: t.TempDir()}
+	issuer := &nfse.Issuer{Config: config, Provider: fake}
+	admin := middleware.User{ID: clientID, Role: "Admin"}
+
+	t.Run("Issue", func(t *testing.T) {
+		serviceID := service(true)
+
+		recorder := send(invoiceshandlers.IssueInvoiceHandler(db, issuer), "POST", serviceID, admin)
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+		var invoice entities.InvoiceEntity
+		json.NewDecoder(recorder.Body).Decode(&invoice)
+		if invoice.Status == nil || *invoice.Status != nfse.Issued || invoice.Number == nil || invoice.PDFURL == nil || invoice.InvoicedAt == nil {
+			t.Fatalf("Expected the invoice issued, got %+v", invoice)
+		}
+		doc, err := os.ReadFile(filepath.Join(fake.Dir, "rps"+*invoice.Number+".xml"))
+		if err != nil {
+			t.Fatalf("Expected the RPS sent to the provider: %v", err)
+		}
+		for _, want := range []string{"<Cpf>52998224725</Cpf>", "<RazaoSocial>Nair Campos</RazaoSocial>", "<Bairro>Centro</Bairro>", "<ValorServicos>90.00</ValorServicos>", ": 2 x Cortina</Discriminacao>"} {
+			if !strings.Contains(string(doc), want) {
+				t.Errorf("Expected %s in the RPS, got %s", want, doc)
+			}
+		}
+
+		// Clients see the invoices of their own services only
+		recorder = send(invoiceshandlers.ShowInvoiceHandler(db), "GET", serviceID, middleware.User{ID: clientID, Role: "Client"})
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+		recorder = send(invoiceshandlers.ShowInvoiceHandler(db), "GET", serviceID, middleware.User{ID: otherID, Role: "Client"})
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected status code %d, got %d", http.StatusForbidden, recorder.Code)
+		}
+
+		// An invoice is issued once
+		recorder = send(invoiceshandlers.IssueInvoiceHandler(db, issuer), "POST", serviceID, admin)
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status code %d, got %d: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("NotPaid", func(t *testing.T) {
+		serviceID := service(false)
+
+		recorder := send(invoiceshandlers.IssueInvoiceHandler(db, issuer), "POST", serviceID, admin)
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status code %d, got %d: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
+		}
+		recorder = send(invoiceshandlers.ShowInvoiceHandler(db), "GET", serviceID, admin)
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		serviceID := service(true)
+		rejecting := &nfse.Issuer{Config: config, Provider: &nfse.Fake{Reject: "E160 - Inscrição do tomador inválida"}}
+
+		recorder := send(invoiceshandlers.IssueInvoiceHandler(db, rejecting), "POST", serviceID, admin)
+		if recorder.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusUnprocessableEntity, recorder.Code, recorder.Body.String())
+		}
+
+		var rejected entities.InvoiceEntity
+		db.Get(&rejected, "SELECT id, rps_number, invoice_status, invoice_number, invoice_verification_code, invoice_pdf_url, invoice_message, invoiced_at FROM laundry_services WHERE id = $1", serviceID)
+		if rejected.Status == nil || *rejected.Status != nfse.Rejected || rejected.Message == nil || rejected.RPSNumber == nil {
+			t.Fatalf("Expected the rejection stored, got %+v", rejected)
+		}
+
+		// Once corrected, the RPS is sent again with its number
+		recorder = send(invoiceshandlers.IssueInvoiceHandler(db, issuer), "POST", serviceID, admin)
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+		var invoice entities.InvoiceEntity
+		json.NewDecoder(recorder.Body).Decode(&invoice)
+		if invoice.RPSNumber == nil || *invoice.RPSNumber != *rejected.RPSNumber || invoice.Message != nil {
+			t.Errorf("Expected RPS %d issued, got %+v", *rejected.RPSNumber, invoice)
+		}
+	})
+
+	t.Run("Processing", func(t *testing.T) {
+		serviceID := service(true)
+		db.Exec("UPDATE laundry_services SET rps_number = nextval('nfse_rps_number'), invoice_status = 'processing', invoiced_at = CURRENT_TIMESTAMP WHERE id = $1", serviceID)
+
+		// An RPS being sent isn't sent again at the same time
+		recorder := send(invoiceshandlers.IssueInvoiceHandler(db, issuer), "POST", serviceID, admin)
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status code %d, got %d: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
+		}
+
+		// Left by a request that didn't finish, it is sent again
+		db.Exec("UPDATE laundry_services SET invoiced_at = CURRENT_TIMESTAMP - INTERVAL '1 hour' WHERE id = $1", serviceID)
+		recorder = send(invoiceshandlers.IssueInvoiceHandler(db, issuer), "POST", serviceID, admin)
+		if recorder.Code != http.StatusCreated {
+			t.Errorf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("ProviderError", func(t *testing.T) {
+		serviceID := service(true)
+		failing := &nfse.Issuer{Config: config, Provider: failingProvider{}}
+
+		recorder := send(invoiceshandlers.IssueInvoiceHandler(db, failing), "POST", serviceID, admin)
+		if recorder.Code != http.StatusBadGateway {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusBadGateway, recorder.Code, recorder.Body.String())
+		}
+
+		var released entities.InvoiceEntity
+		db.Get(&released, "SELECT id, rps_number, invoice_status, invoice_number, invoice_verification_code, invoice_pdf_url, invoice_message, invoiced_at FROM laundry_services WHERE id = $1", serviceID)
+		if released.Status != nil || released.RPSNumber == nil {
+			t.Fatalf("Expected the RPS numbered and no longer processing, got %+v", released)
+		}
+
+		recorder = send(invoiceshandlers.IssueInvoiceHandler(db, issuer), "POST", serviceID, admin)
+		if recorder.Code != http.StatusCreated {
+			t.Errorf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		serviceID := service(true)
+		unconfigured := &nfse.Issuer{Provider: fake}
+
+		recorder := send(invoiceshandlers.IssueInvoiceHandler(db, unconfigured), "POST", serviceID, admin)
+		if recorder.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusServiceUnavailable, recorder.Code, recorder.Body.String())
+		}
+
+		// No RPS number is used up
+		var untouched entities.InvoiceEntity
+		db.Get(&untouched, "SELECT id, rps_number, invoice_status, invoice_number, invoice_verification_code, invoice_pdf_url, invoice_message, invoiced_at FROM laundry_services WHERE id = $1", serviceID)
+		if untouched.RPSNumber != nil || untouched.Status != nil {
+			t.Errorf("Expected the service untouched, got %+v", untouched)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		recorder := send(invoiceshandlers.IssueInvoiceHandler(db, issuer), "POST", "9b2f6a1e-3c4d-4e5f-8a9b-0c1d2e3f4a5b", admin)
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+}
+
+// failingProvider is a provider that doesn't answer
+type failingProvider struct{}
+
+func (failingProvider) Submit(context.Context, []byte, nfse.RPS) (nfse.Result, error) {
+	return nfse.Result{}, errors.New("connection reset by peer")
+}
@@ -1,13 +1,17 @@
 package testhandlers
 
 import (
+	"encoding/json"
 	"lavanderia/entities"
 	serviceshandlers "lavanderia/handlers/laundryServices"
+	middleware "lavanderia/middlewares"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq" // PostgreSQL driver
 )
@@ -67,7 +71,7 @@ func TestListServicesHandler(t *testing.T) {
 	}
 
 	// Setup initial service and items in the database
-	setupInitialService := func(db *sqlx.DB) error {
+	setupInitialService := func(db *sqlx.DB) (string, error) {
 		clientID := setupClient(db)
 		items := setupItems(db)
 
@@ -87,19 +91,53 @@ func TestListServicesHandler(t *testing.T) {
 			}
 		}
 
-		return err
+		return clientID, err
+	}
+
+	// Setup a weight-only service without items
+	setupServiceWithoutItems := func(db *sqlx.DB) (string, error) {
+		clientID := setupClient(db)
+
+		serviceInsertQuery := "INSERT INTO laundry_services (client_id, estimated_completion_date, is_weight, weight, is_piece, is_paid, status, total_price) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)"
+		_, err := db.Exec(serviceInsertQuery, clientID, time.Now().Add(24*time.Hour), true, 3.0, false, false, "Lavando", 60)
+		if err != nil {
+			t.Fatalf("Setup failed: Unable to insert service without items: %v", err)
+		}
+
+		return clientID, err
 	}
 
 	tests := []struct {
 		name       string
+		setup      func(db *sqlx.DB) (string, error)
+		query      string
 		serviceID  string
 		wantStatus int
 		wantErr    bool
+		wantCount  int
 	}{
 		{
 			name:       "Valid list",
+			setup:      setupInitialService,
+			query:      "&status=Separado",
 			wantStatus: http.StatusOK,
 			wantErr:    false,
+			wantCount:  1,
+		},
+		{
+			name:       "Service without items",
+			setup:      setupServiceWithoutItems,
+			query:      "&status=Lavando",
+			wantStatus: http.StatusOK,
+			wantErr:    false,
+			wantCount:  1,
+		},
+		{
+			name:       "Invalid sort field",
+			setup:      setupInitialService,
+			query:      "&sort=password",
+			wantStatus: http.StatusBadRequest,
+			wantErr:    true,
 		},
 	}
 
@@ -112,12 +150,12 @@ func TestListServicesHandler(t *testing.T) {
 				t.Fatalf("Failed to begin transaction: %v", err)
 			}
 
-			err = setupInitialService(db)
+			clientID, err := tc.setup(db)
 			if err != nil {
 				t.Errorf("Error to setup initial service")
 			}
 
-			req, _ := http.NewRequest("GET", "/services", nil)
+			req, _ := http.NewRequest("GET", "/services?client_id="+clientID+tc.query, nil)
 			recorder := httptest.NewRecorder()
 
 			handler.ServeHTTP(recorder, req)
@@ -127,10 +165,22 @@ func TestListServicesHandler(t *testing.T) {
 			}
 
 			if !tc.wantErr {
-				// Verify the service and its items were updated correctly
-				// This could involve querying the service by its ID and checking its fields, as well as associated items
-			} else {
-				// Verify that an appropriate error message is returned
+				var response struct {
+					Services   []serviceshandlers.Service `json:"services"`
+					TotalItems int                        `json:"total_items"`
+				}
+				if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+
+				if response.TotalItems != tc.wantCount || len(response.Services) != tc.wantCount {
+					t.Errorf("Expected %d services, got %d (total_items %d)", tc.wantCount, len(response.Services), response.TotalItems)
+				}
+				for _, service := range response.Services {
+					if service.TrackingCode == "" {
+						t.Errorf("Expected the tracking code of service %s", service.ID)
+					}
+				}
 			}
 
 			if err := tx.Rollback(); err != nil {
@@ -139,3 +189,41 @@ func TestListServicesHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestListServicesByClientHandler(t *testing.T) {
+	var clientID string
+	err := db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		"Lia", "Prado", "lia.prado", "senha123", false, "24998547777", false).Scan(&clientID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert client: %v", err)
+	}
+	_, err = db.Exec("INSERT INTO laundry_services (client_id, status, is_weight, is_piece, total_price) VALUES ($1, 'Separado', false, true, 40)", clientID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert service: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		user       middleware.User
+		wantStatus int
+	}{
+		{name: "Staff", user: middleware.User{ID: uuid.NewString(), Role: "Admin"}, wantStatus: http.StatusOK},
+		{name: "Own services of a client", user: middleware.User{ID: clientID, Role: "Client"}, wantStatus: http.StatusOK},
+		{name: "Services of another client", user: middleware.User{ID: uuid.NewString(), Role: "Client"}, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/clients/"+clientID+"/services", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": clientID})
+			req = req.WithContext(middleware.WithUser(req.Context(), tc.user))
+			recorder := httptest.NewRecorder()
+
+			serviceshandlers.ListServicesByClientHandler(db).ServeHTTP(recorder, req)
+
+			if recorder.Code != tc.wantStatus {
+				t.Errorf("Expected status code %d, got %d: %s", tc.wantStatus, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
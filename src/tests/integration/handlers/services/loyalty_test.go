@@ -0,0 +1,137 @@
+package testhandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"lavanderia/events"
+	serviceshandlers "lavanderia/handlers/laundryServices"
+	loyaltyhandlers "lavanderia/handlers/loyalty"
+	"lavanderia/loyalty"
+	middleware "lavanderia/middlewares"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestLoyalty(t *testing.T) {
+	send := func(handler http.HandlerFunc, method string, body interface{}, id string, user middleware.User) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, "/", bytes.NewBuffer(payload))
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		req = req.WithContext(middleware.WithUser(req.Context(), user))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	var clientID, itemID string
+	err := db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		"Olga", "Ramos", "olga.ramos", "senha123", false, "24998548394", false).Scan(&clientID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert client: %v", err)
+	}
+	err = db.QueryRow("INSERT INTO laundry_items (name, price) VALUES ($1, $2) RETURNING id", "Edredom", 100).Scan(&itemID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert item: %v", err)
+	}
+	client := middleware.User{ID: clientID, Role: "Client"}
+
+	t.Run("Earn", func(t *testing.T) {
+		var serviceID uuid.UUID
+		err := db.QueryRow("INSERT INTO laundry_services (client_id, status, is_weight, is_piece, total_price, is_paid) VALUES ($1, 'Separado', false, true, 100.90, true) RETURNING id",
+			clientID).Scan(&serviceID)
+		if err != nil {
+			t.Fatalf("Setup failed: Unable to insert service: %v", err)
+		}
+
+		// A service paid twice earns once
+		payment := events.Payment{ServiceID: serviceID, ClientID: uuid.MustParse(clientID), TotalPrice: 100.90}
+		events.Publish(db, events.ServicePaid, serviceID, payment)
+		events.Publish(db, events.ServicePaid, serviceID, payment)
+
+		dispatcher := &events.Dispatcher{DB: db}
+		dispatcher.Subscribe("loyalty", loyalty.Subscriber(db), events.ServiceCreated, events.ServicePaid)
+		for n := 1; n > 0; {
+			n, err = dispatcher.Dispatch(context.Background())
+			if err != nil {
+				t.Fatalf("Failed to dispatch: %v", err)
+			}
+		}
+
+		// And points past their date expire
+		_, err = db.Exec("INSERT INTO loyalty_entries (client_id, type, points, remaining, expires_at) VALUES ($1, 'earn', 30, 30, NOW() - INTERVAL '1 day')", clientID)
+		if err != nil {
+			t.Fatalf("Setup failed: Unable to insert expired points: %v", err)
+		}
+
+		recorder := send(loyaltyhandlers.MyLoyaltyHandler(db), "GET", nil, "", client)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+		var response loyaltyhandlers.LoyaltyResponse
+		json.NewDecoder(recorder.Body).Decode(&response)
+		if response.Points != 100 || response.Value != 5 || len(response.Expiring) != 1 {
+			t.Errorf("Expected 100 points worth 5 expiring at once, got %+v", response)
+		}
+
+		total := 0
+		for _, entry := range response.Entries {
+			total += entry.Points
+		}
+		if len(response.Entries) != 3 || total != 100 {
+			t.Errorf("Expected the earned, the expired and their expiry to sum to 100, got %+v", response.Entries)
+		}
+	})
+
+	t.Run("Redeem", func(t *testing.T) {
+		admin := middleware.User{ID: "admin", Role: "Admin"}
+		create := func(points int) *httptest.ResponseRecorder {
+			return send(serviceshandlers.CreateServicesHandler(db), "POST", map[string]interface{}{
+				"client_id":     clientID,
+				"is_piece":      true,
+				"items":         []map[string]interface{}{{"laundry_item_id": itemID, "item_quantity": 1}},
+				"redeem_points": points,
+			}, "", admin)
+		}
+
+		recorder := create(50)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected fewer points than the minimum to be refused, got %d", recorder.Code)
+		}
+
+		recorder = create(100)
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+		var service serviceshandlers.LaundryService
+		json.NewDecoder(recorder.Body).Decode(&service)
+
+		var price, discount float64
+		db.QueryRow("SELECT total_price, loyalty_discount FROM laundry_services WHERE id = $1", service.ID).Scan(&price, &discount)
+		if price != 95 || discount != 5 || service.Discount != 5 {
+			t.Errorf("Expected 5 off 100, got a price of %v and a discount of %v", price, discount)
+		}
+
+		recorder = create(100)
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected points already redeemed to be refused, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		points, _ := loyalty.Balance(db, uuid.MustParse(clientID))
+		if points != 0 {
+			t.Errorf("Expected no points left, got %d", points)
+		}
+	})
+
+	t.Run("Forbidden", func(t *testing.T) {
+		stranger := middleware.User{ID: uuid.NewString(), Role: "Client"}
+		recorder := send(loyaltyhandlers.ShowClientLoyaltyHandler(db), "GET", nil, clientID, stranger)
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected another client not to see the points, got %d", recorder.Code)
+		}
+	})
+}
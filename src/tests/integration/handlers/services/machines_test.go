@@ -0,0 +1,141 @@
+package testhandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"lavanderia/entities"
+	machineshandlers "lavanderia/handlers/machines"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestMachines(t *testing.T) {
+	send := func(handler http.HandlerFunc, method, path string, body interface{}, id string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		if id != "" {
+			req = mux.SetURLVars(req, map[string]string{"id": id})
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	createMachine := func(name, machineType string, capacity float64) entities.MachineEntity {
+		recorder := send(machineshandlers.CreateMachineHandler(db), "POST", "/machines", machineshandlers.MachineRequest{
+			Name: name, Type: machineType, CapacityKg: capacity,
+		}, "")
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+		var machine entities.MachineEntity
+		json.NewDecoder(recorder.Body).Decode(&machine)
+		return machine
+	}
+
+	washer := createMachine("Lavadora 1", "washer", 10)
+	dryer := createMachine("Secadora 1", "dryer", 5)
+
+	recorder := send(machineshandlers.CreateMachineHandler(db), "POST", "/machines", machineshandlers.MachineRequest{
+		Name: "lavadora 1", Type: "washer", CapacityKg: 8,
+	}, "")
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected a repeated name to fail, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var clientID string
+	err := db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		"Lia", "Souza", "lia.souza", "senha123", false, "24998548390", false).Scan(&clientID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert client: %v", err)
+	}
+	var serviceID string
+	err = db.QueryRow("INSERT INTO laundry_services (client_id, status, is_weight, weight, is_piece, total_price) VALUES ($1, 'Separado', true, 6, false, 120) RETURNING id",
+		clientID).Scan(&serviceID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert service: %v", err)
+	}
+
+	var load machineshandlers.Load
+	t.Run("Start load", func(t *testing.T) {
+		recorder := send(machineshandlers.StartLoadHandler(db), "POST", "/machines/"+dryer.ID.String()+"/loads", map[string]interface{}{
+			"service_ids": []string{serviceID},
+		}, dryer.ID.String())
+		if recorder.Code != http.StatusBadRequest || !bytes.Contains(recorder.Body.Bytes(), []byte("load_too_heavy")) {
+			t.Errorf("Expected 6 kg not to fit a 5 kg dryer, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		recorder = send(machineshandlers.StartLoadHandler(db), "POST", "/machines/"+washer.ID.String()+"/loads", map[string]interface{}{
+			"service_ids": []string{serviceID},
+			"notes":       "Ciclo delicado",
+		}, washer.ID.String())
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+		json.NewDecoder(recorder.Body).Decode(&load)
+		if load.Stage != "Lavando" || load.Weight != 6 || load.MachineName != "Lavadora 1" || len(load.ServiceIDs) != 1 {
+			t.Errorf("Expected a washing load of 6 kg with the service, got %+v", load)
+		}
+
+		var status string
+		db.Get(&status, "SELECT status FROM laundry_services WHERE id = $1", serviceID)
+		if status != "Lavando" {
+			t.Errorf("Expected the service to move to Lavando, got %s", status)
+		}
+
+		recorder = send(machineshandlers.StartLoadHandler(db), "POST", "/machines/"+washer.ID.String()+"/loads", map[string]interface{}{
+			"service_ids": []string{serviceID},
+		}, washer.ID.String())
+		if recorder.Code != http.StatusConflict || !bytes.Contains(recorder.Body.Bytes(), []byte("machine_busy")) {
+			t.Errorf("Expected a running washer to be busy, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("Finish load", func(t *testing.T) {
+		recorder := send(machineshandlers.FinishLoadHandler(db), "POST", "/loads/"+load.ID.String()+"/finish", nil, load.ID.String())
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+		var finished machineshandlers.Load
+		json.NewDecoder(recorder.Body).Decode(&finished)
+		if finished.EndedAt == nil {
+			t.Errorf("Expected the load to end, got %+v", finished)
+		}
+
+		recorder = send(machineshandlers.FinishLoadHandler(db), "POST", "/loads/"+load.ID.String()+"/finish", nil, load.ID.String())
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected a finished load to stay finished, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("Reports", func(t *testing.T) {
+		recorder := send(machineshandlers.ListServiceLoadsHandler(db), "GET", "/services/"+serviceID+"/loads", nil, serviceID)
+		var loads []machineshandlers.Load
+		json.NewDecoder(recorder.Body).Decode(&loads)
+		if len(loads) != 1 || loads[0].MachineID != washer.ID {
+			t.Errorf("Expected the service to be processed by the washer, got %+v", loads)
+		}
+
+		recorder = send(machineshandlers.UtilizationHandler(db), "GET", "/machines/"+washer.ID.String()+"/utilization", nil, washer.ID.String())
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+		var report machineshandlers.UtilizationResponse
+		json.NewDecoder(recorder.Body).Decode(&report)
+		if report.Loads != 1 || report.Kg != 6 || report.AverageFill != 0.6 || len(report.Days) != 30 {
+			t.Errorf("Expected one load of 6 kg over 30 days, got %+v", report)
+		}
+
+		recorder = send(machineshandlers.DeleteMachineHandler(db), "DELETE", "/machines/"+washer.ID.String(), nil, washer.ID.String())
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected a used machine to be kept, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		recorder = send(machineshandlers.DeleteMachineHandler(db), "DELETE", "/machines/"+dryer.ID.String(), nil, dryer.ID.String())
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected an unused machine to be deleted, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+}
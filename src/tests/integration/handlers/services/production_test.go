@@ -0,0 +1,142 @@
+package testhandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"lavanderia/calendar"
+	"lavanderia/entities"
+	serviceshandlers "lavanderia/handlers/laundryServices"
+	productionhandlers "lavanderia/handlers/production"
+	"lavanderia/production"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProduction(t *testing.T) {
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM production_settings")
+	})
+
+	send := func(handler http.HandlerFunc, method, path string, body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	var clientID string
+	err := db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		"Ana", "Prado", "ana.prado", "senha123", false, "24998548389", false).Scan(&clientID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert client: %v", err)
+	}
+	var itemID string
+	err = db.QueryRow("INSERT INTO laundry_items (name, price) VALUES ($1, $2) RETURNING id", "Camisa", 10.00).Scan(&itemID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert item: %v", err)
+	}
+
+	due := calendar.Default().AddDays(time.Now(), 1)
+	create := func(priority string) *httptest.ResponseRecorder {
+		return send(serviceshandlers.CreateServicesHandler(db), "POST", "/services", map[string]interface{}{
+			"client_id":                 clientID,
+			"is_piece":                  true,
+			"priority":                  priority,
+			"items":                     []InsertedLaundryItem{{LaundryItemID: itemID, ItemQuantity: 2}},
+			"estimated_completion_date": due,
+		})
+	}
+
+	t.Run("Settings", func(t *testing.T) {
+		recorder := send(productionhandlers.ShowSettingsHandler(db), "GET", "/production/settings", nil)
+		var settings entities.ProductionSettingsEntity
+		json.NewDecoder(recorder.Body).Decode(&settings)
+		if settings.ExpressSurcharge != 50 || settings.SameDayCutoff != "12:00" {
+			t.Errorf("Expected the default settings, got %+v", settings)
+		}
+
+		recorder = send(productionhandlers.UpdateSettingsHandler(db), "PUT", "/production/settings", productionhandlers.SettingsRequest{
+			MaxPiecesPerDay: -1, SameDayCutoff: "12:00",
+		})
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected a negative capacity to fail, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		recorder = send(productionhandlers.UpdateSettingsHandler(db), "PUT", "/production/settings", productionhandlers.SettingsRequest{
+			MaxPiecesPerDay: 3, ExpressSurcharge: 50, SameDaySurcharge: 100, SameDayCutoff: "9:30",
+		})
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+		json.NewDecoder(recorder.Body).Decode(&settings)
+		if settings.MaxPiecesPerDay != 3 || settings.SameDayCutoff != "09:30" {
+			t.Errorf("Expected the saved settings, got %+v", settings)
+		}
+	})
+
+	t.Run("Express with surcharge", func(t *testing.T) {
+		recorder := create(production.Express)
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+		var created serviceshandlers.LaundryService
+		json.NewDecoder(recorder.Body).Decode(&created)
+
+		var stored struct {
+			TotalPrice float64 `db:"total_price"`
+			Priority   string  `db:"priority"`
+			Surcharge  float64 `db:"surcharge"`
+		}
+		err := db.Get(&stored, "SELECT total_price, priority, surcharge FROM laundry_services WHERE id = $1", created.ID)
+		if err != nil {
+			t.Fatalf("Failed to query service: %v", err)
+		}
+		if stored.Priority != production.Express || stored.Surcharge != 50 || stored.TotalPrice != 30 {
+			t.Errorf("Expected an express service of 30 with 50%% surcharge, got %+v", stored)
+		}
+	})
+
+	t.Run("Capacity exceeded", func(t *testing.T) {
+		recorder := create(production.Express)
+		if recorder.Code != http.StatusConflict || !bytes.Contains(recorder.Body.Bytes(), []byte("capacity_exceeded")) {
+			t.Errorf("Expected an express service beyond capacity to be refused, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		recorder = create(production.Normal)
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+		var created serviceshandlers.LaundryService
+		json.NewDecoder(recorder.Body).Decode(&created)
+		if len(created.Warnings) != 1 || created.Warnings[0] != "capacity_exceeded" {
+			t.Errorf("Expected a normal service beyond capacity to be warned, got %+v", created.Warnings)
+		}
+
+		recorder = send(productionhandlers.CapacityHandler(db), "GET", "/production/capacity?date="+due.Format("2006-01-02"), nil)
+		var load production.Load
+		json.NewDecoder(recorder.Body).Decode(&load)
+		if load.Services != 2 || load.Pieces != 4 || !load.Exceeded {
+			t.Errorf("Expected two services and four pieces beyond capacity, got %+v", load)
+		}
+	})
+
+	t.Run("Queue", func(t *testing.T) {
+		recorder := send(productionhandlers.QueueHandler(db), "GET", "/production/queue?status=Separado", nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+		var queue productionhandlers.QueueResponse
+		json.NewDecoder(recorder.Body).Decode(&queue)
+		if len(queue.Stages) != 1 || queue.Stages[0].Count != 2 || queue.Stages[0].Services[0].Priority != production.Express {
+			t.Errorf("Expected the express service first, got %+v", queue.Stages)
+		}
+
+		recorder = send(productionhandlers.QueueHandler(db), "GET", "/production/queue?status=Finalizado", nil)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected finished services not to be a stage, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+}
@@ -3,6 +3,7 @@ package testhandlers
 import (
 	"lavanderia/entities"
 	serviceshandlers "lavanderia/handlers/laundryServices"
+	middleware "lavanderia/middlewares"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -96,14 +97,30 @@ func TestShowServiceHandler(t *testing.T) {
 		name       string
 		serviceID  string
 		updateData entities.LaundryServicesEntity
+		role       string
+		owner      bool
 		wantStatus int
 		wantErr    bool
 	}{
 		{
 			name:       "Valid Detail",
+			role:       "Admin",
 			wantStatus: http.StatusOK,
 			wantErr:    false,
 		},
+		{
+			name:       "Own service of a client",
+			role:       "Client",
+			owner:      true,
+			wantStatus: http.StatusOK,
+			wantErr:    false,
+		},
+		{
+			name:       "Service of another client",
+			role:       "Client",
+			wantStatus: http.StatusForbidden,
+			wantErr:    true,
+		},
 	}
 
 	for _, tc := range tests {
@@ -122,6 +139,11 @@ func TestShowServiceHandler(t *testing.T) {
 			req, _ := http.NewRequest("GET", "/services/"+serviceID, nil)
 			recorder := httptest.NewRecorder()
 			req = mux.SetURLVars(req, map[string]string{"id": serviceID})
+			user := middleware.User{ID: uuid.NewString(), Role: tc.role}
+			if tc.owner {
+				user.ID = clientID
+			}
+			req = req.WithContext(middleware.WithUser(req.Context(), user))
 
 			handler.ServeHTTP(recorder, req)
 
@@ -0,0 +1,70 @@
+package testhandlers
+
+import (
+	"encoding/json"
+	serviceshandlers "lavanderia/handlers/laundryServices"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTrackServiceHandler(t *testing.T) {
+	var clientID string
+	err := db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		"Paula", "Reis", "paula.reis", "senha123", false, "24998548386", false).Scan(&clientID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert client: %v", err)
+	}
+
+	insert := func(code string, isPaid bool, status string) {
+		_, err := db.Exec("INSERT INTO laundry_services (client_id, estimated_completion_date, is_weight, weight, is_piece, is_paid, status, total_price, tracking_code) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+			clientID, time.Now().Add(24*time.Hour), true, 2.0, false, isPaid, status, 40, code)
+		if err != nil {
+			t.Fatalf("Setup failed: Unable to insert service: %v", err)
+		}
+	}
+	insert("7K3QWM9XZ2", false, "Finalizado")
+	insert("0A1B2C3D4E", true, "Lavando")
+
+	tests := []struct {
+		name       string
+		code       string
+		wantStatus int
+		wantReady  bool
+		wantDue    float64
+	}{
+		{name: "Ready and unpaid", code: "7k3qw-m9xz2", wantStatus: http.StatusOK, wantReady: true, wantDue: 40},
+		{name: "Paid, typed with O and I", code: "OA1B2C3D4E", wantStatus: http.StatusOK, wantDue: 0},
+		{name: "Unknown code", code: "ZZZZZZZZZZ", wantStatus: http.StatusNotFound},
+		{name: "Malformed code", code: "abc", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/track/"+tc.code, nil)
+			req = mux.SetURLVars(req, map[string]string{"code": tc.code})
+			recorder := httptest.NewRecorder()
+			serviceshandlers.TrackServiceHandler(db).ServeHTTP(recorder, req)
+
+			if recorder.Code != tc.wantStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.wantStatus, recorder.Code, recorder.Body.String())
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+
+			if strings.Contains(recorder.Body.String(), "Paula") || strings.Contains(recorder.Body.String(), clientID) {
+				t.Errorf("Expected no personal data, got %s", recorder.Body.String())
+			}
+			var order serviceshandlers.OrderStatus
+			json.NewDecoder(recorder.Body).Decode(&order)
+			if order.Ready != tc.wantReady || order.AmountDue != tc.wantDue || !strings.Contains(order.TrackingCode, "-") {
+				t.Errorf("Expected ready %v and %.2f due, got %+v", tc.wantReady, tc.wantDue, order)
+			}
+		})
+	}
+}
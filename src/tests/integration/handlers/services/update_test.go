@@ -3,6 +3,7 @@ package testhandlers
 import (
 	"bytes"
 	"encoding/json"
+	"lavanderia/calendar"
 	"lavanderia/entities"
 	serviceshandlers "lavanderia/handlers/laundryServices"
 	"net/http"
@@ -104,6 +105,16 @@ func TestUpdateServicesHandler(t *testing.T) {
 		return serviceID
 	}
 
+	setupPaidPieceService := func(db *sqlx.DB) string {
+		var serviceID string
+		err := db.QueryRow("INSERT INTO laundry_services (client_id, estimated_completion_date, is_weight, weight, is_piece, is_paid, status, total_price) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+			setupClient(db), time.Now().Add(24*time.Hour), false, 0, true, true, "Separado", 60).Scan(&serviceID)
+		if err != nil {
+			t.Fatalf("Setup failed: Unable to insert paid service: %v", err)
+		}
+		return serviceID
+	}
+
 	completedAtTest := time.Now().Add(48 * time.Hour)
 	completedAtTestPast := time.Now().Add(-48 * time.Hour)
 
@@ -120,7 +131,7 @@ func TestUpdateServicesHandler(t *testing.T) {
 			name:      "Valid Update to piece",
 			serviceID: setupInitialWeightService(db),
 			updateData: entities.LaundryServicesEntity{
-				EstimatedCompletionDate: time.Now().Add(48 * time.Hour),
+				EstimatedCompletionDate: calendar.Default().AddDays(time.Now(), 2),
 				Weight:                  0,
 				IsWeight:                false,
 				IsPiece:                 true,
@@ -137,7 +148,7 @@ func TestUpdateServicesHandler(t *testing.T) {
 			name:      "Valid Update to weight",
 			serviceID: setupInitialPieceService(db),
 			updateData: entities.LaundryServicesEntity{
-				EstimatedCompletionDate: time.Now().Add(48 * time.Hour),
+				EstimatedCompletionDate: calendar.Default().AddDays(time.Now(), 2),
 				Weight:                  2,
 				IsWeight:                true,
 				IsPiece:                 false,
@@ -154,7 +165,7 @@ func TestUpdateServicesHandler(t *testing.T) {
 			name:      "Complete service",
 			serviceID: setupInitialPieceService(db),
 			updateData: entities.LaundryServicesEntity{
-				EstimatedCompletionDate: time.Now().Add(48 * time.Hour),
+				EstimatedCompletionDate: calendar.Default().AddDays(time.Now(), 2),
 				Weight:                  2,
 				IsWeight:                true,
 				IsPiece:                 false,
@@ -171,7 +182,7 @@ func TestUpdateServicesHandler(t *testing.T) {
 			name:      "Invalid status",
 			serviceID: setupInitialPieceService(db),
 			updateData: entities.LaundryServicesEntity{
-				EstimatedCompletionDate: time.Now().Add(48 * time.Hour),
+				EstimatedCompletionDate: calendar.Default().AddDays(time.Now(), 2),
 				Weight:                  2,
 				IsWeight:                true,
 				IsPiece:                 false,
@@ -188,7 +199,7 @@ func TestUpdateServicesHandler(t *testing.T) {
 			name:      "CompletedAt before CreatedAt",
 			serviceID: setupInitialPieceService(db),
 			updateData: entities.LaundryServicesEntity{
-				EstimatedCompletionDate: time.Now().Add(48 * time.Hour),
+				EstimatedCompletionDate: calendar.Default().AddDays(time.Now(), 2),
 				Weight:                  2,
 				IsWeight:                true,
 				IsPiece:                 false,
@@ -222,7 +233,7 @@ func TestUpdateServicesHandler(t *testing.T) {
 			name:      "Verify weight when is_weight is true weight is positive",
 			serviceID: setupInitialPieceService(db),
 			updateData: entities.LaundryServicesEntity{
-				EstimatedCompletionDate: time.Now().Add(48 * time.Hour),
+				EstimatedCompletionDate: calendar.Default().AddDays(time.Now(), 2),
 				Weight:                  0,
 				IsWeight:                true,
 				IsPiece:                 false,
@@ -235,6 +246,49 @@ func TestUpdateServicesHandler(t *testing.T) {
 			wantErr:    true,
 			errField:   "weight",
 		},
+		{
+			name:      "Paid service keeps its price",
+			serviceID: setupPaidPieceService(db),
+			updateData: entities.LaundryServicesEntity{
+				EstimatedCompletionDate: calendar.Default().AddDays(time.Now(), 2),
+				IsPiece:                 true,
+				IsPaid:                  true,
+				Status:                  "Lavando",
+				ClientID:                setupClient(db),
+			},
+			wantStatus:     http.StatusOK,
+			wantTotalPrice: 60,
+			wantErr:        false,
+		},
+		{
+			name:      "Paid service set back to unpaid",
+			serviceID: setupPaidPieceService(db),
+			updateData: entities.LaundryServicesEntity{
+				EstimatedCompletionDate: calendar.Default().AddDays(time.Now(), 2),
+				IsPiece:                 true,
+				IsPaid:                  false,
+				Status:                  "Lavando",
+				ClientID:                setupClient(db),
+			},
+			wantStatus: http.StatusConflict,
+			wantErr:    true,
+			errField:   "is_paid",
+		},
+		{
+			name:      "Paid service repriced",
+			serviceID: setupPaidPieceService(db),
+			updateData: entities.LaundryServicesEntity{
+				EstimatedCompletionDate: calendar.Default().AddDays(time.Now(), 2),
+				Weight:                  2,
+				IsWeight:                true,
+				IsPaid:                  true,
+				Status:                  "Lavando",
+				ClientID:                setupClient(db),
+			},
+			wantStatus: http.StatusConflict,
+			wantErr:    true,
+			errField:   "is_paid",
+		},
 		{
 			name:       "Service ID not found",
 			serviceID:  "00000000-0000-0000-0000-000000000000",
@@ -279,20 +333,25 @@ func TestUpdateServicesHandler(t *testing.T) {
 				}
 			} else {
 				var response struct { // Define a struct that matches your JSON response structure
-					Details struct {
+					Status int    `json:"status"`
+					Code   string `json:"code"`
+					Errors []struct {
 						Field   string `json:"field"`
+						Code    string `json:"code"`
 						Message string `json:"message"`
-						Status  int    `json:"status"`
-					} `json:"details"`
-					Error string `json:"error"`
+					} `json:"errors"`
 				}
 
 				if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
 					t.Fatalf("Failed to decode response body: %v", err)
 				}
 
-				if response.Details.Field != tc.errField {
-					t.Errorf("Expected error field '%s', got '%s'", tc.errField, response.Details.Field)
+				if response.Status != recorder.Code {
+					t.Errorf("Expected body status %d to match response status %d", response.Status, recorder.Code)
+				}
+
+				if len(response.Errors) == 0 || response.Errors[0].Field != tc.errField {
+					t.Errorf("Expected error field '%s', got %+v", tc.errField, response.Errors)
 				}
 			}
 		})
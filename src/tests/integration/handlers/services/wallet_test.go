@@ -0,0 +1,106 @@
+package testhandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	wallethandlers "lavanderia/handlers/wallet"
+	middleware "lavanderia/middlewares"
+	"lavanderia/wallet"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestWallet(t *testing.T) {
+	admin := middleware.User{ID: "admin", Role: "Admin"}
+	send := func(handler http.HandlerFunc, method string, body interface{}, id string, user middleware.User) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, "/", bytes.NewBuffer(payload))
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		req = req.WithContext(middleware.WithUser(req.Context(), user))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	var clientID, serviceID string
+	err := db.QueryRow("INSERT INTO clients (first_name, last_name, username, password, is_admin, phone, is_mensal) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		"Nina", "Prado", "nina.prado", "senha123", false, "24998548393", false).Scan(&clientID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert client: %v", err)
+	}
+	err = db.QueryRow("INSERT INTO laundry_services (client_id, status, is_weight, is_piece, total_price, is_paid) VALUES ($1, 'Separado', false, true, 45, false) RETURNING id",
+		clientID).Scan(&serviceID)
+	if err != nil {
+		t.Fatalf("Setup failed: Unable to insert service: %v", err)
+	}
+
+	t.Run("Top up", func(t *testing.T) {
+		recorder := send(wallethandlers.TopUpHandler(db), "POST", wallethandlers.TopUpRequest{Amount: 30, Reason: "Pix"}, clientID, admin)
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+		var response wallethandlers.TransactionResponse
+		json.NewDecoder(recorder.Body).Decode(&response)
+		if response.Balance != 30 {
+			t.Errorf("Expected a balance of 30, got %v", response.Balance)
+		}
+	})
+
+	t.Run("Pay", func(t *testing.T) {
+		recorder := send(wallethandlers.PayServiceHandler(db), "POST", nil, serviceID, admin)
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected 30 not to pay 45, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		send(wallethandlers.AdjustmentHandler(db), "POST", wallethandlers.AdjustmentRequest{Amount: 20, Reason: "Cortesia"}, clientID, admin)
+
+		stranger := middleware.User{ID: "00000000-0000-0000-0000-000000000001", Role: "Client"}
+		recorder = send(wallethandlers.PayServiceHandler(db), "POST", nil, serviceID, stranger)
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected another client not to pay the service, got %d", recorder.Code)
+		}
+
+		owner := middleware.User{ID: clientID, Role: "Client"}
+		recorder = send(wallethandlers.PayServiceHandler(db), "POST", nil, serviceID, owner)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+		var response wallethandlers.TransactionResponse
+		json.NewDecoder(recorder.Body).Decode(&response)
+		if response.Balance != 5 {
+			t.Errorf("Expected 5 left, got %v", response.Balance)
+		}
+
+		var isPaid bool
+		db.Get(&isPaid, "SELECT is_paid FROM laundry_services WHERE id = $1", serviceID)
+		if !isPaid {
+			t.Error("Expected the service to be paid")
+		}
+
+		recorder = send(wallethandlers.PayServiceHandler(db), "POST", nil, serviceID, owner)
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected a paid service not to be paid again, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Statement", func(t *testing.T) {
+		recorder := send(wallethandlers.ShowWalletHandler(db), "GET", nil, clientID, admin)
+		var response wallethandlers.WalletResponse
+		json.NewDecoder(recorder.Body).Decode(&response)
+		if response.Balance != 5 || len(response.Statement) != 3 {
+			t.Fatalf("Expected 3 transactions leaving 5, got %+v", response)
+		}
+		if last := response.Statement[0]; last.Type != wallet.Payment || last.Amount != -45 || last.Balance != 5 {
+			t.Errorf("Expected the payment first, got %+v", last)
+		}
+
+		var total float64
+		db.Get(&total, "SELECT COALESCE(SUM(amount), 0) FROM wallet_entries")
+		if total != 0 {
+			t.Errorf("Expected the ledger to sum to zero, got %v", total)
+		}
+	})
+}
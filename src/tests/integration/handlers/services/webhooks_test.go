@@ -0,0 +1,138 @@
+package testhandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"lavanderia/entities"
+	"lavanderia/events"
+	webhookshandlers "lavanderia/handlers/webhooks"
+	"lavanderia/webhooks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func sendWebhook(handler http.HandlerFunc, method string, vars map[string]string, body interface{}) *httptest.ResponseRecorder {
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+
+	req, _ := http.NewRequest(method, "/webhooks", &payload)
+	req = mux.SetURLVars(req, vars)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestWebhooks(t *testing.T) {
+	status := http.StatusInternalServerError
+	var received []webhooks.Payload
+	var secret string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := webhooks.Verify(secret, r.Header.Get(webhooks.SignatureHeader), body, time.Minute); err != nil {
+			t.Errorf("Expected a valid signature: %v", err)
+		}
+		var payload webhooks.Payload
+		json.Unmarshal(body, &payload)
+		received = append(received, payload)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		body       webhookshandlers.WebhookRequest
+		wantStatus int
+	}{
+		{name: "Invalid URL", body: webhookshandlers.WebhookRequest{URL: "localhost:9000", EventTypes: []string{string(events.ServicePaid)}}, wantStatus: http.StatusBadRequest},
+		{name: "Unknown event type", body: webhookshandlers.WebhookRequest{URL: server.URL, EventTypes: []string{"service.lost"}}, wantStatus: http.StatusBadRequest},
+		{name: "No event types", body: webhookshandlers.WebhookRequest{URL: server.URL}, wantStatus: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := sendWebhook(webhookshandlers.CreateWebhookHandler(db), "POST", nil, tc.body)
+			if recorder.Code != tc.wantStatus {
+				t.Errorf("Expected status code %d, got %d: %s", tc.wantStatus, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+
+	recorder := sendWebhook(webhookshandlers.CreateWebhookHandler(db), "POST", nil,
+		webhookshandlers.WebhookRequest{URL: server.URL, EventTypes: []string{string(events.ServicePaid)}})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+	}
+	var hook entities.WebhookEntity
+	json.NewDecoder(recorder.Body).Decode(&hook)
+	secret = hook.Secret
+	if secret == "" || !hook.IsActive {
+		t.Fatalf("Expected an active webhook with a generated secret, got %+v", hook)
+	}
+	vars := map[string]string{"id": hook.ID.String()}
+
+	recorder = sendWebhook(webhookshandlers.ShowWebhookHandler(db), "GET", vars, nil)
+	if !bytes.Contains(recorder.Body.Bytes(), []byte(`"event_types":["service.paid"]`)) || bytes.Contains(recorder.Body.Bytes(), []byte(secret)) {
+		t.Errorf("Expected the webhook without its secret, got %s", recorder.Body.String())
+	}
+
+	// Only the subscribed type is queued, once
+	serviceID := uuid.New()
+	events.Publish(db, events.ServiceStatusChanged, serviceID, events.StatusChange{ServiceID: serviceID, To: "Lavando"})
+	events.Publish(db, events.ServicePaid, serviceID, events.Payment{ServiceID: serviceID, TotalPrice: 40})
+
+	dispatcher := &events.Dispatcher{DB: db}
+	dispatcher.Subscribe("webhooks", webhooks.Subscriber(db))
+	for n := 1; n > 0; {
+		n, _ = dispatcher.Dispatch(context.Background())
+	}
+
+	worker := &webhooks.Worker{DB: db}
+	deliver := func() {
+		if _, err := worker.Deliver(context.Background()); err != nil {
+			t.Fatalf("Failed to deliver: %v", err)
+		}
+	}
+	listDeliveries := func() []entities.WebhookDeliveryEntity {
+		var deliveries []entities.WebhookDeliveryEntity
+		json.NewDecoder(sendWebhook(webhookshandlers.ListDeliveriesHandler(db), "GET", vars, nil).Body).Decode(&deliveries)
+		return deliveries
+	}
+
+	// A failed delivery is logged with the response code and retried later
+	deliver()
+	deliveries := listDeliveries()
+	if len(deliveries) != 1 || deliveries[0].Status != webhooks.StatusPending || deliveries[0].ResponseCode == nil || *deliveries[0].ResponseCode != 500 {
+		t.Fatalf("Expected one pending delivery that got a 500, got %+v", deliveries)
+	}
+	if !deliveries[0].NextAttemptAt.After(time.Now().Add(20 * time.Second)) {
+		t.Errorf("Expected the retry to wait, got %s", deliveries[0].NextAttemptAt)
+	}
+
+	// Redelivering sends it right away
+	status = http.StatusOK
+	redeliver := map[string]string{"id": hook.ID.String(), "deliveryID": deliveries[0].ID.String()}
+	if recorder := sendWebhook(webhookshandlers.RedeliverHandler(db), "POST", redeliver, nil); recorder.Code != http.StatusAccepted {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusAccepted, recorder.Code, recorder.Body.String())
+	}
+	deliver()
+	deliveries = listDeliveries()
+	if deliveries[0].Status != webhooks.StatusSucceeded || deliveries[0].DeliveredAt == nil {
+		t.Errorf("Expected the delivery to succeed, got %+v", deliveries[0])
+	}
+	if len(received) != 2 || received[1].Type != events.ServicePaid || received[1].DeliveryID != deliveries[0].ID {
+		t.Errorf("Expected the payment to be posted twice, got %+v", received)
+	}
+
+	redeliver["id"] = uuid.New().String()
+	if recorder := sendWebhook(webhookshandlers.RedeliverHandler(db), "POST", redeliver, nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d for another webhook, got %d", http.StatusNotFound, recorder.Code)
+	}
+}
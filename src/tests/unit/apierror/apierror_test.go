@@ -0,0 +1,145 @@
+package testapierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"lavanderia/apierror"
+)
+
+func TestLanguage(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           apierror.Lang
+	}{
+		{name: "No header", acceptLanguage: "", want: apierror.PtBR},
+		{name: "Brazilian Portuguese", acceptLanguage: "pt-BR,pt;q=0.9", want: apierror.PtBR},
+		{name: "English", acceptLanguage: "en-US,en;q=0.9", want: apierror.En},
+		{name: "Quality ordering", acceptLanguage: "pt;q=0.5, en;q=0.8", want: apierror.En},
+		{name: "Unsupported language falls back", acceptLanguage: "fr-FR", want: apierror.PtBR},
+		{name: "Unsupported first choice", acceptLanguage: "fr-FR, en;q=0.5", want: apierror.En},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/services", nil)
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+
+			if got := apierror.Language(req); got != tc.want {
+				t.Errorf("Expected language %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name           string
+		problem        *apierror.Problem
+		acceptLanguage string
+		wantStatus     int
+		wantCode       apierror.Code
+		wantMessages   []string
+	}{
+		{
+			name: "Validation with multiple fields in English",
+			problem: apierror.Validation(
+				apierror.Field("weight", apierror.MustBePositive),
+				apierror.Field("name", apierror.MaxLength, "max", "100"),
+			),
+			acceptLanguage: "en",
+			wantStatus:     http.StatusBadRequest,
+			wantCode:       apierror.ValidationFailed,
+			wantMessages:   []string{"Must be a positive number.", "Must have at most 100 characters."},
+		},
+		{
+			name:           "Not found in Portuguese",
+			problem:        apierror.NotFound("id", apierror.ServiceNotFound, "id", "123"),
+			acceptLanguage: "pt-BR",
+			wantStatus:     http.StatusNotFound,
+			wantCode:       apierror.ServiceNotFound,
+			wantMessages:   []string{"Serviço 123 não encontrado."},
+		},
+		{
+			name:       "Unknown error is internal",
+			problem:    apierror.From(http.ErrHandlerTimeout),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   apierror.InternalError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/services/123", nil)
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+			recorder := httptest.NewRecorder()
+
+			apierror.Write(recorder, req, tc.problem)
+
+			if recorder.Code != tc.wantStatus {
+				t.Errorf("Expected status code %d, got %d", tc.wantStatus, recorder.Code)
+			}
+			if contentType := recorder.Header().Get("Content-Type"); contentType != "application/problem+json" {
+				t.Errorf("Expected problem+json content type, got %s", contentType)
+			}
+
+			var response struct {
+				Type     string `json:"type"`
+				Title    string `json:"title"`
+				Status   int    `json:"status"`
+				Instance string `json:"instance"`
+				Code     string `json:"code"`
+				Errors   []struct {
+					Field   string `json:"field"`
+					Code    string `json:"code"`
+					Message string `json:"message"`
+				} `json:"errors"`
+			}
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+
+			if response.Status != tc.wantStatus {
+				t.Errorf("Expected body status %d, got %d", tc.wantStatus, response.Status)
+			}
+			if response.Code != string(tc.wantCode) {
+				t.Errorf("Expected code %s, got %s", tc.wantCode, response.Code)
+			}
+			if response.Title == "" || response.Type == "" || response.Instance != "/services/123" {
+				t.Errorf("Expected type, title and instance to be set, got %+v", response)
+			}
+			if len(response.Errors) != len(tc.wantMessages) {
+				t.Fatalf("Expected %d field errors, got %d", len(tc.wantMessages), len(response.Errors))
+			}
+			for i, message := range tc.wantMessages {
+				if response.Errors[i].Message != message {
+					t.Errorf("Expected message %q, got %q", message, response.Errors[i].Message)
+				}
+			}
+		})
+	}
+}
+
+func TestTitles(t *testing.T) {
+	statuses := []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict,
+		http.StatusUnprocessableEntity, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable}
+
+	for _, code := range apierror.Codes() {
+		for _, status := range statuses {
+			for _, lang := range []apierror.Lang{apierror.PtBR, apierror.En} {
+				title := apierror.Title(code, status, lang)
+				if title == "" || strings.Contains(title, "{") {
+					t.Errorf("Expected a title without placeholders for %s (%d, %s), got %q", code, status, lang, title)
+				}
+			}
+		}
+	}
+
+	if title := apierror.Title(apierror.ServiceNotFound, http.StatusNotFound, apierror.En); title != "Not found" {
+		t.Errorf("Expected the title of the status, got %q", title)
+	}
+}
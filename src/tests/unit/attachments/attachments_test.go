@@ -0,0 +1,75 @@
+package testattachments
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"lavanderia/attachments"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 30, B: 30, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Unable to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetect(t *testing.T) {
+	if contentType, extension, ok := attachments.Detect(encodePNG(t, 4, 4)); !ok || contentType != "image/png" || extension != ".png" {
+		t.Errorf("Expected a PNG, got %q %q %v", contentType, extension, ok)
+	}
+	if _, _, ok := attachments.Detect([]byte("%PDF-1.4 fake")); ok {
+		t.Error("Expected a PDF to be refused")
+	}
+	if _, _, ok := attachments.Detect([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>")); ok {
+		t.Error("Expected an SVG to be refused")
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	cases := []struct {
+		w, h           int
+		thumbW, thumbH int
+	}{
+		{1600, 1200, 320, 240},
+		{600, 1200, 160, 320},
+		{100, 50, 100, 50},
+	}
+
+	for _, c := range cases {
+		thumbnail, width, height, err := attachments.Thumbnail(bytes.NewReader(encodePNG(t, c.w, c.h)))
+		if err != nil {
+			t.Fatalf("Thumbnail of %dx%d: %v", c.w, c.h, err)
+		}
+		if width != c.w || height != c.h {
+			t.Errorf("Expected the size of the photo %dx%d, got %dx%d", c.w, c.h, width, height)
+		}
+
+		img, err := jpeg.Decode(bytes.NewReader(thumbnail))
+		if err != nil {
+			t.Fatalf("Expected a JPEG thumbnail: %v", err)
+		}
+		if got := img.Bounds(); got.Dx() != c.thumbW || got.Dy() != c.thumbH {
+			t.Errorf("Expected a %dx%d thumbnail of %dx%d, got %dx%d", c.thumbW, c.thumbH, c.w, c.h, got.Dx(), got.Dy())
+		}
+		r, _, _, _ := img.At(c.thumbW/2, c.thumbH/2).RGBA()
+		if r>>8 < 180 {
+			t.Errorf("Expected the thumbnail to keep the colour, got red %d", r>>8)
+		}
+	}
+
+	if _, _, _, err := attachments.Thumbnail(bytes.NewReader([]byte("\x89PNG\r\n\x1a\nbroken"))); err == nil {
+		t.Error("Expected a broken image to fail")
+	}
+}
@@ -0,0 +1,158 @@
+package testcalendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lavanderia/calendar"
+	"lavanderia/entities"
+)
+
+// monday is a Monday at 10:00, outside any holiday
+var monday = time.Date(2024, time.March, 11, 10, 0, 0, 0, time.UTC)
+
+func TestNationalHolidays(t *testing.T) {
+	holidays := calendar.NationalHolidays(2024)
+	dates := map[string]string{}
+	for i, h := range holidays {
+		if !h.National || h.ID != nil {
+			t.Errorf("Expected %s to be national without an ID", h.Name)
+		}
+		if i > 0 && holidays[i-1].Date > h.Date {
+			t.Errorf("Expected holidays by date, got %s after %s", h.Date, holidays[i-1].Date)
+		}
+		dates[h.Date] = h.Name
+	}
+
+	for _, date := range []string{"2024-01-01", "2024-03-29", "2024-04-21", "2024-11-20", "2024-12-25"} {
+		if _, ok := dates[date]; !ok {
+			t.Errorf("Expected a national holiday on %s, got %v", date, dates)
+		}
+	}
+	if len(holidays) != 10 {
+		t.Errorf("Expected 10 national holidays in 2024, got %d", len(holidays))
+	}
+
+	// Good Friday follows Easter, and Consciência Negra is national since 2024
+	for year, want := range map[int]string{2023: "2023-04-07", 2025: "2025-04-18", 2026: "2026-04-03"} {
+		if holidays := calendar.NationalHolidays(year); !containsDate(holidays, want) {
+			t.Errorf("Expected Good Friday on %s, got %v", want, holidays)
+		}
+	}
+	if containsDate(calendar.NationalHolidays(2023), "2023-11-20") {
+		t.Errorf("Expected no national holiday on 2023-11-20")
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name  string
+		start time.Time
+		add   time.Duration
+		want  time.Time
+	}{
+		{name: "Same day", start: monday, add: 3 * time.Hour, want: monday.Add(3 * time.Hour)},
+		{name: "Overnight", start: monday, add: 10 * time.Hour, want: time.Date(2024, time.March, 12, 10, 0, 0, 0, time.UTC)},
+		{name: "Before opening", start: monday.Add(-4 * time.Hour), add: time.Hour, want: time.Date(2024, time.March, 11, 9, 0, 0, 0, time.UTC)},
+		{name: "Saturday and Sunday", start: time.Date(2024, time.March, 15, 17, 0, 0, 0, time.UTC), add: 7 * time.Hour, want: time.Date(2024, time.March, 18, 9, 0, 0, 0, time.UTC)},
+		{name: "Holiday", start: time.Date(2024, time.April, 20, 12, 0, 0, 0, time.UTC), add: 2 * time.Hour, want: time.Date(2024, time.April, 22, 9, 0, 0, 0, time.UTC)},
+		{name: "Next opening", start: time.Date(2024, time.March, 16, 14, 0, 0, 0, time.UTC), want: time.Date(2024, time.March, 18, 8, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := calendar.Default().Add(tc.start, tc.add); !got.Equal(tc.want) {
+				t.Errorf("Add(%s, %s) = %s, want %s", tc.start, tc.add, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	friday := time.Date(2024, time.March, 15, 17, 0, 0, 0, time.UTC)
+	if got := calendar.Default().Between(friday, friday.Add(72*time.Hour)); got != 15*time.Hour {
+		t.Errorf("Expected 15 working hours until Monday evening, got %s", got)
+	}
+	if got := calendar.Default().Between(monday, monday.Add(-time.Hour)); got != 0 {
+		t.Errorf("Expected no working time backwards, got %s", got)
+	}
+}
+
+func TestRegisteredHolidaysAndClosures(t *testing.T) {
+	id := uuid.New()
+	cal := calendar.New(calendar.DefaultWeek,
+		[]entities.HolidayEntity{
+			{ID: &id, Date: "2020-03-19", Name: "São José", Recurring: true},
+			{ID: &id, Date: "2024-03-13", Name: "Dedetização"},
+		},
+		[]entities.ClosureEntity{
+			{StartsAt: time.Date(2024, time.March, 12, 12, 0, 0, 0, time.UTC), EndsAt: time.Date(2024, time.March, 12, 14, 0, 0, 0, time.UTC), Reason: "Falta de energia"},
+			{StartsAt: time.Date(2024, time.March, 14, 0, 0, 0, 0, time.UTC), EndsAt: time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC), Reason: "Inventário"},
+		},
+	)
+
+	tests := []struct {
+		date   time.Time
+		closed bool
+		reason string
+	}{
+		{date: monday, closed: false},
+		{date: monday.AddDate(0, 0, 1), closed: false},
+		{date: monday.AddDate(0, 0, 2), closed: true, reason: "Dedetização"},
+		{date: monday.AddDate(0, 0, 3), closed: true, reason: "Inventário"},
+		{date: monday.AddDate(0, 0, 6), closed: true, reason: "domingo"},
+		{date: monday.AddDate(0, 0, 8), closed: true, reason: "São José"},
+		{date: monday.AddDate(1, 0, 8), closed: true, reason: "São José"},
+	}
+	for _, tc := range tests {
+		reason, closed := cal.Closed(tc.date)
+		if closed != tc.closed || (closed && reason != tc.reason) {
+			t.Errorf("Closed(%s) = %q, %v, want %q, %v", tc.date.Format("2006-01-02"), reason, closed, tc.reason, tc.closed)
+		}
+	}
+
+	tuesday := cal.Day(monday.AddDate(0, 0, 1))
+	if !tuesday.IsOpen || len(tuesday.Hours) != 2 || tuesday.Hours[0].ClosesAt != "12:00" || tuesday.Hours[1].OpensAt != "14:00" {
+		t.Errorf("Expected Tuesday open around the power cut, got %+v", tuesday)
+	}
+	if cal.IsOpen(time.Date(2024, time.March, 12, 13, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the shop closed during the power cut")
+	}
+
+	// Three open days after Monday, skipping Wednesday and Thursday, is Saturday
+	if got, want := cal.AddDays(monday, 3), time.Date(2024, time.March, 16, 10, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("AddDays(3) = %s, want %s", got, want)
+	}
+	if got, want := cal.AddDays(time.Date(2024, time.March, 15, 10, 0, 0, 0, time.UTC), -2), monday; !got.Equal(want) {
+		t.Errorf("AddDays(-2) = %s, want %s", got, want)
+	}
+
+	holidays := cal.Holidays(2025)
+	if !containsDate(holidays, "2025-03-19") || containsDate(holidays, "2024-03-13") {
+		t.Errorf("Expected the recurring holiday only in 2025, got %v", holidays)
+	}
+}
+
+func containsDate(holidays []entities.HolidayEntity, date string) bool {
+	for _, h := range holidays {
+		if h.Date == date {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCloses(t *testing.T) {
+	cal := calendar.Default()
+	if closes, ok := cal.Closes(monday); !ok || !closes.Equal(time.Date(2024, time.March, 11, 18, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Monday to close at 18:00, got %v, %v", closes, ok)
+	}
+	if closes, ok := cal.Closes(monday.AddDate(0, 0, 5)); !ok || closes.Hour() != 13 {
+		t.Errorf("Expected Saturday to close at 13:00, got %v, %v", closes, ok)
+	}
+	if _, ok := cal.Closes(monday.AddDate(0, 0, 6)); ok {
+		t.Error("Expected Sunday to stay closed")
+	}
+}
@@ -0,0 +1,80 @@
+package testcash
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lavanderia/cash"
+	"lavanderia/entities"
+)
+
+func TestSummarize(t *testing.T) {
+	movements := []entities.CashMovementEntity{
+		{Type: cash.Payment, Method: cash.Cash, Amount: 45.50},
+		{Type: cash.Payment, Method: cash.Cash, Amount: 20.10},
+		{Type: cash.Payment, Method: cash.Card, Amount: 80},
+		{Type: cash.Payment, Method: cash.Pix, Amount: 30},
+		{Type: cash.Withdrawal, Method: cash.Cash, Amount: 40},
+	}
+
+	totals := cash.Summarize(50, movements)
+	want := cash.Totals{Payments: 4, Cash: 65.60, Card: 80, Pix: 30, Received: 175.60, Withdrawals: 40, Expected: 75.60}
+	if totals != want {
+		t.Errorf("Summarize() = %+v, want %+v", totals, want)
+	}
+
+	if totals := cash.Summarize(100, nil); totals.Expected != 100 {
+		t.Errorf("Expected an empty session to hold its float, got %v", totals.Expected)
+	}
+}
+
+func TestWriteZReport(t *testing.T) {
+	openedAt := time.Date(2024, 5, 10, 8, 0, 0, 0, time.Local)
+	closedAt := openedAt.Add(10 * time.Hour)
+	expected, counted, difference := 75.60, 74.10, -1.50
+	report := cash.Report{
+		Session: entities.CashSessionEntity{
+			ID:           uuid.MustParse("6f1c2a9e-0000-4000-8000-000000000000"),
+			OpeningFloat: 50,
+			OpenedAt:     openedAt,
+			ClosedAt:     &closedAt,
+			Expected:     &expected,
+			Counted:      &counted,
+			Difference:   &difference,
+		},
+		Totals: cash.Totals{Payments: 4, Cash: 65.60, Card: 80, Pix: 30, Received: 175.60, Withdrawals: 40, Expected: 75.60},
+		Reconciliation: cash.Reconciliation{
+			Unrecorded: []cash.Unrecorded{{ServiceID: uuid.MustParse("a1b2c3d4-0000-4000-8000-000000000000"), TotalPrice: 60}},
+		},
+	}
+
+	var b strings.Builder
+	if err := cash.WriteZReport(&b, report); err != nil {
+		t.Fatalf("WriteZReport() error = %v", err)
+	}
+	text := b.String()
+
+	for _, want := range []string{
+		"RELATÓRIO Z",
+		"Sessão                          6f1c2a9e",
+		"Fechamento              10/05/2024 18:00",
+		"Esperado em caixa                  75,60",
+		"Diferença                          -1,50",
+		"Pagos fora do caixa                    1",
+		"  a1b2c3d4                         60,00",
+	} {
+		if !strings.Contains(text, want+"\n") {
+			t.Errorf("Expected the report to have the line %q, got:\n%s", want, text)
+		}
+	}
+
+	report.Session.ClosedAt = nil
+	b.Reset()
+	cash.WriteZReport(&b, report)
+	if !strings.Contains(b.String(), "RELATÓRIO X (PARCIAL)") {
+		t.Errorf("Expected an open session to print as an X-report, got:\n%s", b.String())
+	}
+}
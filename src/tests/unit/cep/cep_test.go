@@ -0,0 +1,120 @@
+package testcep
+
+import (
+	"strings"
+	"testing"
+
+	"lavanderia/apierror"
+	"lavanderia/cep"
+)
+
+const dataset = `CEP;Logradouro;Bairro;Cidade;UF
+01310-100;Avenida Paulista;Bela Vista;São Paulo;SP
+20010000;Rua Primeiro de Março;Centro;Rio de Janeiro;Rio de Janeiro
+27600-000;;;Valença;RJ
+`
+
+func useDataset(t *testing.T) {
+	provider, err := cep.Load(strings.NewReader(dataset))
+	if err != nil {
+		t.Fatalf("Failed to load dataset: %v", err)
+	}
+	if provider.Len() != 3 {
+		t.Fatalf("Expected 3 CEPs, got %d", provider.Len())
+	}
+	cep.Use(provider)
+	t.Cleanup(func() { cep.Use(nil) })
+}
+
+func TestLookup(t *testing.T) {
+	useDataset(t)
+
+	address, err := cep.Lookup("20010-000")
+	if err != nil {
+		t.Fatalf("Expected the CEP to be found, got %v", err)
+	}
+	if address.Street != "Rua Primeiro de Março" || address.City != "Rio de Janeiro" || address.State != "RJ" {
+		t.Errorf("Unexpected address %+v", address)
+	}
+
+	if _, err := cep.Lookup("99999-999"); err != cep.ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	useDataset(t)
+
+	tests := []struct {
+		name       string
+		cep        string
+		street     string
+		city       string
+		state      string
+		wantFields []string
+	}{
+		{name: "Exact match", cep: "01310100", street: "Avenida Paulista", city: "São Paulo", state: "SP"},
+		{name: "Abbreviations, accents and case", cep: "20010-000", street: "R. Primeiro de Marco", city: "rio de janeiro", state: "rj"},
+		{name: "City-wide CEP accepts any street", cep: "27600-000", street: "Rua dos Mineiros", city: "Valenca", state: "RJ"},
+		{name: "Unknown CEP is not checked", cep: "99999-999", street: "Rua A", city: "Qualquer", state: "SP"},
+		{name: "Wrong street", cep: "01310-100", street: "Rua Augusta", city: "São Paulo", state: "SP", wantFields: []string{"street"}},
+		{name: "Wrong city and state", cep: "27600-000", street: "Rua A", city: "Vassouras", state: "MG", wantFields: []string{"city", "state"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := cep.Verify(tc.cep, tc.street, tc.city, tc.state)
+			if len(tc.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			errs, ok := err.(apierror.FieldErrors)
+			if !ok || len(errs) != len(tc.wantFields) {
+				t.Fatalf("Expected errors for %v, got %v", tc.wantFields, err)
+			}
+			for i := range errs {
+				if errs[i].Field != tc.wantFields[i] || errs[i].Code != apierror.CEPMismatch {
+					t.Errorf("Expected %s mismatch, got %s %s", tc.wantFields[i], errs[i].Field, errs[i].Code)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeState(t *testing.T) {
+	tests := map[string]string{
+		"rj":             "RJ",
+		"São Paulo":      "SP",
+		"sao paulo":      "SP",
+		" Minas Gerais ": "MG",
+		"Nárnia":         "Nárnia",
+	}
+
+	for state, want := range tests {
+		if got := cep.NormalizeState(state); got != want {
+			t.Errorf("NormalizeState(%q) = %q, want %q", state, got, want)
+		}
+	}
+}
+
+func TestLoadBundledDataset(t *testing.T) {
+	provider, err := cep.LoadFile("../../../data/ceps.csv")
+	if err != nil {
+		t.Fatalf("Failed to load bundled dataset: %v", err)
+	}
+	if provider.Len() == 0 {
+		t.Error("Expected the bundled dataset to have CEPs")
+	}
+}
+
+func TestLoadRejectsInvalidDataset(t *testing.T) {
+	if _, err := cep.Load(strings.NewReader("cep,street\n01310-100,Avenida Paulista\n")); err == nil {
+		t.Error("Expected an error for a dataset without city and state")
+	}
+	if _, err := cep.Load(strings.NewReader("cep,city,state\n0131,São Paulo,SP\n")); err == nil {
+		t.Error("Expected an error for an invalid CEP")
+	}
+}
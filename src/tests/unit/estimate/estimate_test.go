@@ -0,0 +1,80 @@
+package testestimate
+
+import (
+	"testing"
+	"time"
+
+	"lavanderia/calendar"
+	"lavanderia/estimate"
+)
+
+// monday is a Monday at 10:00, outside any holiday
+var monday = time.Date(2024, time.March, 11, 10, 0, 0, 0, time.UTC)
+
+func TestEstimate(t *testing.T) {
+	// Five similar services that took 4 to 8 working hours and five
+	// heavier ones that took two working days
+	var history []estimate.Sample
+	for i := 0; i < 5; i++ {
+		created := monday.AddDate(0, 0, -7).Add(-2 * time.Hour)
+		history = append(history,
+			estimate.Sample{
+				Order:       estimate.Order{IsWeight: true, Weight: 5},
+				CreatedAt:   created,
+				CompletedAt: created.Add(time.Duration(4+i) * time.Hour),
+			},
+			estimate.Sample{
+				Order:       estimate.Order{IsWeight: true, Weight: 20},
+				CreatedAt:   created,
+				CompletedAt: created.AddDate(0, 0, 2),
+			},
+		)
+	}
+
+	t.Run("Similar services", func(t *testing.T) {
+		result := estimate.Estimate(estimate.Input{
+			Order:   estimate.Order{IsWeight: true, Weight: 6},
+			History: history,
+			Now:     monday,
+		}, calendar.Default())
+
+		if result.Basis != estimate.BasisMix || result.Samples != 5 || result.HistoryHours != 7 || result.QueueHours != 0 {
+			t.Fatalf("Expected 7 hours from the 5 similar services, got %+v", result)
+		}
+		if want := monday.Add(7 * time.Hour); !result.EstimatedCompletionDate.Equal(want) {
+			t.Errorf("Expected %s, got %s", want, result.EstimatedCompletionDate)
+		}
+	})
+
+	t.Run("Without history", func(t *testing.T) {
+		result := estimate.Estimate(estimate.Input{Order: estimate.Order{Pieces: 3}, Now: monday}, calendar.Default())
+		if result.Basis != estimate.BasisDefault || result.HistoryHours != estimate.DefaultTurnaround.Hours() {
+			t.Fatalf("Expected the default turnaround, got %+v", result)
+		}
+	})
+
+	t.Run("Busier than usual", func(t *testing.T) {
+		quiet := estimate.Estimate(estimate.Input{
+			Order:   estimate.Order{IsWeight: true, Weight: 6},
+			History: history,
+			Queue:   map[string]int{"Passando": 1},
+			Now:     monday,
+		}, calendar.Default())
+		busy := estimate.Estimate(estimate.Input{
+			Order:   estimate.Order{IsWeight: true, Weight: 6},
+			History: history,
+			Queue:   map[string]int{"Separado": 30, "Lavando": 4, "Finalizado": 50},
+			Now:     monday,
+		}, calendar.Default())
+
+		if quiet.QueueHours != 0 {
+			t.Errorf("Expected no delay with a short queue, got %+v", quiet)
+		}
+		if busy.QueueHours <= 0 || !busy.EstimatedCompletionDate.After(quiet.EstimatedCompletionDate) {
+			t.Errorf("Expected a long queue to delay the estimate, got %+v", busy)
+		}
+		if _, ok := busy.QueueDepth["Finalizado"]; ok || busy.QueueDepth["Separado"] != 30 {
+			t.Errorf("Expected the depth of the queued statuses only, got %v", busy.QueueDepth)
+		}
+	})
+}
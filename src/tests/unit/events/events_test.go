@@ -0,0 +1,23 @@
+package testevents
+
+import (
+	"testing"
+	"time"
+
+	"lavanderia/events"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := map[int]time.Duration{
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		4:  80 * time.Second,
+		20: time.Hour,
+	}
+
+	for attempt, want := range tests {
+		if got := events.RetryDelay(attempt); got != want {
+			t.Errorf("RetryDelay(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package testincidents
+
+import (
+	"testing"
+
+	"lavanderia/incidents"
+)
+
+func TestCanMove(t *testing.T) {
+	cases := []struct {
+		from, to string
+		want     bool
+	}{
+		{incidents.Open, incidents.Investigating, true},
+		{incidents.Open, incidents.Resolved, true},
+		{incidents.Open, incidents.Rejected, true},
+		{incidents.Investigating, incidents.Open, true},
+		{incidents.Investigating, incidents.Resolved, true},
+		{incidents.Open, incidents.Open, false},
+		{incidents.Resolved, incidents.Open, false},
+		{incidents.Rejected, incidents.Resolved, false},
+		{incidents.Open, "closed", false},
+	}
+
+	for _, c := range cases {
+		if got := incidents.CanMove(c.from, c.to); got != c.want {
+			t.Errorf("CanMove(%s, %s) = %v, want %v", c.from, c.to, got, c.want)
+		}
+	}
+}
+
+func TestClosed(t *testing.T) {
+	for _, status := range incidents.Statuses {
+		want := status == incidents.Resolved || status == incidents.Rejected
+		if got := incidents.Closed(status); got != want {
+			t.Errorf("Closed(%s) = %v, want %v", status, got, want)
+		}
+	}
+}
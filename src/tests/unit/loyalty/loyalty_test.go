@@ -0,0 +1,62 @@
+package testloyalty
+
+import (
+	"testing"
+
+	"lavanderia/loyalty"
+)
+
+func TestPoints(t *testing.T) {
+	settings := loyalty.DefaultSettings
+	settings.PointsPerReal = 1.5
+
+	cases := []struct {
+		amount float64
+		want   int
+	}{
+		{100, 150},
+		{45.90, 68},
+		{0.5, 0},
+		{0, 0},
+		{-10, 0},
+	}
+	for _, c := range cases {
+		if got := loyalty.Points(settings, c.amount); got != c.want {
+			t.Errorf("Points(%v) = %d, want %d", c.amount, got, c.want)
+		}
+	}
+
+	settings.IsActive = false
+	if got := loyalty.Points(settings, 100); got != 0 {
+		t.Errorf("Expected no points while the program is inactive, got %d", got)
+	}
+}
+
+func TestDiscount(t *testing.T) {
+	settings := loyalty.DefaultSettings // 5 cents a point, from 100 points, up to 50%
+
+	cases := []struct {
+		points int
+		price  float64
+		want   float64
+		err    error
+	}{
+		{0, 40, 0, nil},
+		{100, 40, 5, nil},
+		{400, 40, 20, nil},
+		{401, 40, 0, loyalty.ErrDiscountTooHigh},
+		{99, 40, 0, loyalty.ErrBelowMinimum},
+		{100, 0, 0, loyalty.ErrDiscountTooHigh},
+	}
+	for _, c := range cases {
+		got, err := loyalty.Discount(settings, c.points, c.price)
+		if got != c.want || err != c.err {
+			t.Errorf("Discount(%d, %v) = %v, %v, want %v, %v", c.points, c.price, got, err, c.want, c.err)
+		}
+	}
+
+	settings.IsActive = false
+	if _, err := loyalty.Discount(settings, 100, 40); err != loyalty.ErrInactive {
+		t.Errorf("Expected ErrInactive, got %v", err)
+	}
+}
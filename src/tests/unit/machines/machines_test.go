@@ -0,0 +1,61 @@
+package testmachines
+
+import (
+	"testing"
+	"time"
+
+	"lavanderia/calendar"
+	"lavanderia/machines"
+)
+
+func TestStage(t *testing.T) {
+	for machineType, want := range map[string]string{machines.Washer: "Lavando", machines.Dryer: "Secando", machines.Iron: "Passando"} {
+		if got := machines.Stage(machineType); got != want {
+			t.Errorf("Expected a %s to work on %s, got %s", machineType, want, got)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, time.March, day, hour, 0, 0, 0, time.UTC)
+	}
+	ended := func(day, hour int) *time.Time {
+		t := at(day, hour)
+		return &t
+	}
+
+	// Monday and Tuesday, open from 8:00 to 18:00
+	from, to := at(11, 0), at(13, 0)
+	runs := []machines.Run{
+		{StartedAt: at(10, 23), EndedAt: ended(11, 1), Weight: 9}, // started on Sunday
+		{StartedAt: at(11, 10), EndedAt: ended(11, 12), Weight: 8},
+		{StartedAt: at(11, 17), EndedAt: ended(11, 19)},
+		{StartedAt: at(12, 9), Weight: 5}, // still running
+	}
+	report := machines.Report(runs, 10, from, to, at(12, 11), calendar.Default())
+
+	if report.Loads != 3 || report.Kg != 13 {
+		t.Errorf("Expected the 3 loads started in the period with 13 kg, got %d with %v kg", report.Loads, report.Kg)
+	}
+	if report.BusyHours != 7 || report.OpenHours != 20 {
+		t.Errorf("Expected 7 busy hours of 20 open, got %v of %v", report.BusyHours, report.OpenHours)
+	}
+	if report.Utilization != 0.25 {
+		t.Errorf("Expected a utilization of 0.25 counting only opening hours, got %v", report.Utilization)
+	}
+	if report.AverageFill != 0.65 {
+		t.Errorf("Expected an average fill of 0.65 over the weighed loads, got %v", report.AverageFill)
+	}
+
+	if len(report.Days) != 2 {
+		t.Fatalf("Expected 2 days, got %+v", report.Days)
+	}
+	monday, tuesday := report.Days[0], report.Days[1]
+	if monday.Date != "2024-03-11" || monday.Loads != 2 || monday.BusyHours != 5 || monday.Kg != 8 {
+		t.Errorf("Unexpected Monday: %+v", monday)
+	}
+	if tuesday.Loads != 1 || tuesday.BusyHours != 2 || tuesday.Kg != 5 {
+		t.Errorf("Unexpected Tuesday: %+v", tuesday)
+	}
+}
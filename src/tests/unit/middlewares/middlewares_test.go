@@ -0,0 +1,72 @@
+package testmiddlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	middleware "lavanderia/middlewares"
+)
+
+func TestRateLimit(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	request := func(handler http.Handler, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/track/7K3QWM9XZ2", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	handler := middleware.RateLimit(3, time.Minute, 0)(ok)
+	for i := 0; i < 3; i++ {
+		if recorder := request(handler, "10.0.0.1:5000", ""); recorder.Code != http.StatusOK {
+			t.Fatalf("Expected request %d to pass, got %d", i+1, recorder.Code)
+		}
+	}
+
+	recorder := request(handler, "10.0.0.1:5001", "")
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") != "20" {
+		t.Errorf("Expected 429 with Retry-After 20, got %d and %q", recorder.Code, recorder.Header().Get("Retry-After"))
+	}
+	if recorder := request(handler, "10.0.0.2:5000", ""); recorder.Code != http.StatusOK {
+		t.Errorf("Expected another IP to pass, got %d", recorder.Code)
+	}
+	// Without trusting the proxy, X-Forwarded-For can't dodge the limit
+	if recorder := request(handler, "10.0.0.1:5000", "192.168.0.9"); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected X-Forwarded-For to be ignored, got %d", recorder.Code)
+	}
+
+	// The proxy appends the address of the client to what it sent
+	proxied := middleware.RateLimit(1, time.Minute, 1)(ok)
+	request(proxied, "10.0.0.9:80", "203.0.113.5")
+	if recorder := request(proxied, "10.0.0.9:80", "203.0.113.6"); recorder.Code != http.StatusOK {
+		t.Errorf("Expected clients behind the proxy to be counted apart, got %d", recorder.Code)
+	}
+	if recorder := request(proxied, "10.0.0.9:80", "203.0.113.5"); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the forwarded client to be limited, got %d", recorder.Code)
+	}
+	if recorder := request(proxied, "10.0.0.9:80", "198.51.100.77, 203.0.113.5"); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected an address sent by the client not to dodge the limit, got %d", recorder.Code)
+	}
+
+	// Behind two proxies the client is the second address from the right
+	chained := middleware.RateLimit(1, time.Minute, 2)(ok)
+	request(chained, "10.0.0.9:80", "198.51.100.1, 203.0.113.5, 10.0.0.2")
+	if recorder := request(chained, "10.0.0.9:80", "198.51.100.2, 203.0.113.5, 10.0.0.2"); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the client behind both proxies to be limited, got %d", recorder.Code)
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	for value, want := range map[string]int{"": 0, "false": 0, "true": 1, "2": 2, "-1": 0} {
+		if got := middleware.TrustedProxies(value); got != want {
+			t.Errorf("Expected %d trusted proxies for %q, got %d", want, value, got)
+		}
+	}
+}
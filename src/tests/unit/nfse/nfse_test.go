@@ -0,0 +1,280 @@
+package testnfse
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"lavanderia/nfse"
+)
+
+var config = nfse.Config{
+	CNPJ:                  "12345678000199",
+	MunicipalRegistration: "98765",
+	CityCode:              "3550308",
+	ServiceItem:           "14.10",
+	ISSRate:               2,
+	SimplesNacional:       true,
+	Series:                "A",
+}
+
+func rps(document string) nfse.RPS {
+	return nfse.RPS{
+		Number:      42,
+		IssuedAt:    time.Date(2024, 3, 15, 10, 0, 0, 0, time.Local),
+		Amount:      57.5,
+		Description: "Serviço de lavanderia ABC: 2 x Camisa; 1 x Calça & Cinto",
+		Taker: nfse.Taker{
+			Name:     "Maria Silva",
+			Document: document,
+			Phone:    "(11) 98888-7777",
+			Address: &nfse.Address{
+				Street:       "Rua das Flores",
+				Number:       "100",
+				Neighborhood: "Centro",
+				State:        "SP",
+				PostalCode:   "01001-000",
+			},
+		},
+	}
+}
+
+// between returns the element name of doc, from its start tag to its end
+// tag
+func between(t *testing.T, doc, name string) string {
+	start := strings.Index(doc, "<"+name)
+	end := strings.Index(doc, "</"+name+">")
+	if start < 0 || end < 0 {
+		t.Fatalf("Expected a %s element in %s", name, doc)
+	}
+	return doc[start : end+len("</"+name+">")]
+}
+
+func TestBuild(t *testing.T) {
+	doc, err := nfse.Build(config, rps("123.456.789-09"), nil)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if err := xml.Unmarshal(doc, new(struct{})); err != nil {
+		t.Fatalf("Expected well formed XML, got %v: %s", err, doc)
+	}
+
+	s := string(doc)
+	for _, want := range []string{
+		`<GerarNfseEnvio xmlns="http://www.abrasf.org.br/nfse.xsd"><Rps><InfDeclaracaoPrestacaoServico Id="rps42">`,
+		`<IdentificacaoRps><Numero>42</Numero><Serie>A</Serie><Tipo>1</Tipo></IdentificacaoRps>`,
+		`<DataEmissao>2024-03-15</DataEmissao>`,
+		`<ValorServicos>57.50</ValorServicos><Aliquota>2.00</Aliquota>`,
+		`<ItemListaServico>14.10</ItemListaServico>`,
+		`<Discriminacao>Serviço de lavanderia ABC: 2 x Camisa; 1 x Calça &amp; Cinto</Discriminacao>`,
+		`<CodigoMunicipio>3550308</CodigoMunicipio>`,
+		`<Prestador><CpfCnpj><Cnpj>12345678000199</Cnpj></CpfCnpj><InscricaoMunicipal>98765</InscricaoMunicipal></Prestador>`,
+		`<IdentificacaoTomador><CpfCnpj><Cpf>12345678909</Cpf></CpfCnpj></IdentificacaoTomador><RazaoSocial>Maria Silva</RazaoSocial>`,
+		`<Endereco><Endereco>Rua das Flores</Endereco><Numero>100</Numero><Bairro>Centro</Bairro><Uf>SP</Uf><Cep>01001000</Cep></Endereco>`,
+		`<Contato><Telefone>11988887777</Telefone></Contato>`,
+		`<OptanteSimplesNacional>1</OptanteSimplesNacional>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Expected %s in %s", want, s)
+		}
+	}
+	if strings.Contains(s, "<Signature") {
+		t.Errorf("Expected no signature without a signer")
+	}
+}
+
+func TestBuildTakerDocument(t *testing.T) {
+	doc, _ := nfse.Build(config, rps("12.345.678/0001-99"), nil)
+	if !strings.Contains(string(doc), "<Cnpj>12345678000199</Cnpj></CpfCnpj></IdentificacaoTomador>") {
+		t.Errorf("Expected a company taker identified by the CNPJ, got %s", doc)
+	}
+
+	doc, _ = nfse.Build(config, rps(""), nil)
+	if strings.Contains(string(doc), "IdentificacaoTomador") {
+		t.Errorf("Expected no identification of a taker without a document, got %s", doc)
+	}
+}
+
+func signer(t *testing.T) (string, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "LAVANDERIA LTDA:12345678000199"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "certificado.pem")
+	content := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})...)
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path, key
+}
+
+func TestBuildSigned(t *testing.T) {
+	path, key := signer(t)
+	s, err := nfse.LoadSigner(path, "")
+	if err != nil {
+		t.Fatalf("LoadSigner: %v", err)
+	}
+
+	doc, err := nfse.Build(config, rps("12345678909"), s)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if err := xml.Unmarshal(doc, new(struct{})); err != nil {
+		t.Fatalf("Expected well formed XML, got %v", err)
+	}
+	text := string(doc)
+
+	if !strings.Contains(text, `</InfDeclaracaoPrestacaoServico><Signature xmlns="http://www.w3.org/2000/09/xmldsig#">`) {
+		t.Fatalf("Expected the signature after the declaration, got %s", text)
+	}
+	if !strings.Contains(text, `<Reference URI="#rps42">`) {
+		t.Errorf("Expected the signature to reference the declaration")
+	}
+
+	// The digest is over the declaration in its canonical form, which
+	// declares the namespace it inherits
+	inf := between(t, text, "InfDeclaracaoPrestacaoServico")
+	inf = strings.Replace(inf, "<InfDeclaracaoPrestacaoServico ", `<InfDeclaracaoPrestacaoServico xmlns="http://www.abrasf.org.br/nfse.xsd" `, 1)
+	digest := sha1.Sum([]byte(inf))
+	want := "<DigestValue>" + base64.StdEncoding.EncodeToString(digest[:]) + "</DigestValue>"
+	if !strings.Contains(text, want) {
+		t.Errorf("Expected %s in %s", want, between(t, text, "SignedInfo"))
+	}
+
+	signedInfo := between(t, text, "SignedInfo")
+	signedInfo = strings.Replace(signedInfo, "<SignedInfo>", `<SignedInfo xmlns="http://www.w3.org/2000/09/xmldsig#">`, 1)
+	hash := sha1.Sum([]byte(signedInfo))
+	value := between(t, text, "SignatureValue")
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "<SignatureValue>"), "</SignatureValue>")
+	signature, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA1, hash[:], signature); err != nil {
+		t.Errorf("Expected a valid signature of SignedInfo, got %v", err)
+	}
+
+	if !strings.Contains(text, "<X509Certificate>"+base64.StdEncoding.EncodeToString(s.Certificate.Raw)+"</X509Certificate>") {
+		t.Errorf("Expected the certificate in KeyInfo")
+	}
+}
+
+func TestLoadSignerInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "certificado.pem")
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("x")})
+	os.WriteFile(path, block, 0o600)
+	if _, err := nfse.LoadSigner(path, ""); err == nil {
+		t.Errorf("Expected an error for an invalid certificate")
+	}
+
+	os.WriteFile(path, []byte("not a pfx"), 0o600)
+	if _, err := nfse.LoadSigner(path, "senha"); err == nil {
+		t.Errorf("Expected an error for an invalid PKCS#12 file")
+	}
+}
+
+func TestIssueNotConfigured(t *testing.T) {
+	issuer := &nfse.Issuer{Provider: &nfse.Fake{}}
+	if _, _, err := issuer.Issue(context.Background(), rps("")); err != nfse.ErrNotConfigured {
+		t.Errorf("Expected ErrNotConfigured, got %v", err)
+	}
+}
+
+func TestFake(t *testing.T) {
+	dir := t.TempDir()
+	issuer := &nfse.Issuer{Config: config, Provider: &nfse.Fake{Dir: dir}}
+	result, doc, err := issuer.Issue(context.Background(), rps("12345678909"))
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if result.Status != nfse.Issued || result.Number != "42" || result.VerificationCode == "" || result.PDFURL != "fake://nfse/42.pdf" {
+		t.Errorf("Expected invoice 42 issued, got %+v", result)
+	}
+	written, err := os.ReadFile(filepath.Join(dir, "rps42.xml"))
+	if err != nil || string(written) != string(doc) {
+		t.Errorf("Expected the document written to the directory, got %v", err)
+	}
+
+	issuer.Provider = &nfse.Fake{Reject: "E160 - CPF do tomador inválido"}
+	result, _, err = issuer.Issue(context.Background(), rps("12345678909"))
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if result.Status != nfse.Rejected || result.Message != "E160 - CPF do tomador inválido" || result.Number != "" {
+		t.Errorf("Expected the RPS rejected, got %+v", result)
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	var status int
+	var answer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Authorization") != "Bearer token" || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/xml") || !strings.Contains(string(body), "GerarNfseEnvio") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, answer)
+	}))
+	defer server.Close()
+
+	provider := &nfse.HTTPProvider{URL: server.URL, Token: "token"}
+	issuer := &nfse.Issuer{Config: config, Provider: provider}
+
+	status, answer = http.StatusOK, `{"number": "2024000123", "verification_code": "AB12CD", "pdf_url": "https://nfse.example/2024000123.pdf"}`
+	result, _, err := issuer.Issue(context.Background(), rps("12345678909"))
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if result.Status != nfse.Issued || result.Number != "2024000123" || result.PDFURL != "https://nfse.example/2024000123.pdf" {
+		t.Errorf("Expected the invoice issued, got %+v", result)
+	}
+
+	status, answer = http.StatusUnprocessableEntity, `{"message": "E10 - RPS já informado"}`
+	result, _, err = issuer.Issue(context.Background(), rps("12345678909"))
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if result.Status != nfse.Rejected || result.Message != "E10 - RPS já informado" {
+		t.Errorf("Expected the RPS rejected, got %+v", result)
+	}
+
+	status, answer = http.StatusInternalServerError, "indisponível"
+	if _, _, err := issuer.Issue(context.Background(), rps("12345678909")); err == nil {
+		t.Errorf("Expected an error when the gateway fails")
+	}
+
+	status, answer = http.StatusOK, `{"status": "issued"}`
+	if _, _, err := issuer.Issue(context.Background(), rps("12345678909")); err == nil {
+		t.Errorf("Expected an error when the gateway answers without the invoice number")
+	}
+}
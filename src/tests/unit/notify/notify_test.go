@@ -0,0 +1,159 @@
+package testnotify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"lavanderia/notify"
+)
+
+func TestRender(t *testing.T) {
+	date := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	data := notify.Data{FirstName: "Maria", ServiceID: "3f2a9c1e-0000-0000-0000-000000000000", Total: 1234.5, Date: date}
+
+	tests := []struct {
+		kind        notify.Kind
+		wantSubject string
+		wantBody    []string
+	}{
+		{kind: notify.ServiceCreated, wantSubject: "Recebemos o seu pedido 3F2A9C1E", wantBody: []string{"Olá, Maria!", "05/03/2024", "R$ 1.234,50"}},
+		{kind: notify.ServiceReady, wantSubject: "Seu pedido 3F2A9C1E está pronto", wantBody: []string{"está pronto. Valor: R$ 1.234,50."}},
+		{kind: notify.PaymentOverdue, wantSubject: "Pagamento pendente do pedido 3F2A9C1E", wantBody: []string{"pendente desde 05/03/2024"}},
+		{kind: notify.SubscriptionExpiring, wantSubject: "Sua mensalidade vence em 05/03/2024", wantBody: []string{"Renove"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(string(tc.kind), func(t *testing.T) {
+			subject, body, err := notify.Render(tc.kind, data)
+			if err != nil {
+				t.Fatalf("Failed to render: %v", err)
+			}
+			if subject != tc.wantSubject {
+				t.Errorf("Expected subject %q, got %q", tc.wantSubject, subject)
+			}
+			for _, want := range tc.wantBody {
+				if !strings.Contains(body, want) {
+					t.Errorf("Expected body to contain %q, got %q", want, body)
+				}
+			}
+		})
+	}
+
+	if _, body, _ := notify.Render(notify.ServiceReady, notify.Data{FirstName: "Maria", ServiceID: "abc"}); strings.Contains(body, "Valor") {
+		t.Errorf("Expected no price for monthly services, got %q", body)
+	}
+	data.TrackingCode = "7K3QW-M9XZ2"
+	if _, body, _ := notify.Render(notify.ServiceCreated, data); !strings.Contains(body, "Acompanhe pelo código 7K3QW-M9XZ2.") {
+		t.Errorf("Expected the tracking code, got %q", body)
+	}
+	if _, _, err := notify.Render("unknown", data); err == nil {
+		t.Error("Expected an error for an unknown kind")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := map[int]time.Duration{
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		5:  16 * time.Minute,
+		20: 6 * time.Hour,
+	}
+
+	for attempt, want := range tests {
+		if got := notify.RetryDelay(attempt); got != want {
+			t.Errorf("RetryDelay(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+func TestFileChannel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.log")
+	channel := &notify.FileChannel{Path: path}
+
+	for _, recipient := range []string{"5524998548386", "maria@example.com"} {
+		err := channel.Send(context.Background(), notify.Message{Channel: notify.WhatsApp, Recipient: recipient, Body: "Olá"})
+		if err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read the file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+
+	var message map[string]string
+	if err := json.Unmarshal([]byte(lines[0]), &message); err != nil {
+		t.Fatalf("Expected JSON lines, got %q", lines[0])
+	}
+	if message["recipient"] != "5524998548386" || message["body"] != "Olá" {
+		t.Errorf("Unexpected message %+v", message)
+	}
+}
+
+func TestHTTPChannels(t *testing.T) {
+	var gotPath, gotAuth string
+	var gotBody map[string]interface{}
+	status := http.StatusOK
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	msg := notify.Message{Recipient: "5524998548386", Body: "Seu pedido está pronto"}
+
+	sms := notify.SMSChannel{URL: server.URL + "/send", Token: "sms-token"}
+	if err := sms.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Failed to send SMS: %v", err)
+	}
+	if gotPath != "/send" || gotAuth != "Bearer sms-token" || gotBody["to"] != msg.Recipient || gotBody["message"] != msg.Body {
+		t.Errorf("Unexpected SMS request %s %s %+v", gotPath, gotAuth, gotBody)
+	}
+
+	whatsapp := notify.WhatsAppChannel{BaseURL: server.URL, PhoneNumberID: "1234", Token: "wa-token"}
+	if err := whatsapp.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Failed to send WhatsApp message: %v", err)
+	}
+	text, _ := gotBody["text"].(map[string]interface{})
+	if gotPath != "/1234/messages" || gotAuth != "Bearer wa-token" || gotBody["messaging_product"] != "whatsapp" || text["body"] != msg.Body {
+		t.Errorf("Unexpected WhatsApp request %s %s %+v", gotPath, gotAuth, gotBody)
+	}
+
+	status = http.StatusBadGateway
+	if err := sms.Send(context.Background(), msg); err == nil {
+		t.Error("Expected an error when the gateway fails")
+	}
+}
+
+func TestChannelsFromEnv(t *testing.T) {
+	t.Setenv("NOTIFY_FAKE", "log")
+	channels := notify.ChannelsFromEnv()
+	for _, name := range []string{notify.Email, notify.SMS, notify.WhatsApp} {
+		if _, ok := channels[name].(*notify.FileChannel); !ok {
+			t.Errorf("Expected %s to be faked, got %T", name, channels[name])
+		}
+	}
+
+	t.Setenv("NOTIFY_FAKE", "")
+	t.Setenv("SMTP_HOST", "")
+	t.Setenv("SMS_GATEWAY_URL", "")
+	t.Setenv("WHATSAPP_PHONE_NUMBER_ID", "1234")
+	channels = notify.ChannelsFromEnv()
+	if len(channels) != 1 || channels[notify.WhatsApp] == nil {
+		t.Errorf("Expected only WhatsApp to be configured, got %v", channels)
+	}
+}
@@ -0,0 +1,91 @@
+package testproduction
+
+import (
+	"testing"
+	"time"
+
+	"lavanderia/production"
+)
+
+// now is a Monday at 10:00
+var now = time.Date(2024, time.March, 11, 10, 0, 0, 0, time.UTC)
+
+func TestSurcharge(t *testing.T) {
+	settings := production.DefaultSettings
+	for priority, want := range map[string]float64{production.Normal: 0, production.Express: 50, production.SameDay: 100} {
+		if got := production.Surcharge(settings, priority); got != want {
+			t.Errorf("Expected a surcharge of %v%% for %s, got %v%%", want, priority, got)
+		}
+	}
+
+	for _, c := range []struct{ price, percent, want float64 }{
+		{40, 0, 40},
+		{40, 50, 60},
+		{33.33, 50, 50},
+		{12.5, 100, 25},
+	} {
+		if got := production.WithSurcharge(c.price, c.percent); got != c.want {
+			t.Errorf("Expected %v with %v%% to be %v, got %v", c.price, c.percent, c.want, got)
+		}
+	}
+}
+
+func TestLoadFits(t *testing.T) {
+	load := production.Load{Kg: 40, Pieces: 90, MaxKg: 50, MaxPieces: 100}
+	if !load.Fits(10, 0) || !load.Fits(0, 10) {
+		t.Error("Expected the day to fit up to its limits")
+	}
+	if load.Fits(10.5, 0) || load.Fits(0, 11) {
+		t.Error("Expected the day not to fit beyond its limits")
+	}
+
+	unlimited := production.Load{Kg: 500, Pieces: 500}
+	if !unlimited.Fits(100, 100) {
+		t.Error("Expected a day without limits to fit anything")
+	}
+}
+
+func TestGroup(t *testing.T) {
+	at := func(hours int) *time.Time {
+		t := now.Add(time.Duration(hours) * time.Hour)
+		return &t
+	}
+	items := []production.Item{
+		{Status: "Lavando", Priority: production.Normal, EstimatedCompletionDate: nil, CreatedAt: now.Add(-5 * time.Hour)},
+		{Status: "Lavando", Priority: production.Normal, EstimatedCompletionDate: at(48), CreatedAt: now.Add(-4 * time.Hour)},
+		{Status: "Lavando", Priority: production.Normal, EstimatedCompletionDate: at(-2), CreatedAt: now.Add(-3 * time.Hour)},
+		{Status: "Lavando", Priority: production.Express, EstimatedCompletionDate: at(72), CreatedAt: now.Add(-2 * time.Hour)},
+		{Status: "Lavando", Priority: production.SameDay, EstimatedCompletionDate: at(8), CreatedAt: now.Add(-1 * time.Hour)},
+		{Status: "Separado", Priority: production.Normal, EstimatedCompletionDate: at(24), CreatedAt: now.Add(-2 * time.Hour)},
+		{Status: "Separado", Priority: production.Normal, EstimatedCompletionDate: at(24), CreatedAt: now.Add(-3 * time.Hour)},
+		{Status: "Finalizado", Priority: production.SameDay, EstimatedCompletionDate: at(1), CreatedAt: now},
+	}
+
+	stages := production.Group(items, production.Statuses, now)
+	if len(stages) != 4 || stages[0].Status != "Separado" || stages[3].Status != "Passando" {
+		t.Fatalf("Expected the four stages in order, got %+v", stages)
+	}
+
+	washing := stages[1].Services
+	if stages[1].Count != 5 || len(washing) != 5 {
+		t.Fatalf("Expected 5 services washing, got %d", len(washing))
+	}
+	wantOrder := []*time.Time{at(8), at(72), at(-2), at(48), nil}
+	for i, item := range washing {
+		if (item.EstimatedCompletionDate == nil) != (wantOrder[i] == nil) ||
+			(item.EstimatedCompletionDate != nil && !item.EstimatedCompletionDate.Equal(*wantOrder[i])) {
+			t.Errorf("Expected position %d to be due at %v, got %v (%s)", i, wantOrder[i], item.EstimatedCompletionDate, item.Priority)
+		}
+	}
+	if !washing[2].Overdue || washing[0].Overdue || washing[4].Overdue {
+		t.Errorf("Expected only the service due before now to be overdue, got %+v", washing)
+	}
+
+	separated := stages[0].Services
+	if len(separated) != 2 || !separated[0].CreatedAt.Before(separated[1].CreatedAt) {
+		t.Errorf("Expected services due together to be ordered by creation, got %+v", separated)
+	}
+	if stages[3].Count != 0 || stages[3].Services == nil {
+		t.Errorf("Expected an empty stage to list no services, got %+v", stages[3])
+	}
+}
@@ -0,0 +1,214 @@
+package testqueryspec
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"lavanderia/queryspec"
+)
+
+var options = queryspec.Options{
+	SortFields: map[string]string{
+		"created_at":                "ls.created_at",
+		"status":                    "ls.status",
+		"estimated_completion_date": "ls.estimated_completion_date",
+	},
+	NullSorts:   map[string]string{"estimated_completion_date": "'infinity'::timestamp"},
+	DefaultSort: "-created_at",
+	TieBreaker:  "ls.id",
+	Filters: []queryspec.Filter{
+		{Param: "status", Column: "ls.status", Type: queryspec.Equals, Allowed: []string{"Separado", "Finalizado"}},
+		{Param: "is_paid", Column: "ls.is_paid", Type: queryspec.Bool},
+		{Param: "created_at", Column: "ls.created_at", Type: queryspec.DateRange},
+		{Param: "client_id", Column: "ls.client_id", Type: queryspec.UUID},
+	},
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		wantErr      bool
+		wantPage     int
+		wantPageSize int
+		wantWhere    string
+		wantOrderBy  string
+		wantArgs     int
+	}{
+		{
+			name:         "Defaults",
+			query:        "",
+			wantPage:     1,
+			wantPageSize: 10,
+			wantWhere:    "",
+			wantOrderBy:  "ORDER BY ls.created_at DESC, ls.id DESC",
+		},
+		{
+			name:         "Legacy pageSize parameter",
+			query:        "page=2&pageSize=5",
+			wantPage:     2,
+			wantPageSize: 5,
+			wantOrderBy:  "ORDER BY ls.created_at DESC, ls.id DESC",
+		},
+		{
+			name:         "Legacy limit parameter",
+			query:        "page=3&limit=20",
+			wantPage:     3,
+			wantPageSize: 20,
+			wantOrderBy:  "ORDER BY ls.created_at DESC, ls.id DESC",
+		},
+		{
+			name:         "Typed filters",
+			query:        "status=Separado&is_paid=true&created_at_from=2024-01-01&created_at_to=2024-01-31&client_id=1b4e28ba-2fa1-11d2-883f-0016d3cca427",
+			wantPage:     1,
+			wantPageSize: 10,
+			wantWhere:    "WHERE ls.status = $1 AND ls.is_paid = $2 AND ls.created_at >= $3 AND ls.created_at < $4 AND ls.client_id = $5",
+			wantOrderBy:  "ORDER BY ls.created_at DESC, ls.id DESC",
+			wantArgs:     5,
+		},
+		{
+			name:         "Multiple sort fields",
+			query:        "sort=status,-created_at",
+			wantPage:     1,
+			wantPageSize: 10,
+			wantOrderBy:  "ORDER BY ls.status ASC, ls.created_at DESC, ls.id ASC",
+		},
+		{
+			name:         "Nullable sort field",
+			query:        "sort=-estimated_completion_date",
+			wantPage:     1,
+			wantPageSize: 10,
+			wantOrderBy:  "ORDER BY COALESCE(ls.estimated_completion_date, 'infinity'::timestamp) DESC, ls.id DESC",
+		},
+		{name: "Page below one", query: "page=0", wantErr: true},
+		{name: "Page size above maximum", query: "page_size=1000", wantErr: true},
+		{name: "Sort field not whitelisted", query: "sort=password", wantErr: true},
+		{name: "Unknown status", query: "status=Perdido", wantErr: true},
+		{name: "Invalid boolean", query: "is_paid=maybe", wantErr: true},
+		{name: "Invalid date", query: "created_at_from=yesterday", wantErr: true},
+		{name: "Invalid client ID", query: "client_id=123", wantErr: true},
+		{name: "Invalid cursor", query: "cursor=not-a-cursor", wantErr: true},
+		{name: "Cursor with page", query: "cursor=&page=2", wantErr: true},
+		{name: "Cursor with mixed directions", query: "cursor=&sort=status,-created_at", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tc.query)
+
+			spec, err := queryspec.Parse(values, options)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("Expected validation error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if spec.Page != tc.wantPage || spec.PageSize != tc.wantPageSize {
+				t.Errorf("Expected page %d of size %d, got page %d of size %d", tc.wantPage, tc.wantPageSize, spec.Page, spec.PageSize)
+			}
+
+			var args queryspec.Args
+			if where := spec.Where(&args); where != tc.wantWhere {
+				t.Errorf("Expected where clause %q, got %q", tc.wantWhere, where)
+			}
+			if len(args.Values()) != tc.wantArgs {
+				t.Errorf("Expected %d arguments, got %d", tc.wantArgs, len(args.Values()))
+			}
+			if orderBy := spec.OrderBy(); orderBy != tc.wantOrderBy {
+				t.Errorf("Expected order by %q, got %q", tc.wantOrderBy, orderBy)
+			}
+		})
+	}
+}
+
+func TestCursorPagination(t *testing.T) {
+	values, _ := url.ParseQuery("cursor=&page_size=2")
+	spec, err := queryspec.Parse(values, options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var args queryspec.Args
+	if limit := spec.Limit(&args); limit != "LIMIT $1" {
+		t.Errorf("Expected cursor limit without offset, got %q", limit)
+	}
+	if args.Values()[0] != 3 {
+		t.Errorf("Expected one extra row to be fetched, got limit %v", args.Values()[0])
+	}
+
+	pagination := spec.Paginate(5, 3, `["2024-01-01 10:00:00","1b4e28ba-2fa1-11d2-883f-0016d3cca427"]`)
+	if pagination.NextCursor == "" {
+		t.Fatalf("Expected next cursor when more rows are available")
+	}
+
+	values.Set("cursor", pagination.NextCursor)
+	next, err := queryspec.Parse(values, options)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing next cursor: %v", err)
+	}
+
+	var nextArgs queryspec.Args
+	want := "WHERE (ls.created_at, ls.id) < ($1, $2)"
+	if where := next.Where(&nextArgs); where != want {
+		t.Errorf("Expected where clause %q, got %q", want, where)
+	}
+
+	last := next.Paginate(5, 2, `["2024-01-01 09:00:00","2c4e28ba-2fa1-11d2-883f-0016d3cca427"]`)
+	if last.NextCursor != "" {
+		t.Errorf("Expected no next cursor on the last page, got %q", last.NextCursor)
+	}
+}
+
+func TestCursorNullableSort(t *testing.T) {
+	values, _ := url.ParseQuery("cursor=&sort=estimated_completion_date&page_size=2")
+	spec, err := queryspec.Parse(values, options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := "json_build_array((COALESCE(ls.estimated_completion_date, 'infinity'::timestamp))::text, (ls.id)::text)::text"
+	if columns := spec.CursorColumns(); columns != want {
+		t.Errorf("Expected cursor columns %q, got %q", want, columns)
+	}
+
+	pagination := spec.Paginate(5, 3, `["infinity","1b4e28ba-2fa1-11d2-883f-0016d3cca427"]`)
+	values.Set("cursor", pagination.NextCursor)
+	next, err := queryspec.Parse(values, options)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing next cursor: %v", err)
+	}
+
+	var args queryspec.Args
+	want = "WHERE (COALESCE(ls.estimated_completion_date, 'infinity'::timestamp), ls.id) > ($1, $2)"
+	if where := next.Where(&args); where != want {
+		t.Errorf("Expected where clause %q, got %q", want, where)
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	values, _ := url.ParseQuery("from=2024-01-01&to=2024-01-31")
+	from, to, err := queryspec.Period(values, queryspec.MaxPeriodDays)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if from.Format("2006-01-02") != "2024-01-01" || to.Format("2006-01-02") != "2024-02-01" {
+		t.Errorf("Expected the period to end the day after its last day, got %s to %s", from, to)
+	}
+
+	from, to, err = queryspec.Period(url.Values{}, queryspec.MaxPeriodDays)
+	if err != nil || to.Sub(from) < 30*24*time.Hour-time.Hour || to.Before(time.Now()) {
+		t.Errorf("Expected the last 30 days by default, got %s to %s (%v)", from, to, err)
+	}
+
+	for _, query := range []string{"from=2024-02-01&to=2024-01-01", "from=2023-01-01&to=2024-06-30", "from=ontem"} {
+		values, _ := url.ParseQuery(query)
+		if _, _, err := queryspec.Period(values, queryspec.MaxPeriodDays); err == nil {
+			t.Errorf("Expected an error for %s", query)
+		}
+	}
+}
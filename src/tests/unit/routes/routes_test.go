@@ -0,0 +1,146 @@
+package testroutes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"lavanderia/routes"
+)
+
+type document struct {
+	OpenAPI string                                       `json:"openapi"`
+	Paths   map[string]map[string]map[string]interface{} `json:"paths"`
+}
+
+func fetchDocument(t *testing.T, router *mux.Router) document {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, recorder.Code)
+	}
+
+	var doc document
+	if err := json.NewDecoder(recorder.Body).Decode(&doc); err != nil {
+		t.Fatalf("Failed to decode OpenAPI document: %v", err)
+	}
+	return doc
+}
+
+// TestEveryRouteIsDocumented fails when a route is added to the router
+// without going through the documented helpers in the routes package
+func TestEveryRouteIsDocumented(t *testing.T) {
+	router := routes.SetupRoutes(nil)
+	doc := fetchDocument(t, router)
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("Expected an OpenAPI 3 document, got version %q", doc.OpenAPI)
+	}
+
+	registered := map[string]bool{}
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || path == "" {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Subrouters only carry a path prefix
+			if route.GetHandler() == nil {
+				return nil
+			}
+			// Routes answering every method are documented under one
+			if len(doc.Paths[path]) == 0 {
+				t.Errorf("Route %s must declare its methods or be documented", path)
+			}
+			for method := range doc.Paths[path] {
+				registered[strings.ToUpper(method)+" "+path] = true
+			}
+			return nil
+		}
+
+		for _, method := range methods {
+			registered[method+" "+path] = true
+
+			op, ok := doc.Paths[path][strings.ToLower(method)]
+			if !ok {
+				t.Errorf("Route %s %s is missing from the OpenAPI document", method, path)
+				continue
+			}
+			if summary, _ := op["summary"].(string); summary == "" {
+				t.Errorf("Route %s %s has no summary", method, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk routes: %v", err)
+	}
+
+	for path, methods := range doc.Paths {
+		for method := range methods {
+			if !registered[strings.ToUpper(method)+" "+path] {
+				t.Errorf("Documented operation %s %s has no route", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestDocumentDescribesSchemasAndRoles(t *testing.T) {
+	doc := fetchDocument(t, routes.SetupRoutes(nil))
+
+	create := doc.Paths["/clients"]["post"]
+	if roles, _ := create["x-roles"].([]interface{}); len(roles) != 1 || roles[0] != "Admin" {
+		t.Errorf("Expected POST /clients to require the Admin role, got %v", create["x-roles"])
+	}
+	if _, ok := create["requestBody"]; !ok {
+		t.Error("Expected POST /clients to document its request body")
+	}
+	responses, _ := create["responses"].(map[string]interface{})
+	for _, status := range []string{"201", "400", "401", "403", "500"} {
+		if _, ok := responses[status]; !ok {
+			t.Errorf("Expected POST /clients to document a %s response", status)
+		}
+	}
+
+	if _, ok := doc.Paths["/items"]["get"]["x-roles"]; ok {
+		t.Error("Expected GET /items to be public")
+	}
+}
+
+func TestSwaggerUI(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/docs", nil)
+	recorder := httptest.NewRecorder()
+	routes.SetupRoutes(nil).ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, recorder.Code)
+	}
+	if !strings.Contains(recorder.Body.String(), "/openapi.json") {
+		t.Error("Expected the Swagger UI to load /openapi.json")
+	}
+}
+
+// TestAuthRoutesAnswerEveryMethod keeps the auth routes answering the
+// methods they always did
+func TestAuthRoutesAnswerEveryMethod(t *testing.T) {
+	router := routes.SetupRoutes(nil)
+
+	for _, method := range []string{"GET", "POST", "HEAD"} {
+		req, _ := http.NewRequest(method, "/api/auth/status", nil)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected %s /api/auth/status to answer %d, got %d", method, http.StatusOK, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package testrouting
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"lavanderia/routing"
+)
+
+// east returns a point on the equator km kilometres east of the origin
+func east(km float64) routing.Point {
+	return routing.Point{Lat: 0, Lng: km / (6371.0 * math.Pi / 180)}
+}
+
+func at(clock string) time.Time {
+	t, _ := time.Parse("2006-01-02 15:04", "2024-03-01 "+clock)
+	return t
+}
+
+func ids(route routing.Route) []string {
+	order := make([]string, 0, len(route.Visits))
+	for _, visit := range route.Visits {
+		order = append(order, visit.ID)
+	}
+	return order
+}
+
+func TestHaversine(t *testing.T) {
+	saoPaulo := routing.Point{Lat: -23.5505, Lng: -46.6333}
+	rio := routing.Point{Lat: -22.9068, Lng: -43.1729}
+
+	if km := routing.Haversine(saoPaulo, rio); km < 355 || km > 362 {
+		t.Errorf("Expected about 358 km between São Paulo and Rio, got %.1f", km)
+	}
+	if km := routing.Haversine(rio, rio); km != 0 {
+		t.Errorf("Expected no distance to the same point, got %f", km)
+	}
+	if km := routing.Haversine(east(0), east(10)); math.Abs(km-10) > 1e-6 {
+		t.Errorf("Expected 10 km, got %f", km)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name      string
+		stops     []routing.Stop
+		wantOrder []string
+		wantKm    float64
+		wantLate  int
+	}{
+		{
+			name:  "No stops",
+			stops: nil,
+		},
+		{
+			name: "Nearest first without windows",
+			stops: []routing.Stop{
+				{ID: "c", Location: east(3)},
+				{ID: "a", Location: east(1)},
+				{ID: "b", Location: east(2)},
+			},
+			wantOrder: []string{"a", "b", "c"},
+			wantKm:    6,
+		},
+		{
+			name: "Urgent window comes before the nearest stop",
+			stops: []routing.Stop{
+				{ID: "near", Location: east(1), WindowStart: at("10:00"), WindowEnd: at("12:00")},
+				{ID: "far", Location: east(10), WindowStart: at("08:00"), WindowEnd: at("08:40")},
+			},
+			wantOrder: []string{"far", "near"},
+			wantKm:    20,
+		},
+		{
+			name: "Unreachable windows are reported as late",
+			stops: []routing.Stop{
+				{ID: "a", Location: east(20), WindowEnd: at("08:10")},
+				{ID: "b", Location: east(25), WindowEnd: at("08:20")},
+			},
+			wantOrder: []string{"a", "b"},
+			wantKm:    50,
+			wantLate:  2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			route := routing.Plan(east(0), tc.stops, routing.Options{Start: at("08:00"), ReturnToOrigin: true})
+
+			order := ids(route)
+			if len(order) != len(tc.wantOrder) {
+				t.Fatalf("Expected order %v, got %v", tc.wantOrder, order)
+			}
+			for i := range order {
+				if order[i] != tc.wantOrder[i] {
+					t.Fatalf("Expected order %v, got %v", tc.wantOrder, order)
+				}
+			}
+			if math.Abs(route.DistanceKm-tc.wantKm) > 1e-6 {
+				t.Errorf("Expected %.2f km, got %.2f", tc.wantKm, route.DistanceKm)
+			}
+			if route.LateStops != tc.wantLate {
+				t.Errorf("Expected %d late stops, got %d", tc.wantLate, route.LateStops)
+			}
+		})
+	}
+}
+
+func TestPlanTimes(t *testing.T) {
+	stops := []routing.Stop{
+		{ID: "a", Location: east(15), WindowStart: at("09:00"), WindowEnd: at("10:00")},
+	}
+
+	route := routing.Plan(east(0), stops, routing.Options{Start: at("08:00"), SpeedKmh: 30, ServiceTime: 10 * time.Minute})
+
+	visit := route.Visits[0]
+	if !visit.Arrival.Equal(at("08:30")) {
+		t.Errorf("Expected arrival at 08:30, got %s", visit.Arrival.Format("15:04"))
+	}
+	if !visit.Start.Equal(at("09:00")) {
+		t.Errorf("Expected the visit to wait for the window at 09:00, got %s", visit.Start.Format("15:04"))
+	}
+	if !route.Finish.Equal(at("09:10")) {
+		t.Errorf("Expected to finish at 09:10 without returning, got %s", route.Finish.Format("15:04"))
+	}
+	if route.DistanceKm != visit.DistanceKm {
+		t.Errorf("Expected only the outbound distance, got %.2f", route.DistanceKm)
+	}
+}
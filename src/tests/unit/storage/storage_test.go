@@ -0,0 +1,107 @@
+package teststorage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"lavanderia/storage"
+)
+
+func roundTrip(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	key := "services/abc/photo 1.jpg"
+
+	err := store.Put(ctx, key, strings.NewReader("conteúdo"), int64(len("conteúdo")), "image/jpeg")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	content, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, _ := io.ReadAll(content)
+	content.Close()
+	if string(got) != "conteúdo" {
+		t.Errorf("Expected the stored content, got %q", got)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); err != storage.ErrNotFound {
+		t.Errorf("Expected ErrNotFound after the delete, got %v", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Expected deleting a missing file to succeed, got %v", err)
+	}
+}
+
+func TestLocal(t *testing.T) {
+	store := storage.Local{Dir: t.TempDir()}
+	roundTrip(t, store)
+
+	err := store.Put(context.Background(), "../escape.txt", strings.NewReader("x"), 1, "text/plain")
+	if err == nil {
+		t.Error("Expected a key leaving the directory to be refused")
+	}
+}
+
+// bucket is a fake S3-compatible service keeping the objects in memory
+type bucket struct {
+	mu      sync.Mutex
+	objects map[string]string
+	auth    []string
+}
+
+func (b *bucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.auth = append(b.auth, r.Header.Get("Authorization"))
+
+	key := r.URL.EscapedPath()
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		b.objects[key] = string(body)
+	case http.MethodGet:
+		body, ok := b.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, body)
+	case http.MethodDelete:
+		delete(b.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3(t *testing.T) {
+	fake := &bucket{objects: map[string]string{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store := &storage.S3{Endpoint: server.URL, Bucket: "fotos", Region: "sa-east-1", AccessKeyID: "AKID", SecretAccessKey: "secret"}
+	roundTrip(t, store)
+
+	if len(fake.auth) == 0 {
+		t.Fatal("Expected requests to reach the bucket")
+	}
+	for _, auth := range fake.auth {
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/sa-east-1/s3/aws4_request") ||
+			!strings.Contains(auth, "SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=") {
+			t.Errorf("Expected a Signature Version 4 header, got %q", auth)
+		}
+	}
+
+	store.Put(context.Background(), "services/abc/photo 1.jpg", strings.NewReader("x"), 1, "image/jpeg")
+	if _, ok := fake.objects["/fotos/services/abc/photo%201.jpg"]; !ok {
+		t.Errorf("Expected a path-style URL with the key escaped, got %v", fake.objects)
+	}
+}
@@ -0,0 +1,112 @@
+package teststream
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lavanderia/events"
+	serviceshandlers "lavanderia/handlers/laundryServices"
+	middleware "lavanderia/middlewares"
+	"lavanderia/stream"
+)
+
+func message(id int64, clientID uuid.UUID, status string) stream.Message {
+	return stream.Message{EventID: id, Type: events.ServiceStatusChanged, Service: stream.Service{ID: uuid.New(), ClientID: clientID, Status: status}}
+}
+
+func TestBroker(t *testing.T) {
+	broker := stream.NewBroker()
+	maria, joao := uuid.New(), uuid.New()
+
+	all, cancelAll := broker.Subscribe(stream.Filter{})
+	defer cancelAll()
+	mine, cancelMine := broker.Subscribe(stream.Filter{ClientID: maria, Status: "Finalizado"})
+
+	broker.Publish(message(1, joao, "Finalizado"))
+	broker.Publish(message(2, maria, "Lavando"))
+	broker.Publish(message(3, maria, "Finalizado"))
+
+	if got := len(all); got != 3 {
+		t.Errorf("Expected every message without a filter, got %d", got)
+	}
+	if got := <-mine; got.EventID != 3 || len(mine) != 0 {
+		t.Errorf("Expected only the finished service of the client, got %+v", got)
+	}
+
+	cancelMine()
+	if _, ok := <-mine; ok {
+		t.Error("Expected the channel to be closed after cancel")
+	}
+	cancelMine()
+
+	// A connection that falls behind is dropped instead of blocking the others
+	for i := int64(0); i < 40; i++ {
+		broker.Publish(message(i, joao, "Lavando"))
+	}
+	if broker.Connections() != 0 {
+		t.Errorf("Expected the slow connection to be dropped, got %d open", broker.Connections())
+	}
+}
+
+func TestStreamServicesHandler(t *testing.T) {
+	broker := stream.NewBroker()
+	clientID := uuid.New()
+
+	user := middleware.User{ID: clientID.String(), Role: "Client"}
+	handler := serviceshandlers.StreamServicesHandler(broker)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(middleware.WithUser(r.Context(), user)))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?status=Molhado")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an unknown status, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"?client_id="+uuid.NewString(), nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %s", resp.Header.Get("Content-Type"))
+	}
+
+	for broker.Connections() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// The client asked for someone else's services but only gets their own
+	broker.Publish(message(1, uuid.New(), "Lavando"))
+	broker.Publish(message(2, clientID, "Lavando"))
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read the stream: %v", err)
+		}
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "retry:") {
+			lines = append(lines, line)
+		}
+	}
+
+	if lines[0] != "id: 2" || lines[1] != "event: service.status_changed" || !strings.Contains(lines[2], clientID.String()) {
+		t.Errorf("Expected the event of the client's own service, got %v", lines)
+	}
+}
@@ -0,0 +1,56 @@
+package testtracking
+
+import (
+	"strings"
+	"testing"
+
+	"lavanderia/tracking"
+)
+
+func TestNewCode(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		code, err := tracking.NewCode()
+		if err != nil {
+			t.Fatalf("Failed to generate a code: %v", err)
+		}
+		if len(code) != tracking.Length || strings.ContainsAny(code, "ILOU") {
+			t.Fatalf("Expected %d characters without I, L, O or U, got %s", tracking.Length, code)
+		}
+		if normalized, ok := tracking.Normalize(code); !ok || normalized != code {
+			t.Fatalf("Expected %s to be a valid code, got %s", code, normalized)
+		}
+		if seen[code] {
+			t.Fatalf("Expected unique codes, got %s twice", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{input: "7K3QW-M9XZ2", want: "7K3QWM9XZ2", ok: true},
+		{input: "7k3qw m9xz2", want: "7K3QWM9XZ2", ok: true},
+		{input: "oK3QW-M9XZl", want: "0K3QWM9XZ1", ok: true},
+		{input: "7K3QW-M9XZ", ok: false},
+		{input: "7K3QW-M9XZU", ok: false},
+		{input: "7K3QW/M9XZ2", ok: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			got, ok := tracking.Normalize(tc.input)
+			if ok != tc.ok || (ok && got != tc.want) {
+				t.Errorf("Normalize(%q) = %q, %v; want %q, %v", tc.input, got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+
+	if got := tracking.Format("7K3QWM9XZ2"); got != "7K3QW-M9XZ2" {
+		t.Errorf("Expected 7K3QW-M9XZ2, got %s", got)
+	}
+}
@@ -0,0 +1,218 @@
+package testvalidation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lavanderia/apierror"
+	"lavanderia/validation"
+)
+
+type line struct {
+	ItemID   uuid.UUID `json:"item_id" validate:"required"`
+	Quantity int       `json:"quantity" validate:"required,positive"`
+}
+
+type payload struct {
+	Name        string    `json:"name" validate:"required,max=10"`
+	Phone       string    `json:"phone" validate:"phone"`
+	CPF         *string   `json:"cpf" validate:"cpf"`
+	State       string    `json:"state" validate:"uf"`
+	PostalCode  string    `json:"postal_code" validate:"cep"`
+	Status      string    `json:"status" validate:"oneof=open|closed"`
+	IsWeight    bool      `json:"is_weight"`
+	Weight      float64   `json:"weight" validate:"required_if=IsWeight,positive"`
+	RenewalDate string    `json:"renewal_date" validate:"date"`
+	DueDate     time.Time `json:"due_date" validate:"future,max_days=30"`
+	Lines       []line    `json:"lines" validate:"required,dive"`
+	Latitude    *float64  `json:"latitude" validate:"lat"`
+	Longitude   *float64  `json:"longitude" validate:"lng"`
+	Email       string    `json:"email" validate:"email"`
+	Callback    string    `json:"callback" validate:"url"`
+}
+
+func validPayload() payload {
+	cpf := "529.982.247-25"
+	return payload{
+		Name:        "Gabriel",
+		Phone:       "(24) 99854-8386",
+		CPF:         &cpf,
+		State:       "RJ",
+		PostalCode:  "27600-000",
+		Status:      "open",
+		IsWeight:    true,
+		Weight:      2.5,
+		RenewalDate: "2024-02-10",
+		DueDate:     time.Now().Add(48 * time.Hour),
+		Lines:       []line{{ItemID: uuid.New(), Quantity: 1}},
+	}
+}
+
+func TestStruct(t *testing.T) {
+	tests := []struct {
+		name       string
+		modify     func(p *payload)
+		wantFields []string
+		wantCodes  []apierror.Code
+	}{
+		{
+			name:   "Valid payload",
+			modify: func(p *payload) {},
+		},
+		{
+			name: "Optional fields can be omitted",
+			modify: func(p *payload) {
+				p.Phone, p.CPF, p.State, p.PostalCode, p.Status = "", nil, "", "", ""
+				p.IsWeight, p.Weight, p.RenewalDate, p.DueDate = false, 0, "", time.Time{}
+			},
+		},
+		{
+			name: "Every invalid field is reported",
+			modify: func(p *payload) {
+				p.Name = ""
+				p.Phone = "12345"
+				p.State = "XX"
+				p.PostalCode = "2760"
+				p.Lines = nil
+			},
+			wantFields: []string{"name", "phone", "state", "postal_code", "lines"},
+			wantCodes:  []apierror.Code{apierror.Required, apierror.InvalidPhone, apierror.InvalidUF, apierror.InvalidCEP, apierror.Required},
+		},
+		{
+			name: "Length, CPF and status",
+			modify: func(p *payload) {
+				cpf := "111.111.111-11"
+				p.Name = "Gabriel Almeida"
+				p.CPF = &cpf
+				p.Status = "pending"
+			},
+			wantFields: []string{"name", "cpf", "status"},
+			wantCodes:  []apierror.Code{apierror.MaxLength, apierror.InvalidCPF, apierror.OneOf},
+		},
+		{
+			name: "Weight required when charged by weight",
+			modify: func(p *payload) {
+				p.Weight = 0
+			},
+			wantFields: []string{"weight"},
+			wantCodes:  []apierror.Code{apierror.Required},
+		},
+		{
+			name: "Negative weight",
+			modify: func(p *payload) {
+				p.Weight = -1
+			},
+			wantFields: []string{"weight"},
+			wantCodes:  []apierror.Code{apierror.MustBePositive},
+		},
+		{
+			name: "Dates",
+			modify: func(p *payload) {
+				p.RenewalDate = "10/02/2024"
+				p.DueDate = time.Now().AddDate(0, 0, 31)
+			},
+			wantFields: []string{"renewal_date", "due_date"},
+			wantCodes:  []apierror.Code{apierror.InvalidDate, apierror.DateTooFar},
+		},
+		{
+			name: "Date in the past",
+			modify: func(p *payload) {
+				p.DueDate = time.Now().Add(-time.Hour)
+			},
+			wantFields: []string{"due_date"},
+			wantCodes:  []apierror.Code{apierror.DateInPast},
+		},
+		{
+			name: "Coordinates",
+			modify: func(p *payload) {
+				lat, lng := -91.0, 180.0
+				p.Latitude, p.Longitude = &lat, &lng
+			},
+			wantFields: []string{"latitude"},
+			wantCodes:  []apierror.Code{apierror.OutOfRange},
+		},
+		{
+			name: "E-mail",
+			modify: func(p *payload) {
+				p.Email = "Maria <maria@example.com>"
+			},
+			wantFields: []string{"email"},
+			wantCodes:  []apierror.Code{apierror.InvalidEmail},
+		},
+		{
+			name: "URL",
+			modify: func(p *payload) {
+				p.Callback = "ftp://example.com/hook"
+			},
+			wantFields: []string{"callback"},
+			wantCodes:  []apierror.Code{apierror.InvalidURL},
+		},
+		{
+			name: "Slice elements are validated with their index",
+			modify: func(p *payload) {
+				p.Lines = append(p.Lines, line{Quantity: -2})
+			},
+			wantFields: []string{"lines[1].item_id", "lines[1].quantity"},
+			wantCodes:  []apierror.Code{apierror.Required, apierror.MustBePositive},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := validPayload()
+			tc.modify(&p)
+
+			err := validation.Struct(p)
+			if len(tc.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			errs, ok := err.(apierror.FieldErrors)
+			if !ok {
+				t.Fatalf("Expected apierror.FieldErrors, got %T", err)
+			}
+			if len(errs) != len(tc.wantFields) {
+				t.Fatalf("Expected errors for %v, got %+v", tc.wantFields, errs)
+			}
+			for i := range errs {
+				if errs[i].Field != tc.wantFields[i] || errs[i].Code != tc.wantCodes[i] {
+					t.Errorf("Expected %s %s, got %s %s", tc.wantFields[i], tc.wantCodes[i], errs[i].Field, errs[i].Code)
+				}
+			}
+		})
+	}
+}
+
+func TestPhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  bool
+		wantN string
+	}{
+		{phone: "24998548386", want: true, wantN: "24998548386"},
+		{phone: "(24) 2453-1234", want: true, wantN: "2424531234"},
+		{phone: "+55 24 99854-8386", want: true, wantN: "24998548386"},
+		{phone: "998548386", want: false},
+		{phone: "(24) 8854-8386 ramal", want: false},
+		{phone: "24898548386", want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.phone, func(t *testing.T) {
+			err := validation.Struct(struct {
+				Phone string `json:"phone" validate:"phone"`
+			}{tc.phone})
+			if (err == nil) != tc.want {
+				t.Fatalf("Expected valid=%v, got %v", tc.want, err)
+			}
+			if tc.want && validation.NationalPhone(tc.phone) != tc.wantN {
+				t.Errorf("Expected national number %s, got %s", tc.wantN, validation.NationalPhone(tc.phone))
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package testwallet
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+
+	"lavanderia/wallet"
+)
+
+func TestBalanced(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	cases := []struct {
+		entries []wallet.Entry
+		want    bool
+	}{
+		{[]wallet.Entry{{AccountID: a, Amount: 50}, {AccountID: b, Amount: -50}}, true},
+		{[]wallet.Entry{{AccountID: a, Amount: 0.1}, {AccountID: b, Amount: 0.2}, {AccountID: c, Amount: -0.3}}, true},
+		{[]wallet.Entry{{AccountID: a, Amount: 50}, {AccountID: b, Amount: -49.99}}, false},
+		{[]wallet.Entry{{AccountID: a, Amount: 10}}, false},
+		{nil, true},
+	}
+
+	for _, c := range cases {
+		if got := wallet.Balanced(c.entries); got != c.want {
+			t.Errorf("Balanced(%v) = %v, want %v", c.entries, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package testwebhooks
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"lavanderia/webhooks"
+)
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"type":"service.paid"}`)
+	now := time.Now()
+	header := webhooks.Sign("whsec_test", now, body)
+
+	if !strings.HasPrefix(header, "t=") || !strings.Contains(header, ",v1=") {
+		t.Fatalf("Expected t=<time>,v1=<hmac>, got %s", header)
+	}
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		wantErr bool
+	}{
+		{name: "Valid", secret: "whsec_test", header: header, body: body},
+		{name: "Other secret", secret: "whsec_other", header: header, body: body, wantErr: true},
+		{name: "Tampered body", secret: "whsec_test", header: header, body: []byte(`{"type":"service.created"}`), wantErr: true},
+		{name: "Too old", secret: "whsec_test", header: webhooks.Sign("whsec_test", now.Add(-time.Hour), body), body: body, wantErr: true},
+		{name: "Malformed", secret: "whsec_test", header: "v1=abc", body: body, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := webhooks.Verify(tc.secret, tc.header, tc.body, 5*time.Minute)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := webhooks.NewSecret()
+	if err != nil {
+		t.Fatalf("Failed to generate a secret: %v", err)
+	}
+	b, _ := webhooks.NewSecret()
+	if a == b || len(a) != len("whsec_")+64 {
+		t.Errorf("Expected two different 32-byte secrets, got %s and %s", a, b)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := map[int]time.Duration{
+		1:  30 * time.Second,
+		3:  2 * time.Minute,
+		30: 12 * time.Hour,
+	}
+
+	for attempt, want := range tests {
+		if got := webhooks.RetryDelay(attempt); got != want {
+			t.Errorf("RetryDelay(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
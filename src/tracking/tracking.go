@@ -0,0 +1,58 @@
+// Package tracking makes the codes printed on receipts that let clients
+// follow their orders without logging in
+package tracking
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// Length is the number of characters of a code
+const Length = 10
+
+// alphabet is Crockford's base 32: digits and letters without I, L, O and U,
+// which are easily confused or misread. A code carries 50 random bits.
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewCode returns a random code
+func NewCode() (string, error) {
+	b := make([]byte, Length)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = alphabet[b[i]%32]
+	}
+	return string(b), nil
+}
+
+// Format splits a code in two groups for printing, as ABCDE-FGHJK
+func Format(code string) string {
+	if len(code) != Length {
+		return code
+	}
+	return code[:Length/2] + "-" + code[Length/2:]
+}
+
+// Normalize returns the code as stored from the way a person typed it:
+// upper case, without separators, and with the letters that look like
+// digits read as those digits. It returns false when the result can't be a
+// code.
+func Normalize(s string) (string, bool) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(s) {
+		switch r {
+		case '-', ' ', '.':
+			continue
+		case 'O':
+			r = '0'
+		case 'I', 'L':
+			r = '1'
+		}
+		if !strings.ContainsRune(alphabet, r) {
+			return "", false
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), b.Len() == Length
+}
@@ -0,0 +1,184 @@
+package validation
+
+import (
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"lavanderia/apierror"
+)
+
+const dateLayout = "2006-01-02"
+
+// states are the Brazilian federative units accepted by the uf rule
+var states = map[string]bool{
+	"AC": true, "AL": true, "AP": true, "AM": true, "BA": true, "CE": true,
+	"DF": true, "ES": true, "GO": true, "MA": true, "MT": true, "MS": true,
+	"MG": true, "PA": true, "PB": true, "PR": true, "PE": true, "PI": true,
+	"RJ": true, "RN": true, "RS": true, "RO": true, "RR": true, "SC": true,
+	"SP": true, "SE": true, "TO": true,
+}
+
+func positive(v reflect.Value, _ reflect.Value, _ string) (apierror.Code, []string, bool) {
+	n, ok := toFloat(v)
+	return apierror.MustBePositive, nil, ok && n > 0
+}
+
+func maxLength(v reflect.Value, _ reflect.Value, param string) (apierror.Code, []string, bool) {
+	return apierror.MaxLength, []string{"max", param}, len([]rune(v.String())) <= atoi(param)
+}
+
+func oneOf(v reflect.Value, _ reflect.Value, param string) (apierror.Code, []string, bool) {
+	values := strings.Split(param, "|")
+	for _, value := range values {
+		if v.String() == value {
+			return "", nil, true
+		}
+	}
+	return apierror.OneOf, []string{"values", strings.Join(values, ", ")}, false
+}
+
+// cep accepts a postal code written as 00000000 or 00000-000
+func cep(v reflect.Value, _ reflect.Value, _ string) (apierror.Code, []string, bool) {
+	s := v.String()
+	valid := len(Digits(s)) == 8 && (len(s) == 8 || (len(s) == 9 && s[5] == '-'))
+	return apierror.InvalidCEP, nil, valid
+}
+
+func uf(v reflect.Value, _ reflect.Value, _ string) (apierror.Code, []string, bool) {
+	return apierror.InvalidUF, nil, states[v.String()]
+}
+
+// phone accepts a Brazilian number with area code, landline or mobile,
+// ignoring formatting and an optional +55 country code
+func phone(v reflect.Value, _ reflect.Value, _ string) (apierror.Code, []string, bool) {
+	s := v.String()
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789()-+ ", r) {
+			return apierror.InvalidPhone, nil, false
+		}
+	}
+
+	number := NationalPhone(s)
+
+	switch len(number) {
+	case 10:
+		return apierror.InvalidPhone, nil, number[0] != '0' && number[2] >= '2' && number[2] <= '5'
+	case 11:
+		return apierror.InvalidPhone, nil, number[0] != '0' && number[2] == '9'
+	}
+	return apierror.InvalidPhone, nil, false
+}
+
+// cpf checks the length and both check digits of a CPF, formatted or not
+func cpf(v reflect.Value, _ reflect.Value, _ string) (apierror.Code, []string, bool) {
+	number := Digits(v.String())
+	if len(number) != 11 || strings.Count(number, number[:1]) == 11 {
+		return apierror.InvalidCPF, nil, false
+	}
+
+	for _, size := range []int{9, 10} {
+		sum := 0
+		for i := 0; i < size; i++ {
+			sum += int(number[i]-'0') * (size + 1 - i)
+		}
+		check := sum * 10 % 11 % 10
+		if check != int(number[size]-'0') {
+			return apierror.InvalidCPF, nil, false
+		}
+	}
+
+	return "", nil, true
+}
+
+// email accepts a bare address such as maria@example.com, without a
+// display name
+func email(v reflect.Value, _ reflect.Value, _ string) (apierror.Code, []string, bool) {
+	address, err := mail.ParseAddress(v.String())
+	return apierror.InvalidEmail, nil, err == nil && address.Address == v.String()
+}
+
+// absoluteURL accepts an http or https URL with a host
+func absoluteURL(v reflect.Value, _ reflect.Value, _ string) (apierror.Code, []string, bool) {
+	u, err := url.Parse(v.String())
+	return apierror.InvalidURL, nil, err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// NationalPhone returns the digits of a Brazilian phone number without
+// formatting or the 55 country code
+func NationalPhone(s string) string {
+	number := Digits(s)
+	if strings.HasPrefix(strings.TrimSpace(s), "+55") || (len(number) > 11 && strings.HasPrefix(number, "55")) {
+		return number[2:]
+	}
+	return number
+}
+
+// latitude and longitude accept coordinates in decimal degrees
+func latitude(v reflect.Value, _ reflect.Value, _ string) (apierror.Code, []string, bool) {
+	n, ok := toFloat(v)
+	return apierror.OutOfRange, []string{"min", "-90", "max", "90"}, ok && n >= -90 && n <= 90
+}
+
+func longitude(v reflect.Value, _ reflect.Value, _ string) (apierror.Code, []string, bool) {
+	n, ok := toFloat(v)
+	return apierror.OutOfRange, []string{"min", "-180", "max", "180"}, ok && n >= -180 && n <= 180
+}
+
+func date(v reflect.Value, _ reflect.Value, _ string) (apierror.Code, []string, bool) {
+	_, ok := toTime(v)
+	return apierror.InvalidDate, nil, ok
+}
+
+// clock accepts a time of day written as HH:MM
+func clock(v reflect.Value, _ reflect.Value, _ string) (apierror.Code, []string, bool) {
+	_, err := time.Parse("15:04", v.String())
+	return apierror.InvalidTime, nil, err == nil
+}
+
+// future rejects dates before the current time. Dates without a time of
+// day are compared against the start of today.
+func future(v reflect.Value, _ reflect.Value, _ string) (apierror.Code, []string, bool) {
+	t, ok := toTime(v)
+	if !ok {
+		return apierror.InvalidDate, nil, false
+	}
+
+	limit := time.Now()
+	if v.Kind() == reflect.String {
+		limit = startOfDay(limit)
+	}
+	return apierror.DateInPast, []string{"date", t.Format(dateLayout)}, !t.Before(limit)
+}
+
+// maxDays rejects dates more than param days after the current time
+func maxDays(v reflect.Value, _ reflect.Value, param string) (apierror.Code, []string, bool) {
+	t, ok := toTime(v)
+	if !ok {
+		return apierror.InvalidDate, nil, false
+	}
+
+	limit := time.Now().AddDate(0, 0, atoi(param))
+	return apierror.DateTooFar, []string{"date", t.Format(dateLayout), "days", param}, !t.After(limit)
+}
+
+func toTime(v reflect.Value) (time.Time, bool) {
+	switch value := v.Interface().(type) {
+	case time.Time:
+		return value, true
+	case string:
+		t, err := time.ParseInLocation(dateLayout, value, time.Local)
+		if err != nil {
+			t, err = time.Parse(time.RFC3339, value)
+		}
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
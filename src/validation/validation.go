@@ -0,0 +1,179 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"lavanderia/apierror"
+)
+
+// rule checks a single field value. It returns the error code and its
+// message parameters when the value is invalid.
+type rule func(field reflect.Value, parent reflect.Value, param string) (apierror.Code, []string, bool)
+
+// rules maps the names used in `validate` struct tags to their checks.
+// Every rule except required and required_if skips zero values, so optional
+// fields are only checked when they are sent.
+var rules = map[string]rule{
+	"positive": positive,
+	"max":      maxLength,
+	"oneof":    oneOf,
+	"cep":      cep,
+	"uf":       uf,
+	"phone":    phone,
+	"cpf":      cpf,
+	"date":     date,
+	"clock":    clock,
+	"future":   future,
+	"max_days": maxDays,
+	"lat":      latitude,
+	"lng":      longitude,
+	"email":    email,
+	"url":      absoluteURL,
+}
+
+// Struct validates v using its `validate` struct tags and returns every
+// invalid field at once as apierror.FieldErrors, or nil when v is valid.
+//
+// Supported rules: required, required_if=<BoolField>, positive, max=<n>,
+// oneof=<a|b>, cep, uf, phone, cpf, email, url, date, clock, future,
+// max_days=<n>, lat, lng and dive (validates each element of a slice of structs).
+func Struct(v interface{}) error {
+	errs := validateStruct(reflect.ValueOf(v), "")
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateStruct(v reflect.Value, prefix string) apierror.FieldErrors {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs apierror.FieldErrors
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		field := v.Field(i)
+
+		if sf.Anonymous {
+			errs = append(errs, validateStruct(field, prefix)...)
+			continue
+		}
+
+		tag := sf.Tag.Get("validate")
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		name := prefix + fieldName(sf)
+		errs = append(errs, validateField(field, v, name, tag)...)
+	}
+
+	return errs
+}
+
+func validateField(field reflect.Value, parent reflect.Value, name string, tag string) apierror.FieldErrors {
+	for _, r := range strings.Split(tag, ",") {
+		ruleName, param, _ := strings.Cut(r, "=")
+
+		switch ruleName {
+		case "required":
+			if isZero(field) {
+				return apierror.FieldErrors{apierror.Field(name, apierror.Required)}
+			}
+			continue
+		case "required_if":
+			condition := parent.FieldByName(param)
+			if condition.IsValid() && condition.Kind() == reflect.Bool && condition.Bool() && isZero(field) {
+				return apierror.FieldErrors{apierror.Field(name, apierror.Required)}
+			}
+			continue
+		case "dive":
+			var errs apierror.FieldErrors
+			for i := 0; i < field.Len(); i++ {
+				errs = append(errs, validateStruct(field.Index(i), fmt.Sprintf("%s[%d].", name, i))...)
+			}
+			return errs
+		}
+
+		check, ok := rules[ruleName]
+		if !ok {
+			panic(fmt.Sprintf("validation: unknown rule %q on field %s", ruleName, name))
+		}
+		if isZero(field) {
+			continue
+		}
+		if code, params, valid := check(indirect(field), parent, param); !valid {
+			return apierror.FieldErrors{apierror.Field(name, code, params...)}
+		}
+	}
+
+	return nil
+}
+
+func fieldName(sf reflect.StructField) string {
+	name := strings.Split(sf.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+	return name
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil() || isZero(v.Elem())
+	}
+	return v.IsZero()
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	return v
+}
+
+// Digits strips formatting from documents, postal codes and phone numbers
+// so they are stored the same way regardless of how they were typed
+func Digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func toFloat(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func atoi(param string) int {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		panic(fmt.Sprintf("validation: invalid rule parameter %q", param))
+	}
+	return n
+}
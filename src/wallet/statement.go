@@ -0,0 +1,50 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// StatementLine is a transaction of a wallet, with the balance it left
+type StatementLine struct {
+	TransactionID uuid.UUID  `json:"transaction_id" db:"transaction_id"`
+	Type          string     `json:"type" db:"type"`
+	Amount        float64    `json:"amount" db:"amount"`
+	Balance       float64    `json:"balance" db:"balance"`
+	Reason        string     `json:"reason" db:"reason"`
+	ServiceID     *uuid.UUID `json:"service_id" db:"service_id"`
+	IncidentID    *uuid.UUID `json:"incident_id" db:"incident_id"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Statement returns the balance of the wallet of a client before from and
+// its transactions from from until to, the newest first
+func Statement(db sqlx.Queryer, clientID uuid.UUID, from, to time.Time) (float64, []StatementLine, error) {
+	var opening float64
+	err := sqlx.Get(db, &opening, `
+		SELECT COALESCE(SUM(e.amount), 0)
+		FROM wallet_entries e
+		JOIN wallet_accounts a ON a.id = e.account_id
+		JOIN wallet_transactions t ON t.id = e.transaction_id
+		WHERE a.client_id = $1 AND t.created_at < $2`, clientID, from)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	lines := []StatementLine{}
+	err = sqlx.Select(db, &lines, `
+		SELECT transaction_id, type, amount, balance, reason, service_id, incident_id, created_at FROM (
+			SELECT t.id AS transaction_id, t.type, e.amount,
+			       SUM(e.amount) OVER (ORDER BY e.id) AS balance,
+			       t.reason, t.laundry_service_id AS service_id, t.incident_id, t.created_at, e.id AS entry_id
+			FROM wallet_entries e
+			JOIN wallet_accounts a ON a.id = e.account_id
+			JOIN wallet_transactions t ON t.id = e.transaction_id
+			WHERE a.client_id = $1 AND t.created_at < $3
+		) s
+		WHERE s.created_at >= $2
+		ORDER BY s.entry_id DESC`, clientID, from, to)
+	return opening, lines, err
+}
@@ -0,0 +1,164 @@
+// Package wallet keeps the credit clients have with the laundry, from
+// prepaid top-ups and incident compensations, as a double-entry ledger:
+// every movement of a client's wallet is balanced by an opposite entry on
+// a system account, so the ledger always sums to zero.
+package wallet
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Types of transaction
+const (
+	TopUp      = "top_up"
+	Payment    = "payment"
+	Credit     = "credit"
+	Adjustment = "adjustment"
+)
+
+// Types lists the types of transaction
+var Types = []string{TopUp, Payment, Credit, Adjustment}
+
+// System accounts, the counterparts of the client wallets
+const (
+	Cash         = "cash"         // money received for top-ups
+	Sales        = "sales"        // services paid from wallets
+	Compensation = "compensation" // incidents credited to wallets
+	Adjustments  = "adjustments"  // corrections made by the staff
+)
+
+// counterparts are the system accounts balancing each type of transaction
+var counterparts = map[string]string{
+	TopUp:      Cash,
+	Payment:    Sales,
+	Credit:     Compensation,
+	Adjustment: Adjustments,
+}
+
+// ErrInsufficientBalance is returned when a transaction would take more
+// than the balance of the wallet
+var ErrInsufficientBalance = errors.New("wallet: insufficient balance")
+
+// ErrUnbalanced is returned when the entries of a transaction don't sum to
+// zero
+var ErrUnbalanced = errors.New("wallet: entries don't sum to zero")
+
+// Transaction is a movement of the wallet of a client. Amount is what is
+// added to the wallet; payments take from it with a negative amount.
+type Transaction struct {
+	Type       string
+	ClientID   uuid.UUID
+	Amount     float64
+	ServiceID  *uuid.UUID
+	IncidentID *uuid.UUID
+	Reason     string
+	CreatedBy  *uuid.UUID
+}
+
+// Entry is a line of the ledger: an amount added to an account
+type Entry struct {
+	AccountID uuid.UUID
+	Amount    float64
+}
+
+// Post records the transaction with an entry on the client's wallet and
+// the opposite one on the system account of its type, and returns its id.
+// The wallet is created on its first transaction.
+func Post(tx *sqlx.Tx, t Transaction) (uuid.UUID, error) {
+	amount := round(t.Amount)
+	if amount == 0 {
+		return uuid.Nil, errors.New("wallet: empty transaction")
+	}
+
+	walletID, balance, err := account(tx, t.ClientID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if round(balance+amount) < 0 {
+		return uuid.Nil, ErrInsufficientBalance
+	}
+
+	var counterpartID uuid.UUID
+	err = tx.Get(&counterpartID, "SELECT id FROM wallet_accounts WHERE code = $1", counterparts[t.Type])
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var transactionID uuid.UUID
+	err = tx.Get(&transactionID, `
+		INSERT INTO wallet_transactions (type, client_id, laundry_service_id, incident_id, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`,
+		t.Type, t.ClientID, t.ServiceID, t.IncidentID, t.Reason, t.CreatedBy)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = post(tx, transactionID, []Entry{{walletID, amount}, {counterpartID, -amount}})
+	return transactionID, err
+}
+
+// Balance returns the balance of the wallet of a client, zero when it has
+// none yet
+func Balance(db sqlx.Queryer, clientID uuid.UUID) (float64, error) {
+	var balance float64
+	err := sqlx.Get(db, &balance, "SELECT balance FROM wallet_accounts WHERE client_id = $1", clientID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return balance, err
+}
+
+// post writes the entries of a transaction and moves the balances of their
+// accounts
+func post(tx *sqlx.Tx, transactionID uuid.UUID, entries []Entry) error {
+	if !Balanced(entries) {
+		return ErrUnbalanced
+	}
+	for _, e := range entries {
+		_, err := tx.Exec("INSERT INTO wallet_entries (transaction_id, account_id, amount) VALUES ($1, $2, $3)", transactionID, e.AccountID, e.Amount)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE wallet_accounts SET balance = balance + $1 WHERE id = $2", e.Amount, e.AccountID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Balanced tells whether the entries sum to zero, to the cent
+func Balanced(entries []Entry) bool {
+	var cents int64
+	for _, e := range entries {
+		cents += int64(math.Round(e.Amount * 100))
+	}
+	return cents == 0
+}
+
+// account returns the wallet account of the client, locked until the end
+// of tx, creating it on the first transaction
+func account(tx *sqlx.Tx, clientID uuid.UUID) (uuid.UUID, float64, error) {
+	_, err := tx.Exec("INSERT INTO wallet_accounts (client_id) VALUES ($1) ON CONFLICT (client_id) DO NOTHING", clientID)
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+
+	var wallet struct {
+		ID      uuid.UUID `db:"id"`
+		Balance float64   `db:"balance"`
+	}
+	err = tx.Get(&wallet, "SELECT id, balance FROM wallet_accounts WHERE client_id = $1 FOR UPDATE", clientID)
+	return wallet.ID, wallet.Balance, err
+}
+
+// round keeps the cents of an amount
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,95 @@
+// Package webhooks sends the domain events to the endpoints registered by
+// the admins. Each request body is signed with the webhook's secret so the
+// receiver can check it came from us.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lavanderia/events"
+)
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-Lavanderia-Signature"
+	EventHeader     = "X-Lavanderia-Event"
+	DeliveryHeader  = "X-Lavanderia-Delivery"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Payload is the body posted to a webhook
+type Payload struct {
+	DeliveryID uuid.UUID       `json:"delivery_id"`
+	EventID    int64           `json:"event_id"`
+	Type       events.Type     `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// NewSecret returns a random secret to sign the deliveries of a webhook
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature header of body sent at t: the Unix time and
+// the hex HMAC-SHA256 of "<time>.<body>", as "t=<time>,v1=<hmac>". Signing
+// the time lets receivers reject old requests replayed later.
+func Sign(secret string, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + mac(secret, timestamp, body)
+}
+
+// Verify checks a signature header made by Sign, rejecting it when it is
+// older than tolerance. Receivers written in Go can use it as is.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return fmt.Errorf("webhooks: malformed signature header")
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("webhooks: signature is too old")
+	}
+	if !hmac.Equal([]byte(signature), []byte(mac(secret, timestamp, body))) {
+		return fmt.Errorf("webhooks: signature does not match")
+	}
+	return nil
+}
+
+func mac(secret, timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
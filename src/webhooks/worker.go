@@ -0,0 +1,201 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"lavanderia/events"
+)
+
+// maxResponseBody is how much of each response is kept in the delivery log
+const maxResponseBody = 1024
+
+// Subscriber returns the events handler that queues a delivery of each
+// event to the active webhooks subscribed to its type. Queueing the same
+// event twice does nothing, so redelivered events aren't posted twice.
+func Subscriber(db *sqlx.DB) events.Handler {
+	return func(ctx context.Context, event events.Event) error {
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO webhook_deliveries (webhook_id, event_id, event_type)
+			SELECT id, $1, $2 FROM webhooks WHERE is_active AND $2 = ANY(event_types)
+			ON CONFLICT (webhook_id, event_id) DO NOTHING`, event.ID, string(event.Type))
+		return err
+	}
+}
+
+// Worker posts the queued deliveries to the webhooks
+type Worker struct {
+	DB     *sqlx.DB
+	Client *http.Client // defaults to a client with a 10 second timeout
+
+	Interval    time.Duration // between polls, defaults to 10 seconds
+	BatchSize   int           // deliveries posted per poll, defaults to 20
+	MaxAttempts int           // before a delivery is marked failed, defaults to 8
+}
+
+// RetryDelay is how long to wait before the next attempt after attempt
+// failed ones: 30 seconds doubling up to 12 hours
+func RetryDelay(attempt int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempt && delay < 12*time.Hour; i++ {
+		delay *= 2
+	}
+	return min(delay, 12*time.Hour)
+}
+
+// Run posts the queued deliveries until ctx is done
+func (w *Worker) Run(ctx context.Context) {
+	w.defaults()
+
+	poll := time.NewTicker(w.Interval)
+	defer poll.Stop()
+
+	for {
+		for {
+			sent, err := w.Deliver(ctx)
+			if err != nil {
+				log.Printf("webhooks: delivering: %v", err)
+			}
+			if sent < w.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		}
+	}
+}
+
+func (w *Worker) defaults() {
+	if w.Client == nil {
+		w.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if w.Interval <= 0 {
+		w.Interval = 10 * time.Second
+	}
+	if w.BatchSize <= 0 {
+		w.BatchSize = 20
+	}
+	if w.MaxAttempts <= 0 {
+		w.MaxAttempts = 8
+	}
+}
+
+// deliveryRow is a delivery claimed for sending, with its webhook and event
+type deliveryRow struct {
+	ID         uuid.UUID   `db:"id"`
+	Attempts   int         `db:"attempts"`
+	URL        string      `db:"url"`
+	Secret     string      `db:"secret"`
+	EventID    int64       `db:"event_id"`
+	EventType  events.Type `db:"event_type"`
+	OccurredAt time.Time   `db:"occurred_at"`
+	Data       string      `db:"data"`
+}
+
+// Deliver posts one batch of due deliveries of active webhooks and returns
+// how many were attempted. Rows are locked while being sent, so several
+// workers can run at once without posting a delivery twice.
+func (w *Worker) Deliver(ctx context.Context) (int, error) {
+	w.defaults()
+
+	tx, err := w.DB.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	// Rolls back on the early returns; a no-op after Commit
+	defer tx.Rollback()
+
+	rows := []deliveryRow{}
+	err = tx.Select(&rows, `
+		SELECT wd.id, wd.attempts, wh.url, wh.secret, wd.event_id, wd.event_type, de.occurred_at, de.payload::text AS data
+		FROM webhook_deliveries wd
+		JOIN webhooks wh ON wh.id = wd.webhook_id
+		JOIN domain_events de ON de.id = wd.event_id
+		WHERE wd.status = $1 AND wd.next_attempt_at <= NOW() AND wh.is_active
+		ORDER BY wd.next_attempt_at, wd.event_id
+		LIMIT $2
+		FOR UPDATE OF wd SKIP LOCKED`, StatusPending, w.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, row := range rows {
+		code, body, sendErr := w.post(ctx, row)
+		attempts := row.Attempts + 1
+		if sendErr == nil {
+			_, err = tx.Exec(`
+				UPDATE webhook_deliveries SET status = $1, attempts = $2, response_code = $3, response_body = $4, last_error = NULL, delivered_at = NOW()
+				WHERE id = $5`, StatusSucceeded, attempts, code, body, row.ID)
+		} else {
+			status := StatusPending
+			if attempts >= w.MaxAttempts {
+				status = StatusFailed
+			}
+			var responseCode interface{}
+			if code != 0 {
+				responseCode = code
+			}
+			_, err = tx.Exec(`
+				UPDATE webhook_deliveries SET status = $1, attempts = $2, next_attempt_at = $3, response_code = $4, response_body = $5, last_error = $6
+				WHERE id = $7`, status, attempts, time.Now().Add(RetryDelay(attempts)), responseCode, body, sendErr.Error(), row.ID)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(rows), tx.Commit()
+}
+
+// post sends a delivery and returns the response code and the start of the
+// response body. Any status other than 2xx is an error.
+func (w *Worker) post(ctx context.Context, row deliveryRow) (int, string, error) {
+	body, err := json.Marshal(Payload{
+		DeliveryID: row.ID,
+		EventID:    row.EventID,
+		Type:       row.EventType,
+		OccurredAt: row.OccurredAt,
+		Data:       json.RawMessage(row.Data),
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, row.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Lavanderia-Webhooks/1.0")
+	req.Header.Set(EventHeader, string(row.EventType))
+	req.Header.Set(DeliveryHeader, row.ID.String())
+	req.Header.Set(SignatureHeader, Sign(row.Secret, time.Now(), body))
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	// Kept as text, so drop what Postgres can't store
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	text := strings.ReplaceAll(strings.ToValidUTF8(string(response), ""), "\x00", "")
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, text, fmt.Errorf("webhooks: %s responded %s", row.URL, resp.Status)
+	}
+	return resp.StatusCode, text, nil
+}